@@ -0,0 +1,155 @@
+// Package cacheset
+//
+// Path: audit.go
+//
+// Description: audit.go contains the audit log of the last mutations of a cache.
+package cacheset
+
+import "time"
+
+// MutationOp is the kind of a Mutation
+type MutationOp int
+
+const (
+	// MutationAdd means that the element was added or its expiration was changed
+	MutationAdd MutationOp = iota
+	// MutationRemove means that the element was removed, see the Reason of the mutation
+	MutationRemove
+	// MutationClear means that all elements were removed with Clear
+	MutationClear
+)
+
+// String returns the name of the mutation kind
+func (op MutationOp) String() string {
+	switch op {
+	case MutationAdd:
+		return "add"
+	case MutationRemove:
+		return "remove"
+	case MutationClear:
+		return "clear"
+	default:
+		return "unknown"
+	}
+}
+
+// Mutation is a change recorded by the audit log of a cache
+type Mutation[T comparable] struct {
+	Op     MutationOp    // Op is the kind of change
+	Elem   T             // Elem is the added or removed element, the zero value for MutationClear
+	Reason RemovalReason // Reason is the reason of a MutationRemove
+	At     time.Time     // At is the time of the change
+	Actor  string        // Actor is the label given to As by the caller, empty for the other callers and the cleaner
+}
+
+// WithAuditLog records the last n mutations of the cache, returned by RecentMutations
+//
+// Description: The mutations made through the handle returned by As are labeled with its actor, so
+// that the question "who deleted this element?" can be answered during an incident review.
+// An eviction or a removal caused by a labeled mutation carries its label too.
+func WithAuditLog(n int) Option {
+	return func(o *options) {
+		o.auditLog = max(n, 0)
+	}
+}
+
+// RecentMutations returns the mutations recorded by the audit log, oldest first, nil without WithAuditLog
+func (c *Cache[T]) RecentMutations() []Mutation[T] {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.auditLog == nil {
+		return nil
+	}
+	return c.auditLog.list()
+}
+
+// audit records a mutation in the audit log, the cache must be locked
+func (c *Cache[T]) audit(op MutationOp, elem T, reason RemovalReason) {
+	if c.auditLog == nil {
+		return
+	}
+	c.auditLog.record(Mutation[T]{Op: op, Elem: elem, Reason: reason, At: time.Now(), Actor: c.actor})
+}
+
+// act labels the mutations with the given actor until the returned function is called, the cache must be locked
+func (c *Cache[T]) act(actor string) func() {
+	c.actor = actor
+	return func() {
+		c.actor = ""
+	}
+}
+
+// auditLog is a ring buffer of the last mutations
+type auditLog[T comparable] struct {
+	mutations []Mutation[T] // mutations are the recorded mutations
+	next      int           // next is the index of the next recorded mutation
+	full      bool          // full is true once the buffer wrapped around
+}
+
+// newAuditLog returns an audit log recording the last n mutations
+func newAuditLog[T comparable](n int) *auditLog[T] {
+	return &auditLog[T]{mutations: make([]Mutation[T], n)}
+}
+
+// record adds the given mutation, overwriting the oldest one if the buffer is full
+func (l *auditLog[T]) record(m Mutation[T]) {
+	l.mutations[l.next] = m
+	l.next = (l.next + 1) % len(l.mutations)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// list returns the recorded mutations, oldest first
+func (l *auditLog[T]) list() []Mutation[T] {
+	if !l.full {
+		return append([]Mutation[T](nil), l.mutations[:l.next]...)
+	}
+	return append(append([]Mutation[T](nil), l.mutations[l.next:]...), l.mutations[:l.next]...)
+}
+
+// Actor is a handle on a cache labeling its mutations in the audit log
+type Actor[T comparable] struct {
+	c     *Cache[T] // c is the cache
+	label string    // label identifies the caller
+}
+
+// As returns a handle on the cache labeling the mutations made through it with the given actor
+//
+//	c.As("billing-job").Delete("user:42")
+func (c *Cache[T]) As(actor string) Actor[T] {
+	return Actor[T]{c: c, label: actor}
+}
+
+// Add adds the given element to the cache like Cache.Add
+func (a Actor[T]) Add(elem T, duration time.Duration) error {
+	_, _, err := a.c.addReport(elem, duration, 0, a.label)
+	return err
+}
+
+// AddWithIdle adds the given element to the cache like Cache.AddWithIdle
+func (a Actor[T]) AddWithIdle(elem T, ttl, maxIdle time.Duration) error {
+	_, _, err := a.c.addReport(elem, ttl, maxIdle, a.label)
+	return err
+}
+
+// Delete removes the given element from the cache like Cache.Delete
+func (a Actor[T]) Delete(elem T) {
+	a.c.deleteAs(elem, a.label)
+}
+
+// TryDelete removes the given element from the cache like Cache.TryDelete
+func (a Actor[T]) TryDelete(elem T) bool {
+	return a.c.deleteAs(elem, a.label)
+}
+
+// DeleteFunc removes the elements for which pred returns true like Cache.DeleteFunc
+func (a Actor[T]) DeleteFunc(pred func(T) bool) int {
+	return a.c.deleteFuncAs(pred, a.label)
+}
+
+// Clear removes all elements like Cache.Clear
+func (a Actor[T]) Clear() {
+	a.c.clearAs(a.label)
+}
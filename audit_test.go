@@ -0,0 +1,58 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_RecentMutations(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		c := New[string](time.Minute)
+		defer c.Close()
+		c.Add("a", 0)
+		if got := c.RecentMutations(); got != nil {
+			t.Errorf("RecentMutations() = %v, want nil", got)
+		}
+	})
+
+	t.Run("actors", func(t *testing.T) {
+		c := New[string](time.Minute, WithAuditLog(8))
+		defer c.Close()
+		c.Add("a", 0)
+		c.As("billing").Add("b", 0)
+		c.As("janitor").Delete("a")
+		c.As("admin").Clear()
+
+		want := []Mutation[string]{
+			{Op: MutationAdd, Elem: "a"},
+			{Op: MutationAdd, Elem: "b", Actor: "billing"},
+			{Op: MutationRemove, Elem: "a", Reason: RemovalDeleted, Actor: "janitor"},
+			{Op: MutationClear, Actor: "admin"},
+		}
+		got := c.RecentMutations()
+		if len(got) != len(want) {
+			t.Fatalf("RecentMutations() = %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i].At.IsZero() {
+				t.Errorf("RecentMutations()[%d].At is zero", i)
+			}
+			got[i].At = time.Time{}
+			if got[i] != want[i] {
+				t.Errorf("RecentMutations()[%d] = %v, want %v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("ring", func(t *testing.T) {
+		c := New[int](time.Minute, WithAuditLog(3))
+		defer c.Close()
+		for i := range 5 {
+			c.Add(i, 0)
+		}
+		got := c.RecentMutations()
+		if len(got) != 3 || got[0].Elem != 2 || got[2].Elem != 4 {
+			t.Errorf("RecentMutations() = %v, want the elements 2, 3 and 4", got)
+		}
+	})
+}
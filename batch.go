@@ -0,0 +1,95 @@
+// Package cacheset
+//
+// Path: batch.go
+//
+// Description: batch.go contains the membership checks of several elements under a single lock.
+package cacheset
+
+import "maps"
+
+// ContainsBatch returns for each given element whether it is in the cache
+//
+// Description: The whole batch is answered under a single read lock. Every element is
+// recorded as an access, as if Contains was called for each of them.
+func (c *Cache[T]) ContainsBatch(elems []T) []bool {
+	found := make([]bool, len(elems))
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for i, elem := range elems {
+		found[i] = c.contains(elem)
+	}
+	return found
+}
+
+// InfoBatch returns the description of each given element that is in the cache and has not expired
+//
+// Description: The whole batch is described under a single read lock. Unlike Lookup, InfoBatch is meant for
+// the administration tools: it does not count as an access to the elements, nor as hits or misses.
+func (c *Cache[T]) InfoBatch(elems []T) map[T]EntryInfo[T] {
+	infos := make(map[T]EntryInfo[T], len(elems))
+
+	c.RLock()
+	defer c.RUnlock()
+
+	now := nanotime()
+	for _, elem := range elems {
+		e, ok := c.set.Get(elem)
+		if !ok || e.expired(now) {
+			continue
+		}
+		infos[elem] = EntryInfo[T]{
+			Entry:    newLookupEntry(elem, e),
+			Metadata: c.metadata(elem, now),
+			Pinned:   c.pinned(elem),
+			Version:  c.versions[elem],
+		}
+	}
+	return infos
+}
+
+// ContainsAny returns true if at least one of the given elements is in the cache
+//
+// Description: The elements are checked in order under a single read lock, stopping at the first one found.
+func (c *Cache[T]) ContainsAny(elems []T) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, elem := range elems {
+		if c.contains(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAll returns true if all the given elements are in the cache
+//
+// Description: The elements are checked in order under a single read lock, stopping at the first one missing.
+func (c *Cache[T]) ContainsAll(elems []T) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, elem := range elems {
+		if !c.contains(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// InfoBatch returns the description of each given element that is in its shard, one shard at a time,
+// see Cache.InfoBatch
+func (s *Sharded[T]) InfoBatch(elems []T) map[T]EntryInfo[T] {
+	groups := make(map[*Cache[T]][]T)
+	for _, elem := range elems {
+		shard := s.shard(elem)
+		groups[shard] = append(groups[shard], elem)
+	}
+	infos := make(map[T]EntryInfo[T], len(elems))
+	for shard, group := range groups {
+		maps.Copy(infos, shard.InfoBatch(group))
+	}
+	return infos
+}
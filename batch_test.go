@@ -0,0 +1,68 @@
+package cacheset
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache_InfoBatch(t *testing.T) {
+	c := New[int64](time.Minute, WithEntryMetadata(), WithVersions())
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, time.Minute)
+	c.Add(3, time.Nanosecond)
+	c.Pin(1)
+	c.Contains(2)
+	time.Sleep(time.Millisecond)
+
+	infos := c.InfoBatch([]int64{1, 2, 3, 4})
+	if len(infos) != 2 {
+		t.Fatalf("InfoBatch() = %v, want the elements %v", infos, []int64{1, 2})
+	}
+	if got := infos[1]; !got.Pinned || !got.IsPermanent() || got.Version == 0 {
+		t.Errorf("InfoBatch()[1] = %+v, want a pinned permanent element with a version", got)
+	}
+	if got := infos[2]; got.Pinned || got.TTL() <= 0 || got.Metadata.TTL != time.Minute || got.Metadata.Hits != 1 {
+		t.Errorf("InfoBatch()[2] = %+v, want an unpinned element added for %v with %v hit", got, time.Minute, 1)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 0 {
+		t.Errorf("Stats() = %+v, want only the hit of Contains", s)
+	}
+}
+
+func TestCache_ContainsBatch(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, time.Minute)
+	c.Add(3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		name  string
+		elems []int64
+		want  []bool
+		any   bool
+		all   bool
+	}{
+		{name: "All", elems: []int64{1, 2}, want: []bool{true, true}, any: true, all: true},
+		{name: "Some", elems: []int64{1, 4}, want: []bool{true, false}, any: true, all: false},
+		{name: "None", elems: []int64{4, 5}, want: []bool{false, false}, any: false, all: false},
+		{name: "Expired", elems: []int64{3}, want: []bool{true}, any: true, all: true},
+		{name: "Empty", elems: nil, want: []bool{}, any: false, all: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.ContainsBatch(tt.elems); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ContainsBatch() = %v, want %v", got, tt.want)
+			}
+			if got := c.ContainsAny(tt.elems); got != tt.any {
+				t.Errorf("ContainsAny() = %v, want %v", got, tt.any)
+			}
+			if got := c.ContainsAll(tt.elems); got != tt.all {
+				t.Errorf("ContainsAll() = %v, want %v", got, tt.all)
+			}
+		})
+	}
+}
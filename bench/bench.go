@@ -0,0 +1,197 @@
+// Package bench replays access traces against caches to measure their throughput and hit ratio.
+//
+// Path: bench/bench.go
+//
+// Description: bench.go contains the traces, the configurations of the caches and their replay.
+// Each access of a trace checks whether the key is in the cache and adds it on a miss, like a
+// cache in front of a slower store. The replay reports the throughput, the hit ratio and the
+// allocations per access, so that performance regressions are measurable.
+//
+// Usage:
+//
+//	trace := bench.Zipf(1_000_000, 100_000, 1.1, 42)
+//	for _, cfg := range bench.Configs(1000) {
+//		fmt.Println(bench.Replay(cfg, trace, 8))
+//	}
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// Trace is a sequence of accessed keys
+type Trace []string
+
+// Zipf returns a trace of n accesses to keys following a zipfian distribution of parameter s > 1,
+// generated from seed so that the same arguments give the same trace
+func Zipf(n, keys int, s float64, seed int64) Trace {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), s, 1, uint64(max(keys, 1)-1))
+	trace := make(Trace, n)
+	for i := range trace {
+		trace[i] = strconv.FormatUint(z.Uint64(), 10)
+	}
+	return trace
+}
+
+// ReadTrace reads a trace whose lines start with the accessed key, the empty lines are skipped
+func ReadTrace(r io.Reader) (Trace, error) {
+	var trace Trace
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			trace = append(trace, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("bench: reading trace: %w", err)
+	}
+	return trace, nil
+}
+
+// Target is the cache a trace is replayed against, implemented by *cacheset.Cache and *cacheset.Sharded
+type Target interface {
+	Contains(key string) bool
+	Add(key string, ttl time.Duration) error
+	Stats() cacheset.Stats
+	Close()
+}
+
+// Config is a named configuration of a cache
+type Config struct {
+	Name string        // Name identifies the configuration in the results
+	New  func() Target // New creates an empty cache
+}
+
+// Cache returns the configuration of a cache created by cacheset.New with the given options
+func Cache(name string, opts ...cacheset.Option) Config {
+	return Config{Name: name, New: func() Target {
+		return cacheset.New[string](time.Hour, opts...)
+	}}
+}
+
+// Sharded returns the configuration of a cache created by cacheset.NewSharded with the given options
+func Sharded(name string, opts ...cacheset.Option) Config {
+	return Config{Name: name, New: func() Target {
+		return cacheset.NewSharded[string](time.Hour, opts...)
+	}}
+}
+
+// Configs returns the configurations compared by default, all with the given capacity: each eviction
+// policy, TinyLFU admission, the compact storage and a sharded cache
+func Configs(capacity int) []Config {
+	var configs []Config
+	for _, policy := range []cacheset.EvictionPolicy{cacheset.EvictLRU, cacheset.EvictSLRU, cacheset.Evict2Q, cacheset.EvictCLOCK} {
+		configs = append(configs, Cache(policy.String(),
+			cacheset.WithCapacity(capacity), cacheset.WithEvictionPolicy(policy)))
+	}
+	return append(configs,
+		Cache("TinyLFU", cacheset.WithCapacity(capacity), cacheset.WithTinyLFU()),
+		Cache("Compact", cacheset.WithCapacity(capacity), cacheset.WithCompactStorage()),
+		Sharded("Sharded", cacheset.WithCapacity(capacity)),
+	)
+}
+
+// Result is the outcome of the replay of a trace
+type Result struct {
+	Name        string        // Name is the name of the configuration
+	Accesses    int           // Accesses is the number of replayed accesses
+	Duration    time.Duration // Duration is the wall-clock duration of the replay
+	HitRatio    float64       // HitRatio is the ratio of accesses finding their key in the cache
+	AllocsPerOp float64       // AllocsPerOp is the number of heap allocations per access
+	BytesPerOp  float64       // BytesPerOp is the number of allocated bytes per access
+}
+
+// Throughput returns the number of accesses per second
+func (r Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Accesses) / r.Duration.Seconds()
+}
+
+// String returns the result on a single line
+func (r Result) String() string {
+	return fmt.Sprintf("%s: %.0f accesses/s, hit ratio %.4f, %.2f allocs/op, %.1f B/op",
+		r.Name, r.Throughput(), r.HitRatio, r.AllocsPerOp, r.BytesPerOp)
+}
+
+// Replay replays the trace against a new cache of the configuration from the given number of goroutines,
+// each replaying an interleaved part of the trace
+func Replay(cfg Config, trace Trace, goroutines int) Result {
+	goroutines = max(goroutines, 1)
+	c := cfg.New()
+	defer c.Close()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := g; i < len(trace); i += goroutines {
+				access(c, trace[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	r := Result{
+		Name:     cfg.Name,
+		Accesses: len(trace),
+		Duration: elapsed,
+		HitRatio: c.Stats().HitRatio(),
+	}
+	if len(trace) > 0 {
+		r.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(len(trace))
+		r.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / float64(len(trace))
+	}
+	return r
+}
+
+// Run runs a sub-benchmark per configuration, each iteration replaying the trace against a new cache,
+// and reports the hit ratio and the accesses per second besides the allocations
+func Run(b *testing.B, trace Trace, configs []Config) {
+	for _, cfg := range configs {
+		b.Run(cfg.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			var s cacheset.Stats
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				c := cfg.New()
+				for _, key := range trace {
+					access(c, key)
+				}
+				s = c.Stats()
+				c.Close()
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(s.HitRatio(), "hit-ratio")
+			b.ReportMetric(float64(b.N*len(trace))/elapsed.Seconds(), "accesses/s")
+		})
+	}
+}
+
+// access checks whether the key is in the cache and adds it on a miss
+func access(c Target, key string) {
+	if !c.Contains(key) {
+		_ = c.Add(key, 0)
+	}
+}
@@ -0,0 +1,70 @@
+package bench
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestZipf(t *testing.T) {
+	a, b := Zipf(1000, 100, 1.1, 42), Zipf(1000, 100, 1.1, 42)
+	if !slices.Equal(a, b) {
+		t.Errorf("Zipf() is not deterministic")
+	}
+	if len(a) != 1000 {
+		t.Errorf("len(Zipf()) = %v, want %v", len(a), 1000)
+	}
+}
+
+func TestReadTrace(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader("a 1\n\nb\n  c x y\n"))
+	if err != nil {
+		t.Fatalf("ReadTrace() error = %v", err)
+	}
+	if want := (Trace{"a", "b", "c"}); !slices.Equal(trace, want) {
+		t.Errorf("ReadTrace() = %v, want %v", trace, want)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	trace := Trace{"a", "b", "a", "a", "c", "b"}
+	for _, cfg := range Configs(10) {
+		t.Run(cfg.Name, func(t *testing.T) {
+			r := Replay(cfg, trace, 1)
+			if r.Accesses != len(trace) {
+				t.Errorf("Replay().Accesses = %v, want %v", r.Accesses, len(trace))
+			}
+			if want := 0.5; r.HitRatio != want {
+				t.Errorf("Replay().HitRatio = %v, want %v", r.HitRatio, want)
+			}
+		})
+	}
+}
+
+// BenchmarkReplay_Zipf replays a zipfian trace against the default configurations
+func BenchmarkReplay_Zipf(b *testing.B) {
+	Run(b, Zipf(200_000, 100_000, 1.1, 42), Configs(1000))
+}
+
+// BenchmarkReplay_Trace replays the trace file named by the CACHESET_TRACE environment variable
+// against the default configurations with a capacity of 1000
+//
+//	CACHESET_TRACE=trace.txt go test ./bench -run=^$ -bench=Replay_Trace
+func BenchmarkReplay_Trace(b *testing.B) {
+	path := os.Getenv("CACHESET_TRACE")
+	if path == "" {
+		b.Skip("CACHESET_TRACE is not set")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	trace, err := ReadTrace(f)
+	if err != nil {
+		b.Fatal(err)
+	}
+	Run(b, trace, Configs(1000))
+}
@@ -0,0 +1,71 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithBloomFilter(t *testing.T) {
+	c := New[int64](time.Minute, WithBloomFilter(100))
+	defer c.Close()
+	for i := int64(0); i < 100; i++ {
+		c.Add(i, 0)
+	}
+
+	t.Run("Added", func(t *testing.T) {
+		for i := int64(0); i < 100; i++ {
+			if !c.Contains(i) {
+				t.Errorf("Contains(%v) = %v, want %v", i, false, true)
+			}
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		rejected := 0
+		for i := int64(100); i < 1100; i++ {
+			if c.Contains(i) {
+				t.Errorf("Contains(%v) = %v, want %v", i, true, false)
+			}
+			if !c.filter.Load().mayContain(i) {
+				rejected++
+			}
+		}
+		if rejected < 900 {
+			t.Errorf("WithBloomFilter() rejected %v misses, want at least %v", rejected, 900)
+		}
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		for i := int64(0); i < 100; i++ {
+			c.Delete(i)
+		}
+		for i := int64(0); i < 100; i++ {
+			if c.filter.Load().mayContain(i) {
+				t.Errorf("mayContain(%v) = %v, want %v", i, true, false)
+			}
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		if got := c.Stats().Misses; got != 1000 {
+			t.Errorf("Stats().Misses = %v, want %v", got, 1000)
+		}
+	})
+}
+
+func TestWithBloomFilter_Reset(t *testing.T) {
+	c := New[int](time.Minute, WithBloomFilter(100))
+	defer c.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			c.Contains(i)
+		}
+	}()
+	for range 10 {
+		c.Reset()
+	}
+	<-done
+}
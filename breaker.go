@@ -0,0 +1,124 @@
+// Package cacheset
+//
+// Path: breaker.go
+//
+// Description: breaker.go contains the circuit breaker stopping the calls to a failing loader.
+package cacheset
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned by GetOrLoad when the loader is not called because it keeps failing
+var ErrBreakerOpen = errors.New("cacheset: loader circuit breaker open")
+
+// BreakerState is the state of the circuit breaker of a loader
+type BreakerState int
+
+const (
+	// BreakerClosed means that the loader is called
+	BreakerClosed BreakerState = iota
+	// BreakerOpen means that the loader failed too many times in a row and is not called until the cooldown elapsed
+	BreakerOpen
+	// BreakerHalfOpen means that the cooldown elapsed and a single call probes whether the loader recovered
+	BreakerHalfOpen
+)
+
+// String returns the name of the breaker state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// WithLoadBreaker stops calling the loader of WithLoader after the given number of consecutive failures
+//
+// Description: Once the breaker is open, GetOrLoad returns ErrBreakerOpen for the missing elements and keeps
+// serving the stale ones of WithMaxStale without reloading them. After cooldown, the breaker is half-open:
+// a single call probes the loader, closing the breaker if it succeeds and opening it again if it fails.
+// The state of the breaker and the number of times it opened are reported in Stats.
+func WithLoadBreaker(failures int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.breakerFailures = max(failures, 0)
+		o.breakerCooldown = cooldown
+	}
+}
+
+// breaker is the circuit breaker of a loader
+type breaker struct {
+	mu       sync.Mutex
+	failures int           // failures is the number of consecutive failures opening the breaker
+	cooldown time.Duration // cooldown is the duration for which the breaker stays open
+	state    BreakerState  // state is the current state of the breaker
+	failed   int           // failed is the number of consecutive failures
+	opened   time.Time     // opened is the time the breaker opened
+	probing  bool          // probing is true while a half-open breaker waits for the result of its probe
+	trips    uint64        // trips is the number of times the breaker opened
+}
+
+// newBreaker returns a closed breaker opening after the given number of consecutive failures
+func newBreaker(failures int, cooldown time.Duration) *breaker {
+	return &breaker{failures: failures, cooldown: cooldown}
+}
+
+// allow returns true if the loader may be called, in which case record must be called with its result
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.opened) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+	default:
+		return true
+	}
+	b.probing = true
+	return true
+}
+
+// record records the result of an allowed call to the loader
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.state, b.failed = BreakerClosed, 0
+		return
+	}
+	b.failed++
+	if b.state == BreakerHalfOpen || b.failed >= b.failures {
+		if b.state != BreakerOpen {
+			b.trips++
+		}
+		b.state, b.opened = BreakerOpen, time.Now()
+	}
+}
+
+// status returns the state of the breaker, an open breaker whose cooldown elapsed being half-open,
+// and the number of times it opened
+func (b *breaker) status() (BreakerState, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.opened) >= b.cooldown {
+		return BreakerHalfOpen, b.trips
+	}
+	return b.state, b.trips
+}
@@ -0,0 +1,81 @@
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithLoadBreaker(t *testing.T) {
+	var calls atomic.Int64
+	var failing atomic.Bool
+	failing.Store(true)
+	load := func(ctx context.Context, elem int) (bool, error) {
+		calls.Add(1)
+		if failing.Load() {
+			return false, errors.New("backend down")
+		}
+		return true, nil
+	}
+	c := New[int](time.Hour, WithLoader(load), WithLoadBreaker(3, 20*time.Millisecond))
+	defer c.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad(ctx, i); err == nil || errors.Is(err, ErrBreakerOpen) {
+			t.Errorf("GetOrLoad(%v) error = %v, want the loader error", i, err)
+		}
+	}
+	if st := c.Stats(); st.Breaker != BreakerOpen || st.BreakerTrips != 1 {
+		t.Errorf("Stats() breaker = %v with %v trips, want %v with %v", st.Breaker, st.BreakerTrips, BreakerOpen, 1)
+	}
+
+	t.Run("Open", func(t *testing.T) {
+		if _, err := c.GetOrLoad(ctx, 10); !errors.Is(err, ErrBreakerOpen) || calls.Load() != 3 {
+			t.Errorf("GetOrLoad() error = %v after %v calls, want %v after %v", err, calls.Load(), ErrBreakerOpen, 3)
+		}
+	})
+
+	t.Run("HalfOpenFailure", func(t *testing.T) {
+		time.Sleep(25 * time.Millisecond)
+		if st := c.Stats(); st.Breaker != BreakerHalfOpen {
+			t.Errorf("Stats() breaker = %v, want %v", st.Breaker, BreakerHalfOpen)
+		}
+		if _, err := c.GetOrLoad(ctx, 10); err == nil || errors.Is(err, ErrBreakerOpen) {
+			t.Errorf("GetOrLoad() error = %v, want the loader error of the probe", err)
+		}
+		if st := c.Stats(); st.Breaker != BreakerOpen || st.BreakerTrips != 2 {
+			t.Errorf("Stats() breaker = %v with %v trips, want %v with %v", st.Breaker, st.BreakerTrips, BreakerOpen, 2)
+		}
+	})
+
+	t.Run("HalfOpenSuccess", func(t *testing.T) {
+		failing.Store(false)
+		time.Sleep(25 * time.Millisecond)
+		if found, err := c.GetOrLoad(ctx, 10); !found || err != nil {
+			t.Errorf("GetOrLoad() = %v, %v, want %v, nil", found, err, true)
+		}
+		if st := c.Stats(); st.Breaker != BreakerClosed {
+			t.Errorf("Stats() breaker = %v, want %v", st.Breaker, BreakerClosed)
+		}
+	})
+}
+
+func TestBreakerState_String(t *testing.T) {
+	tests := []struct {
+		state BreakerState
+		want  string
+	}{
+		{BreakerClosed, "closed"},
+		{BreakerOpen, "open"},
+		{BreakerHalfOpen, "half-open"},
+		{BreakerState(-1), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("String() = %v, want %v", got, tt.want)
+		}
+	}
+}
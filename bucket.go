@@ -0,0 +1,107 @@
+// Package cacheset
+//
+// Path: bucket.go
+//
+// Description: bucket.go contains the expiration buckets, an index of the elements by expiration time.
+//
+// Without an index, each cleaning checks the expiration of every element. With buckets, the elements
+// are grouped by expiration time into buckets of a fixed width, and each cleaning removes the elements
+// of the buckets whose time has passed, only checking the elements of the current bucket. A cleaning
+// then costs a time proportional to the number of expired elements rather than to the number of
+// elements, which matters when most elements have the same time to live.
+package cacheset
+
+import "time"
+
+// WithExpirationBuckets groups the elements into expiration buckets of the given width, so that the
+// cleanings only visit the expired elements
+//
+// Description: A width close to the clean interval is a good start: a narrower width makes more buckets,
+// a wider one makes more elements checked in the current bucket. The elements with a maximum idle
+// duration, whose expiration moves with their accesses, are still checked at each cleaning.
+// The index costs about two map entries per element.
+func WithExpirationBuckets(width time.Duration) Option {
+	return func(o *options) {
+		o.bucketWidth = width
+	}
+}
+
+// buckets is an expiration index grouping the elements into buckets of a fixed width
+//
+// Description: The elements expiring in [n*width, (n+1)*width) are in bucket n.
+type buckets[T comparable] struct {
+	width int64                    // width is the width of a bucket in nanoseconds
+	index map[int64]map[T]struct{} // index are the elements of each non-empty bucket
+	of    map[T]int64              // of is the bucket of each indexed element
+	first int64                    // first is lower than or equal to the number of the first non-empty bucket
+}
+
+// newBuckets returns an empty index of buckets of the given width
+func newBuckets[T comparable](width time.Duration) *buckets[T] {
+	return &buckets[T]{
+		width: max(int64(width), 1),
+		index: make(map[int64]map[T]struct{}),
+		of:    make(map[T]int64),
+	}
+}
+
+func (b *buckets[T]) add(elem T, expires int64) {
+	n := expires / b.width
+	bucket, ok := b.index[n]
+	if !ok {
+		bucket = make(map[T]struct{})
+		b.index[n] = bucket
+	}
+	bucket[elem] = struct{}{}
+	b.of[elem] = n
+	if len(b.index) == 1 || n < b.first {
+		b.first = n
+	}
+}
+
+func (b *buckets[T]) remove(elem T) {
+	n, ok := b.of[elem]
+	if !ok {
+		return
+	}
+	delete(b.of, elem)
+	bucket := b.index[n]
+	delete(bucket, elem)
+	if len(bucket) == 0 {
+		delete(b.index, n)
+	}
+}
+
+// due returns the elements of the past buckets and of the current bucket
+func (b *buckets[T]) due(now int64) []T {
+	current := now / b.width
+
+	var due []T
+	if current-b.first < int64(len(b.index)) {
+		for n := b.first; n <= current; n++ {
+			for elem := range b.index[n] {
+				due = append(due, elem)
+			}
+		}
+	} else {
+		// the buckets are sparse, visiting them is cheaper than visiting the bucket numbers
+		for n, bucket := range b.index {
+			if n <= current {
+				for elem := range bucket {
+					due = append(due, elem)
+				}
+			}
+		}
+	}
+	b.first = current
+	return due
+}
+
+func (b *buckets[T]) clear() {
+	clear(b.index)
+	clear(b.of)
+}
+
+func (b *buckets[T]) empty() expiryIndex[T] {
+	return newBuckets[T](time.Duration(b.width))
+}
@@ -0,0 +1,104 @@
+package cacheset
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// indexLen returns the number of elements in the buckets of b
+func indexLen[T comparable](b *buckets[T]) int {
+	var n int
+	for _, bucket := range b.index {
+		n += len(bucket)
+	}
+	return n
+}
+
+func Test_indexed_ExpireAll(t *testing.T) {
+	for name, index := range map[string]expiryIndex[int]{
+		"Buckets": newBuckets[int](time.Millisecond),
+		"Wheel":   newWheel[int](100 * time.Microsecond),
+	} {
+		t.Run(name, func(t *testing.T) {
+			x := newIndexed[int](newSet[int](), index)
+			for i := 0; i < 100; i++ {
+				ttl := time.Hour // the even elements are not expired
+				if i%2 == 1 {
+					ttl = time.Duration(1+i%5) * time.Millisecond
+				}
+				x.Add(i, ttl)
+			}
+			x.Add(100, 0)
+			x.AddWithIdle(101, 0, time.Millisecond)
+			x.AddWithIdle(102, 0, time.Hour)
+			time.Sleep(10 * time.Millisecond)
+
+			removed := x.ExpireAll()
+			slices.Sort(removed)
+			var want []int
+			for i := 1; i < 100; i += 2 {
+				want = append(want, i)
+			}
+			want = append(want, 101)
+			if !slices.Equal(removed, want) {
+				t.Errorf("ExpireAll() = %v, want %v", removed, want)
+			}
+			if got, want := x.Len(), 52; got != want {
+				t.Errorf("Len() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_buckets_reindex(t *testing.T) {
+	b := newBuckets[string](time.Second)
+	x := newIndexed[string](newSet[string](), b)
+	x.Add("a", time.Millisecond)
+	x.Add("a", time.Hour)
+	x.Add("b", time.Millisecond)
+	x.Delete("b")
+
+	other := newSet[string]()
+	other.Add("c", time.Millisecond)
+	x.Merge(other, func(a, b int64) int64 { return b })
+	if got := indexLen(b); got != 2 {
+		t.Errorf("indexLen = %v, want %v", got, 2)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	// the sparse walk covers a first bucket far in the past
+	b.first = 0
+	if got := x.ExpireAll(); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("ExpireAll() = %v, want [c]", got)
+	}
+
+	c := x.Copy().(*indexed[string])
+	x.Clear()
+	if indexLen(b) != 0 || indexLen(c.index.(*buckets[string])) != 1 {
+		t.Errorf("Clear() or Copy() did not keep the index in sync")
+	}
+}
+
+func TestWithExpirationBuckets(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithExpirationBuckets(time.Millisecond)},
+		{WithExpirationBuckets(time.Millisecond), WithCompactStorage()},
+		{WithTimingWheel(100 * time.Microsecond)},
+	} {
+		c := New[int](time.Millisecond, opts...)
+		for i := 0; i < 1000; i++ {
+			c.Add(i, time.Duration(1+i%2)*5*time.Millisecond)
+		}
+		c.Add(-1, 0)
+		time.Sleep(30 * time.Millisecond)
+
+		if got, want := c.ToSlice(), []int{-1}; !slices.Equal(got, want) {
+			t.Errorf("ToSlice() = %v elements, want %v", len(got), want)
+		}
+		if got := c.Stats().Expirations; got != 1000 {
+			t.Errorf("Stats().Expirations = %v, want %v", got, 1000)
+		}
+		c.Close()
+	}
+}
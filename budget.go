@@ -0,0 +1,109 @@
+// Package cacheset
+//
+// Path: budget.go
+//
+// Description: budget.go contains the capacity budget shared by a group of caches.
+package cacheset
+
+import "sync"
+
+// Budget is a maximum number of elements shared by a group of caches
+//
+// Description: When the caches attached to a budget hold more elements than its limit together,
+// each of them evicts a share of the excess proportional to its size. A cache with a capacity
+// evicts the elements chosen by its eviction policy, the others evict the elements expiring first.
+// Evictions are reported with RemovalEvicted. The budget is applied after each addition, so the
+// limit may be exceeded briefly by concurrent additions.
+type Budget struct {
+	members []budgetMember // members are the caches attached to the budget
+	limit   int            // limit is the maximum number of elements of all members together
+	mu      sync.Mutex
+}
+
+// budgetMember is a cache attached to a budget
+type budgetMember interface {
+	Len() int
+	shed(n int) int
+}
+
+// NewBudget returns a budget of the given number of elements
+func NewBudget(limit int) *Budget {
+	return &Budget{limit: max(limit, 0)}
+}
+
+// WithBudget attaches the cache to the given budget until it is closed
+//
+// Description: The shards of a Sharded cache are all attached to the budget.
+func WithBudget(b *Budget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+// Limit returns the maximum number of elements of the caches attached to the budget
+func (b *Budget) Limit() int {
+	return b.limit
+}
+
+// Len returns the number of elements of the caches attached to the budget
+func (b *Budget) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var total int
+	for _, m := range b.members {
+		total += m.Len()
+	}
+	return total
+}
+
+// attach adds the given cache to the budget
+func (b *Budget) attach(m budgetMember) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.members = append(b.members, m)
+}
+
+// detach removes the given cache from the budget
+func (b *Budget) detach(m budgetMember) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, member := range b.members {
+		if member == m {
+			b.members = append(b.members[:i], b.members[i+1:]...)
+			return
+		}
+	}
+}
+
+// enforce evicts elements from the members, proportionally to their sizes, until the budget is respected
+func (b *Budget) enforce() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lens := make([]int, len(b.members))
+	var total int
+	for i, m := range b.members {
+		lens[i] = m.Len()
+		total += lens[i]
+	}
+	if total <= b.limit {
+		return
+	}
+
+	excess := total - b.limit
+	for i, m := range b.members {
+		if n := (excess*lens[i] + total - 1) / total; n > 0 {
+			m.shed(n)
+		}
+	}
+}
+
+// spend applies the budget of the cache after an addition, the cache must not be locked
+func (c *Cache[T]) spend() {
+	if c.options.budget != nil {
+		c.options.budget.enforce()
+	}
+}
@@ -0,0 +1,53 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithBudget(t *testing.T) {
+	b := NewBudget(30)
+	small := New[int64](time.Minute, WithBudget(b))
+	defer small.Close()
+	large := New[int64](time.Minute, WithBudget(b), WithCapacity(100))
+	defer large.Close()
+
+	for i := int64(0); i < 10; i++ {
+		small.Add(i, time.Duration(i+1)*time.Minute)
+	}
+	for i := int64(0); i < 20; i++ {
+		large.Add(i, 0)
+	}
+
+	t.Run("Within", func(t *testing.T) {
+		if got := b.Len(); got != 30 {
+			t.Errorf("Budget.Len() = %v, want %v", got, 30)
+		}
+	})
+
+	for i := int64(20); i < 26; i++ {
+		large.Add(i, 0)
+	}
+
+	t.Run("Exceeded", func(t *testing.T) {
+		if got := b.Len(); got > b.Limit() {
+			t.Errorf("Budget.Len() = %v, want at most %v", got, b.Limit())
+		}
+		if small.Len() == 10 {
+			t.Errorf("WithBudget() did not evict from the smaller cache")
+		}
+		if small.Contains(0) {
+			t.Errorf("WithBudget() kept the element expiring first")
+		}
+		if large.Contains(0) {
+			t.Errorf("WithBudget() kept the least recently used element")
+		}
+	})
+
+	t.Run("Detached", func(t *testing.T) {
+		large.Close()
+		if got := b.Len(); got != small.Len() {
+			t.Errorf("Budget.Len() = %v, want %v", got, small.Len())
+		}
+	})
+}
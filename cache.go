@@ -28,41 +28,300 @@
 package cacheset
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Cache is a thread-safe map with expiration times.
 type Cache[T comparable] struct {
-	set[T]                     // set is a map with expiration times
-	close        chan struct{} // close is a channel that stops the cache's cleaning goroutine
-	sync.RWMutex               // RWMutex is a mutex that can be locked for reading or writing
+	dirty         map[T]struct{}                   // dirty are the elements changed since the last snapshot, nil unless delta snapshots are enabled
+	dirtyCleared  bool                             // dirtyCleared is true if the cache was cleared since the last snapshot
+	lastSnapshot  int64                            // lastSnapshot is the wall-clock time of the last snapshot in nanoseconds
+	checkpoints   int                              // checkpoints is the number of periodic snapshots written
+	deltaFiles    int                              // deltaFiles is the number of deltas written since the last full snapshot of a cache with durability
+	staleDeltas   int                              // staleDeltas is the number of deltas found on restore, removed by the next full snapshot
+	snapshotMu    sync.Mutex                       // snapshotMu serializes the snapshots, which freeze the storage
+	set           store[T]                         // set stores the elements with their expiration times
+	watchers      map[T][]chan RemovalEvent[T]     // watchers are the channels notified when an element is removed
+	subscribers   map[*Subscription[T]]struct{}    // subscribers are the subscriptions receiving the events of the cache
+	close         chan struct{}                    // close is a channel that stops the cache's cleaning goroutine
+	done          chan struct{}                    // done is closed when the cache's cleaning goroutine returns
+	closeOnce     sync.Once                        // closeOnce ensures that the cache is closed once
+	sync.RWMutex                                   // RWMutex is a mutex that can be locked for reading or writing
+	cleanInterval time.Duration                    // cleanInterval is the interval between two cleanings of the cache, written under both the lock of the cache and health.mu
+	stats         stats                            // stats are the counters of the cache
+	options       options                          // options are the settings of the cache
+	health        health                           // health records the activity of the cleaning goroutine
+	policy        policy[T]                        // policy chooses the evicted elements when the cache has a capacity
+	admission     *tinyLFU[T]                      // admission filters the elements added to a full cache
+	hot           *hotKeys[T]                      // hot tracks the most accessed elements
+	auditLog      *auditLog[T]                     // auditLog records the last mutations
+	actor         string                           // actor is the label of the caller of the current mutation, set while locked
+	unique        *hyperLogLog[T]                  // unique counts the distinct added elements
+	filter        atomic.Pointer[countingBloom[T]] // filter answers the negative lookups without locking, replaced by Reset
+	onFull        func(T) OverflowPolicy           // onFull decides what to do when the cache is full
+	loads         *loader[T]                       // loads runs the loader of the read-through mode, nil without WithLoader
+	breaker       *breaker                         // breaker stops calling a failing loader, nil without WithLoadBreaker
+	tags          *tagIndex[T]                     // tags index the tags of the elements, nil until an element is tagged
+	tombstones    map[T]int64                      // tombstones are the end of the tombstone window of the deleted elements
+	bindings      map[T]*doneBinding               // bindings are the contexts bound to the elements added with AddUntilDone
+	doneEvents    chan doneEvent[T]                // doneEvents receives the elements whose context is done, nil until AddUntilDone is called
+	versions      map[T]uint64                     // versions are the versions of the elements with WithVersions
+	lastVersion   uint64                           // lastVersion is the last version given to an element
+	pins          map[T]*entry                     // pins are the pinned elements, with their expiration set aside by WithPinnedNoExpiry or nil
+	pinCount      atomic.Int64                     // pinCount is the number of pinned elements, read by Stats without the lock
+	quotas        map[T]int                        // quotas are the acquisitions counted by TryAcquire in the current window of each key
+	latency       *latencies                       // latency are the latency histograms of the operations, nil without WithLatencyHistograms
+	history       *statsHistory                    // history is the rolling history of the counters, nil without WithStatsHistory
+	lifetimes     *lifetimes[T]                    // lifetimes tracks the lifetimes of the elements, nil without WithTTLHistograms or WithEntryMetadata
+	order         *insertionOrder[T]               // order tracks the insertion order of the elements, nil without WithDeterministicIteration
+	sorted        func(a, b T) int                 // sorted sorts the elements returned by ToSlice, nil without WithSortedIteration
+	ttlPolicy     func(T) (time.Duration, bool)    // ttlPolicy returns the default TTL of an element chosen by the TTL policies
+	namespace     func(T) string                   // namespace returns the namespace of an element
+	spill         func(elem T, deadline int64)     // spill receives the evicted elements of the hot tier of a Tiered set, nil otherwise
+	policyMu      sync.Mutex                       // policyMu protects the policy from concurrent readers
+	closed        bool                             // closed is true once the elements of the cache are released
+	ticker        *time.Ticker                     // ticker ticks every clean interval, nil with WithCoalescedCleaning
+	rearm         chan struct{}                    // rearm wakes the coalesced cleaning goroutine to schedule its next cleaning, nil without coalescing
+	probe         atomic.Int64                     // probe is the position of the storage where amortize and Pressure resume probing
+	earliest      atomic.Int64                     // earliest is a lower bound of the deadlines of the elements with WithCoalescedCleaning or a capacity, 0 meaning none
+	nextClean     atomic.Int64                     // nextClean is the time of the next coalesced cleaning, 0 meaning none
+	length        atomic.Int64                     // length is the number of elements, updated when the cache is unlocked
 }
 
 // New creates a new cache that asynchronously cleans
-func New[T comparable](cleanInterval time.Duration) *Cache[T] {
+func New[T comparable](cleanInterval time.Duration, opts ...Option) *Cache[T] {
+	c := newCache[T](cleanInterval, newOptions(opts))
+	if c.options.sink != nil {
+		if err := c.restoreSink(context.Background()); err != nil {
+			c.report(err)
+		}
+	}
+	return c
+}
+
+// newCache creates a new cache with the given options and starts its cleaning goroutine
+func newCache[T comparable](cleanInterval time.Duration, o options) *Cache[T] {
+	checkStrict(cleanInterval, o)
 	c := &Cache[T]{
-		set:   newSet[T](),
-		close: make(chan struct{}),
+		cleanInterval: cleanInterval,
+		options:       o,
+		stats:         newStats(),
+	}
+	c.init()
+	if o.budget != nil {
+		o.budget.attach(c)
 	}
+	c.start()
 
-	ticker := time.NewTicker(cleanInterval) // ticker is a ticker that cleans the cache every cleanInterval
-	defer ticker.Stop()                     // defer ticker.Stop() stops the ticker when the function returns
+	return c
+}
+
+// init creates the elements, the watchers and the trackers of an empty cache
+func (c *Cache[T]) init() {
+	o := c.options
+	c.set = newStore[T](o)
+	c.watchers = make(map[T][]chan RemovalEvent[T])
+	c.subscribers = make(map[*Subscription[T]]struct{})
+	c.close = make(chan struct{})
+	c.done = make(chan struct{})
+	c.closed = false
+	c.quotas = nil
+	c.pins = nil
+	c.pinCount.Store(0)
+	c.bindings, c.doneEvents = nil, nil
+	c.versions = nil
+	c.tombstones = nil
+	c.tags = nil
+	c.history = nil
+	if o.historyBuckets > 0 {
+		c.history = newStatsHistory(o.historyBuckets, time.Now())
+	}
+	c.latency = nil
+	if o.latencySampling > 0 {
+		c.latency = newLatencies(o.latencySampling)
+	}
+	c.lifetimes = nil
+	if o.ttlHistograms || o.entryMetadata {
+		c.lifetimes = newLifetimes[T]()
+	}
+	c.order, c.sorted = nil, nil
+	if o.sortOrder != nil {
+		sorted, ok := o.sortOrder.(func(T, T) int)
+		if !ok {
+			panic("cacheset: the WithSortedIteration function does not match the cache's element type")
+		}
+		c.sorted = sorted
+	} else if o.insertionOrder {
+		c.order = newInsertionOrder[T]()
+	}
+	c.rearm = nil
+	c.earliest.Store(0)
+	c.probe.Store(0)
+	if o.coalescing() {
+		c.rearm = make(chan struct{}, 1)
+	}
+	c.policy, c.admission, c.unique, c.hot = nil, nil, nil, nil
+	c.filter.Store(nil)
+	if o.capacity > 0 {
+		c.policy = newPolicy[T](o.evictionPolicy, o)
+		if o.tinyLFU {
+			c.admission = newTinyLFU[T](o.capacity)
+		}
+	}
+	c.dirty, c.dirtyCleared = nil, false
+	if o.deltaEvery > 0 {
+		c.dirty = make(map[T]struct{})
+	}
+	c.auditLog = nil
+	if o.auditLog > 0 {
+		c.auditLog = newAuditLog[T](o.auditLog)
+	}
+	if o.bloomFilter > 0 {
+		c.filter.Store(newCountingBloom[T](o.bloomFilter))
+	}
+	if o.uniqueAdds {
+		c.unique = newHyperLogLog[T](o.uniqueAddsPeriod)
+	}
+	if o.hotKeys > 0 {
+		c.hot = newHotKeys[T](o.hotKeys, o.hotKeysWindow, o.hotKeysSampling)
+	}
+	if o.onFull != nil {
+		onFull, ok := o.onFull.(func(T) OverflowPolicy)
+		if !ok {
+			panic("cacheset: the WithOnFull callback does not match the cache's element type")
+		}
+		c.onFull = onFull
+	}
+	if o.loader != nil {
+		load, ok := o.loader.(func(context.Context, T) (bool, error))
+		if !ok {
+			panic("cacheset: the WithLoader function does not match the cache's element type")
+		}
+		c.loads = newLoader(load)
+	}
+	c.breaker = nil
+	if o.loader != nil && o.breakerFailures > 0 {
+		c.breaker = newBreaker(o.breakerFailures, o.breakerCooldown)
+	}
+	c.ttlPolicy = nil
+	if len(o.ttlPolicies) > 0 {
+		policies := make([]func(T) time.Duration, len(o.ttlPolicies))
+		for i, p := range o.ttlPolicies {
+			policy, ok := p.(func(T) time.Duration)
+			if !ok {
+				panic("cacheset: the WithTTLPolicy function does not match the cache's element type")
+			}
+			policies[i] = policy
+		}
+		c.ttlPolicy = newTTLPolicy(policies, o.ttlRule)
+	}
+	if o.namespace != nil {
+		namespace, ok := o.namespace.(func(T) string)
+		if !ok {
+			panic("cacheset: the WithNamespace function does not match the cache's element type")
+		}
+		c.namespace = namespace
+	}
+}
+
+// start starts the cleaning goroutine of the cache
+func (c *Cache[T]) start() {
+	o := c.options
+	var ticker *time.Ticker // ticker is a ticker that cleans the cache every cleanInterval, unless the cleanings are coalesced or external
+	var ticks <-chan time.Time
+	if c.rearm == nil && !o.external {
+		ticker = time.NewTicker(c.cleanInterval)
+		ticks = ticker.C
+	}
+	c.Lock()
+	c.ticker = ticker
+	c.Unlock()
+	c.health.start(time.Now())
+	c.health.alive.Store(true)
+	if c.history != nil {
+		go c.recordHistory(c.history, o.historyWidth, c.close)
+	}
 
 	go func() {
+		defer close(c.done)               // c.done tells Shutdown that the goroutine returned
+		defer c.health.alive.Store(false) // the cleaning goroutine is not alive anymore when it returns
+
+		var snapshots <-chan time.Time // snapshots ticks every snapshot interval of a cache with durability
+		if o.sink != nil && o.snapshotInterval > 0 {
+			snapshotTicker := time.NewTicker(o.snapshotInterval)
+			defer snapshotTicker.Stop()
+			snapshots = snapshotTicker.C
+		}
+
+		if c.rearm != nil {
+			c.coalesce(snapshots) // coalesce sleeps until an element can expire
+			return
+		}
+		if ticker != nil {
+			defer ticker.Stop() // defer ticker.Stop() stops the ticker when the goroutine returns
+		}
+
 		for {
 			select {
 			case <-c.close: // c.close is a channel that stops the cache's cleaning goroutine
 				return
-			case <-ticker.C: // ticker.C is a channel that sends a value every time the ticker ticks
-				c.Lock()
-				c.ExpireAll() // ExpireAll expires all elements in the cache
-				c.Unlock()
+			case <-ticks: // ticks is a channel that sends a value every time the ticker ticks, nil for external cleanings
+				c.health.wakeup()
+				c.clean() // clean expires all elements in the cache
+			case <-snapshots:
+				c.checkpoint() // checkpoint writes a snapshot of a cache with durability
 			}
 		}
 	}()
+}
 
-	return c
+// Reset closes the cache if needed, then empties it and restarts its cleaning goroutine
+//
+// Description: The counters, the health and the trackers of the cache are reset too, and it is attached
+// again to its budget. Like Close, a cache with durability writes its final snapshot, but the snapshot
+// is not restored. The cache must be registered again. Reset must not be called concurrently with
+// Close, Shutdown or another Reset.
+func (c *Cache[T]) Reset() {
+	c.Close()
+
+	c.Lock()
+	c.init()
+	c.closeOnce = sync.Once{}
+	c.stats.reset()
+	c.Unlock()
+
+	if c.options.budget != nil {
+		c.options.budget.attach(c)
+	}
+	c.start()
+}
+
+// clean expires all elements in the cache and logs the result
+//
+// Description: A panic during the cleaning is recovered and reported, so the cleaning goroutine keeps running.
+func (c *Cache[T]) clean() {
+	defer c.recoverPanic()
+
+	start := time.Now()
+	removed := c.expireAll()
+	c.relieve()
+	duration := time.Since(start)
+	c.health.sweep(start, duration, removed)
+	if c.latency != nil {
+		c.latency.sweep.record(duration)
+	}
+
+	c.options.logger.Debug("cacheset: cleaned cache",
+		slog.Duration("duration", duration),
+		slog.Int("removed", removed),
+		slog.Int("len", c.Len()),
+	)
 }
 
 // CopySet returns a copy of the cache's set
@@ -73,62 +332,421 @@ func (c *Cache[T]) CopySet() map[T]int64 {
 	c.RLock()
 	defer c.RUnlock()
 
-	return c.set.Copy()
+	return c.set.Expirations()
 }
 
 // Delete removes the given element from the cache
 func (c *Cache[T]) Delete(elem T) {
+	c.deleteAs(elem, "")
+}
+
+// TryDelete removes the given element from the cache and returns true if it was in the cache
+//
+// Description: The check and the removal happen under a single lock, unlike Contains followed by Delete.
+// An expired element that was not cleaned yet is removed too and reported like Delete would remove it,
+// while Consume only reports unexpired elements and counts as a hit or a miss.
+func (c *Cache[T]) TryDelete(elem T) bool {
+	return c.deleteAs(elem, "")
+}
+
+// deleteAs removes the given element from the cache on behalf of actor and returns true if it was in the cache
+func (c *Cache[T]) deleteAs(elem T, actor string) bool {
+	c.Lock()
+	defer c.Unlock()
+	defer c.act(actor)()
+
+	if !c.set.Contains(elem) {
+		return false
+	}
+	c.remove(elem, RemovalDeleted)
+	return true
+}
+
+// Consume removes the given element and returns true if it was in the cache and had not expired
+//
+// Description: The check and the removal happen under a single lock, so only one of concurrent callers
+// consumes a given element, which suits the single-use tokens. Consume counts as a hit or a miss, and an
+// expired element that was not cleaned yet is left to the cleaner.
+func (c *Cache[T]) Consume(elem T) bool {
+	if !c.mayContain(elem) {
+		c.stats.hit(false)
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.set.Get(elem)
+	found := ok && !e.expired(nanotime())
+	c.stats.hit(found)
+	if found {
+		c.remove(elem, RemovalDeleted)
+	}
+	return found
+}
+
+// DeleteFunc removes all elements for which pred returns true under a single lock and returns their number
+//
+// Description: Expired elements that were not cleaned yet are also passed to pred. pred must not use the cache.
+func (c *Cache[T]) DeleteFunc(pred func(T) bool) int {
+	return c.deleteFuncAs(pred, "")
+}
+
+// deleteFuncAs removes all elements for which pred returns true on behalf of actor
+func (c *Cache[T]) deleteFuncAs(pred func(T) bool, actor string) int {
 	c.Lock()
 	defer c.Unlock()
+	defer c.act(actor)()
+
+	var matched []T
+	for elem := range c.set.All() {
+		if pred(elem) {
+			matched = append(matched, elem)
+		}
+	}
+	for _, elem := range matched {
+		c.remove(elem, RemovalDeleted)
+	}
+	return len(matched)
+}
+
+// DeletePrefix removes all elements starting with the given prefix and returns their number
+func DeletePrefix[T ~string](c *Cache[T], prefix string) int {
+	return c.DeleteFunc(func(elem T) bool {
+		return strings.HasPrefix(string(elem), prefix)
+	})
+}
 
+// remove removes the given element from the cache and notifies its watchers, the cache must be locked
+func (c *Cache[T]) remove(elem T, reason RemovalReason) {
+	now := nanotime()
+	if reason == RemovalEvicted && c.spill != nil {
+		if e, ok := c.set.Get(elem); ok && !e.expired(now) {
+			c.spill(elem, e.deadline())
+		}
+	}
+	meta := c.metadata(elem, now)
+	if c.lifetimes != nil {
+		c.lifetimes.remove(elem, reason, now)
+	}
 	c.set.Delete(elem)
+	c.forget(elem, reason)
+	c.markDirty(elem)
+	if reason == RemovalDeleted {
+		c.bury(elem)
+	}
+
+	switch reason {
+	case RemovalExpired:
+		c.stats.add(statExpirations, 1)
+	case RemovalDeleted:
+		c.stats.add(statDeletes, 1)
+	case RemovalEvicted:
+		c.stats.add(statEvictions, 1)
+	}
+	c.notify(RemovalEvent[T]{Elem: elem, Reason: reason, EntryMetadata: meta})
+	if len(c.subscribers) > 0 {
+		c.publish(Event[T]{Kind: EventRemoved, Elem: elem, Reason: reason, EntryMetadata: meta})
+	}
+	c.audit(MutationRemove, elem, reason)
 }
 
 // Len returns the number of elements in the cache
+//
+// Description: Len does not lock the cache, it reads the number of elements recorded by the last Unlock.
 func (c *Cache[T]) Len() int {
-	c.RLock()
-	defer c.RUnlock()
+	return int(c.length.Load())
+}
 
-	return c.set.Len()
+// Unlock records the number of elements of the cache and unlocks it for writing
+func (c *Cache[T]) Unlock() {
+	c.length.Store(int64(c.set.Len()))
+	c.RWMutex.Unlock()
 }
 
 // Close stops the cache's cleaning goroutine
+//
+// Description: With WithDurability, a final snapshot is written before the cache is closed.
+// Close waits for the cleaning goroutine without deadline, errors are reported to the error handler.
 func (c *Cache[T]) Close() {
-	c.close <- struct{}{}
+	if err := c.Shutdown(context.Background()); err != nil {
+		c.report(err)
+	}
+}
+
+// Shutdown gracefully closes the cache before ctx is done
+//
+// Description: Shutdown stops the cleaning goroutine and waits for its current cleaning, runs a final
+// cleaning so that the watchers of expired elements are notified, writes the final snapshot with
+// WithDurability, then closes the watchers' channels. If ctx is done first, Shutdown returns an error
+// describing the abandoned work, which is completed in the background except the final snapshot, even
+// with a sink ignoring ctx. Calling Shutdown or Close again has no effect.
+func (c *Cache[T]) Shutdown(ctx context.Context) error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.shutdown(ctx)
+	})
+	return err
+}
+
+// shutdown closes the cache
+func (c *Cache[T]) shutdown(ctx context.Context) error {
+	unregisterCache(c)
+	if c.options.budget != nil {
+		c.options.budget.detach(c)
+	}
 	close(c.close)
-	c.set = nil
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		go func() {
+			<-c.done
+			c.teardown()
+		}()
+		return fmt.Errorf("cacheset: close abandoned the final cleaning and snapshot: %w", ctx.Err())
+	}
+
+	c.expireAll()
+
+	var err error
+	if c.options.sink != nil {
+		// a sink ignoring ctx must not block Shutdown past its deadline
+		flushed := make(chan error, 1)
+		go func() {
+			flushed <- c.flush(ctx)
+		}()
+		select {
+		case ferr := <-flushed:
+			if ferr != nil {
+				err = fmt.Errorf("cacheset: close abandoned the final snapshot: %w", ferr)
+			}
+		case <-ctx.Done():
+			go func() {
+				<-flushed
+				c.teardown()
+			}()
+			return fmt.Errorf("cacheset: close abandoned the final snapshot: %w", ctx.Err())
+		}
+	}
+	c.teardown()
+
+	return err
+}
+
+// teardown closes the watchers and releases the elements of the cache
+func (c *Cache[T]) teardown() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.closeWatchers()
+	c.endSubscriptions()
+	c.forgetAll()
+	c.set = set[T](nil)
+	c.closed = true
 }
 
 // Add adds the given element to the cache
-func (c *Cache[T]) Add(elem T, duration time.Duration) {
+//
+// Description: If the cache has a capacity and is full, an element is evicted to make room for the new one,
+// unless the overflow policy rejects the element, in which case ErrCapacityExceeded is returned.
+func (c *Cache[T]) Add(elem T, duration time.Duration) error {
+	return c.add(elem, duration, 0)
+}
+
+// AddWithIdle adds the given element to the cache until ttl elapses or it is not accessed for maxIdle
+//
+// Description: The element expires at the first of both bounds, a zero or negative bound is not enforced.
+// Contains and Exists count as accesses. Like Add, it may evict an element or return an error if the cache is full.
+func (c *Cache[T]) AddWithIdle(elem T, ttl, maxIdle time.Duration) error {
+	return c.add(elem, ttl, maxIdle)
+}
+
+// add adds the given element to the cache
+func (c *Cache[T]) add(elem T, ttl, maxIdle time.Duration) error {
+	_, _, err := c.addReport(elem, ttl, maxIdle, "")
+	return err
+}
+
+// AddReport adds the given element to the cache like Add and reports its state before the addition
+//
+// Description: existed is true if the element was in the cache and had not expired, and prevExpiry
+// is then its previous expiration time, the zero time.Time meaning that it would never expire.
+func (c *Cache[T]) AddReport(elem T, ttl time.Duration) (existed bool, prevExpiry time.Time, err error) {
+	return c.addReport(elem, ttl, 0, "")
+}
+
+// addReport adds the given element to the cache on behalf of actor and returns its state before the addition
+func (c *Cache[T]) addReport(elem T, ttl, maxIdle time.Duration, actor string) (existed bool, prevExpiry time.Time, err error) {
+	if start := c.latency.sample(); start != 0 {
+		defer c.latency.add.observe(start)
+	}
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+	defer c.act(actor)()
+
+	if e, ok := c.set.Get(elem); ok && !e.expired(nanotime()) {
+		existed, prevExpiry = true, toTime(e.deadline())
+	}
+	if ok, err := c.accept(elem, ttl, maxIdle); !ok {
+		return existed, prevExpiry, err
+	}
+
+	c.put(elem, ttl, maxIdle)
+	c.stats.add(statAdds, 1)
+	c.recordHot(elem)
+	c.recordUnique(elem)
+
+	return existed, prevExpiry, nil
+}
+
+// AddDefault adds the given element to the cache for the TTL chosen by the policies set with WithTTLPolicy,
+// or else for the default TTL of its namespace, set with WithNamespaceTTL, or else for the default TTL set
+// with WithDefaultTTL
+func (c *Cache[T]) AddDefault(elem T) error {
+	c.RLock()
+	ttl := c.defaultTTL(elem)
+	c.RUnlock()
+
+	return c.add(elem, ttl, 0)
+}
+
+// GetOrAdd adds the given element to the cache unless it is already there and returns true if it was added
+//
+// Description: The lookup and the addition happen under a single lock, so only one of concurrent
+// callers adds a given element. An expired element that was not cleaned yet is added again.
+// Like Add, it may evict an element or return an error if the cache is full.
+func (c *Cache[T]) GetOrAdd(elem T, ttl time.Duration) (added bool, err error) {
+	defer c.spend()
 	c.Lock()
 	defer c.Unlock()
 
-	c.set.Add(elem, duration)
+	if e, ok := c.set.Get(elem); ok && !e.expired(nanotime()) {
+		e.touch(nanotime())
+		c.stats.hit(true)
+		c.recordHot(elem)
+		c.touch(elem, true)
+		return false, nil
+	}
+	c.stats.hit(false)
+
+	if ok, err := c.accept(elem, ttl, 0); !ok {
+		return false, err
+	}
+
+	c.put(elem, ttl, 0)
+	c.stats.add(statAdds, 1)
+	c.recordHot(elem)
+	c.recordUnique(elem)
+
+	return true, nil
 }
 
 // Contains returns true if the given element is in the cache
 func (c *Cache[T]) Contains(elem T) bool {
+	if !c.mayContain(elem) {
+		c.stats.hit(false)
+		return false
+	}
+
+	if start := c.latency.sample(); start != 0 {
+		defer c.latency.contains.observe(start)
+	}
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.contains(elem)
+}
+
+// Peek returns true if the given element is in the cache and has not expired, without counting as an access
+//
+// Description: Unlike Contains, Peek neither updates the eviction policy nor the idle time of the element,
+// and is not counted in the hits and misses.
+func (c *Cache[T]) Peek(elem T) bool {
 	c.RLock()
 	defer c.RUnlock()
 
-	return c.set.Contains(elem)
+	e, ok := c.set.Get(elem)
+	return ok && !e.expired(nanotime())
+}
+
+// contains records an access to the given element and returns true if it is in the cache
+//
+// Description: The caller must hold the read lock.
+func (c *Cache[T]) contains(elem T) bool {
+	if !c.mayContain(elem) {
+		c.stats.hit(false)
+		return false
+	}
+	found := c.set.Touch(elem)
+	c.stats.hit(found)
+	c.recordHot(elem)
+	c.touch(elem, found)
+
+	return found
 }
 
 // ToSlice returns a slice of all elements in the cache
+//
+// Description: The elements are in random order, unless the cache was created with WithDeterministicIteration
+// or WithSortedIteration.
 func (c *Cache[T]) ToSlice() []T {
 	c.RLock()
 	defer c.RUnlock()
 
-	return c.set.ToSlice()
+	return c.ordered(c.set.ToSlice())
+}
+
+// Filter returns a slice of the unexpired elements in the cache for which pred returns true
+//
+// Description: Filter is computed under a single read lock, pred must not call the cache's methods.
+func (c *Cache[T]) Filter(pred func(T) bool) []T {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.ordered(c.set.Filter(pred))
+}
+
+// Partition splits the unexpired elements in the cache into those for which pred returns true and the others
+//
+// Description: Partition is computed under a single read lock, pred must not call the cache's methods.
+func (c *Cache[T]) Partition(pred func(T) bool) (in []T, out []T) {
+	c.RLock()
+	defer c.RUnlock()
+
+	in, out = c.set.Partition(pred)
+	return c.ordered(in), c.ordered(out)
 }
 
 // Clear clears the cache
 func (c *Cache[T]) Clear() {
+	c.clearAs("")
+}
+
+// clearAs clears the cache on behalf of actor
+func (c *Cache[T]) clearAs(actor string) {
 	c.Lock()
 	defer c.Unlock()
+	defer c.act(actor)()
+
+	c.clear()
+}
 
+// clear removes all elements, the cache must be locked
+func (c *Cache[T]) clear() {
+	now := nanotime()
+	for elem := range c.watchers {
+		if c.set.Contains(elem) {
+			c.notify(RemovalEvent[T]{Elem: elem, Reason: RemovalDeleted, EntryMetadata: c.metadata(elem, now)})
+		}
+	}
+	c.stats.add(statDeletes, uint64(c.set.Len()))
 	c.set.Clear()
+	c.forgetAll()
+	c.markCleared()
+	c.publish(Event[T]{Kind: EventCleared})
+	c.audit(MutationClear, *new(T), 0)
 }
 
 // Expire expires the given element
@@ -136,21 +754,135 @@ func (c *Cache[T]) Expire(elem T) {
 	c.Lock()
 	defer c.Unlock()
 
-	c.set.Expire(elem)
+	if c.set.Expired(elem) {
+		c.remove(elem, RemovalExpired)
+	}
 }
 
 // ExpireAll expires all elements in the cache
 func (c *Cache[T]) ExpireAll() {
+	c.expireAll()
+}
+
+// Sweep expires all elements in the cache like ExpireAll, and returns the number of removed elements
+func (c *Cache[T]) Sweep() int {
+	return c.expireAll()
+}
+
+// expireAll expires all elements in the cache and returns the number of removed elements
+func (c *Cache[T]) expireAll() int {
 	c.Lock()
 	defer c.Unlock()
 
-	c.set.ExpireAll()
+	removed := c.set.ExpireAll()
+	for _, elem := range removed {
+		c.remove(elem, RemovalExpired)
+	}
+	c.sweepTombstones()
+	c.dropWatchers()
+
+	return len(removed)
+}
+
+// Expired returns true if the given element is in the cache and has expired, but was not cleaned yet
+func (c *Cache[T]) Expired(elem T) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.set.Expired(elem)
 }
 
 // Exists returns true if the given key exists
 func (c *Cache[T]) Exists(elem T) bool {
+	if !c.mayContain(elem) {
+		c.stats.hit(false)
+		return false
+	}
+
 	c.RLock()
 	defer c.RUnlock()
 
-	return c.set.Contains(elem)
+	return c.contains(elem)
+}
+
+// Clone returns an independent copy of the cache
+//
+// Description: Clone creates a new cache with the same clean interval and options, and its own cleaning goroutine.
+// Unexpired elements are copied with their expiration times, so their remaining time to live is preserved.
+func (c *Cache[T]) Clone() *Cache[T] {
+	clone := c.clone()
+	clone.spend()
+
+	return clone
+}
+
+// clone returns an independent copy of the cache, without applying its budget
+//
+// Description: The cache is not locked while the clone is created, since the clone may join its budget.
+func (c *Cache[T]) clone() *Cache[T] {
+	c.RLock()
+	o := c.options
+	interval := c.cleanInterval
+	src := c.set.Copy()
+	elems := c.ordered(src.ToSlice())
+	c.RUnlock()
+
+	o.sink = nil
+	clone := newCache[T](interval, o)
+
+	clone.Lock()
+	defer clone.Unlock()
+
+	clone.set = src
+	clone.set.ExpireAll()
+	for _, elem := range elems {
+		if clone.set.Contains(elem) {
+			clone.track(elem)
+			clone.due(elem)
+		}
+	}
+	clone.shrink()
+
+	return clone
+}
+
+// Merge adds all unexpired elements of other to the cache
+//
+// Description: When an element exists in both caches, resolve is called with both expiration times
+// (the cache's first, other's second) and its result becomes the element's expiration time.
+// A zero time.Time means that the element never expires. If resolve is nil, other's expiration time wins.
+func (c *Cache[T]) Merge(other *Cache[T], resolve func(a, b time.Time) time.Time) {
+	if other == c {
+		return
+	}
+
+	other.RLock()
+	src := other.set.Copy()
+	other.RUnlock()
+
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	for elem := range c.tombstones {
+		if c.tombstoned(elem) {
+			src.Delete(elem)
+		}
+	}
+
+	added := c.set.Merge(src, func(a, b int64) int64 {
+		if resolve == nil {
+			return b
+		}
+		return fromTime(resolve(toTime(a), toTime(b)))
+	})
+	for _, elem := range added {
+		c.track(elem)
+		c.publishAdded(elem)
+	}
+	for elem := range src.All() {
+		c.markDirty(elem)
+		c.due(elem)
+	}
+	c.shrink()
 }
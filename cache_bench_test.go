@@ -0,0 +1,77 @@
+package cacheset
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// BenchmarkCache_Churn replaces the elements of a cache holding a million elements,
+// reporting the garbage collections per replaced element
+func BenchmarkCache_Churn(b *testing.B) {
+	const size = 1_000_000
+
+	c := New[int](time.Hour)
+	defer c.Close()
+	for i := 0; i < size; i++ {
+		c.Add(i, time.Hour)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		c.Delete(i)
+		c.Add(size+i, time.Hour)
+	}
+
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gcs/op")
+}
+
+// BenchmarkCache_ExpireAll measures a cleaning of a cache holding a million elements of which none
+// has expired, with and without an expiration index
+func BenchmarkCache_ExpireAll(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{"Scan", nil},
+		{"Buckets", []Option{WithExpirationBuckets(time.Second)}},
+		{"Wheel", []Option{WithTimingWheel(time.Second)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			c := New[int](time.Hour, bench.opts...)
+			defer c.Close()
+			for i := 0; i < 1_000_000; i++ {
+				c.Add(i, time.Hour)
+			}
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				c.ExpireAll()
+			}
+		})
+	}
+}
+
+// BenchmarkCache_ContainsParallel measures the lookups of all processors in a small cache, whose
+// counters are updated by every lookup
+func BenchmarkCache_ContainsParallel(b *testing.B) {
+	c := New[int](time.Hour)
+	defer c.Close()
+	for i := 0; i < 1024; i++ {
+		c.Add(i, time.Hour)
+	}
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Contains(i & 2047)
+		}
+	})
+}
@@ -0,0 +1,613 @@
+package cacheset
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_Clone(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, time.Minute)
+
+	clone := c.Clone()
+	defer clone.Close()
+
+	t.Run("Clone", func(t *testing.T) {
+		if got := clone.Len(); got != 2 {
+			t.Errorf("Clone() Len = %v, want %v", got, 2)
+		}
+		if got, want := expiration(clone, 2), expiration(c, 2); got != want {
+			t.Errorf("Clone() expiration = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Independent", func(t *testing.T) {
+		clone.Delete(1)
+		if !c.Contains(1) {
+			t.Errorf("Clone() shares its set with the original cache")
+		}
+	})
+}
+
+// expiration returns the monotonic expiration time of the given element
+func expiration[T comparable](c *Cache[T], elem T) int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	e, _ := c.set.Get(elem)
+	return e.expires
+}
+
+func TestCache_Merge(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+	c.Add(1, time.Minute)
+	c.Add(2, 0)
+
+	other := New[int64](time.Minute)
+	defer other.Close()
+	other.Add(1, time.Hour)
+	other.Add(3, time.Minute)
+
+	c.Merge(other, func(a, b time.Time) time.Time {
+		if a.After(b) {
+			return a
+		}
+		return b
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		if got := c.Len(); got != 3 {
+			t.Errorf("Merge() Len = %v, want %v", got, 3)
+		}
+		if got, want := expiration(c, 1), expiration(other, 1); got != want {
+			t.Errorf("Merge() expiration = %v, want %v", got, want)
+		}
+		if got := c.CopySet()[2]; got != 0 {
+			t.Errorf("Merge() expiration = %v, want %v", got, 0)
+		}
+	})
+}
+
+func TestCache_Watch(t *testing.T) {
+	c := New[int64](10 * time.Millisecond)
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, 20*time.Millisecond)
+
+	deleted := c.Watch(1)
+	expired := c.Watch(2)
+	missing := c.Watch(3)
+
+	c.Delete(1)
+
+	t.Run("Deleted", func(t *testing.T) {
+		if ev := <-deleted; ev.Elem != 1 || ev.Reason != RemovalDeleted {
+			t.Errorf("Watch() = %v, want %v", ev, RemovalEvent[int64]{Elem: 1, Reason: RemovalDeleted})
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		if ev := <-expired; ev.Elem != 2 || ev.Reason != RemovalExpired {
+			t.Errorf("Watch() = %v, want %v", ev, RemovalEvent[int64]{Elem: 2, Reason: RemovalExpired})
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		if ev, ok := <-missing; ok {
+			t.Errorf("Watch() = %v, want closed channel", ev)
+		}
+	})
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c := New[int64](10*time.Millisecond, WithLogger(logger))
+	c.Add(1, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	c.Close()
+
+	t.Run("Cleaned", func(t *testing.T) {
+		if out := buf.String(); !strings.Contains(out, "removed=1") {
+			t.Errorf("WithLogger() output = %q, want a cleaning with removed=1", out)
+		}
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	errs := make(chan error, 1)
+	c := New[int64](5*time.Millisecond, WithErrorHandler(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+	defer c.Close()
+
+	// the predicate runs in the cleaning goroutine when it removes the expired element
+	var panicked atomic.Bool
+	sub := c.SubscribeFunc(func(ev Event[int64]) bool {
+		if ev.Kind == EventRemoved && ev.Reason == RemovalExpired && !panicked.Swap(true) {
+			panic("boom")
+		}
+		return false
+	})
+	defer sub.Close()
+	c.Add(1, time.Millisecond)
+
+	var got error
+	select {
+	case got = <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("WithErrorHandler() handler not called after a panic of the cleaning goroutine")
+	}
+	sweeps := c.Health().Sweeps
+
+	t.Run("PanicError", func(t *testing.T) {
+		var perr *PanicError
+		if !errors.As(got, &perr) || perr.Value != "boom" {
+			t.Errorf("WithErrorHandler() error = %v, want a *PanicError with value %v", got, "boom")
+		}
+	})
+
+	t.Run("Alive", func(t *testing.T) {
+		deadline := time.Now().Add(time.Second)
+		for c.Health().Sweeps <= sweeps && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if h := c.Health(); !h.Alive || h.Sweeps <= sweeps {
+			t.Errorf("Health() = %+v, want Alive with more than %v sweeps", h, sweeps)
+		}
+	})
+}
+
+func TestCache_Health(t *testing.T) {
+	c := New[int64](10 * time.Millisecond)
+	c.Add(1, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	t.Run("Running", func(t *testing.T) {
+		h := c.Health()
+		if !h.Alive || !h.Healthy() || h.Sweeps == 0 || h.LastSweep.IsZero() {
+			t.Errorf("Health() = %+v, want a healthy cleaning goroutine", h)
+		}
+	})
+
+	c.Close()
+
+	t.Run("Closed", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		if h := c.Health(); h.Alive || h.Healthy() {
+			t.Errorf("Health() = %+v, want a stopped cleaning goroutine", h)
+		}
+	})
+}
+
+func TestWithTTLJitter(t *testing.T) {
+	c := New[int64](time.Minute, WithTTLJitter(0.1))
+	defer c.Close()
+
+	start := time.Now()
+	for i := int64(0); i < 100; i++ {
+		c.Add(i, time.Hour)
+	}
+	c.Add(100, 0)
+
+	expirations := make(map[int64]struct{})
+	for elem, expires := range c.CopySet() {
+		if elem == 100 {
+			if expires != 0 {
+				t.Errorf("WithTTLJitter() expiration = %v, want %v", expires, 0)
+			}
+			continue
+		}
+		ttl := time.Duration(expires - start.UnixNano())
+		if ttl < 54*time.Minute || ttl > 67*time.Minute {
+			t.Errorf("WithTTLJitter() ttl = %v, want within 10%% of %v", ttl, time.Hour)
+		}
+		expirations[expires] = struct{}{}
+	}
+
+	if len(expirations) < 2 {
+		t.Errorf("WithTTLJitter() expirations are all equal")
+	}
+}
+
+func TestCache_Shutdown(t *testing.T) {
+	c := New[int64](time.Minute)
+	c.Add(1, time.Millisecond)
+	c.Add(2, 0)
+
+	expired := c.Watch(1)
+	closed := c.Watch(2)
+	time.Sleep(5 * time.Millisecond)
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	t.Run("FinalCleaning", func(t *testing.T) {
+		if ev := <-expired; ev.Reason != RemovalExpired {
+			t.Errorf("Shutdown() = %v, want %v", ev.Reason, RemovalExpired)
+		}
+		if _, ok := <-closed; ok {
+			t.Errorf("Shutdown() did not close the watchers")
+		}
+	})
+
+	t.Run("Twice", func(t *testing.T) {
+		if err := c.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown() error = %v", err)
+		}
+		c.Close()
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		short, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		tests := []struct {
+			name string
+			ctx  context.Context
+		}{
+			{name: "Expired", ctx: expired},
+			{name: "Short", ctx: short},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				sink := &blockSink{memSink: memSink{files: map[string][]byte{}}, release: make(chan struct{})}
+				defer close(sink.release)
+				c := New[int64](time.Minute, WithSnapshotSink(sink, "members"))
+				c.Add(1, 0)
+
+				start := time.Now()
+				err := c.Shutdown(tt.ctx)
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+				}
+				if elapsed := time.Since(start); elapsed > time.Second {
+					t.Errorf("Shutdown() returned after %v with a blocking sink", elapsed)
+				}
+			})
+		}
+	})
+}
+
+func TestCache_GetOrAdd(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		name string
+		elem int64
+		want bool
+	}{
+		{name: "Present", elem: 1, want: false},
+		{name: "Expired", elem: 2, want: true},
+		{name: "Missing", elem: 3, want: true},
+		{name: "Added", elem: 3, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.GetOrAdd(tt.elem, time.Minute)
+			if err != nil || got != tt.want {
+				t.Errorf("GetOrAdd() = %v, %v, want %v, nil", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		added := 0
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ok, _ := c.GetOrAdd(4, time.Minute); ok {
+					mu.Lock()
+					added++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if added != 1 {
+			t.Errorf("GetOrAdd() added %v times, want %v", added, 1)
+		}
+	})
+}
+
+func TestCache_Len(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := int64(0); i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := int64(0); j < 100; j++ {
+				c.Add(i*100+j, 0)
+				_ = c.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	tests := []struct {
+		name string
+		op   func()
+		want int
+	}{
+		{name: "Add", op: func() {}, want: 800},
+		{name: "Delete", op: func() { c.Delete(0) }, want: 799},
+		{name: "Clear", op: c.Clear, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.op()
+			if got := c.Len(); got != tt.want {
+				t.Errorf("Len() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Reset(t *testing.T) {
+	c := New[int64](10*time.Millisecond, WithCapacity(2))
+	c.Add(1, 0)
+	c.Contains(1)
+
+	tests := []struct {
+		name  string
+		close bool
+	}{
+		{name: "Open", close: false},
+		{name: "Closed", close: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.close {
+				c.Close()
+			}
+			c.Reset()
+
+			if got := c.Stats(); got.Len != 0 || got.Hits != 0 || got.Adds != 0 {
+				t.Errorf("Reset() Stats = %+v, want zero counters", got)
+			}
+			c.Add(1, time.Millisecond)
+			c.Add(2, 0)
+			c.Add(3, 0)
+			if got := c.Len(); got != 2 {
+				t.Errorf("Reset() Len = %v, want the capacity %v", got, 2)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if h := c.Health(); !h.Alive || h.Sweeps == 0 {
+				t.Errorf("Reset() Health = %+v, want a running cleaning goroutine", h)
+			}
+		})
+	}
+	c.Close()
+}
+
+func TestCache_DeleteFunc(t *testing.T) {
+	c := New[string](time.Minute)
+	defer c.Close()
+	for _, elem := range []string{"user:1", "user:2", "session:1", "session:2", "session:3"} {
+		c.Add(elem, 0)
+	}
+	deleted := c.Watch("user:1")
+
+	t.Run("DeletePrefix", func(t *testing.T) {
+		if got := DeletePrefix(c, "user:"); got != 2 {
+			t.Errorf("DeletePrefix() = %v, want %v", got, 2)
+		}
+		if ev := <-deleted; ev.Reason != RemovalDeleted {
+			t.Errorf("DeletePrefix() reason = %v, want %v", ev.Reason, RemovalDeleted)
+		}
+	})
+
+	t.Run("DeleteFunc", func(t *testing.T) {
+		if got := c.DeleteFunc(func(elem string) bool { return elem != "session:1" }); got != 2 {
+			t.Errorf("DeleteFunc() = %v, want %v", got, 2)
+		}
+		if got := c.ToSlice(); len(got) != 1 || got[0] != "session:1" {
+			t.Errorf("DeleteFunc() left %v, want %v", got, []string{"session:1"})
+		}
+		if got := c.Stats().Deletes; got != 4 {
+			t.Errorf("Stats().Deletes = %v, want %v", got, 4)
+		}
+	})
+}
+
+func TestCache_AddReport(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, time.Hour)
+	c.Add(3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		name      string
+		elem      int64
+		existed   bool
+		permanent bool
+	}{
+		{name: "Permanent", elem: 1, existed: true, permanent: true},
+		{name: "Refreshed", elem: 2, existed: true, permanent: false},
+		{name: "Expired", elem: 3, existed: false, permanent: true},
+		{name: "Inserted", elem: 4, existed: false, permanent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existed, prev, err := c.AddReport(tt.elem, time.Minute)
+			if err != nil || existed != tt.existed || prev.IsZero() != tt.permanent {
+				t.Errorf("AddReport() = %v, %v, %v, want %v, zero time %v, nil", existed, prev, err, tt.existed, tt.permanent)
+			}
+		})
+	}
+}
+
+func TestCache_Peek(t *testing.T) {
+	c := New[string](time.Hour, WithCapacity(2))
+	defer c.Close()
+	c.Add("a", 0)
+	c.Add("b", 0)
+
+	if !c.Peek("b") || c.Peek("missing") {
+		t.Errorf("Peek() does not report the membership")
+	}
+	// peeking at the least recently used element does not save it from the eviction
+	c.Peek("a")
+	c.Add("c", 0)
+	if c.Peek("a") {
+		t.Errorf("Peek() counted as an access")
+	}
+	if s := c.Stats(); s.Hits+s.Misses != 0 {
+		t.Errorf("Peek() counted %v hits and %v misses, want none", s.Hits, s.Misses)
+	}
+}
+
+func TestCache_Consume(t *testing.T) {
+	c := New[string](time.Hour)
+	defer c.Close()
+	c.Add("token", 0)
+	c.Add("expired", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var consumed atomic.Int64
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.Consume("token") {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := consumed.Load(); n != 1 || c.Contains("token") {
+		t.Errorf("Consume() succeeded %v times, want %v", n, 1)
+	}
+	if c.Consume("expired") || c.Consume("missing") {
+		t.Errorf("Consume() = %v, want %v for expired and missing elements", true, false)
+	}
+	if s := c.Stats(); s.Deletes != 1 || s.Hits != 1 {
+		t.Errorf("Stats() = %+v, want %v delete and %v hit", s, 1, 1)
+	}
+}
+
+func TestCache_TryDelete(t *testing.T) {
+	c := New[string](time.Hour)
+	defer c.Close()
+	c.Add("present", 0)
+	c.Add("expired", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		elem string
+		want bool
+	}{
+		{elem: "present", want: true},
+		{elem: "present", want: false},
+		{elem: "expired", want: true},
+		{elem: "missing", want: false},
+	}
+	for _, tt := range tests {
+		if got := c.TryDelete(tt.elem); got != tt.want {
+			t.Errorf("TryDelete(%v) = %v, want %v", tt.elem, got, tt.want)
+		}
+	}
+	if s := c.Stats(); s.Deletes != 2 || s.Hits+s.Misses != 0 {
+		t.Errorf("Stats() = %+v, want %v deletes and no lookups", s, 2)
+	}
+}
+
+func TestCache_WaitFor(t *testing.T) {
+	c := New[string](time.Minute)
+	defer c.Close()
+
+	t.Run("Present", func(t *testing.T) {
+		_ = c.Add("ready", 0)
+		if err := c.WaitFor(context.Background(), "ready"); err != nil {
+			t.Errorf("WaitFor() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("Added", func(t *testing.T) {
+		done := make(chan error, 1)
+		go func() {
+			done <- c.WaitFor(context.Background(), "finished")
+		}()
+		time.Sleep(10 * time.Millisecond)
+		_ = c.Add("other", 0)
+		_ = c.Add("finished", 0)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("WaitFor() error = %v, want nil", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("WaitFor() did not return after the element was added")
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := c.WaitFor(ctx, "never"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("WaitFor() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		d := New[string](time.Minute)
+		done := make(chan error, 1)
+		go func() {
+			done <- d.WaitFor(context.Background(), "never")
+		}()
+		time.Sleep(10 * time.Millisecond)
+		d.Close()
+		if err := <-done; !errors.Is(err, ErrClosed) {
+			t.Errorf("WaitFor() error = %v, want %v", err, ErrClosed)
+		}
+	})
+}
@@ -0,0 +1,487 @@
+// Package cachesetbolt stores the elements of a set on disk in a bbolt database, for the sets too large
+// to fit in memory.
+//
+// Path: cachesetbolt/bolt.go
+//
+// Description: bolt.go contains Set, an implementation of cacheset.CacheSet keeping each element and its
+// expiration time in a bbolt file (go.etcd.io/bbolt). An index ordered by expiration time lets a sweeper
+// delete the expired elements in small transactions, without visiting the unexpired ones.
+//
+// bbolt reuses the pages freed by the deletions but never shrinks its file: a file that grew during a
+// peak keeps its size, and can be compacted offline with bbolt compact while the set is closed.
+//
+// Usage:
+//
+//	s, err := cachesetbolt.Open[string]("dedup.db", cachesetbolt.WithSweepInterval(time.Minute))
+//	if err != nil {
+//		return err
+//	}
+//	defer s.Close()
+//	err = s.Add(messageID, 30*24*time.Hour)
+package cachesetbolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	bolt "go.etcd.io/bbolt"
+)
+
+// buckets of the database
+var (
+	elemsBucket  = []byte("elems")  // elemsBucket maps each element to its expiration time
+	expiryBucket = []byte("expiry") // expiryBucket indexes the expiring elements by expiration time then element
+	metaBucket   = []byte("meta")   // metaBucket holds the number of elements
+	lenKey       = []byte("len")    // lenKey is the key of the number of elements in metaBucket
+)
+
+// Option configures a Set
+type Option func(*config)
+
+// config are the settings of a Set
+type config struct {
+	sweepInterval time.Duration // sweepInterval is the duration between two sweeps, 0 meaning no sweeper
+	sweepBatch    int           // sweepBatch is the maximum number of elements deleted per transaction
+	timeout       time.Duration // timeout bounds the wait for the file lock of the database
+	errorHandler  func(error)   // errorHandler is called with the errors of the methods that cannot return one
+}
+
+// WithSweepInterval sets the duration between two sweeps of the expired elements, 1 minute by default,
+// 0 disabling the sweeper so that the expired elements are only removed by ExpireAll
+func WithSweepInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.sweepInterval = max(interval, 0)
+	}
+}
+
+// WithSweepBatch sets the maximum number of expired elements deleted per transaction, 1000 by default
+//
+// Description: The sweeps delete the expired elements in several transactions, so that a large backlog
+// does not hold the writer lock of the database for long, nor grow its list of free pages at once.
+func WithSweepBatch(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.sweepBatch = n
+		}
+	}
+}
+
+// WithTimeout sets the maximum wait for the file lock of the database held by another process, 1 second
+// by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithErrorHandler sets a function called with the errors of the methods that cannot return one, such as
+// Contains and the sweeps, which report them as missing elements or ignore them
+func WithErrorHandler(handler func(error)) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+	}
+}
+
+// Set is a set whose elements are stored in a bbolt database
+//
+// Description: The elements are written in their JSON form, so T must be encodable in JSON and its JSON
+// form must identify it, as for the strings, the integers and the structs of them. The expiration times
+// are wall-clock times, so that they survive the restarts of the process. Each addition and deletion is
+// a transaction, durable when it returns.
+type Set[T comparable] struct {
+	db     *bolt.DB
+	config config
+
+	close     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	adds    atomic.Uint64
+	deletes atomic.Uint64
+	expired atomic.Uint64
+}
+
+var _ cacheset.ColdTier[int] = (*Set[int])(nil)
+
+// Open opens or creates the database at path and returns a Set of its elements, starting its sweeper
+func Open[T comparable](path string, opts ...Option) (*Set[T], error) {
+	cfg := config{sweepInterval: time.Minute, sweepBatch: 1000, timeout: time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: cfg.timeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{elemsBucket, expiryBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	s := &Set[T]{db: db, config: cfg, close: make(chan struct{}), done: make(chan struct{})}
+	go s.sweeper()
+	return s, nil
+}
+
+// Add adds the given element until ttl elapses, 0 meaning no expiration
+func (s *Set[T]) Add(elem T, ttl time.Duration) error {
+	key, err := json.Marshal(elem)
+	if err != nil {
+		return err
+	}
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixNano()
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		elems := tx.Bucket(elemsBucket)
+		if old := elems.Get(key); old != nil {
+			if err := unindex(tx, key, old); err != nil {
+				return err
+			}
+		} else if err := addLen(tx, 1); err != nil {
+			return err
+		}
+		if err := elems.Put(key, encodeTime(expires)); err != nil {
+			return err
+		}
+		if expires == 0 {
+			return nil
+		}
+		return tx.Bucket(expiryBucket).Put(indexKey(expires, key), nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.adds.Add(1)
+	return nil
+}
+
+// Contains returns true if the given element is in the set and has not expired
+func (s *Set[T]) Contains(elem T) bool {
+	_, found := s.Expiration(elem)
+	if found {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+	return found
+}
+
+// Expiration returns the expiration time of the given element, the zero time.Time meaning never, and false
+// if it is not in the set or has expired
+//
+// Description: Unlike Contains, Expiration is not counted as a hit or a miss.
+func (s *Set[T]) Expiration(elem T) (time.Time, bool) {
+	key, err := json.Marshal(elem)
+	if err != nil {
+		s.report(err)
+		return time.Time{}, false
+	}
+
+	var expires int64
+	var found bool
+	err = s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(elemsBucket).Get(key)
+		if v == nil {
+			return nil
+		}
+		expires = decodeTime(v)
+		found = expires == 0 || time.Now().UnixNano() < expires
+		return nil
+	})
+	if err != nil {
+		s.report(err)
+		return time.Time{}, false
+	}
+	if !found || expires == 0 {
+		return time.Time{}, found
+	}
+	return time.Unix(0, expires), true
+}
+
+// Delete removes the given element from the set
+func (s *Set[T]) Delete(elem T) {
+	key, err := json.Marshal(elem)
+	if err != nil {
+		s.report(err)
+		return
+	}
+
+	var deleted bool
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		old := tx.Bucket(elemsBucket).Get(key)
+		if old == nil {
+			return nil
+		}
+		deleted = true
+		return remove(tx, key, old)
+	})
+	if err != nil {
+		s.report(err)
+		return
+	}
+	if deleted {
+		s.deletes.Add(1)
+	}
+}
+
+// Clear removes all elements from the set
+func (s *Set[T]) Clear() {
+	var n uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		n = readLen(tx)
+		for _, name := range [][]byte{elemsBucket, expiryBucket, metaBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.report(err)
+		return
+	}
+	s.deletes.Add(n)
+}
+
+// Len returns the number of elements of the set that have not expired
+//
+// Description: Len reads the number of elements kept up to date by the transactions, and subtracts the
+// expired elements not swept yet, visiting only those in the expiration index.
+func (s *Set[T]) Len() int {
+	var n int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		n = int(readLen(tx))
+		now := time.Now().UnixNano()
+		c := tx.Bucket(expiryBucket).Cursor()
+		for k, _ := c.First(); k != nil && indexTime(k) <= now; k, _ = c.Next() {
+			n--
+		}
+		return nil
+	})
+	if err != nil {
+		s.report(err)
+		return 0
+	}
+	return n
+}
+
+// ToSlice returns the elements of the set that have not expired, visiting the whole database
+func (s *Set[T]) ToSlice() []T {
+	var elems []T
+	err := s.db.View(func(tx *bolt.Tx) error {
+		now := time.Now().UnixNano()
+		return tx.Bucket(elemsBucket).ForEach(func(k, v []byte) error {
+			if expires := decodeTime(v); expires != 0 && expires <= now {
+				return nil
+			}
+			var elem T
+			if err := json.Unmarshal(k, &elem); err != nil {
+				return err
+			}
+			elems = append(elems, elem)
+			return nil
+		})
+	})
+	if err != nil {
+		s.report(err)
+	}
+	return elems
+}
+
+// ExpireAll removes all expired elements, see WithSweepBatch
+func (s *Set[T]) ExpireAll() {
+	if _, err := s.sweep(); err != nil {
+		s.report(err)
+	}
+}
+
+// Stats returns the counters of the set
+func (s *Set[T]) Stats() cacheset.Stats {
+	return cacheset.Stats{
+		Len:         s.Len(),
+		Hits:        s.hits.Load(),
+		Misses:      s.misses.Load(),
+		Adds:        s.adds.Load(),
+		Deletes:     s.deletes.Load(),
+		Expirations: s.expired.Load(),
+	}
+}
+
+// Close stops the sweeper and closes the database
+func (s *Set[T]) Close() {
+	if err := s.Shutdown(context.Background()); err != nil {
+		s.report(err)
+	}
+}
+
+// Shutdown stops the sweeper, waiting for its current sweep until ctx is done, and closes the database
+func (s *Set[T]) Shutdown(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.close)
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			s.closeErr = ctx.Err()
+		}
+		if err := s.db.Close(); err != nil {
+			s.closeErr = errors.Join(s.closeErr, err)
+		}
+	})
+	return s.closeErr
+}
+
+// sweeper sweeps the expired elements every sweep interval until the set is closed
+func (s *Set[T]) sweeper() {
+	defer close(s.done)
+	if s.config.sweepInterval == 0 {
+		<-s.close
+		return
+	}
+	ticker := time.NewTicker(s.config.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.close:
+			return
+		case <-ticker.C:
+			if _, err := s.sweep(); err != nil {
+				s.report(err)
+			}
+		}
+	}
+}
+
+// sweep deletes the expired elements in transactions of up to sweepBatch elements, and returns their number
+func (s *Set[T]) sweep() (int, error) {
+	var swept int
+	for {
+		var n int
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			n = 0
+			now := time.Now().UnixNano()
+			elems, expiry := tx.Bucket(elemsBucket), tx.Bucket(expiryBucket)
+			c := expiry.Cursor()
+			for k, _ := c.First(); k != nil && indexTime(k) <= now && n < s.config.sweepBatch; k, _ = c.First() {
+				k = bytes.Clone(k)
+				if err := expiry.Delete(k); err != nil {
+					return err
+				}
+				if key := k[8:]; elems.Get(key) != nil {
+					if err := elems.Delete(key); err != nil {
+						return err
+					}
+					if err := addLen(tx, -1); err != nil {
+						return err
+					}
+					n++
+				}
+			}
+			return nil
+		})
+		swept += n
+		s.expired.Add(uint64(n))
+		if err != nil || n < s.config.sweepBatch {
+			return swept, err
+		}
+		select {
+		case <-s.close:
+			return swept, nil
+		default:
+		}
+	}
+}
+
+// remove removes the given element, whose encoded expiration time is old, and its index entry
+func remove(tx *bolt.Tx, key, old []byte) error {
+	if err := unindex(tx, key, old); err != nil {
+		return err
+	}
+	if err := tx.Bucket(elemsBucket).Delete(key); err != nil {
+		return err
+	}
+	return addLen(tx, -1)
+}
+
+// unindex removes the index entry of the given element, whose encoded expiration time is old
+func unindex(tx *bolt.Tx, key, old []byte) error {
+	if old == nil {
+		return nil
+	}
+	expires := decodeTime(old)
+	if expires == 0 {
+		return nil
+	}
+	return tx.Bucket(expiryBucket).Delete(indexKey(expires, key))
+}
+
+// readLen returns the number of elements of the database
+func readLen(tx *bolt.Tx) uint64 {
+	v := tx.Bucket(metaBucket).Get(lenKey)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+// addLen adds delta to the number of elements of the database
+func addLen(tx *bolt.Tx, delta int64) error {
+	n := uint64(int64(readLen(tx)) + delta)
+	return tx.Bucket(metaBucket).Put(lenKey, binary.BigEndian.AppendUint64(nil, n))
+}
+
+// indexKey returns the key of the index entry of the given element expiring at expires, ordered by
+// expiration time
+func indexKey(expires int64, key []byte) []byte {
+	return append(encodeTime(expires), key...)
+}
+
+// indexTime returns the expiration time of the given index key
+func indexTime(k []byte) int64 {
+	return decodeTime(k[:8])
+}
+
+// encodeTime encodes a time in nanoseconds since the Unix epoch, in big-endian so that the encoded times
+// sort like the times
+func encodeTime(t int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t))
+}
+
+// decodeTime decodes a time encoded by encodeTime
+func decodeTime(b []byte) int64 {
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// report passes the given error to the error handler
+func (s *Set[T]) report(err error) {
+	if s.config.errorHandler != nil {
+		s.config.errorHandler(err)
+	}
+}
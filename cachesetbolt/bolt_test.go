@@ -0,0 +1,98 @@
+package cachesetbolt
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"github.com/corentings/go-set/cachetest"
+)
+
+// open opens a Set in a new file of the temporary directory of the test
+func open[T comparable](t *testing.T, opts ...Option) (*Set[T], string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "set.db")
+	s, err := Open[T](path, opts...)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return s, path
+}
+
+func TestSet_Conformance(t *testing.T) {
+	dir := t.TempDir()
+	var n int
+	cachetest.RunConformance(t, func() cacheset.CacheSet[string] {
+		n++
+		s, err := Open[string](filepath.Join(dir, fmt.Sprintf("set%d.db", n)))
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		return s
+	}, cachetest.StringKey)
+}
+
+func TestTiered_Conformance(t *testing.T) {
+	dir := t.TempDir()
+	var n int
+	cachetest.RunConformance(t, func() cacheset.CacheSet[string] {
+		n++
+		cold, err := Open[string](filepath.Join(dir, fmt.Sprintf("tiered%d.db", n)))
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		return cacheset.NewTiered[string](16, cold, time.Minute)
+	}, cachetest.StringKey)
+}
+
+func TestSet_Reopen(t *testing.T) {
+	s, path := open[int](t)
+	_ = s.Add(1, time.Hour)
+	_ = s.Add(2, 0)
+	_ = s.Add(3, time.Hour)
+	s.Delete(3)
+	s.Close()
+
+	s, err := Open[int](path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+	if !s.Contains(1) || !s.Contains(2) || s.Contains(3) || s.Len() != 2 {
+		t.Errorf("ToSlice() = %v after reopening, want %v", s.ToSlice(), []int{1, 2})
+	}
+	if expires, ok := s.Expiration(1); !ok || time.Until(expires) < 59*time.Minute {
+		t.Errorf("Expiration() = %v, %v, want about an hour", expires, ok)
+	}
+	if expires, ok := s.Expiration(2); !ok || !expires.IsZero() {
+		t.Errorf("Expiration() = %v, %v, want no expiration", expires, ok)
+	}
+}
+
+func TestSet_Sweep(t *testing.T) {
+	s, _ := open[int](t, WithSweepBatch(7), WithSweepInterval(0))
+	defer s.Close()
+
+	for i := range 50 {
+		_ = s.Add(i, time.Millisecond)
+	}
+	_ = s.Add(1, time.Hour)
+	_ = s.Add(100, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if got := s.Len(); got != 2 {
+		t.Errorf("Len() = %v before the sweep, want %v", got, 2)
+	}
+	swept, err := s.sweep()
+	if err != nil || swept != 49 {
+		t.Errorf("sweep() = %v, %v, want %v", swept, err, 49)
+	}
+	if got := s.Len(); got != 2 || !s.Contains(1) {
+		t.Errorf("Len() = %v after the sweep, want %v", got, 2)
+	}
+	if got := s.Stats().Expirations; got != 49 {
+		t.Errorf("Stats().Expirations = %v, want %v", got, 49)
+	}
+}
@@ -0,0 +1,339 @@
+// Package cachesetgossip replicates a cacheset.ORSet between the nodes of a small cluster by gossip.
+//
+// Path: cachesetgossip/node.go
+//
+// Description: node.go contains a gossip node pushing the recent additions and removals of its set
+// to a few random peers, and periodically comparing a digest of its state with a random peer
+// (anti-entropy) to repair the deltas lost while a peer was down. It only depends on the standard
+// library: the messages are encoded with encoding/gob over short-lived TCP connections.
+//
+// Usage:
+//
+//	ln, _ := net.Listen("tcp", ":7946")
+//	node := cachesetgossip.New(cacheset.NewORSet[string]("node-1"), ln, []string{"node-2:7946", "node-3:7946"})
+//	defer node.Close()
+//
+//	node.Add("request-id", time.Hour)
+package cachesetgossip
+
+import (
+	"cmp"
+	"encoding/binary"
+	"encoding/gob"
+	"hash/fnv"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// Option configures a Node
+type Option func(*config)
+
+// config is the configuration of a Node
+type config struct {
+	gossipInterval time.Duration // gossipInterval is the interval between two pushes of the recent deltas
+	syncInterval   time.Duration // syncInterval is the interval between two anti-entropy rounds
+	fanout         int           // fanout is the number of peers receiving each push
+	timeout        time.Duration // timeout bounds the connection and the exchange of a message with a peer
+	advertise      string        // advertise is the address given to the peers to reach this node
+	logger         *slog.Logger  // logger reports the failed exchanges
+}
+
+// WithGossipInterval sets the interval between two pushes of the recent deltas, 200ms by default
+func WithGossipInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.gossipInterval = d
+	}
+}
+
+// WithSyncInterval sets the interval between two anti-entropy rounds, 10s by default
+func WithSyncInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.syncInterval = d
+	}
+}
+
+// WithFanout sets the number of random peers receiving each push, 3 by default
+func WithFanout(n int) Option {
+	return func(c *config) {
+		c.fanout = n
+	}
+}
+
+// WithTimeout bounds the connection and the exchange of a message with a peer, 1s by default
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithAdvertiseAddr sets the address given to the peers to reach this node, the listener's address by default
+func WithAdvertiseAddr(addr string) Option {
+	return func(c *config) {
+		c.advertise = addr
+	}
+}
+
+// WithLogger sets the logger reporting the failed exchanges, nothing is logged by default
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// kind is the kind of a message
+type kind int
+
+const (
+	// kindDelta carries recent deltas
+	kindDelta kind = iota
+	// kindDigest carries the digest of the sender's state
+	kindDigest
+	// kindState carries the full state of the sender
+	kindState
+)
+
+// message is exchanged between the nodes
+type message[T comparable] struct {
+	Kind   kind                   // Kind is the kind of the message
+	From   string                 // From is the advertised address of the sender
+	Delta  cacheset.ORSetDelta[T] // Delta is the delta or state carried by a kindDelta or kindState message
+	Digest uint64                 // Digest is the digest carried by a kindDigest message
+	Reply  bool                   // Reply asks the receiver of a kindState message to send back its own state
+}
+
+// Node replicates an ORSet with its peers
+type Node[T comparable] struct {
+	set     *cacheset.ORSet[T]
+	ln      net.Listener
+	cfg     config
+	mu      sync.Mutex
+	peers   []string               // peers are the addresses of the other nodes
+	pending cacheset.ORSetDelta[T] // pending are the local changes not pushed yet
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New returns a node replicating set with the given peers, receiving their messages on ln
+//
+// Description: The node owns ln and closes it on Close. The set may be shared with other code,
+// but only the changes made through the node are pushed: the other ones are repaired by anti-entropy.
+func New[T comparable](set *cacheset.ORSet[T], ln net.Listener, peers []string, opts ...Option) *Node[T] {
+	cfg := config{
+		gossipInterval: 200 * time.Millisecond,
+		syncInterval:   10 * time.Second,
+		fanout:         3,
+		timeout:        time.Second,
+		advertise:      ln.Addr().String(),
+		logger:         slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	n := &Node[T]{
+		set:   set,
+		ln:    ln,
+		cfg:   cfg,
+		peers: slices.Clone(peers),
+		done:  make(chan struct{}),
+	}
+	n.wg.Add(2)
+	go n.serve()
+	go n.run()
+	return n
+}
+
+// Set returns the replicated set
+func (n *Node[T]) Set() *cacheset.ORSet[T] {
+	return n.set
+}
+
+// Add adds the given element for the given duration and pushes the addition to the peers
+func (n *Node[T]) Add(elem T, ttl time.Duration) {
+	n.enqueue(n.set.Add(elem, ttl))
+}
+
+// Remove removes the given element and pushes the removal to the peers
+func (n *Node[T]) Remove(elem T) {
+	n.enqueue(n.set.Remove(elem))
+}
+
+// Contains returns true if the given element is in the replicated set
+func (n *Node[T]) Contains(elem T) bool {
+	return n.set.Contains(elem)
+}
+
+// SetPeers replaces the addresses of the other nodes
+func (n *Node[T]) SetPeers(peers []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.peers = slices.Clone(peers)
+}
+
+// Close stops the node and closes its listener, the pending deltas are not pushed
+func (n *Node[T]) Close() error {
+	close(n.done)
+	err := n.ln.Close()
+	n.wg.Wait()
+	return err
+}
+
+// enqueue records a local change to push on the next gossip round
+func (n *Node[T]) enqueue(delta cacheset.ORSetDelta[T]) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pending.Adds = append(n.pending.Adds, delta.Adds...)
+	n.pending.Removes = append(n.pending.Removes, delta.Removes...)
+}
+
+// run pushes the pending deltas and starts the anti-entropy rounds until the node is closed
+func (n *Node[T]) run() {
+	defer n.wg.Done()
+
+	gossip := time.NewTicker(n.cfg.gossipInterval)
+	defer gossip.Stop()
+	antiEntropy := time.NewTicker(n.cfg.syncInterval)
+	defer antiEntropy.Stop()
+
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-gossip.C:
+			n.mu.Lock()
+			delta := n.pending
+			n.pending = cacheset.ORSetDelta[T]{}
+			n.mu.Unlock()
+			if delta.Empty() {
+				continue
+			}
+			for _, peer := range n.pick(n.cfg.fanout) {
+				n.send(peer, message[T]{Kind: kindDelta, Delta: delta})
+			}
+		case <-antiEntropy.C:
+			n.set.Compact()
+			for _, peer := range n.pick(1) {
+				n.send(peer, message[T]{Kind: kindDigest, Digest: digest(n.set.State())})
+			}
+		}
+	}
+}
+
+// pick returns up to k random peers
+func (n *Node[T]) pick(k int) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	picked := make([]string, 0, min(k, len(n.peers)))
+	for _, i := range rand.Perm(len(n.peers)) {
+		if len(picked) == k {
+			break
+		}
+		picked = append(picked, n.peers[i])
+	}
+	return picked
+}
+
+// serve handles the incoming messages until the listener is closed
+func (n *Node[T]) serve() {
+	defer n.wg.Done()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := n.ln.Accept()
+		if err != nil {
+			select {
+			case <-n.done:
+			default:
+				n.cfg.logger.Error("cachesetgossip: accept failed", slog.Any("error", err))
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.handle(conn)
+		}()
+	}
+}
+
+// handle decodes and applies the message received on conn
+func (n *Node[T]) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var msg message[T]
+	_ = conn.SetDeadline(time.Now().Add(n.cfg.timeout))
+	if err := gob.NewDecoder(conn).Decode(&msg); err != nil {
+		n.cfg.logger.Warn("cachesetgossip: invalid message", slog.String("remote", conn.RemoteAddr().String()), slog.Any("error", err))
+		return
+	}
+
+	switch msg.Kind {
+	case kindDelta:
+		n.set.Merge(msg.Delta)
+	case kindDigest:
+		if state := n.set.State(); digest(state) != msg.Digest {
+			n.send(msg.From, message[T]{Kind: kindState, Delta: state, Reply: true})
+		}
+	case kindState:
+		n.set.Merge(msg.Delta)
+		if msg.Reply {
+			n.send(msg.From, message[T]{Kind: kindState, Delta: n.set.State()})
+		}
+	}
+}
+
+// send sends the given message to the given peer, logging the failures
+func (n *Node[T]) send(peer string, msg message[T]) {
+	msg.From = n.cfg.advertise
+	conn, err := net.DialTimeout("tcp", peer, n.cfg.timeout)
+	if err != nil {
+		n.cfg.logger.Warn("cachesetgossip: peer unreachable", slog.String("peer", peer), slog.Any("error", err))
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(n.cfg.timeout))
+	if err := gob.NewEncoder(conn).Encode(msg); err != nil {
+		n.cfg.logger.Warn("cachesetgossip: send failed", slog.String("peer", peer), slog.Any("error", err))
+	}
+}
+
+// digest returns a hash of the dots of the given state, equal on two nodes holding the same additions and tombstones
+func digest[T comparable](state cacheset.ORSetDelta[T]) uint64 {
+	adds := make([]cacheset.Dot, 0, len(state.Adds))
+	for _, add := range state.Adds {
+		adds = append(adds, add.Dot)
+	}
+	removes := make([]cacheset.Dot, 0, len(state.Removes))
+	for _, rm := range state.Removes {
+		removes = append(removes, rm.Dot)
+	}
+
+	h := fnv.New64a()
+	for _, dots := range [][]cacheset.Dot{adds, removes} {
+		slices.SortFunc(dots, compareDots)
+		for _, dot := range dots {
+			h.Write([]byte(dot.Replica))
+			h.Write(binary.LittleEndian.AppendUint64([]byte{0}, dot.Epoch))
+			h.Write(binary.LittleEndian.AppendUint64(nil, dot.Seq))
+		}
+		h.Write([]byte{0xff})
+	}
+	return h.Sum64()
+}
+
+// compareDots orders the dots by replica, epoch and sequence number
+func compareDots(a, b cacheset.Dot) int {
+	return cmp.Or(strings.Compare(a.Replica, b.Replica), cmp.Compare(a.Epoch, b.Epoch), cmp.Compare(a.Seq, b.Seq))
+}
@@ -0,0 +1,85 @@
+package cachesetgossip
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// cluster starts n nodes knowing each other
+func cluster(t *testing.T, n int, opts ...Option) []*Node[string] {
+	t.Helper()
+	listeners := make([]net.Listener, n)
+	addrs := make([]string, n)
+	for i := range n {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listeners[i], addrs[i] = ln, ln.Addr().String()
+	}
+	nodes := make([]*Node[string], n)
+	for i, ln := range listeners {
+		var peers []string
+		for j, addr := range addrs {
+			if j != i {
+				peers = append(peers, addr)
+			}
+		}
+		nodes[i] = New(cacheset.NewORSet[string](addrs[i]), ln, peers, opts...)
+		t.Cleanup(func() { nodes[i].Close() })
+	}
+	return nodes
+}
+
+// eventually fails the test if cond is still false after a second
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Errorf("%s not reached", what)
+}
+
+func TestNode_Gossip(t *testing.T) {
+	nodes := cluster(t, 3, WithGossipInterval(10*time.Millisecond), WithSyncInterval(time.Hour))
+
+	nodes[0].Add("a", 0)
+	for i, n := range nodes {
+		eventually(t, fmt.Sprintf("node %d Contains(a)", i), func() bool { return n.Contains("a") })
+	}
+
+	nodes[1].Remove("a")
+	for i, n := range nodes {
+		eventually(t, fmt.Sprintf("node %d !Contains(a)", i), func() bool { return !n.Contains("a") })
+	}
+}
+
+func TestNode_AntiEntropy(t *testing.T) {
+	nodes := cluster(t, 2, WithGossipInterval(10*time.Millisecond), WithSyncInterval(20*time.Millisecond))
+
+	// changes made on the sets directly are not pushed, only repaired
+	nodes[0].Set().Add("a", 0)
+	nodes[1].Set().Add("b", 0)
+	for _, n := range nodes {
+		eventually(t, "Contains(a) && Contains(b)", func() bool { return n.Contains("a") && n.Contains("b") })
+	}
+}
+
+func TestDigest(t *testing.T) {
+	a, b := cacheset.NewORSet[int]("a"), cacheset.NewORSet[int]("b")
+	b.Merge(a.Add(1, 0))
+	a.Merge(b.Add(2, 0))
+	if digest(a.State()) != digest(b.State()) {
+		t.Errorf("digest() differs on converged sets")
+	}
+	a.Remove(1)
+	if digest(a.State()) == digest(b.State()) {
+		t.Errorf("digest() equal on diverged sets")
+	}
+}
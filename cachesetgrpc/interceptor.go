@@ -0,0 +1,98 @@
+// Package cachesetgrpc rejects the duplicated gRPC calls carrying an idempotency key, replicates caches over gRPC
+// and serves them as the nodes of a cacheset.Cluster.
+//
+// Path: cachesetgrpc/interceptor.go
+//
+// Description: interceptor.go contains a unary server interceptor recording the idempotency keys
+// of the calls in a cache and rejecting the calls whose key was seen within the TTL.
+//
+// Usage:
+//
+//	keys := cacheset.New[string](time.Minute)
+//	defer keys.Close()
+//
+//	srv := grpc.NewServer(grpc.UnaryInterceptor(cachesetgrpc.UnaryServerInterceptor(keys, time.Hour)))
+package cachesetgrpc
+
+import (
+	"context"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMetadataKey is the metadata key carrying the idempotency key by default
+const DefaultMetadataKey = "idempotency-key"
+
+// KeyExtractor returns the idempotency key of a call and false if the call has none
+type KeyExtractor func(ctx context.Context, info *grpc.UnaryServerInfo) (string, bool)
+
+// Option configures the interceptor
+type Option func(*config)
+
+// config is the configuration of the interceptor
+type config struct {
+	extractor KeyExtractor
+}
+
+// WithKeyExtractor sets the function returning the idempotency key of a call
+func WithKeyExtractor(extractor KeyExtractor) Option {
+	return func(c *config) {
+		c.extractor = extractor
+	}
+}
+
+// WithMetadataKey reads the idempotency key from the given metadata key instead of DefaultMetadataKey
+func WithMetadataKey(name string) Option {
+	return WithKeyExtractor(MetadataKey(name))
+}
+
+// MetadataKey returns a KeyExtractor reading the first value of the given incoming metadata key,
+// prefixed with the full method name so that the same key may be reused by different methods
+func MetadataKey(name string) KeyExtractor {
+	return func(ctx context.Context, info *grpc.UnaryServerInfo) (string, bool) {
+		values := metadata.ValueFromIncomingContext(ctx, name)
+		if len(values) == 0 || values[0] == "" {
+			return "", false
+		}
+		return info.FullMethod + "/" + values[0], true
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor rejecting with codes.AlreadyExists the calls whose
+// idempotency key was recorded in keys during the last ttl
+//
+// Description: The calls without an idempotency key are not checked. The key of a call is recorded
+// before its handler runs, so concurrent duplicates are rejected too, and forgotten if the handler
+// fails, so that the call can be retried.
+func UnaryServerInterceptor(keys *cacheset.Cache[string], ttl time.Duration, opts ...Option) grpc.UnaryServerInterceptor {
+	cfg := config{extractor: MetadataKey(DefaultMetadataKey)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key, ok := cfg.extractor(ctx, info)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		added, err := keys.GetOrAdd(key, ttl)
+		if err != nil {
+			return nil, status.Errorf(codes.ResourceExhausted, "cachesetgrpc: recording idempotency key: %v", err)
+		}
+		if !added {
+			return nil, status.Errorf(codes.AlreadyExists, "cachesetgrpc: duplicate call with idempotency key %q", key)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			keys.Delete(key)
+		}
+		return resp, err
+	}
+}
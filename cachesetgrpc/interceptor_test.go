@@ -0,0 +1,66 @@
+package cachesetgrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	keys := cacheset.New[string](time.Minute)
+	defer keys.Close()
+
+	interceptor := UnaryServerInterceptor(keys, time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}
+	ok := func(context.Context, any) (any, error) { return "ok", nil }
+	fail := func(context.Context, any) (any, error) { return nil, errors.New("boom") }
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(DefaultMetadataKey, key))
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		handler grpc.UnaryHandler
+		want    codes.Code
+	}{
+		{name: "First", ctx: withKey("a"), handler: ok, want: codes.OK},
+		{name: "Duplicate", ctx: withKey("a"), handler: ok, want: codes.AlreadyExists},
+		{name: "NoKey", ctx: context.Background(), handler: ok, want: codes.OK},
+		{name: "NoKeyAgain", ctx: context.Background(), handler: ok, want: codes.OK},
+		{name: "Failed", ctx: withKey("b"), handler: fail, want: codes.Unknown},
+		{name: "Retried", ctx: withKey("b"), handler: ok, want: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(tt.ctx, nil, info, tt.handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("UnaryServerInterceptor() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithMetadataKey(t *testing.T) {
+	keys := cacheset.New[string](time.Minute)
+	defer keys.Close()
+
+	interceptor := UnaryServerInterceptor(keys, time.Hour, WithMetadataKey("x-request-id"))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "a"))
+	ok := func(context.Context, any) (any, error) { return "ok", nil }
+
+	interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/svc/A"}, ok)
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/svc/B"}, ok); err != nil {
+		t.Errorf("UnaryServerInterceptor() error = %v, want the key scoped by method", err)
+	}
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/svc/A"}, ok); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("UnaryServerInterceptor() error = %v, want %v", err, codes.AlreadyExists)
+	}
+}
@@ -0,0 +1,135 @@
+// Package cachesetgrpc
+//
+// Path: cachesetgrpc/node.go
+//
+// Description: node.go contains a gRPC service exposing a cache as a node of a cacheset.Cluster, and the
+// client reaching it, so that a cluster routes its elements to caches in other processes.
+//
+// Like the replication service, the node service does not need generated code: the requests are
+// encoded with encoding/gob through the codec of the replication calls.
+//
+// Usage:
+//
+//	// node
+//	cachesetgrpc.RegisterNodeServer(srv, cache)
+//
+//	// client
+//	cluster := cacheset.NewCluster[string]()
+//	cluster.AddNode("node-a", cachesetgrpc.NewNode[string](connA))
+//	cluster.AddNode("node-b", cachesetgrpc.NewNode[string](connB))
+package cachesetgrpc
+
+import (
+	"context"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/grpc"
+)
+
+// NodeService is the full name of the node service
+const NodeService = "cacheset.Node"
+
+// nodeRequest is a call to a node
+type nodeRequest[T comparable] struct {
+	Elem T             // Elem is the added, looked up or deleted element
+	TTL  time.Duration // TTL is the duration of an added element, 0 meaning no expiration
+}
+
+// nodeReply is the reply of a node
+type nodeReply struct {
+	Found bool // Found is true if the element looked up is in the cache
+}
+
+// nodeServer is implemented by the caches registered by RegisterNodeServer
+type nodeServer interface {
+	serve(ctx context.Context, method string, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error)
+}
+
+// node serves the calls of the node service with a cache
+type node[T comparable] struct {
+	c *cacheset.Cache[T]
+}
+
+// serve decodes a call to the given method, and applies it to the cache through the interceptor
+func (n node[T]) serve(ctx context.Context, method string, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	var req nodeRequest[T]
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	handler := func(_ context.Context, r any) (any, error) {
+		req := r.(*nodeRequest[T])
+		switch method {
+		case "Add":
+			return &nodeReply{}, n.c.Add(req.Elem, req.TTL)
+		case "Contains":
+			return &nodeReply{Found: n.c.Contains(req.Elem)}, nil
+		default:
+			n.c.Delete(req.Elem)
+			return &nodeReply{}, nil
+		}
+	}
+	if interceptor == nil {
+		return handler(ctx, &req)
+	}
+	return interceptor(ctx, &req, &grpc.UnaryServerInfo{Server: n, FullMethod: "/" + NodeService + "/" + method}, handler)
+}
+
+// nodeMethod describes the given method of the node service
+func nodeMethod(method string) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			return srv.(nodeServer).serve(ctx, method, dec, interceptor)
+		},
+	}
+}
+
+// RegisterNodeServer registers on s a node service serving the calls of the clusters with c
+//
+// Description: A server exposes a single cache, the clients must use the same element type. The errors
+// of Add, such as cacheset.ErrCapacityExceeded, reach the clients as gRPC errors of code Unknown.
+func RegisterNodeServer[T comparable](s grpc.ServiceRegistrar, c *cacheset.Cache[T]) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: NodeService,
+		HandlerType: (*nodeServer)(nil),
+		Methods:     []grpc.MethodDesc{nodeMethod("Add"), nodeMethod("Contains"), nodeMethod("Delete")},
+	}, node[T]{c: c})
+}
+
+// Node is a cacheset.ClusterNode reaching a cache registered with RegisterNodeServer in another process
+type Node[T comparable] struct {
+	conn grpc.ClientConnInterface
+}
+
+var _ cacheset.ClusterNode[int] = (*Node[int])(nil)
+
+// NewNode returns a node calling the node service reached through conn, which the caller closes
+func NewNode[T comparable](conn grpc.ClientConnInterface) *Node[T] {
+	return &Node[T]{conn: conn}
+}
+
+// Add adds the given element to the remote cache for the given duration, 0 meaning no expiration
+func (n *Node[T]) Add(ctx context.Context, elem T, ttl time.Duration) error {
+	_, err := n.invoke(ctx, "Add", nodeRequest[T]{Elem: elem, TTL: ttl})
+	return err
+}
+
+// Contains returns true if the given element is in the remote cache
+func (n *Node[T]) Contains(ctx context.Context, elem T) (bool, error) {
+	reply, err := n.invoke(ctx, "Contains", nodeRequest[T]{Elem: elem})
+	return reply.Found, err
+}
+
+// Delete removes the given element from the remote cache
+func (n *Node[T]) Delete(ctx context.Context, elem T) error {
+	_, err := n.invoke(ctx, "Delete", nodeRequest[T]{Elem: elem})
+	return err
+}
+
+// invoke calls the given method of the node service
+func (n *Node[T]) invoke(ctx context.Context, method string, req nodeRequest[T]) (nodeReply, error) {
+	var reply nodeReply
+	err := n.conn.Invoke(ctx, "/"+NodeService+"/"+method, &req, &reply, grpc.CallContentSubtype(codecName))
+	return reply, err
+}
@@ -0,0 +1,89 @@
+package cachesetgrpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serveNode serves c with the node service on an in-memory listener and returns a client of it
+func serveNode(t *testing.T, c *cacheset.Cache[string]) *Node[string] {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterNodeServer(srv, c)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewNode[string](conn)
+}
+
+func TestNode_Cluster(t *testing.T) {
+	ctx := context.Background()
+	caches := map[string]*cacheset.Cache[string]{}
+	cluster := cacheset.NewCluster[string]()
+	for _, name := range []string{"a", "b", "c"} {
+		caches[name] = cacheset.New[string](time.Minute)
+		defer caches[name].Close()
+		cluster.AddNode(name, serveNode(t, caches[name]))
+	}
+
+	for i := range 100 {
+		if err := cluster.Add(ctx, fmt.Sprintf("elem-%d", i), time.Hour); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	var total int
+	for name, c := range caches {
+		if c.Len() == 0 {
+			t.Errorf("node %s Len() = 0, want some elements", name)
+		}
+		total += c.Len()
+	}
+	if total != 100 {
+		t.Errorf("Len() = %v over the nodes, want %v", total, 100)
+	}
+
+	owner, _ := cluster.Locate("elem-7")
+	if !caches[owner].Contains("elem-7") {
+		t.Errorf("node %s does not contain elem-7", owner)
+	}
+	if ok, err := cluster.Contains(ctx, "elem-7"); !ok || err != nil {
+		t.Errorf("Contains() = %v, %v, want true, nil", ok, err)
+	}
+	if err := cluster.Delete(ctx, "elem-7"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if ok, err := cluster.Contains(ctx, "elem-7"); ok || err != nil {
+		t.Errorf("Contains() = %v, %v after Delete, want false, nil", ok, err)
+	}
+}
+
+func TestNode_Error(t *testing.T) {
+	c := cacheset.New[string](time.Minute, cacheset.WithCapacity(1), cacheset.WithOverflowPolicy(cacheset.OverflowReject))
+	defer c.Close()
+	n := serveNode(t, c)
+
+	ctx := context.Background()
+	if err := n.Add(ctx, "a", 0); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := n.Add(ctx, "b", 0); err == nil || !strings.Contains(err.Error(), cacheset.ErrCapacityExceeded.Error()) {
+		t.Errorf("Add() error = %v on a full node, want %v", err, cacheset.ErrCapacityExceeded)
+	}
+}
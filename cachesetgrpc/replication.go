@@ -0,0 +1,157 @@
+// Package cachesetgrpc
+//
+// Path: cachesetgrpc/replication.go
+//
+// Description: replication.go contains a gRPC service streaming the events of a primary cache
+// to the replicas following it.
+//
+// The service does not need generated code: the events are encoded with encoding/gob through
+// a codec selected by the content subtype of the calls, the other services of the server are
+// not affected.
+//
+// Usage:
+//
+//	// primary
+//	cachesetgrpc.RegisterReplicationServer(srv, primary)
+//
+//	// replica
+//	replica := cacheset.NewReplica[string](time.Minute)
+//	for ctx.Err() == nil {
+//		err := cachesetgrpc.Follow(ctx, conn, replica)
+//		log.Println("replication interrupted:", err)
+//		time.Sleep(time.Second)
+//	}
+package cachesetgrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// ReplicationService is the full name of the replication service
+const ReplicationService = "cacheset.Replication"
+
+// codecName is the content subtype of the replication calls
+const codecName = "cacheset-gob"
+
+func init() {
+	encoding.RegisterCodec(gobCodec{})
+}
+
+// gobCodec encodes the replication messages with encoding/gob
+type gobCodec struct{}
+
+// Marshal encodes the given message
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the given message
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Name returns the content subtype of the codec
+func (gobCodec) Name() string {
+	return codecName
+}
+
+// replicationRequest opens a replication stream
+type replicationRequest struct {
+	Version int // Version is the version of the replication protocol
+}
+
+// replicationVersion is the version of the replication protocol
+const replicationVersion = 1
+
+// replicationServer is implemented by the primaries registered by RegisterReplicationServer
+type replicationServer interface {
+	stream(stream grpc.ServerStream) error
+}
+
+// primary streams the events of a cache
+type primary[T comparable] struct {
+	c *cacheset.Cache[T]
+}
+
+// stream sends the elements of the cache, an EventSynced, and then its events until the client leaves or the cache is closed
+func (p primary[T]) stream(stream grpc.ServerStream) error {
+	var req replicationRequest
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+
+	sub := p.c.Subscribe(cacheset.WithSyncMarker())
+	defer sub.Close()
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := stream.SendMsg(&ev); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// replicationStream describes the streaming method of the replication service
+var replicationStream = grpc.StreamDesc{
+	StreamName:    "Stream",
+	ServerStreams: true,
+	Handler: func(srv any, stream grpc.ServerStream) error {
+		return srv.(replicationServer).stream(stream)
+	},
+}
+
+// RegisterReplicationServer registers on s a replication service streaming the events of c
+//
+// Description: A server replicates a single cache, the replicas must use the same element type.
+func RegisterReplicationServer[T comparable](s grpc.ServiceRegistrar, c *cacheset.Cache[T]) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: ReplicationService,
+		HandlerType: (*replicationServer)(nil),
+		Streams:     []grpc.StreamDesc{replicationStream},
+	}, primary[T]{c: c})
+}
+
+// Follow applies to r the events of the primary reached through conn until ctx is done or the stream fails
+//
+// Description: Each call replays the elements of the primary, so Follow can be called again after
+// a failure: the replica keeps serving its elements while it catches up.
+func Follow[T comparable](ctx context.Context, conn grpc.ClientConnInterface, r *cacheset.Replica[T]) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &replicationStream, "/"+ReplicationService+"/"+replicationStream.StreamName, grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&replicationRequest{Version: replicationVersion}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	r.AbortReplay()
+	for {
+		var ev cacheset.Event[T]
+		if err := stream.RecvMsg(&ev); err != nil {
+			return err
+		}
+		r.Apply(ev)
+	}
+}
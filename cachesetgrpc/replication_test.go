@@ -0,0 +1,64 @@
+package cachesetgrpc
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestFollow(t *testing.T) {
+	primary := cacheset.New[string](time.Minute)
+	defer primary.Close()
+	primary.Add("a", 0)
+	primary.Add("b", time.Hour)
+
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterReplicationServer(srv, primary)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	replica := cacheset.NewReplica[string](time.Minute)
+	defer replica.Close()
+	replica.Apply(cacheset.Event[string]{Kind: cacheset.EventAdded, Elem: "stale"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Follow(ctx, conn, replica) }()
+
+	primary.Add("c", 0)
+	primary.Delete("a")
+	want := []string{"b", "c"}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		got := replica.ToSlice()
+		slices.Sort(got)
+		if slices.Equal(got, want) {
+			break
+		}
+	}
+	got := replica.ToSlice()
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("ToSlice() = %v, want %v", got, want)
+	}
+
+	cancel()
+	if err := <-done; err == nil {
+		t.Errorf("Follow() error = nil after the cancellation")
+	}
+}
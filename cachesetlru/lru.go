@@ -0,0 +1,226 @@
+// Package cachesetlru adapts a cache to the method set of the caches of hashicorp/golang-lru.
+//
+// Path: cachesetlru/lru.go
+//
+// Description: lru.go contains Cache, which implements the methods of the *lru.Cache[K, V] type of
+// github.com/hashicorp/golang-lru/v2 on top of a cacheset.Cache of the keys, the values being kept
+// beside it until their key is removed, so that code written against golang-lru can migrate to
+// cacheset one call site at a time. A cache of keys only is a Cache[K, struct{}].
+//
+// The methods depending on the exact recency order of golang-lru are not provided: GetOldest,
+// RemoveOldest and Resize. Keys and Values return the keys in no particular order.
+//
+// Usage:
+//
+//	cache, err := cachesetlru.New[string, int](128)
+//	if err != nil {
+//		return err
+//	}
+//	defer cache.Close()
+//
+//	cache.Add("answer", 42)
+//	v, ok := cache.Get("answer")
+package cachesetlru
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// Cache is a cache of values by key with the method set of the golang-lru caches
+type Cache[K comparable, V any] struct {
+	keys    *cacheset.Cache[K]        // keys are the keys of the values, evicted by the cache's policy
+	values  map[K]V                   // values are the values of the keys, until the removal of the key is processed
+	onEvict func(key K, value V)      // onEvict is called with the removed keys and values, may be nil
+	sub     *cacheset.Subscription[K] // sub receives the removals of the keys
+	mu      sync.Mutex
+}
+
+// New returns a cache holding up to size keys, evicting the least recently used ones
+//
+// Description: opts are passed to cacheset.New after WithCapacity(size), so that the eviction policy
+// or a default time to live, set with cacheset.WithDefaultTTL, can be changed.
+func New[K comparable, V any](size int, opts ...cacheset.Option) (*Cache[K, V], error) {
+	return NewWithEvict[K, V](size, nil, opts...)
+}
+
+// NewWithEvict returns a cache like New, calling onEvicted with the keys and values that are removed
+//
+// Description: onEvicted is called for the evicted, expired and removed keys from a goroutine of the cache,
+// shortly after their removal, except for the keys added again in the meantime. It is called synchronously
+// by Purge.
+func NewWithEvict[K comparable, V any](size int, onEvicted func(key K, value V), opts ...cacheset.Option) (*Cache[K, V], error) {
+	if size <= 0 {
+		return nil, errors.New("cachesetlru: must provide a positive size")
+	}
+
+	keys := cacheset.New[K](time.Minute, append([]cacheset.Option{cacheset.WithCapacity(size)}, opts...)...)
+	c := &Cache[K, V]{
+		keys:    keys,
+		values:  make(map[K]V),
+		onEvict: onEvicted,
+	}
+	c.sub = keys.SubscribeFunc(func(ev cacheset.Event[K]) bool {
+		return ev.Kind == cacheset.EventRemoved
+	})
+	go c.drop()
+
+	return c, nil
+}
+
+// drop forgets the values of the removed keys until the subscription is closed
+func (c *Cache[K, V]) drop() {
+	for ev := range c.sub.Events() {
+		c.mu.Lock()
+		v, ok := c.values[ev.Elem]
+		if ok && !c.keys.Peek(ev.Elem) {
+			delete(c.values, ev.Elem)
+		} else {
+			ok = false
+		}
+		c.mu.Unlock()
+
+		if ok && c.onEvict != nil {
+			c.onEvict(ev.Elem, v)
+		}
+	}
+}
+
+// Add adds a value to the cache and returns true if an eviction occurred
+func (c *Cache[K, V]) Add(key K, value V) (evicted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.add(key, value)
+}
+
+// add adds a value to the cache and returns true if an eviction occurred, the cache must be locked
+func (c *Cache[K, V]) add(key K, value V) bool {
+	before := c.keys.Stats().Evictions
+	if err := c.keys.AddDefault(key); err != nil {
+		return false
+	}
+	c.values[key] = value
+	return c.keys.Stats().Evictions != before
+}
+
+// Get returns the value of a key and updates its recency
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.keys.Contains(key) || c.keys.Expired(key) {
+		return value, false
+	}
+	value, ok = c.values[key]
+	return value, ok
+}
+
+// Contains returns true if the key is in the cache, without updating its recency
+func (c *Cache[K, V]) Contains(key K) bool {
+	return c.keys.Peek(key)
+}
+
+// Peek returns the value of a key without updating its recency
+func (c *Cache[K, V]) Peek(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.peek(key)
+}
+
+// peek returns the value of a key without updating its recency, the cache must be locked
+func (c *Cache[K, V]) peek(key K) (value V, ok bool) {
+	if !c.keys.Peek(key) {
+		return value, false
+	}
+	value, ok = c.values[key]
+	return value, ok
+}
+
+// ContainsOrAdd adds the value unless the key is in the cache, without updating its recency,
+// and returns whether it was found and whether an eviction occurred
+func (c *Cache[K, V]) ContainsOrAdd(key K, value V) (ok, evicted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys.Peek(key) {
+		return true, false
+	}
+	return false, c.add(key, value)
+}
+
+// PeekOrAdd returns the value of the key if it is in the cache, without updating its recency,
+// and adds the value otherwise
+func (c *Cache[K, V]) PeekOrAdd(key K, value V) (previous V, ok, evicted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if previous, ok = c.peek(key); ok {
+		return previous, true, false
+	}
+	return previous, false, c.add(key, value)
+}
+
+// Remove removes the key from the cache and returns true if it was in the cache
+func (c *Cache[K, V]) Remove(key K) (present bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	present = c.keys.Peek(key)
+	c.keys.Delete(key)
+	return present
+}
+
+// Keys returns the keys of the cache, in no particular order
+func (c *Cache[K, V]) Keys() []K {
+	return c.keys.Filter(all[K])
+}
+
+// Values returns the values of the cache, in no particular order
+func (c *Cache[K, V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var values []V
+	for _, key := range c.keys.Filter(all[K]) {
+		if v, ok := c.values[key]; ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// all selects all the unexpired keys with Filter
+func all[K any](K) bool {
+	return true
+}
+
+// Len returns the number of keys in the cache
+func (c *Cache[K, V]) Len() int {
+	return c.keys.Len()
+}
+
+// Purge removes all keys from the cache, calling the eviction callback for each of them
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	values := c.values
+	c.values = make(map[K]V)
+	c.keys.Clear()
+	c.mu.Unlock()
+
+	if c.onEvict != nil {
+		for key, value := range values {
+			c.onEvict(key, value)
+		}
+	}
+}
+
+// Close stops the cache of the keys, the cache must not be used anymore
+func (c *Cache[K, V]) Close() {
+	c.sub.Close()
+	c.keys.Close()
+}
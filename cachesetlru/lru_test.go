@@ -0,0 +1,102 @@
+package cachesetlru
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New[string, int](0); err == nil {
+		t.Errorf("New(0) error = nil, want an error")
+	}
+}
+
+func TestCache(t *testing.T) {
+	c, err := New[string, int](2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if evicted := c.Add("a", 1); evicted {
+		t.Errorf("Add(a) = true, want false")
+	}
+	c.Add("b", 2)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want %v, %v", v, ok, 1, true)
+	}
+	// a was used more recently than b, so b is evicted
+	if evicted := c.Add("c", 3); !evicted {
+		t.Errorf("Add(c) = false, want true")
+	}
+	if c.Contains("b") {
+		t.Errorf("Contains(b) = true after its eviction")
+	}
+	keys := c.Keys()
+	slices.Sort(keys)
+	if want := []string{"a", "c"}; !slices.Equal(keys, want) {
+		t.Errorf("Keys() = %v, want %v", keys, want)
+	}
+	if ok, _ := c.ContainsOrAdd("a", 10); !ok {
+		t.Errorf("ContainsOrAdd(a) = false, want true")
+	}
+	if prev, ok, _ := c.PeekOrAdd("a", 10); !ok || prev != 1 {
+		t.Errorf("PeekOrAdd(a) = %v, %v, want %v, %v", prev, ok, 1, true)
+	}
+	if !c.Remove("a") || c.Remove("a") {
+		t.Errorf("Remove(a) does not report the presence of a")
+	}
+	if _, ok := c.Peek("a"); ok {
+		t.Errorf("Peek(a) = true after Remove")
+	}
+	if got := c.Len(); got != 1 {
+		t.Errorf("Len() = %v, want %v", got, 1)
+	}
+}
+
+func TestNewWithEvict(t *testing.T) {
+	evicted := make(chan string, 10)
+	c, err := NewWithEvict(1, func(key string, value int) {
+		evicted <- key
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+	select {
+	case key := <-evicted:
+		if key != "a" {
+			t.Errorf("onEvicted(%v), want a", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("onEvicted was not called")
+	}
+
+	c.Purge()
+	if key := <-evicted; key != "b" {
+		t.Errorf("Purge() called onEvicted(%v), want b", key)
+	}
+	if got := c.Values(); len(got) != 0 {
+		t.Errorf("Values() = %v after Purge, want none", got)
+	}
+}
+
+func TestNew_defaultTTL(t *testing.T) {
+	c, err := New[string, int](10, cacheset.WithDefaultTTL(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Add("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(a) = true after the default TTL")
+	}
+}
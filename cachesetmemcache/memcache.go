@@ -0,0 +1,439 @@
+// Package cachesetmemcache stores the elements of a set in a memcached server.
+//
+// Path: cachesetmemcache/memcache.go
+//
+// Description: memcache.go contains Set, an implementation of cacheset.CacheSet storing each element as
+// a key of a memcached server, written with the text protocol of memcached and the standard library only.
+// The elements expire with their keys, and memcached may evict them earlier under memory pressure.
+//
+// Memcached cannot list its keys: Len, ToSlice and Clear only see the elements added through the Set, and
+// an element added by another client is only visible to Contains.
+//
+// Usage:
+//
+//	s := cachesetmemcache.New[string]("localhost:11211", cachesetmemcache.WithPrefix("members:"))
+//	defer s.Close()
+//	if err := s.Add("alice", time.Hour); err != nil {
+//		return err
+//	}
+package cachesetmemcache
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// maxKeyLength is the maximum length of a memcached key
+const maxKeyLength = 250
+
+// maxRelativeExpiry is the longest expiration memcached reads as a number of seconds, the longer ones
+// being read as Unix times
+const maxRelativeExpiry = 30 * 24 * time.Hour
+
+// ErrClosed is returned by Add once the Set is closed
+var ErrClosed = errors.New("cachesetmemcache: set closed")
+
+// Option configures a Set
+type Option func(*config)
+
+// config are the settings of a Set
+type config struct {
+	prefix       string        // prefix is prepended to the keys of the elements
+	timeout      time.Duration // timeout bounds each request to the server
+	errorHandler func(error)   // errorHandler is called with the errors of the methods that cannot return one
+}
+
+// WithPrefix sets the prefix of the keys of the elements, which lets several sets share a server
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithTimeout sets the deadline of each request to the server, 1 second by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithErrorHandler sets a function called with the errors of Contains, Delete and Clear, which report
+// them as missing elements or ignore them
+func WithErrorHandler(handler func(error)) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+	}
+}
+
+// Set is a set whose elements are the keys of a memcached server
+//
+// Description: The requests go through a single connection, dialed on the first request and again after
+// a network error. The Set keeps the expiration of the elements it added, so that Contains answers
+// without a request once they have expired, at the precision of the local clock rather than of the
+// seconds of memcached.
+type Set[T comparable] struct {
+	addr   string
+	config config
+
+	connMu sync.Mutex
+	conn   net.Conn
+	rw     *bufio.ReadWriter
+	closed bool
+
+	mu      sync.RWMutex
+	elems   map[T]time.Time // elems are the elements added through the Set, with their expiration time, zero meaning never
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	adds    atomic.Uint64
+	deletes atomic.Uint64
+	expired atomic.Uint64
+}
+
+var _ cacheset.CacheSet[int] = (*Set[int])(nil)
+
+// New returns a Set storing its elements in the memcached server at addr, such as "localhost:11211"
+func New[T comparable](addr string, opts ...Option) *Set[T] {
+	cfg := config{timeout: time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Set[T]{addr: addr, config: cfg, elems: make(map[T]time.Time)}
+}
+
+// Add stores the given element in the server until ttl elapses, 0 meaning no expiration
+//
+// Description: Memcached counts the expirations in seconds, so ttl is rounded up to the second on the
+// server.
+func (s *Set[T]) Add(elem T, ttl time.Duration) error {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	line := fmt.Sprintf("set %s 0 %d 1\r\n1\r\n", s.key(elem), exptime(ttl))
+	if err := s.expect(line, "STORED"); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.elems[elem] = expires
+	s.mu.Unlock()
+	s.adds.Add(1)
+	return nil
+}
+
+// Contains returns true if the given element is in the server
+func (s *Set[T]) Contains(elem T) bool {
+	s.mu.RLock()
+	expires, known := s.elems[elem]
+	s.mu.RUnlock()
+	if known && !expires.IsZero() && !time.Now().Before(expires) {
+		s.misses.Add(1)
+		return false
+	}
+
+	var found bool
+	err := s.do(fmt.Sprintf("get %s\r\n", s.key(elem)), func(r *bufio.Reader) error {
+		var err error
+		found, err = readValue(r)
+		return err
+	})
+	if err != nil {
+		s.report(err)
+	}
+	if !found && known && err == nil {
+		s.forget(elem)
+	}
+	if found {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+	return found
+}
+
+// Delete removes the given element from the server
+func (s *Set[T]) Delete(elem T) {
+	s.forget(elem)
+	if err := s.delete(elem); err != nil {
+		s.report(err)
+	}
+}
+
+// Clear removes from the server the elements added through the Set, stopping at the first error
+func (s *Set[T]) Clear() {
+	s.mu.Lock()
+	elems := s.elems
+	s.elems = make(map[T]time.Time)
+	s.mu.Unlock()
+
+	for elem := range elems {
+		if err := s.delete(elem); err != nil {
+			s.report(err)
+			return
+		}
+	}
+}
+
+// Len returns the number of unexpired elements added through the Set
+func (s *Set[T]) Len() int {
+	return len(s.ToSlice())
+}
+
+// ToSlice returns the unexpired elements added through the Set
+func (s *Set[T]) ToSlice() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	elems := make([]T, 0, len(s.elems))
+	for elem, expires := range s.elems {
+		if expires.IsZero() || now.Before(expires) {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
+
+// ExpireAll removes from the server the expired elements added through the Set
+//
+// Description: Memcached expires the keys by itself, but at the second, so the keys expired at the
+// precision of the local clock may still be in the server: they are deleted, stopping at the first error.
+func (s *Set[T]) ExpireAll() {
+	s.mu.Lock()
+	now := time.Now()
+	var expired []T
+	for elem, expires := range s.elems {
+		if !expires.IsZero() && !now.Before(expires) {
+			delete(s.elems, elem)
+			expired = append(expired, elem)
+		}
+	}
+	s.mu.Unlock()
+
+	s.expired.Add(uint64(len(expired)))
+	for _, elem := range expired {
+		if _, err := s.remove(elem); err != nil {
+			s.report(err)
+			return
+		}
+	}
+}
+
+// Stats returns the counters of the Set, the evictions of memcached are not reported
+func (s *Set[T]) Stats() cacheset.Stats {
+	return cacheset.Stats{
+		Len:         s.Len(),
+		Hits:        s.hits.Load(),
+		Misses:      s.misses.Load(),
+		Adds:        s.adds.Load(),
+		Deletes:     s.deletes.Load(),
+		Expirations: s.expired.Load(),
+	}
+}
+
+// Close closes the connection to the server, the elements are kept in the server
+func (s *Set[T]) Close() {
+	_ = s.Shutdown(context.Background())
+}
+
+// Shutdown closes the connection to the server, see Close
+func (s *Set[T]) Shutdown(ctx context.Context) error {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	s.closed = true
+	s.drop()
+	return nil
+}
+
+// key returns the memcached key of the given element: the prefix followed by the escaped text of the
+// element, or by its SHA-256 if the key would be too long
+func (s *Set[T]) key(elem T) string {
+	key := s.config.prefix + url.QueryEscape(fmt.Sprint(elem))
+	if len(key) <= maxKeyLength {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return s.config.prefix + "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// forget forgets the expiration of the given element
+func (s *Set[T]) forget(elem T) {
+	s.mu.Lock()
+	delete(s.elems, elem)
+	s.mu.Unlock()
+}
+
+// delete removes the given element from the server, and counts it as deleted if it was there
+func (s *Set[T]) delete(elem T) error {
+	found, err := s.remove(elem)
+	if found {
+		s.deletes.Add(1)
+	}
+	return err
+}
+
+// remove removes the given element from the server and returns true if it was there
+func (s *Set[T]) remove(elem T) (bool, error) {
+	var found bool
+	err := s.do(fmt.Sprintf("delete %s\r\n", s.key(elem)), func(r *bufio.Reader) error {
+		reply, err := readLine(r)
+		switch {
+		case err != nil:
+			return err
+		case reply == "DELETED":
+			found = true
+			return nil
+		case reply == "NOT_FOUND":
+			return nil
+		default:
+			return replyError(reply)
+		}
+	})
+	return found, err
+}
+
+// expect sends the given request and checks that the server replies with the given line
+func (s *Set[T]) expect(request, want string) error {
+	return s.do(request, func(r *bufio.Reader) error {
+		reply, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if reply != want {
+			return replyError(reply)
+		}
+		return nil
+	})
+}
+
+// do sends the given request and reads its reply with read, dialing the server if needed
+//
+// Description: The connection is dropped after any error, so that the next request does not read the rest
+// of a broken reply.
+func (s *Set[T]) do(request string, read func(r *bufio.Reader) error) error {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.addr, s.config.timeout)
+		if err != nil {
+			return fmt.Errorf("cachesetmemcache: dial %s: %w", s.addr, err)
+		}
+		s.conn = conn
+		s.rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	}
+
+	err := s.conn.SetDeadline(time.Now().Add(s.config.timeout))
+	if err == nil {
+		_, err = s.rw.WriteString(request)
+	}
+	if err == nil {
+		err = s.rw.Flush()
+	}
+	if err == nil {
+		err = read(s.rw.Reader)
+	}
+	if err != nil {
+		s.drop()
+	}
+	return err
+}
+
+// drop closes the connection, the connection must be locked
+func (s *Set[T]) drop() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn, s.rw = nil, nil
+	}
+}
+
+// report passes the given error to the error handler
+func (s *Set[T]) report(err error) {
+	if s.config.errorHandler != nil {
+		s.config.errorHandler(err)
+	}
+}
+
+// ServerError is an error reply of the server
+type ServerError struct {
+	Reply string // Reply is the line sent by the server, such as "SERVER_ERROR out of memory"
+}
+
+// Error returns the reply of the server
+func (e *ServerError) Error() string {
+	return "cachesetmemcache: " + e.Reply
+}
+
+// replyError returns the error of an unexpected reply: a *ServerError for the error replies of memcached,
+// and a protocol error otherwise
+func replyError(reply string) error {
+	if reply == "ERROR" || strings.HasPrefix(reply, "CLIENT_ERROR") || strings.HasPrefix(reply, "SERVER_ERROR") {
+		return &ServerError{Reply: reply}
+	}
+	return fmt.Errorf("cachesetmemcache: unexpected reply %q", reply)
+}
+
+// readValue reads the reply of a get of a single key and returns true if the key was found
+func readValue(r *bufio.Reader) (bool, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return false, err
+	}
+	if line == "END" {
+		return false, nil
+	}
+	var key string
+	var flags, size int
+	if _, err := fmt.Sscanf(line, "VALUE %s %d %d", &key, &flags, &size); err != nil {
+		return false, replyError(line)
+	}
+	if _, err := r.Discard(size + 2); err != nil {
+		return false, err
+	}
+	if line, err = readLine(r); err != nil {
+		return false, err
+	}
+	if line != "END" {
+		return false, fmt.Errorf("cachesetmemcache: unexpected reply %q", line)
+	}
+	return true, nil
+}
+
+// readLine reads a line of a reply without its CRLF terminator
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// exptime returns the expiration sent to memcached for ttl: 0 for no expiration, a number of seconds
+// rounded up, or a Unix time beyond 30 days
+func exptime(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > maxRelativeExpiry {
+		return time.Now().Add(ttl).Unix() + 1
+	}
+	return int64((ttl + time.Second - 1) / time.Second)
+}
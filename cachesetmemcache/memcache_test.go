@@ -0,0 +1,178 @@
+package cachesetmemcache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"github.com/corentings/go-set/cachetest"
+)
+
+// server is an in-memory memcached server speaking the get, set and delete commands of the text protocol
+type server struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	items map[string]time.Time
+}
+
+// newServer starts a server listening on a local port until the end of the test
+func newServer(t *testing.T) *server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	srv := &server{ln: ln, items: make(map[string]time.Time)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+// serve answers the requests of a connection
+func (srv *server) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		var reply string
+		switch {
+		case len(fields) == 5 && fields[0] == "set":
+			var exptime, size int64
+			fmt.Sscan(fields[3], &exptime)
+			fmt.Sscan(fields[4], &size)
+			if _, err := r.Discard(int(size) + 2); err != nil {
+				return
+			}
+			var expires time.Time
+			if exptime > 0 {
+				expires = time.Now().Add(time.Duration(exptime) * time.Second)
+			}
+			srv.mu.Lock()
+			srv.items[fields[1]] = expires
+			srv.mu.Unlock()
+			reply = "STORED\r\n"
+		case len(fields) == 2 && fields[0] == "get":
+			srv.mu.Lock()
+			expires, ok := srv.items[fields[1]]
+			srv.mu.Unlock()
+			reply = "END\r\n"
+			if ok && (expires.IsZero() || time.Now().Before(expires)) {
+				reply = "VALUE " + fields[1] + " 0 1\r\n1\r\nEND\r\n"
+			}
+		case len(fields) == 2 && fields[0] == "delete":
+			srv.mu.Lock()
+			_, ok := srv.items[fields[1]]
+			delete(srv.items, fields[1])
+			srv.mu.Unlock()
+			reply = "NOT_FOUND\r\n"
+			if ok {
+				reply = "DELETED\r\n"
+			}
+		default:
+			reply = "ERROR\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func TestSet_Conformance(t *testing.T) {
+	srv := newServer(t)
+	var sets atomic.Int64
+	cachetest.RunConformance(t, func() cacheset.CacheSet[string] {
+		return New[string](srv.ln.Addr().String(), WithPrefix(fmt.Sprintf("set%d:", sets.Add(1))))
+	}, cachetest.StringKey)
+}
+
+func TestSet_Key(t *testing.T) {
+	s := New[string]("localhost:0", WithPrefix("p:"))
+	tests := []struct {
+		elem string
+		want string
+	}{
+		{elem: "alice", want: "p:alice"},
+		{elem: "a b\r\n", want: "p:a+b%0D%0A"},
+		{elem: strings.Repeat("x", 300), want: "p:sha256:"},
+	}
+	for _, tt := range tests {
+		if got := s.key(tt.elem); !strings.HasPrefix(got, tt.want) || len(got) > maxKeyLength {
+			t.Errorf("key(%.10q) = %v, want %v", tt.elem, got, tt.want)
+		}
+	}
+}
+
+func TestSet_SharedServer(t *testing.T) {
+	srv := newServer(t)
+	a := New[string](srv.ln.Addr().String(), WithPrefix("shared:"))
+	defer a.Close()
+	b := New[string](srv.ln.Addr().String(), WithPrefix("shared:"))
+	defer b.Close()
+
+	if err := a.Add("alice", time.Hour); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !b.Contains("alice") || b.Len() != 0 {
+		t.Errorf("Contains() = %v, Len() = %v, want an element visible to Contains only", b.Contains("alice"), b.Len())
+	}
+	b.Delete("alice")
+	if a.Contains("alice") || a.Len() != 0 {
+		t.Errorf("Contains() = true after a Delete by another client, want false")
+	}
+}
+
+func TestSet_Errors(t *testing.T) {
+	var reported []error
+	s := New[string]("127.0.0.1:1", WithTimeout(100*time.Millisecond), WithErrorHandler(func(err error) {
+		reported = append(reported, err)
+	}))
+	if err := s.Add("alice", 0); err == nil {
+		t.Errorf("Add() error = nil without a server, want an error")
+	}
+	if s.Contains("alice") || len(reported) != 1 {
+		t.Errorf("Contains() reported %v, want %v error", reported, 1)
+	}
+	s.Close()
+	if err := s.Add("alice", 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Add() error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestExptime(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want int64
+	}{
+		{ttl: 0, want: 0},
+		{ttl: 20 * time.Millisecond, want: 1},
+		{ttl: time.Minute, want: 60},
+		{ttl: 1500 * time.Millisecond, want: 2},
+	}
+	for _, tt := range tests {
+		if got := exptime(tt.ttl); got != tt.want {
+			t.Errorf("exptime(%v) = %v, want %v", tt.ttl, got, tt.want)
+		}
+	}
+	if got := exptime(60 * 24 * time.Hour); got < time.Now().Unix() {
+		t.Errorf("exptime() = %v beyond 30 days, want a Unix time", got)
+	}
+}
@@ -0,0 +1,209 @@
+// Package cachesetotel instruments a cache with OpenTelemetry traces and metrics.
+//
+// Path: cachesetotel/otel.go
+//
+// Description: otel.go contains a wrapper recording spans for the cache's maintenance operations
+// (Cleanup, Snapshot and Restore) and for its context variants (AddCtx and ContainsCtx), and observable
+// instruments reporting the cache's stats.
+//
+// Usage:
+//
+//	c, err := cachesetotel.New(cacheset.New[string](5*time.Minute), "sessions")
+//	if err != nil {
+//		// ...
+//	}
+//	defer c.Close()
+//
+//	// Expire all elements under a span
+//	c.Cleanup(ctx)
+package cachesetotel
+
+import (
+	"context"
+	"io"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer and meter used by the wrapper
+const instrumentationName = "github.com/corentings/go-set/cachesetotel"
+
+// Option configures the instrumentation
+type Option func(*config)
+
+// config is the configuration of the instrumentation
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the tracer provider, the global one is used by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider, the global one is used by default
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// Cache is a cacheset.Cache instrumented with OpenTelemetry
+type Cache[T comparable] struct {
+	*cacheset.Cache[T]                     // Cache is the instrumented cache
+	tracer             trace.Tracer        // tracer records the spans of the maintenance operations
+	registration       metric.Registration // registration is the callback reporting the cache's stats
+	attrs              attribute.Set       // attrs are the attributes identifying the cache
+	name               string              // name identifies the cache in spans and metrics
+}
+
+// New instruments the given cache, identified by name in spans and metrics
+func New[T comparable](c *cacheset.Cache[T], name string, opts ...Option) (*Cache[T], error) {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ic := &Cache[T]{
+		Cache:  c,
+		tracer: cfg.tracerProvider.Tracer(instrumentationName),
+		attrs:  attribute.NewSet(attribute.String("cacheset.name", name)),
+		name:   name,
+	}
+
+	if err := ic.registerMetrics(cfg.meterProvider.Meter(instrumentationName)); err != nil {
+		return nil, err
+	}
+
+	return ic, nil
+}
+
+// registerMetrics creates the observable instruments reporting the cache's stats
+func (c *Cache[T]) registerMetrics(meter metric.Meter) error {
+	size, err := meter.Int64ObservableGauge("cacheset.size",
+		metric.WithDescription("Number of elements in the cache."))
+	if err != nil {
+		return err
+	}
+	hitRatio, err := meter.Float64ObservableGauge("cacheset.hit_ratio",
+		metric.WithDescription("Ratio of lookups that found the element."))
+	if err != nil {
+		return err
+	}
+	hits, err := meter.Int64ObservableCounter("cacheset.hits",
+		metric.WithDescription("Number of lookups that found the element."))
+	if err != nil {
+		return err
+	}
+	misses, err := meter.Int64ObservableCounter("cacheset.misses",
+		metric.WithDescription("Number of lookups that did not find the element."))
+	if err != nil {
+		return err
+	}
+	removals, err := meter.Int64ObservableCounter("cacheset.removals",
+		metric.WithDescription("Number of elements removed from the cache, by reason: expired, deleted or evicted."))
+	if err != nil {
+		return err
+	}
+
+	c.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s := c.Stats()
+		attrs := metric.WithAttributeSet(c.attrs)
+		o.ObserveInt64(size, int64(s.Len), attrs)
+		o.ObserveFloat64(hitRatio, s.HitRatio(), attrs)
+		o.ObserveInt64(hits, int64(s.Hits), attrs)
+		o.ObserveInt64(misses, int64(s.Misses), attrs)
+		o.ObserveInt64(removals, int64(s.Expirations), c.reason("expired"))
+		o.ObserveInt64(removals, int64(s.Deletes), c.reason("deleted"))
+		o.ObserveInt64(removals, int64(s.Evictions), c.reason("evicted"))
+		return nil
+	}, size, hitRatio, hits, misses, removals)
+
+	return err
+}
+
+// reason returns the attributes of the cache with the given removal reason
+func (c *Cache[T]) reason(reason string) metric.MeasurementOption {
+	return metric.WithAttributes(append(c.attrs.ToSlice(), attribute.String("cacheset.reason", reason))...)
+}
+
+// start starts a span for the given operation
+func (c *Cache[T]) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, "cacheset."+operation,
+		trace.WithAttributes(attribute.String("cacheset.name", c.name)))
+}
+
+// Cleanup expires all elements in the cache under a span
+func (c *Cache[T]) Cleanup(ctx context.Context) {
+	_, span := c.start(ctx, "Cleanup")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("cacheset.removed", c.Sweep()))
+}
+
+// Snapshot writes a snapshot of the cache to w under a span, stopping once ctx is done
+func (c *Cache[T]) Snapshot(ctx context.Context, w io.Writer, mode cacheset.SnapshotMode) error {
+	ctx, span := c.start(ctx, "Snapshot")
+	defer span.End()
+
+	err := c.Cache.SnapshotCtx(ctx, w, mode)
+	c.fail(span, err)
+	return err
+}
+
+// Restore restores a snapshot in the cache under a span, stopping once ctx is done
+func (c *Cache[T]) Restore(ctx context.Context, r io.Reader) error {
+	ctx, span := c.start(ctx, "Restore")
+	defer span.End()
+
+	err := c.Cache.RestoreCtx(ctx, r)
+	c.fail(span, err)
+	return err
+}
+
+// AddCtx adds the given element to the cache under a span, a child of the span of ctx
+func (c *Cache[T]) AddCtx(ctx context.Context, elem T, ttl time.Duration) error {
+	ctx, span := c.start(ctx, "Add")
+	defer span.End()
+
+	err := c.Cache.AddCtx(ctx, elem, ttl)
+	c.fail(span, err)
+	return err
+}
+
+// ContainsCtx returns true if the given element is in the cache under a span, a child of the span of ctx
+func (c *Cache[T]) ContainsCtx(ctx context.Context, elem T) (bool, error) {
+	ctx, span := c.start(ctx, "Contains")
+	defer span.End()
+
+	found, err := c.Cache.ContainsCtx(ctx, elem)
+	span.SetAttributes(attribute.Bool("cacheset.found", found))
+	c.fail(span, err)
+	return found, err
+}
+
+// fail records the given error in the span, if any
+func (c *Cache[T]) fail(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Close unregisters the instruments and closes the cache
+func (c *Cache[T]) Close() {
+	_ = c.registration.Unregister()
+	c.Cache.Close()
+}
@@ -0,0 +1,103 @@
+package cachesetotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCache(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	c, err := New(cacheset.New[string](time.Minute), "test",
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Add("foo", 0)
+	c.Add("bar", time.Millisecond)
+	c.Contains("foo")
+	time.Sleep(5 * time.Millisecond)
+	c.Cleanup(context.Background())
+
+	t.Run("Spans", func(t *testing.T) {
+		spans := recorder.Ended()
+		if len(spans) != 1 || spans[0].Name() != "cacheset.Cleanup" {
+			t.Fatalf("Cleanup() spans = %v, want a single cacheset.Cleanup span", spans)
+		}
+		var removed int64
+		for _, attr := range spans[0].Attributes() {
+			if attr.Key == "cacheset.removed" {
+				removed = attr.Value.AsInt64()
+			}
+		}
+		if removed != 1 {
+			t.Errorf("cacheset.removed = %v, want %v", removed, 1)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		c, err := New(cacheset.New[string](time.Minute), "ctx", WithTracerProvider(provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+		_ = c.AddCtx(ctx, "foo", 0)
+		found, _ := c.ContainsCtx(ctx, "foo")
+		parent.End()
+		if !found {
+			t.Errorf("ContainsCtx() = false, want true")
+		}
+
+		spans := recorder.Ended()
+		if len(spans) != 3 || spans[0].Name() != "cacheset.Add" || spans[1].Name() != "cacheset.Contains" {
+			t.Fatalf("spans = %v, want cacheset.Add and cacheset.Contains", spans)
+		}
+		for _, span := range spans[:2] {
+			if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+				t.Errorf("%v parent = %v, want %v", span.Name(), span.Parent().SpanID(), parent.SpanContext().SpanID())
+			}
+		}
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := c.AddCtx(cancelled, "bar", 0); err == nil {
+			t.Errorf("AddCtx() error = nil with a cancelled context, want %v", context.Canceled)
+		}
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(context.Background(), &rm); err != nil {
+			t.Fatal(err)
+		}
+		if len(rm.ScopeMetrics) != 1 || len(rm.ScopeMetrics[0].Metrics) != 5 {
+			t.Fatalf("Collect() = %+v, want 5 metrics", rm.ScopeMetrics)
+		}
+		var removals metricdata.Metrics
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			if m.Name == "cacheset.removals" {
+				removals = m
+			}
+		}
+		sum, ok := removals.Data.(metricdata.Sum[int64])
+		if !ok || len(sum.DataPoints) != 3 {
+			t.Fatalf("cacheset.removals = %+v, want a data point per reason", removals)
+		}
+	})
+}
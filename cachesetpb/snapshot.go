@@ -0,0 +1,310 @@
+// Package cachesetpb reads and writes dumps of a cache in the protobuf format of snapshot.proto.
+//
+// Path: cachesetpb/snapshot.go
+//
+// Description: snapshot.go contains the Snapshot and Element types matching the messages of
+// snapshot.proto, encoded with the protowire package rather than generated code, and the helpers
+// dumping a cache to a Snapshot and loading a Snapshot into a cache. Unlike the gob snapshots of
+// the cacheset package, the dumps can be read and written by any language with a protobuf library.
+//
+// Usage:
+//
+//	data, err := cachesetpb.Marshal(c)
+//	...
+//	err = cachesetpb.Unmarshal(data, c)
+package cachesetpb
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrUnsupportedElement is returned when an element cannot be converted to or from a variant of Element
+var ErrUnsupportedElement = errors.New("cachesetpb: unsupported element type")
+
+// Snapshot is a dump of the elements of a cache, the Snapshot message of snapshot.proto
+type Snapshot struct {
+	Taken    time.Time // Taken is the time of the dump
+	Elements []Element // Elements are the unexpired elements of the cache
+}
+
+// Element is an element of a cache, the Element message of snapshot.proto
+type Element struct {
+	Value     any       // Value is a []byte, a string or an int64, the variant of the value oneof
+	ExpiresAt time.Time // ExpiresAt is the expiration time, the zero time meaning no expiration
+}
+
+// field numbers of snapshot.proto
+const (
+	fieldTaken    protowire.Number = 1
+	fieldElements protowire.Number = 2
+
+	fieldBytes   protowire.Number = 1
+	fieldString  protowire.Number = 2
+	fieldInt64   protowire.Number = 3
+	fieldExpires protowire.Number = 4
+)
+
+// MarshalBinary encodes the snapshot in the protobuf wire format
+func (s Snapshot) MarshalBinary() ([]byte, error) {
+	var b []byte
+	if taken := unixNano(s.Taken); taken != 0 {
+		b = protowire.AppendTag(b, fieldTaken, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(taken))
+	}
+	for i, e := range s.Elements {
+		elem, err := e.marshal()
+		if err != nil {
+			return nil, fmt.Errorf("cachesetpb: element %d: %w", i, err)
+		}
+		b = protowire.AppendTag(b, fieldElements, protowire.BytesType)
+		b = protowire.AppendBytes(b, elem)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a snapshot encoded in the protobuf wire format, skipping the unknown fields
+func (s *Snapshot) UnmarshalBinary(data []byte) error {
+	*s = Snapshot{}
+	return walk(data, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch {
+		case num == fieldTaken && typ == protowire.VarintType:
+			s.Taken = fromUnixNano(int64(v))
+		case num == fieldElements && typ == protowire.BytesType:
+			var e Element
+			if err := e.unmarshal(b); err != nil {
+				return fmt.Errorf("element %d: %w", len(s.Elements), err)
+			}
+			s.Elements = append(s.Elements, e)
+		}
+		return nil
+	})
+}
+
+// marshal encodes the element
+func (e Element) marshal() ([]byte, error) {
+	var b []byte
+	switch v := e.Value.(type) {
+	case []byte:
+		b = protowire.AppendTag(b, fieldBytes, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	case string:
+		b = protowire.AppendTag(b, fieldString, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case int64:
+		b = protowire.AppendTag(b, fieldInt64, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedElement, e.Value)
+	}
+	if expires := unixNano(e.ExpiresAt); expires != 0 {
+		b = protowire.AppendTag(b, fieldExpires, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(expires))
+	}
+	return b, nil
+}
+
+// unmarshal decodes an element
+func (e *Element) unmarshal(data []byte) error {
+	return walk(data, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch {
+		case num == fieldBytes && typ == protowire.BytesType:
+			e.Value = append([]byte{}, b...)
+		case num == fieldString && typ == protowire.BytesType:
+			e.Value = string(b)
+		case num == fieldInt64 && typ == protowire.VarintType:
+			e.Value = int64(v)
+		case num == fieldExpires && typ == protowire.VarintType:
+			e.ExpiresAt = fromUnixNano(int64(v))
+		}
+		return nil
+	})
+}
+
+// walk calls field for each field of the given message, with its value for a varint and its bytes for a length-delimited field
+func walk(data []byte, field func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var v uint64
+		var b []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if err := field(num, typ, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dump returns a snapshot of the unexpired elements of the cache
+//
+// Description: The elements are converted to a variant of Element: the strings to string_value,
+// the integers to int64_value, the byte arrays and the encoding.BinaryMarshaler to bytes_value,
+// and the encoding.TextMarshaler to string_value.
+func Dump[T comparable](c *cacheset.Cache[T]) (Snapshot, error) {
+	s := Snapshot{Taken: time.Now()}
+	for elem, expires := range c.CopySet() {
+		if expires != 0 && expires <= s.Taken.UnixNano() {
+			continue
+		}
+		value, err := toValue(elem)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		s.Elements = append(s.Elements, Element{Value: value, ExpiresAt: fromUnixNano(expires)})
+	}
+	return s, nil
+}
+
+// Load adds the unexpired elements of the snapshot to the cache
+func Load[T comparable](s Snapshot, c *cacheset.Cache[T]) error {
+	now := time.Now()
+	for i, e := range s.Elements {
+		elem, err := fromValue[T](e.Value)
+		if err != nil {
+			return fmt.Errorf("cachesetpb: element %d: %w", i, err)
+		}
+		var ttl time.Duration
+		if !e.ExpiresAt.IsZero() {
+			if ttl = e.ExpiresAt.Sub(now); ttl <= 0 {
+				continue
+			}
+		}
+		if err := c.Add(elem, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Marshal dumps the unexpired elements of the cache in the protobuf wire format
+func Marshal[T comparable](c *cacheset.Cache[T]) ([]byte, error) {
+	s, err := Dump(c)
+	if err != nil {
+		return nil, err
+	}
+	return s.MarshalBinary()
+}
+
+// Unmarshal adds the unexpired elements of a snapshot encoded in the protobuf wire format to the cache
+func Unmarshal[T comparable](data []byte, c *cacheset.Cache[T]) error {
+	var s Snapshot
+	if err := s.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("cachesetpb: %w", err)
+	}
+	return Load(s, c)
+}
+
+// toValue converts an element to a variant of Element
+func toValue[T comparable](elem T) (any, error) {
+	if m, ok := any(elem).(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	if m, ok := any(elem).(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	v := reflect.ValueOf(elem)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %d overflows int64", ErrUnsupportedElement, v.Uint())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedElement, elem)
+}
+
+// fromValue converts a variant of Element to an element
+func fromValue[T comparable](value any) (T, error) {
+	var elem T
+	if u, ok := any(&elem).(encoding.BinaryUnmarshaler); ok {
+		b, ok := value.([]byte)
+		if !ok {
+			return elem, fmt.Errorf("%w: %T from %T", ErrUnsupportedElement, elem, value)
+		}
+		return elem, u.UnmarshalBinary(b)
+	}
+	if u, ok := any(&elem).(encoding.TextUnmarshaler); ok {
+		s, ok := value.(string)
+		if !ok {
+			return elem, fmt.Errorf("%w: %T from %T", ErrUnsupportedElement, elem, value)
+		}
+		return elem, u.UnmarshalText([]byte(s))
+	}
+	v := reflect.ValueOf(&elem).Elem()
+	switch x := value.(type) {
+	case string:
+		if v.Kind() == reflect.String {
+			v.SetString(x)
+			return elem, nil
+		}
+	case int64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !v.OverflowInt(x) {
+				v.SetInt(x)
+				return elem, nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if x >= 0 && !v.OverflowUint(uint64(x)) {
+				v.SetUint(uint64(x))
+				return elem, nil
+			}
+		}
+	case []byte:
+		if v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 && v.Len() == len(x) {
+			reflect.Copy(v, reflect.ValueOf(x))
+			return elem, nil
+		}
+	}
+	return elem, fmt.Errorf("%w: %T from %T", ErrUnsupportedElement, elem, value)
+}
+
+// unixNano returns the given time in nanoseconds since the Unix epoch, 0 for the zero time
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano returns the time of the given nanoseconds since the Unix epoch, the zero time for 0
+func fromUnixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
@@ -0,0 +1,170 @@
+package cachesetpb
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// golden is the snapshot of testdata/snapshot.golden
+var golden = Snapshot{
+	Taken: time.Unix(0, 1700000000000000000),
+	Elements: []Element{
+		{Value: "alice", ExpiresAt: time.Unix(0, 1700000060000000000)},
+		{Value: int64(-42)},
+		{Value: []byte{0, 1, 2}},
+	},
+}
+
+func TestSnapshot_Golden(t *testing.T) {
+	path := filepath.Join("testdata", "snapshot.golden")
+	data, err := golden.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("MarshalBinary() = %x, want %x", data, want)
+	}
+
+	var got Snapshot
+	if err := got.UnmarshalBinary(want); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, golden) {
+		t.Errorf("UnmarshalBinary() = %v, want %v", got, golden)
+	}
+}
+
+// descriptor returns the descriptor of the Snapshot message of snapshot.proto
+func descriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(), Label: label.Enum(), JsonName: proto.String(name)}
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	elements := field("elements", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated)
+	elements.TypeName = proto.String(".cacheset.v1.Element")
+	oneof := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.OneofIndex = proto.Int32(0)
+		return f
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("snapshot.proto"),
+		Package: proto.String("cacheset.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Snapshot"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("taken_unix_nano", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional), elements},
+			},
+			{
+				Name: proto.String("Element"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					oneof(field("bytes_value", 1, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional)),
+					oneof(field("string_value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional)),
+					oneof(field("int64_value", 3, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional)),
+					field("expires_unix_nano", 4, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional),
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("value")}},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file.Messages().ByName("Snapshot")
+}
+
+func TestSnapshot_Schema(t *testing.T) {
+	// the golden file is decoded by the protobuf runtime with the schema of snapshot.proto
+	data, err := os.ReadFile(filepath.Join("testdata", "snapshot.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := descriptor(t)
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		t.Fatalf("proto.Unmarshal() error = %v", err)
+	}
+	if got := msg.Get(desc.Fields().ByName("taken_unix_nano")).Int(); got != golden.Taken.UnixNano() {
+		t.Errorf("taken_unix_nano = %v, want %v", got, golden.Taken.UnixNano())
+	}
+	elements := msg.Get(desc.Fields().ByName("elements")).List()
+	if elements.Len() != 3 {
+		t.Fatalf("len(elements) = %v, want %v", elements.Len(), 3)
+	}
+	first := elements.Get(0).Message()
+	fields := first.Descriptor().Fields()
+	if got := first.Get(fields.ByName("string_value")).String(); got != "alice" {
+		t.Errorf("elements[0].string_value = %v, want %v", got, "alice")
+	}
+	if got := elements.Get(1).Message().Get(fields.ByName("int64_value")).Int(); got != -42 {
+		t.Errorf("elements[1].int64_value = %v, want %v", got, -42)
+	}
+
+	// and a message written by the protobuf runtime is decoded by UnmarshalBinary
+	out, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Snapshot
+	if err := got.UnmarshalBinary(out); err != nil || !reflect.DeepEqual(got, golden) {
+		t.Errorf("UnmarshalBinary() = %v, %v, want %v", got, err, golden)
+	}
+}
+
+// id is an element type encoded as bytes
+type id [4]byte
+
+func TestMarshal(t *testing.T) {
+	src := cacheset.New[id](time.Minute)
+	defer src.Close()
+	src.Add(id{1, 2, 3, 4}, 0)
+	src.Add(id{5, 6, 7, 8}, time.Hour)
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := cacheset.New[id](time.Minute)
+	defer dst.Close()
+	if err := Unmarshal(data, dst); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !dst.Contains(id{1, 2, 3, 4}) || !dst.Contains(id{5, 6, 7, 8}) {
+		t.Errorf("ToSlice() = %v, want both elements", dst.ToSlice())
+	}
+	if e, _ := dst.Lookup(id{5, 6, 7, 8}); e.IsPermanent() {
+		t.Errorf("Lookup().IsPermanent() = true, want the expiration of the source")
+	}
+
+	other := cacheset.New[string](time.Minute)
+	defer other.Close()
+	if err := Unmarshal(data, other); !errors.Is(err, ErrUnsupportedElement) {
+		t.Errorf("Unmarshal() into a string cache error = %v, want %v", err, ErrUnsupportedElement)
+	}
+}
@@ -0,0 +1,107 @@
+// Package cachesetprom exports the metrics of the registered caches to Prometheus.
+//
+// Path: cachesetprom/collector.go
+//
+// Description: collector.go contains a prometheus.Collector reading the global registry of named caches.
+// The histograms of the caches created with cacheset.WithTTLHistograms and cacheset.WithLatencyHistograms
+// are exported too.
+//
+// Usage:
+//
+//	c := cacheset.New[string](5 * time.Minute)
+//	_ = cacheset.Register("sessions", c)
+//
+//	prometheus.MustRegister(cachesetprom.NewCollector())
+package cachesetprom
+
+import (
+	cacheset "github.com/corentings/go-set"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector exporting the metrics of every registered cache labeled by name
+type Collector struct {
+	elements    *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	adds        *prometheus.Desc
+	deletes     *prometheus.Desc
+	expirations *prometheus.Desc
+	evictions   *prometheus.Desc
+	remaining   *prometheus.Desc
+	evicted     *prometheus.Desc
+	expired     *prometheus.Desc
+	unused      *prometheus.Desc
+	latency     *prometheus.Desc
+}
+
+// NewCollector returns a new Collector
+func NewCollector() *Collector {
+	labels := []string{"cache"}
+	return &Collector{
+		elements:    prometheus.NewDesc("cacheset_elements", "Number of elements in the cache.", labels, nil),
+		hits:        prometheus.NewDesc("cacheset_hits_total", "Number of lookups that found the element.", labels, nil),
+		misses:      prometheus.NewDesc("cacheset_misses_total", "Number of lookups that did not find the element.", labels, nil),
+		adds:        prometheus.NewDesc("cacheset_adds_total", "Number of elements added to the cache.", labels, nil),
+		deletes:     prometheus.NewDesc("cacheset_deletes_total", "Number of elements deleted from the cache.", labels, nil),
+		expirations: prometheus.NewDesc("cacheset_expirations_total", "Number of elements removed because they expired.", labels, nil),
+		evictions:   prometheus.NewDesc("cacheset_evictions_total", "Number of elements evicted because the cache was full.", labels, nil),
+		remaining:   prometheus.NewDesc("cacheset_remaining_ttl_seconds", "Remaining time to live of the expiring elements.", labels, nil),
+		evicted:     prometheus.NewDesc("cacheset_evicted_lifetime_seconds", "Time between the addition and the eviction of the elements.", labels, nil),
+		expired:     prometheus.NewDesc("cacheset_expired_lifetime_seconds", "Time between the addition and the expiration of the elements.", labels, nil),
+		unused:      prometheus.NewDesc("cacheset_expired_unused_total", "Number of elements which expired without being looked up.", labels, nil),
+		latency:     prometheus.NewDesc("cacheset_operation_duration_seconds", "Latency of the sampled operations of the cache.", append(labels, "operation"), nil),
+	}
+}
+
+// Describe sends the descriptors of the collector's metrics to ch
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.elements
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.adds
+	ch <- c.deletes
+	ch <- c.expirations
+	ch <- c.evictions
+	ch <- c.remaining
+	ch <- c.evicted
+	ch <- c.expired
+	ch <- c.unused
+	ch <- c.latency
+}
+
+// Collect sends the metrics of every registered cache to ch
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, info := range cacheset.Registered() {
+		s := info.Stats
+		ch <- prometheus.MustNewConstMetric(c.elements, prometheus.GaugeValue, float64(s.Len), info.Name)
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits), info.Name)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses), info.Name)
+		ch <- prometheus.MustNewConstMetric(c.adds, prometheus.CounterValue, float64(s.Adds), info.Name)
+		ch <- prometheus.MustNewConstMetric(c.deletes, prometheus.CounterValue, float64(s.Deletes), info.Name)
+		ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(s.Expirations), info.Name)
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(s.Evictions), info.Name)
+		if s.RemainingTTLs.Bounds != nil {
+			ch <- histogram(c.remaining, s.RemainingTTLs, info.Name)
+			ch <- histogram(c.evicted, s.EvictedLifetimes, info.Name)
+			ch <- histogram(c.expired, s.ExpiredLifetimes, info.Name)
+			ch <- prometheus.MustNewConstMetric(c.unused, prometheus.CounterValue, float64(s.ExpiredUnused), info.Name)
+		}
+		if s.AddLatency.Bounds != nil {
+			ch <- histogram(c.latency, s.AddLatency, info.Name, "add")
+			ch <- histogram(c.latency, s.ContainsLatency, info.Name, "contains")
+			ch <- histogram(c.latency, s.SweepLatency, info.Name, "sweep")
+		}
+	}
+}
+
+// histogram converts a histogram of durations to a Prometheus histogram in seconds
+func histogram(desc *prometheus.Desc, h cacheset.Histogram, labels ...string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(h.Bounds))
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		buckets[bound.Seconds()] = cumulative
+	}
+	return prometheus.MustNewConstHistogram(desc, h.Count, h.Sum.Seconds(), buckets, labels...)
+}
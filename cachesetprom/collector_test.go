@@ -0,0 +1,33 @@
+package cachesetprom
+
+import (
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	a := cacheset.New[string](time.Minute)
+	defer a.Close()
+	b := cacheset.New[int](time.Minute, cacheset.WithTTLHistograms(), cacheset.WithLatencyHistograms(1))
+	defer b.Close()
+
+	if err := cacheset.Register("a", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := cacheset.Register("b", b); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.CollectAndCount(NewCollector(), "cacheset_elements"); got != 2 {
+		t.Errorf("CollectAndCount() = %v, want %v", got, 2)
+	}
+	if got := testutil.CollectAndCount(NewCollector(), "cacheset_remaining_ttl_seconds", "cacheset_expired_unused_total"); got != 2 {
+		t.Errorf("CollectAndCount() = %v, want %v", got, 2)
+	}
+	if got := testutil.CollectAndCount(NewCollector(), "cacheset_operation_duration_seconds"); got != 3 {
+		t.Errorf("CollectAndCount() = %v, want %v", got, 3)
+	}
+}
@@ -0,0 +1,233 @@
+// Package cachesetresp migrates the elements of a cache to and from a Redis set.
+//
+// Path: cachesetresp/resp.go
+//
+// Description: resp.go contains an exporter writing the elements of a cache as Redis protocol (RESP)
+// commands in the mass-insert format of redis-cli --pipe, and an importer reading them back. It only
+// depends on the standard library.
+//
+// A Redis set has a single expiration for all its members: the export sets the expiration of the key to
+// the latest expiration of the elements, or none if an element never expires, and the import gives all
+// the members the expiration of the key.
+//
+// Usage:
+//
+//	// cache-set to Redis: redis-cli --pipe < members.resp
+//	err := cachesetresp.Export(f, c, "members")
+//
+//	// Redis to cache-set, from a file of SADD and EXPIRE commands
+//	err := cachesetresp.Import(f, c, "members")
+package cachesetresp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// batch is the number of members per SADD command written by Export
+const batch = 1000
+
+// ErrProtocol is returned by Import when the input is not made of RESP arrays of bulk strings
+var ErrProtocol = errors.New("cachesetresp: invalid protocol")
+
+// Export writes the unexpired elements of c as SADD commands adding them to the Redis set key, followed
+// by a PEXPIREAT command if they all expire
+//
+// Description: The elements are written like the keys of the object of Cache.MarshalJSON, sorted,
+// in SADD commands of up to 1000 members. The output replaces nothing: the members are added to the set
+// key if it already exists.
+func Export[T comparable](w io.Writer, c *cacheset.Cache[T], key string) error {
+	data, err := c.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var elems map[string]*time.Time
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	members := slices.Sorted(maps.Keys(elems))
+	for chunk := range slices.Chunk(members, batch) {
+		writeCommand(bw, append([]string{"SADD", key}, chunk...))
+	}
+
+	var latest time.Time
+	for _, expires := range elems {
+		if expires == nil {
+			latest = time.Time{}
+			break
+		}
+		if expires.After(latest) {
+			latest = *expires
+		}
+	}
+	if !latest.IsZero() {
+		writeCommand(bw, []string{"PEXPIREAT", key, strconv.FormatInt(latest.UnixMilli(), 10)})
+	}
+	return bw.Flush()
+}
+
+// writeCommand writes the given command as a RESP array of bulk strings
+func writeCommand(w *bufio.Writer, args []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// Import reads the commands of the Redis set key written by Export or by redis-cli, and adds its members
+// to c until the expiration of the key
+//
+// Description: SADD and SREM change the members, DEL removes them all, and EXPIRE, PEXPIRE, EXPIREAT,
+// PEXPIREAT and PERSIST change the expiration of the key. The commands of the other keys are skipped,
+// and the other commands return an error. The members are parsed like the keys of the object of
+// Cache.UnmarshalJSON, and added like with Add once the whole input is read, unless the key has expired.
+func Import[T comparable](r io.Reader, c *cacheset.Cache[T], key string) error {
+	members := make(map[string]struct{})
+	var expires *time.Time
+
+	br := bufio.NewReader(r)
+	for {
+		args, err := readCommand(br)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(args) < 2 || args[1] != key {
+			continue
+		}
+
+		switch cmd := strings.ToUpper(args[0]); cmd {
+		case "SADD":
+			for _, member := range args[2:] {
+				members[member] = struct{}{}
+			}
+		case "SREM":
+			for _, member := range args[2:] {
+				delete(members, member)
+			}
+		case "DEL":
+			clear(members)
+			expires = nil
+		case "PERSIST":
+			expires = nil
+		case "EXPIRE", "PEXPIRE", "EXPIREAT", "PEXPIREAT":
+			if len(args) != 3 {
+				return fmt.Errorf("%w: %s has %d arguments", ErrProtocol, cmd, len(args))
+			}
+			n, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: %s %s: %w", ErrProtocol, cmd, args[2], err)
+			}
+			t := expiration(cmd, n)
+			expires = &t
+		default:
+			return fmt.Errorf("cachesetresp: unsupported command %s", cmd)
+		}
+	}
+
+	elems := make(map[string]*time.Time, len(members))
+	for member := range members {
+		elems[member] = expires
+	}
+	data, err := json.Marshal(elems)
+	if err != nil {
+		return err
+	}
+	return c.UnmarshalJSON(data)
+}
+
+// expiration returns the expiration time set by the given expiration command and argument
+func expiration(cmd string, n int64) time.Time {
+	switch cmd {
+	case "EXPIRE":
+		return time.Now().Add(time.Duration(n) * time.Second)
+	case "PEXPIRE":
+		return time.Now().Add(time.Duration(n) * time.Millisecond)
+	case "EXPIREAT":
+		return time.Unix(n, 0)
+	default:
+		return time.UnixMilli(n)
+	}
+}
+
+// readCommand reads a RESP array of bulk strings, io.EOF at the end of the input
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	n, err := parseHeader(line, '*')
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		size, err := parseHeader(line, '$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, unexpected(err)
+		}
+		if string(buf[size:]) != "\r\n" {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", ErrProtocol)
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// readLine reads a line terminated by CRLF without its terminator, io.EOF at the end of the input
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		return "", io.EOF
+	}
+	if err != nil {
+		return "", unexpected(err)
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("%w: line not terminated by CRLF", ErrProtocol)
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// parseHeader parses the length of an array or a bulk string, which starts with the given prefix
+func parseHeader(line string, prefix byte) (int, error) {
+	if line == "" || line[0] != prefix {
+		return 0, fmt.Errorf("%w: expected %q, got %q", ErrProtocol, prefix, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: invalid length %q", ErrProtocol, line)
+	}
+	return n, nil
+}
+
+// unexpected returns io.ErrUnexpectedEOF instead of io.EOF, for an input ending in the middle of a command
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
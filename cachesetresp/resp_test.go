@@ -0,0 +1,94 @@
+package cachesetresp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+func TestExport(t *testing.T) {
+	c := cacheset.New[string](time.Minute)
+	defer c.Close()
+	_ = c.Add("b", time.Hour)
+	_ = c.Add("a", time.Minute)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, c, "members"); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	prefix := "*4\r\n$4\r\nSADD\r\n$7\r\nmembers\r\n$1\r\na\r\n$1\r\nb\r\n*3\r\n$9\r\nPEXPIREAT\r\n$7\r\nmembers\r\n"
+	if !strings.HasPrefix(buf.String(), prefix) {
+		t.Errorf("Export() = %q, want the prefix %q", buf.String(), prefix)
+	}
+
+	_ = c.Add("c", 0)
+	buf.Reset()
+	if err := Export(&buf, c, "members"); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "PEXPIREAT") {
+		t.Errorf("Export() = %q, want no expiration with a permanent element", buf.String())
+	}
+}
+
+func TestImport(t *testing.T) {
+	src := cacheset.New[int](time.Minute)
+	defer src.Close()
+	for i := range 2500 {
+		_ = src.Add(i, time.Hour)
+	}
+	var buf bytes.Buffer
+	if err := Export(&buf, src, "members"); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	buf.WriteString("*3\r\n$4\r\nSADD\r\n$5\r\nother\r\n$4\r\n9999\r\n")
+	buf.WriteString("*3\r\n$4\r\nSREM\r\n$7\r\nmembers\r\n$1\r\n0\r\n")
+
+	dst := cacheset.New[int](time.Minute)
+	defer dst.Close()
+	if err := Import(&buf, dst, "members"); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got := dst.Len(); got != 2499 {
+		t.Errorf("Len() = %v, want %v", got, 2499)
+	}
+	if dst.Contains(0) || dst.Contains(9999) || !dst.Contains(2499) {
+		t.Errorf("Import() = %v elements, want the members of the key but the removed one", dst.Len())
+	}
+	if entry, ok := dst.Lookup(1); !ok || entry.TTL() < 59*time.Minute || entry.TTL() > time.Hour {
+		t.Errorf("Lookup() = %+v, want the expiration of the key", entry)
+	}
+}
+
+func TestImport_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{name: "Inline", input: "SADD members a\r\n", wantErr: ErrProtocol},
+		{name: "Truncated", input: "*3\r\n$4\r\nSADD\r\n$7\r\nmembers\r\n", wantErr: io.ErrUnexpectedEOF},
+		{name: "NoCRLF", input: "*1\r\n$4\r\nSADDxx", wantErr: ErrProtocol},
+		{name: "BadExpire", input: "*3\r\n$6\r\nEXPIRE\r\n$7\r\nmembers\r\n$2\r\nxx\r\n", wantErr: ErrProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cacheset.New[string](time.Minute)
+			defer c.Close()
+			if err := Import(strings.NewReader(tt.input), c, "members"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Import() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	c := cacheset.New[string](time.Minute)
+	defer c.Close()
+	if err := Import(strings.NewReader("*2\r\n$6\r\nHSET\r\n$7\r\nmembers\r\n"), c, "members"); err == nil {
+		t.Errorf("Import() error = nil for an unsupported command, want an error")
+	}
+}
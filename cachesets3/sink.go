@@ -0,0 +1,297 @@
+// Package cachesets3 stores the snapshots of a cache with durability in an S3-compatible object store.
+//
+// Path: cachesets3/sink.go
+//
+// Description: sink.go contains a cacheset.SnapshotSink writing the snapshots as objects of a bucket,
+// with requests signed with AWS Signature Version 4. It only depends on the standard library and
+// works with AWS S3 and the S3-compatible stores (MinIO, Ceph, R2, ...). A snapshot is buffered in
+// memory and uploaded with a single PUT on commit, which replaces the previous object atomically.
+//
+// Usage:
+//
+//	sink := cachesets3.New("my-bucket", cachesets3.WithPrefix("members/"))
+//	c := cacheset.New[string](time.Minute, cacheset.WithSnapshotSink(sink, "snapshot"))
+//	defer c.Close()
+package cachesets3
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// Option configures a Sink
+type Option func(*Sink)
+
+// WithEndpoint sets the URL of the object store, such as "http://localhost:9000" for MinIO,
+// the AWS S3 endpoint of the region by default
+//
+// Description: The objects of a custom endpoint are addressed with path-style URLs.
+func WithEndpoint(endpoint string) Option {
+	return func(s *Sink) {
+		s.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithRegion sets the region of the bucket, the AWS_REGION environment variable or "us-east-1" by default
+func WithRegion(region string) Option {
+	return func(s *Sink) {
+		s.region = region
+	}
+}
+
+// WithCredentials sets the credentials signing the requests, read from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables by default
+func WithCredentials(accessKey, secretKey, sessionToken string) Option {
+	return func(s *Sink) {
+		s.accessKey, s.secretKey, s.sessionToken = accessKey, secretKey, sessionToken
+	}
+}
+
+// WithPrefix prepends the given prefix to the names of the snapshots to build the object keys
+func WithPrefix(prefix string) Option {
+	return func(s *Sink) {
+		s.prefix = prefix
+	}
+}
+
+// WithHTTPClient sets the client sending the requests, http.DefaultClient by default
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Sink) {
+		s.client = client
+	}
+}
+
+// Sink is a cacheset.SnapshotSink storing the snapshots as objects of a bucket
+type Sink struct {
+	bucket       string           // bucket is the name of the bucket
+	endpoint     string           // endpoint is the URL of the object store, empty for AWS S3
+	region       string           // region is the region of the bucket
+	prefix       string           // prefix is prepended to the names of the snapshots
+	accessKey    string           // accessKey is the access key ID
+	secretKey    string           // secretKey is the secret access key
+	sessionToken string           // sessionToken is the session token of temporary credentials, empty otherwise
+	client       *http.Client     // client sends the requests
+	now          func() time.Time // now returns the time of the signatures, replaced by the tests
+}
+
+// New returns a sink storing the snapshots in the given bucket
+func New(bucket string, opts ...Option) *Sink {
+	s := &Sink{
+		bucket:       bucket,
+		region:       cmp.Or(os.Getenv("AWS_REGION"), "us-east-1"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       http.DefaultClient,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create returns a writer uploading the snapshot on Commit
+func (s *Sink) Create(ctx context.Context, name string) (cacheset.SnapshotWriter, error) {
+	return &writer{ctx: ctx, sink: s, name: name}, nil
+}
+
+// Open downloads the snapshot with the given name
+func (s *Sink) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cachesets3: %s: %w", name, fs.ErrNotExist)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+// Remove deletes the snapshot with the given name
+func (s *Sink) Remove(ctx context.Context, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// writer buffers a snapshot until it is committed
+type writer struct {
+	bytes.Buffer
+	ctx  context.Context
+	sink *Sink
+	name string
+}
+
+// Commit uploads the snapshot
+func (w *writer) Commit() error {
+	resp, err := w.sink.do(w.ctx, http.MethodPut, w.name, w.Bytes())
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Abort discards the snapshot
+func (w *writer) Abort() error {
+	w.Reset()
+	return nil
+}
+
+// do sends a signed request on the object of the snapshot with the given name
+func (s *Sink) do(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	u := s.objectURL(s.prefix + name)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	sign(req, body, s.accessKey, s.secretKey, s.region, s.now().UTC())
+	return s.client.Do(req)
+}
+
+// objectURL returns the URL of the object with the given key
+func (s *Sink) objectURL(key string) *url.URL {
+	if s.endpoint == "" {
+		u := &url.URL{Scheme: "https", Host: s.bucket + ".s3." + s.region + ".amazonaws.com", Path: "/" + key}
+		u.RawPath = escapePath(u.Path)
+		return u
+	}
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: s.endpoint}
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawPath = escapePath(u.Path)
+	return u
+}
+
+// sign adds the AWS Signature Version 4 headers to the given request
+//
+// Description: The host and all the headers of the request are signed.
+func sign(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery returns the query sorted by name and value and escaped as required by Signature Version 4
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escape(name)+"="+escape(value))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// escapePath escapes each segment of the given path as required by Signature Version 4
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape escapes all the bytes of s but the unreserved characters of RFC 3986
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with the given key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// responseError returns the error of an unexpected response and closes its body
+func responseError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("cachesets3: %s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, bytes.TrimSpace(body))
+}
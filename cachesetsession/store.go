@@ -0,0 +1,114 @@
+// Package cachesetsession stores the sessions of a web application in a cache.
+//
+// Path: cachesetsession/store.go
+//
+// Description: store.go contains a session store implementing the Find, Commit and Delete methods
+// of the scs.Store interface (github.com/alexedwards/scs/v2), so that a cache can serve as the
+// in-memory session backend of a web application.
+//
+// The expiration of the sessions is handled by a cacheset.Cache of the session tokens, and their
+// encoded data is kept beside it until the cache removes the token: the store subscribes to the
+// removals of the cache and drops the data of the removed sessions.
+//
+// Usage:
+//
+//	sessions := cacheset.New[string](time.Minute)
+//	defer sessions.Close()
+//
+//	manager := scs.New()
+//	manager.Store = cachesetsession.New(sessions)
+package cachesetsession
+
+import (
+	"sync"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// Store is a session store keeping the session tokens in a cache and their data beside it
+type Store struct {
+	tokens *cacheset.Cache[string]        // tokens are the tokens of the sessions, expiring with them
+	data   map[string][]byte              // data is the encoded data of each session
+	sub    *cacheset.Subscription[string] // sub receives the removals of the tokens
+	mu     sync.Mutex
+}
+
+// New returns a Store keeping the session tokens in the given cache
+//
+// Description: The cache must not be used for anything else, its capacity and eviction policy
+// apply to the sessions. The store drops the data of the sessions until the cache or the store is closed.
+func New(tokens *cacheset.Cache[string]) *Store {
+	s := &Store{tokens: tokens, data: make(map[string][]byte)}
+	s.sub = tokens.SubscribeFunc(func(ev cacheset.Event[string]) bool {
+		return ev.Kind == cacheset.EventRemoved || ev.Kind == cacheset.EventCleared
+	})
+	go s.drop()
+	return s
+}
+
+// Close stops dropping the data of the sessions removed from the cache, which it does not close
+func (s *Store) Close() {
+	s.sub.Close()
+}
+
+// Find returns the data of the session with the given token and false if it does not exist or has expired
+//
+// Description: Find peeks at the token, a lookup is neither counted as a hit nor refreshes the idle time.
+func (s *Store) Find(token string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.tokens.Peek(token) {
+		delete(s.data, token)
+		return nil, false, nil
+	}
+	b, ok := s.data[token]
+	return b, ok, nil
+}
+
+// Commit stores the data of the session with the given token until expiry
+func (s *Store) Commit(token string, b []byte, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return s.Delete(token)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.tokens.Add(token, ttl); err != nil {
+		return err
+	}
+	s.data[token] = b
+
+	return nil
+}
+
+// Delete removes the session with the given token
+func (s *Store) Delete(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens.Delete(token)
+	delete(s.data, token)
+
+	return nil
+}
+
+// drop removes the data of the sessions removed from the cache, unless they were committed again since
+func (s *Store) drop() {
+	for ev := range s.sub.Events() {
+		s.mu.Lock()
+		if ev.Kind == cacheset.EventCleared {
+			for token := range s.data {
+				if !s.tokens.Peek(token) {
+					delete(s.data, token)
+				}
+			}
+		} else if !s.tokens.Peek(ev.Elem) {
+			delete(s.data, ev.Elem)
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,116 @@
+package cachesetsession
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+func TestStore(t *testing.T) {
+	tokens := cacheset.New[string](10 * time.Millisecond)
+	defer tokens.Close()
+	s := New(tokens)
+	defer s.Close()
+
+	if err := s.Commit("a", []byte("alice"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := s.Commit("b", []byte("bob"), time.Now().Add(5*time.Millisecond)); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := s.Commit("c", []byte("carol"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := s.Delete("c"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	tests := []struct {
+		name  string
+		token string
+		want  []byte
+		found bool
+	}{
+		{name: "Found", token: "a", want: []byte("alice"), found: true},
+		{name: "Expired", token: "b", found: false},
+		{name: "Deleted", token: "c", found: false},
+		{name: "Missing", token: "d", found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := s.Find(tt.token)
+			if err != nil || found != tt.found || !bytes.Equal(got, tt.want) {
+				t.Errorf("Find() = %q, %v, %v, want %q, %v, nil", got, found, err, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestStore_Find(t *testing.T) {
+	tokens := cacheset.New[string](time.Minute)
+	defer tokens.Close()
+	s := New(tokens)
+	defer s.Close()
+
+	if err := s.Commit("a", []byte("alice"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	for range 3 {
+		if _, found, err := s.Find("a"); !found || err != nil {
+			t.Fatalf("Find() = %v, %v, want true, nil", found, err)
+		}
+	}
+	if st := tokens.Stats(); st.Hits != 0 || st.Misses != 0 {
+		t.Errorf("Stats() = %v hits, %v misses after Find, want 0, 0", st.Hits, st.Misses)
+	}
+}
+
+func TestStore_drop(t *testing.T) {
+	tokens := cacheset.New[string](5 * time.Millisecond)
+	defer tokens.Close()
+	s := New(tokens)
+	defer s.Close()
+
+	for i := range 100 {
+		if err := s.Commit(fmt.Sprintf("expiring-%d", i), []byte("data"), time.Now().Add(time.Millisecond)); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+	}
+	waitData(t, s, 0)
+
+	for i := range 10 {
+		if err := s.Commit(fmt.Sprintf("cleared-%d", i), []byte("data"), time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+	}
+	tokens.Clear()
+	if err := s.Commit("kept", []byte("data"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	waitData(t, s, 1)
+	if got, found, err := s.Find("kept"); !found || err != nil || string(got) != "data" {
+		t.Errorf("Find() = %q, %v, %v, want %q, true, nil", got, found, err, "data")
+	}
+}
+
+// waitData waits until the store keeps the data of want sessions
+func waitData(t *testing.T, s *Store, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.data)
+		s.mu.Unlock()
+		if n == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("len(data) = %v, want %v once the sessions are removed", n, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,556 @@
+// Package cachesetsql stores the elements of a set in a table of a SQL database, for the deployments
+// where the database is the only allowed storage.
+//
+// Path: cachesetsql/sql.go
+//
+// Description: sql.go contains Set, an implementation of cacheset.CacheSet keeping each element and its
+// expiration time in a row of a single table, written with database/sql only: the caller brings the
+// driver. The statements target PostgreSQL, and also run on SQLite with WithPlaceholder(Question). A
+// sweeper deletes the expired rows in small batches, and AddBatch upserts many elements per statement.
+//
+// The table has two columns, key holding the JSON form of the element and expires_at its expiration
+// time, NULL meaning never:
+//
+//	CREATE TABLE cacheset (key TEXT PRIMARY KEY, expires_at TIMESTAMPTZ);
+//	CREATE INDEX cacheset_expires_at ON cacheset (expires_at);
+//
+// Usage:
+//
+//	db, err := sql.Open("pgx", dsn)
+//	if err != nil {
+//		return err
+//	}
+//	s, err := cachesetsql.New[string](ctx, db, cachesetsql.WithTable("dedup"))
+//	if err != nil {
+//		return err
+//	}
+//	defer s.Close()
+//	err = s.Add(messageID, 30*24*time.Hour)
+package cachesetsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// ErrInvalidTable is returned by New when the table name is not a plain SQL identifier
+var ErrInvalidTable = errors.New("cachesetsql: invalid table name")
+
+// Placeholder is the style of the parameters of the statements, which depends on the driver
+type Placeholder int
+
+const (
+	// Dollar numbers the parameters $1, $2..., as PostgreSQL does
+	Dollar Placeholder = iota
+	// Question marks the parameters with ?, as SQLite and MySQL do
+	Question
+)
+
+// Option configures a Set
+type Option func(*config)
+
+// config are the settings of a Set
+type config struct {
+	table         string        // table is the name of the table of the elements
+	placeholder   Placeholder   // placeholder is the style of the parameters of the statements
+	createTable   bool          // createTable creates the table and its index if they do not exist
+	sweepInterval time.Duration // sweepInterval is the duration between two sweeps, 0 meaning no sweeper
+	sweepBatch    int           // sweepBatch is the maximum number of rows deleted per statement
+	batchSize     int           // batchSize is the maximum number of rows upserted per statement by AddBatch
+	timeout       time.Duration // timeout bounds each statement, 0 meaning no bound
+	errorHandler  func(error)   // errorHandler is called with the errors of the methods that cannot return one
+}
+
+// WithTable sets the name of the table of the elements, "cacheset" by default, which may be qualified by
+// a schema
+func WithTable(table string) Option {
+	return func(c *config) {
+		c.table = table
+	}
+}
+
+// WithPlaceholder sets the style of the parameters of the statements, Dollar by default
+func WithPlaceholder(p Placeholder) Option {
+	return func(c *config) {
+		c.placeholder = p
+	}
+}
+
+// WithoutCreateTable does not create the table and its index, for the databases whose schema is managed
+// by migrations, see the package documentation for the expected schema
+func WithoutCreateTable() Option {
+	return func(c *config) {
+		c.createTable = false
+	}
+}
+
+// WithSweepInterval sets the duration between two sweeps of the expired elements, 1 minute by default,
+// 0 disabling the sweeper so that the expired elements are only deleted by ExpireAll
+//
+// Description: The expired rows are never returned, the sweeps only reclaim their storage. Several
+// processes sharing the table may all run sweepers, their deletions do not conflict.
+func WithSweepInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.sweepInterval = max(interval, 0)
+	}
+}
+
+// WithSweepBatch sets the maximum number of expired rows deleted per statement, 1000 by default
+//
+// Description: The sweeps delete the expired rows in several statements, so that a large backlog does
+// not hold the locks of the rows nor grow the transaction log of the database at once.
+func WithSweepBatch(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.sweepBatch = n
+		}
+	}
+}
+
+// WithBatchSize sets the maximum number of elements upserted per statement by AddBatch, 500 by default,
+// PostgreSQL accepting up to 65535 parameters, two per element
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.batchSize = min(n, 32767)
+		}
+	}
+}
+
+// WithTimeout sets the maximum duration of each statement, no bound by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = max(timeout, 0)
+	}
+}
+
+// WithErrorHandler sets a function called with the errors of the methods that cannot return one, such as
+// Contains and the sweeps, which report them as missing elements or ignore them
+func WithErrorHandler(handler func(error)) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+	}
+}
+
+// queries are the statements of a Set, built once for its table and placeholder style
+type queries struct {
+	upsert      string // upsert inserts or updates an element
+	upsertBatch string // upsertBatch inserts or updates a full batch of elements
+	expiration  string // expiration selects the expiration time of an unexpired element
+	count       string // count counts the unexpired elements
+	keys        string // keys selects the keys of the unexpired elements
+	delete      string // delete deletes an element
+	clear       string // clear deletes all elements
+	sweep       string // sweep deletes a batch of expired elements
+}
+
+// Set is a set whose elements are stored in a table of a SQL database
+//
+// Description: The elements are written in their JSON form, so T must be encodable in JSON and its JSON
+// form must identify it, as for the strings, the integers and the structs of them. The expiration times
+// are wall-clock times compared with the clock of the process, so the processes sharing a table should
+// keep their clocks synchronized. Each Add is a single upsert, durable when it returns. The Set does not
+// own the database, Close leaves it open.
+type Set[T comparable] struct {
+	db      *sql.DB
+	config  config
+	queries queries
+
+	close     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	adds    atomic.Uint64
+	deletes atomic.Uint64
+	expired atomic.Uint64
+}
+
+var _ cacheset.ColdTier[int] = (*Set[int])(nil)
+
+// New returns a Set of the elements of the table, creating the table unless WithoutCreateTable is given,
+// and starts its sweeper
+func New[T comparable](ctx context.Context, db *sql.DB, opts ...Option) (*Set[T], error) {
+	cfg := config{table: "cacheset", createTable: true, sweepInterval: time.Minute, sweepBatch: 1000, batchSize: 500}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !validTable(cfg.table) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTable, cfg.table)
+	}
+
+	s := &Set[T]{db: db, config: cfg, close: make(chan struct{}), done: make(chan struct{})}
+	s.queries = s.buildQueries()
+	if cfg.createTable {
+		index := cfg.table[strings.LastIndexByte(cfg.table, '.')+1:] + "_expires_at"
+		for _, stmt := range []string{
+			"CREATE TABLE IF NOT EXISTS " + cfg.table + " (key TEXT PRIMARY KEY, expires_at TIMESTAMPTZ)",
+			"CREATE INDEX IF NOT EXISTS " + index + " ON " + cfg.table + " (expires_at)",
+		} {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	go s.sweeper()
+	return s, nil
+}
+
+// Add adds the given element until ttl elapses, 0 meaning no expiration
+func (s *Set[T]) Add(elem T, ttl time.Duration) error {
+	key, err := json.Marshal(elem)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, s.queries.upsert, string(key), expiresAt(ttl)); err != nil {
+		return err
+	}
+	s.adds.Add(1)
+	return nil
+}
+
+// AddBatch adds the given elements until ttl elapses, 0 meaning no expiration, upserting them in a single
+// transaction of statements of up to WithBatchSize elements
+//
+// Description: AddBatch saves the round trips of one Add per element, as when loading a set. Either all
+// elements are added or none. An element given twice is added once.
+func (s *Set[T]) AddBatch(elems []T, ttl time.Duration) error {
+	keys := make([]string, 0, len(elems))
+	seen := make(map[string]struct{}, len(elems))
+	for _, elem := range elems {
+		key, err := json.Marshal(elem)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[string(key)]; !ok {
+			seen[string(key)] = struct{}{}
+			keys = append(keys, string(key))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	expires := expiresAt(ttl)
+	for chunk := range slices.Chunk(keys, s.config.batchSize) {
+		query := s.queries.upsertBatch
+		if len(chunk) < s.config.batchSize {
+			query = s.upsertQuery(len(chunk))
+		}
+		args := make([]any, 0, 2*len(chunk))
+		for _, key := range chunk {
+			args = append(args, key, expires)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.adds.Add(uint64(len(keys)))
+	return nil
+}
+
+// Contains returns true if the given element is in the set and has not expired
+func (s *Set[T]) Contains(elem T) bool {
+	_, found := s.Expiration(elem)
+	if found {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+	return found
+}
+
+// Expiration returns the expiration time of the given element, the zero time.Time meaning never, and false
+// if it is not in the set or has expired
+//
+// Description: Unlike Contains, Expiration is not counted as a hit or a miss.
+func (s *Set[T]) Expiration(elem T) (time.Time, bool) {
+	key, err := json.Marshal(elem)
+	if err != nil {
+		s.report(err)
+		return time.Time{}, false
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+
+	var expires sql.NullTime
+	err = s.db.QueryRowContext(ctx, s.queries.expiration, string(key), time.Now()).Scan(&expires)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return time.Time{}, false
+	case err != nil:
+		s.report(err)
+		return time.Time{}, false
+	case !expires.Valid:
+		return time.Time{}, true
+	}
+	return expires.Time, true
+}
+
+// Delete removes the given element from the set
+func (s *Set[T]) Delete(elem T) {
+	key, err := json.Marshal(elem)
+	if err != nil {
+		s.report(err)
+		return
+	}
+	n, err := s.exec(s.queries.delete, string(key))
+	if err != nil {
+		s.report(err)
+		return
+	}
+	s.deletes.Add(uint64(n))
+}
+
+// Clear removes all elements from the set
+func (s *Set[T]) Clear() {
+	n, err := s.exec(s.queries.clear)
+	if err != nil {
+		s.report(err)
+		return
+	}
+	s.deletes.Add(uint64(n))
+}
+
+// Len returns the number of elements of the set that have not expired, counted by the database
+func (s *Set[T]) Len() int {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, s.queries.count, time.Now()).Scan(&n); err != nil {
+		s.report(err)
+		return 0
+	}
+	return n
+}
+
+// ToSlice returns the elements of the set that have not expired, reading the whole table
+func (s *Set[T]) ToSlice() []T {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.queries.keys, time.Now())
+	if err != nil {
+		s.report(err)
+		return nil
+	}
+	defer rows.Close()
+
+	var elems []T
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			s.report(err)
+			return elems
+		}
+		var elem T
+		if err := json.Unmarshal([]byte(key), &elem); err != nil {
+			s.report(err)
+			continue
+		}
+		elems = append(elems, elem)
+	}
+	if err := rows.Err(); err != nil {
+		s.report(err)
+	}
+	return elems
+}
+
+// ExpireAll deletes all expired elements, see WithSweepBatch
+func (s *Set[T]) ExpireAll() {
+	if _, err := s.sweep(); err != nil {
+		s.report(err)
+	}
+}
+
+// Stats returns the counters of the set
+//
+// Description: The counters are those of this process, while Len counts the elements added by all the
+// processes sharing the table.
+func (s *Set[T]) Stats() cacheset.Stats {
+	return cacheset.Stats{
+		Len:         s.Len(),
+		Hits:        s.hits.Load(),
+		Misses:      s.misses.Load(),
+		Adds:        s.adds.Load(),
+		Deletes:     s.deletes.Load(),
+		Expirations: s.expired.Load(),
+	}
+}
+
+// Close stops the sweeper, leaving the database open
+func (s *Set[T]) Close() {
+	if err := s.Shutdown(context.Background()); err != nil {
+		s.report(err)
+	}
+}
+
+// Shutdown stops the sweeper, waiting for its current sweep until ctx is done, leaving the database open
+func (s *Set[T]) Shutdown(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.close)
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			s.closeErr = ctx.Err()
+		}
+	})
+	return s.closeErr
+}
+
+// sweeper sweeps the expired elements every sweep interval until the set is closed
+func (s *Set[T]) sweeper() {
+	defer close(s.done)
+	if s.config.sweepInterval == 0 {
+		<-s.close
+		return
+	}
+	ticker := time.NewTicker(s.config.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.close:
+			return
+		case <-ticker.C:
+			if _, err := s.sweep(); err != nil {
+				s.report(err)
+			}
+		}
+	}
+}
+
+// sweep deletes the expired elements in statements of up to sweepBatch rows, and returns their number
+func (s *Set[T]) sweep() (int, error) {
+	var swept int
+	for {
+		n, err := s.exec(s.queries.sweep, time.Now(), s.config.sweepBatch)
+		swept += int(n)
+		s.expired.Add(uint64(n))
+		if err != nil || n < int64(s.config.sweepBatch) {
+			return swept, err
+		}
+		select {
+		case <-s.close:
+			return swept, nil
+		default:
+		}
+	}
+}
+
+// exec executes the given statement and returns the number of rows it affected
+func (s *Set[T]) exec(query string, args ...any) (int64, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// context returns the context of a statement, bounded by the timeout
+func (s *Set[T]) context() (context.Context, context.CancelFunc) {
+	if s.config.timeout == 0 {
+		return context.Background(), func() {}
+	}
+	return context.WithTimeout(context.Background(), s.config.timeout)
+}
+
+// buildQueries builds the statements of the set
+func (s *Set[T]) buildQueries() queries {
+	t, p := s.config.table, s.placeholder
+	unexpired := "(expires_at IS NULL OR expires_at > " + p(2) + ")"
+	return queries{
+		upsert:      s.upsertQuery(1),
+		upsertBatch: s.upsertQuery(s.config.batchSize),
+		expiration:  "SELECT expires_at FROM " + t + " WHERE key = " + p(1) + " AND " + unexpired,
+		count:       "SELECT COUNT(*) FROM " + t + " WHERE expires_at IS NULL OR expires_at > " + p(1),
+		keys:        "SELECT key FROM " + t + " WHERE expires_at IS NULL OR expires_at > " + p(1),
+		delete:      "DELETE FROM " + t + " WHERE key = " + p(1),
+		clear:       "DELETE FROM " + t,
+		sweep: "DELETE FROM " + t + " WHERE key IN (SELECT key FROM " + t + " WHERE expires_at <= " + p(1) +
+			" LIMIT " + p(2) + ")",
+	}
+}
+
+// upsertQuery returns the statement upserting n elements
+func (s *Set[T]) upsertQuery(n int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + s.config.table + " (key, expires_at) VALUES ")
+	for i := range n {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(" + s.placeholder(2*i+1) + ", " + s.placeholder(2*i+2) + ")")
+	}
+	b.WriteString(" ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at")
+	return b.String()
+}
+
+// placeholder returns the i-th parameter of a statement, counted from 1
+func (s *Set[T]) placeholder(i int) string {
+	if s.config.placeholder == Question {
+		return "?"
+	}
+	return "$" + strconv.Itoa(i)
+}
+
+// report passes the given error to the error handler
+func (s *Set[T]) report(err error) {
+	if s.config.errorHandler != nil {
+		s.config.errorHandler(err)
+	}
+}
+
+// expiresAt returns the expiration time of an element added for ttl, nil meaning never
+func expiresAt(ttl time.Duration) any {
+	if ttl <= 0 {
+		return nil
+	}
+	return time.Now().Add(ttl)
+}
+
+// validTable returns true if the given table name is made of identifiers separated by dots, so that it
+// can be written in the statements without quoting
+func validTable(table string) bool {
+	for _, part := range strings.Split(table, ".") {
+		if part == "" || (part[0] >= '0' && part[0] <= '9') {
+			return false
+		}
+		for _, r := range part {
+			if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
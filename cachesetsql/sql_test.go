@@ -0,0 +1,312 @@
+package cachesetsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"github.com/corentings/go-set/cachetest"
+)
+
+// fakeDriver is a database/sql driver keeping a table per data source name in memory, which understands
+// the statements of Set only
+type fakeDriver struct {
+	mu     sync.Mutex
+	tables map[string]*fakeTable
+}
+
+// fakeTable is a table of the fake driver, mapping each key to its expiration time, nil meaning never
+type fakeTable struct {
+	mu    sync.Mutex
+	rows  map[string]*time.Time
+	stmts []string // stmts are the executed statements, in order
+}
+
+var (
+	testDriver = &fakeDriver{tables: make(map[string]*fakeTable)}
+	testDBs    atomic.Int64 // testDBs numbers the data source names, so that each database starts empty
+)
+
+func init() {
+	sql.Register("cachesetsql-fake", testDriver)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.tables[name]
+	if !ok {
+		t = &fakeTable{rows: make(map[string]*time.Time)}
+		d.tables[name] = t
+	}
+	return &fakeConn{table: t}, nil
+}
+
+// fakeConn is a connection of the fake driver
+type fakeConn struct {
+	table *fakeTable
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{table: c.table, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+// fakeTx is a transaction of the fake driver, which applies the statements immediately
+type fakeTx struct{}
+
+func (fakeTx) Commit() error { return nil }
+
+func (fakeTx) Rollback() error { return nil }
+
+// fakeStmt is a statement of the fake driver
+type fakeStmt struct {
+	table *fakeTable
+	query string
+}
+
+func (s *fakeStmt) Close() error { return nil }
+
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	t := s.table
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stmts = append(t.stmts, s.query)
+
+	var n int64
+	switch q := s.query; {
+	case strings.HasPrefix(q, "CREATE"):
+	case strings.HasPrefix(q, "INSERT"):
+		for i := 0; i < len(args); i += 2 {
+			t.rows[args[i].(string)] = timeArg(args[i+1])
+			n++
+		}
+	case strings.Contains(q, "WHERE key IN"):
+		now, limit := args[0].(time.Time), args[1].(int64)
+		for key, expires := range t.rows {
+			if n < limit && expires != nil && !expires.After(now) {
+				delete(t.rows, key)
+				n++
+			}
+		}
+	case strings.Contains(q, "WHERE key ="):
+		if _, ok := t.rows[args[0].(string)]; ok {
+			delete(t.rows, args[0].(string))
+			n++
+		}
+	case strings.HasPrefix(q, "DELETE"):
+		n = int64(len(t.rows))
+		clear(t.rows)
+	default:
+		return nil, fmt.Errorf("unexpected statement %q", q)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	t := s.table
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stmts = append(t.stmts, s.query)
+
+	now := args[len(args)-1].(time.Time)
+	unexpired := func(expires *time.Time) bool { return expires == nil || expires.After(now) }
+	rows := &fakeRows{}
+	switch q := s.query; {
+	case strings.HasPrefix(q, "SELECT expires_at"):
+		rows.columns = []string{"expires_at"}
+		if expires, ok := t.rows[args[0].(string)]; ok && unexpired(expires) {
+			var v driver.Value
+			if expires != nil {
+				v = *expires
+			}
+			rows.values = append(rows.values, []driver.Value{v})
+		}
+	case strings.HasPrefix(q, "SELECT COUNT"):
+		rows.columns = []string{"count"}
+		var n int64
+		for _, expires := range t.rows {
+			if unexpired(expires) {
+				n++
+			}
+		}
+		rows.values = append(rows.values, []driver.Value{n})
+	case strings.HasPrefix(q, "SELECT key"):
+		rows.columns = []string{"key"}
+		for key, expires := range t.rows {
+			if unexpired(expires) {
+				rows.values = append(rows.values, []driver.Value{key})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected query %q", q)
+	}
+	return rows, nil
+}
+
+// fakeRows are the rows returned by a query of the fake driver
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// timeArg returns the expiration time passed as a parameter, nil meaning never
+func timeArg(v driver.Value) *time.Time {
+	if v == nil {
+		return nil
+	}
+	t := v.(time.Time)
+	return &t
+}
+
+// dsn returns a new data source name of the fake driver
+func dsn(t *testing.T) string {
+	return fmt.Sprintf("%s/%d", t.Name(), testDBs.Add(1))
+}
+
+// open opens a database of the fake driver, empty for each test, and returns a Set of it
+func open[T comparable](t *testing.T, opts ...Option) (*Set[T], *fakeTable) {
+	t.Helper()
+	name := dsn(t)
+	db, err := sql.Open("cachesetsql-fake", name)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	s, err := New[T](context.Background(), db, opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	testDriver.mu.Lock()
+	defer testDriver.mu.Unlock()
+	return s, testDriver.tables[name]
+}
+
+func TestSet_Conformance(t *testing.T) {
+	cachetest.RunConformance(t, func() cacheset.CacheSet[string] {
+		db, err := sql.Open("cachesetsql-fake", dsn(t))
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		s, err := New[string](context.Background(), db)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return s
+	}, cachetest.StringKey)
+}
+
+func TestSet_Statements(t *testing.T) {
+	s, table := open[int](t, WithTable("audit.dedup"), WithPlaceholder(Question), WithSweepInterval(0))
+	_ = s.Add(1, time.Hour)
+	s.Contains(1)
+
+	want := []string{
+		"CREATE TABLE IF NOT EXISTS audit.dedup (key TEXT PRIMARY KEY, expires_at TIMESTAMPTZ)",
+		"CREATE INDEX IF NOT EXISTS dedup_expires_at ON audit.dedup (expires_at)",
+		"INSERT INTO audit.dedup (key, expires_at) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at",
+		"SELECT expires_at FROM audit.dedup WHERE key = ? AND (expires_at IS NULL OR expires_at > ?)",
+	}
+	if !slices.Equal(table.stmts, want) {
+		t.Errorf("statements = %q, want %q", table.stmts, want)
+	}
+
+	if got := s.upsertQuery(2); !strings.Contains(got, "VALUES (?, ?), (?, ?) ON CONFLICT") {
+		t.Errorf("upsertQuery() = %q, want two rows", got)
+	}
+	s.config.placeholder = Dollar
+	if got := s.upsertQuery(2); !strings.Contains(got, "VALUES ($1, $2), ($3, $4) ON CONFLICT") {
+		t.Errorf("upsertQuery() = %q, want numbered parameters", got)
+	}
+}
+
+func TestSet_AddBatch(t *testing.T) {
+	s, table := open[int](t, WithBatchSize(4), WithSweepInterval(0))
+
+	elems := make([]int, 10)
+	for i := range elems {
+		elems[i] = i
+	}
+	if err := s.AddBatch(append(elems, 3), time.Hour); err != nil {
+		t.Fatalf("AddBatch() error = %v", err)
+	}
+	if got := s.Len(); got != 10 {
+		t.Errorf("Len() = %v, want %v", got, 10)
+	}
+	var inserts int
+	for _, stmt := range table.stmts {
+		if strings.HasPrefix(stmt, "INSERT") {
+			inserts++
+		}
+	}
+	if inserts != 3 {
+		t.Errorf("AddBatch() executed %v upserts, want %v", inserts, 3)
+	}
+	if expires, ok := s.Expiration(9); !ok || time.Until(expires) < 59*time.Minute {
+		t.Errorf("Expiration() = %v, %v, want about an hour", expires, ok)
+	}
+	if st := s.Stats(); st.Adds != 10 {
+		t.Errorf("Stats().Adds = %v, want %v", st.Adds, 10)
+	}
+}
+
+func TestSet_Sweep(t *testing.T) {
+	s, table := open[int](t, WithSweepBatch(7), WithSweepInterval(0))
+
+	for i := range 50 {
+		_ = s.Add(i, time.Millisecond)
+	}
+	_ = s.Add(100, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	n, err := s.sweep()
+	if err != nil || n != 50 {
+		t.Errorf("sweep() = %v, %v, want %v, nil", n, err, 50)
+	}
+	if len(table.rows) != 1 || s.Stats().Expirations != 50 {
+		t.Errorf("rows = %v after a sweep, want only the unexpired element", len(table.rows))
+	}
+}
+
+func TestNew_InvalidTable(t *testing.T) {
+	db, err := sql.Open("cachesetsql-fake", dsn(t))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+	for _, table := range []string{"", "users; DROP TABLE users", "1table", "a..b"} {
+		if _, err := New[int](context.Background(), db, WithTable(table)); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("New(%q) error = %v, want %v", table, err, ErrInvalidTable)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package cachesetttl
+//
+// Path: cachesetttl/gcache.go
+//
+// Description: gcache.go contains GCache, which has the method set of the gcache.Cache interface
+// of github.com/bluele/gcache, without the loader functions.
+package cachesetttl
+
+import (
+	"errors"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// ErrKeyNotFound is returned by the Get methods of GCache for the missing keys, like gcache.KeyNotFoundError
+var ErrKeyNotFound = errors.New("cachesetttl: key not found")
+
+// GCache is a cache of values by key with the method set of the gcache caches
+type GCache struct {
+	kv *kv[any, any]
+}
+
+// NewGCache returns a cache holding up to size keys, evicting the least recently used ones, or any number
+// of keys if size is 0 or less
+//
+// Description: evicted, if not nil, is called with the evicted, expired and removed keys and values,
+// like the EvictedFunc of gcache. opts are passed to cacheset.New, so that a default expiration can be
+// set with cacheset.WithDefaultTTL.
+func NewGCache(size int, evicted func(key, value any), opts ...cacheset.Option) *GCache {
+	if size > 0 {
+		opts = append([]cacheset.Option{cacheset.WithCapacity(size)}, opts...)
+	}
+	g := &GCache{kv: newKV[any, any](opts)}
+	if evicted != nil {
+		g.kv.onRemoval(func(key, value any, _ cacheset.RemovalReason) {
+			evicted(key, value)
+		})
+	}
+	return g
+}
+
+// Set sets the value of the key for the default time to live of the cache
+func (g *GCache) Set(key, value any) error {
+	return g.kv.set(key, value, -1)
+}
+
+// SetWithExpire sets the value of the key for the given duration, 0 meaning no expiration
+func (g *GCache) SetWithExpire(key, value any, expiration time.Duration) error {
+	return g.kv.set(key, value, max(expiration, 0))
+}
+
+// Get returns the value of the key, or ErrKeyNotFound
+func (g *GCache) Get(key any) (any, error) {
+	v, _, ok := g.kv.get(key)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return v.value, nil
+}
+
+// GetIFPresent returns the value of the key, or ErrKeyNotFound, like Get since there is no loader
+func (g *GCache) GetIFPresent(key any) (any, error) {
+	return g.Get(key)
+}
+
+// GetALL returns the values of the unexpired keys, checkExpired is ignored since expired keys are never returned
+func (g *GCache) GetALL(checkExpired bool) map[any]any {
+	return g.kv.all()
+}
+
+// Remove removes the key and returns true if it was in the cache
+func (g *GCache) Remove(key any) bool {
+	return g.kv.remove(key)
+}
+
+// Purge removes all keys, calling the eviction callback for each of them
+func (g *GCache) Purge() {
+	g.kv.purge()
+}
+
+// Keys returns the unexpired keys, checkExpired is ignored since expired keys are never returned
+func (g *GCache) Keys(checkExpired bool) []any {
+	return g.kv.keys.Filter(func(any) bool { return true })
+}
+
+// Len returns the number of keys, only the unexpired ones if checkExpired is true
+func (g *GCache) Len(checkExpired bool) int {
+	if checkExpired {
+		return len(g.Keys(true))
+	}
+	return g.kv.keys.Len()
+}
+
+// Has returns true if the key is in the cache and has not expired, without counting as an access
+func (g *GCache) Has(key any) bool {
+	return g.kv.keys.Peek(key)
+}
+
+// HitCount returns the number of lookups that found their key
+func (g *GCache) HitCount() uint64 {
+	return g.kv.keys.Stats().Hits
+}
+
+// MissCount returns the number of lookups that did not find their key
+func (g *GCache) MissCount() uint64 {
+	return g.kv.keys.Stats().Misses
+}
+
+// LookupCount returns the number of lookups
+func (g *GCache) LookupCount() uint64 {
+	s := g.kv.keys.Stats()
+	return s.Hits + s.Misses
+}
+
+// HitRate returns the ratio of the lookups that found their key
+func (g *GCache) HitRate() float64 {
+	return g.kv.keys.Stats().HitRatio()
+}
+
+// Close stops the cache, which must not be used anymore
+func (g *GCache) Close() {
+	g.kv.close()
+}
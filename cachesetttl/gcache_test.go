@@ -0,0 +1,61 @@
+package cachesetttl
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGCache(t *testing.T) {
+	evicted := make(chan any, 10)
+	g := NewGCache(2, func(key, value any) {
+		evicted <- key
+	})
+	defer g.Close()
+
+	g.Set("a", 1)
+	g.SetWithExpire("b", 2, time.Hour)
+	if v, err := g.Get("a"); err != nil || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want %v, nil", v, err, 1)
+	}
+	if _, err := g.Get("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Get(missing) error = %v, want %v", err, ErrKeyNotFound)
+	}
+
+	// a was used more recently than b, so b is evicted
+	g.Set("c", 3)
+	select {
+	case key := <-evicted:
+		if key != "b" {
+			t.Errorf("EvictedFunc(%v), want b", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("EvictedFunc was not called")
+	}
+	if g.Has("b") || !g.Has("c") {
+		t.Errorf("Has() does not reflect the eviction")
+	}
+	if got := g.GetALL(true); len(got) != 2 || got["a"] != 1 || got["c"] != 3 {
+		t.Errorf("GetALL() = %v, want map[a:1 c:3]", got)
+	}
+	if !g.Remove("a") || g.Len(true) != 1 {
+		t.Errorf("Remove(a) did not remove a")
+	}
+	if g.HitCount() != 1 || g.MissCount() != 1 || g.LookupCount() != 2 {
+		t.Errorf("HitCount() = %v, MissCount() = %v, want 1 and 1", g.HitCount(), g.MissCount())
+	}
+}
+
+func TestGCache_SetWithExpire(t *testing.T) {
+	g := NewGCache(0, nil)
+	defer g.Close()
+
+	g.SetWithExpire("a", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := g.Get("a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Get(a) error = %v after its expiration, want %v", err, ErrKeyNotFound)
+	}
+	if got := g.Keys(true); len(got) != 0 {
+		t.Errorf("Keys() = %v after the expiration, want none", got)
+	}
+}
@@ -0,0 +1,180 @@
+// Package cachesetttl adapts a cache to the method sets of popular TTL caches.
+//
+// Path: cachesetttl/kv.go
+//
+// Description: kv.go contains the values kept beside a cacheset.Cache of their keys, shared by the
+// adapters. GCache has the method set of the caches of bluele/gcache, and TTLCache the method set of
+// the caches of jellydator/ttlcache/v3, so that cacheset can be dropped behind the abstraction
+// layers written for them. The values are kept until the removal of their key is processed,
+// and the eviction callbacks are called from a goroutine shortly after the removals.
+//
+// Usage:
+//
+//	gc := cachesetttl.NewGCache(1000, func(key, value any) { log.Println("evicted", key) })
+//	defer gc.Close()
+//	gc.SetWithExpire("answer", 42, time.Minute)
+//
+//	tc := cachesetttl.NewTTLCache[string, int](cacheset.WithDefaultTTL(time.Minute))
+//	defer tc.Close()
+//	tc.Set("answer", 42, cachesetttl.DefaultTTL)
+package cachesetttl
+
+import (
+	"sync"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// slot is a value and the time to live it was set for
+type slot[V any] struct {
+	value V
+	ttl   time.Duration
+}
+
+// kv is a cache of keys with their values beside it
+type kv[K comparable, V any] struct {
+	keys      *cacheset.Cache[K]                                          // keys are the keys of the values
+	values    map[K]slot[V]                                               // values are the values of the keys, until the removal of the key is processed
+	callbacks map[int]func(key K, value V, reason cacheset.RemovalReason) // callbacks are called with the removed keys and values, by id
+	nextID    int                                                         // nextID is the id of the next callback
+	sub       *cacheset.Subscription[K]                                   // sub receives the removals of the keys
+	mu        sync.Mutex
+}
+
+// newKV returns a kv whose keys are stored in a cache created with the given options
+func newKV[K comparable, V any](opts []cacheset.Option) *kv[K, V] {
+	keys := cacheset.New[K](time.Second, opts...)
+	s := &kv[K, V]{
+		keys:      keys,
+		values:    make(map[K]slot[V]),
+		callbacks: make(map[int]func(K, V, cacheset.RemovalReason)),
+	}
+	s.sub = keys.SubscribeFunc(func(ev cacheset.Event[K]) bool {
+		return ev.Kind == cacheset.EventRemoved
+	})
+	go s.drop()
+	return s
+}
+
+// drop forgets the values of the removed keys and calls the callbacks, until the subscription is closed
+func (s *kv[K, V]) drop() {
+	for ev := range s.sub.Events() {
+		s.mu.Lock()
+		v, ok := s.values[ev.Elem]
+		if ok && !s.keys.Peek(ev.Elem) {
+			delete(s.values, ev.Elem)
+		} else {
+			ok = false
+		}
+		callbacks := s.snapshotCallbacks()
+		s.mu.Unlock()
+
+		if ok {
+			for _, fn := range callbacks {
+				fn(ev.Elem, v.value, ev.Reason)
+			}
+		}
+	}
+}
+
+// onRemoval registers a callback and returns the function unregistering it
+func (s *kv[K, V]) onRemoval(fn func(key K, value V, reason cacheset.RemovalReason)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.callbacks[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.callbacks, id)
+	}
+}
+
+// snapshotCallbacks returns the registered callbacks, the kv must be locked
+func (s *kv[K, V]) snapshotCallbacks() []func(K, V, cacheset.RemovalReason) {
+	callbacks := make([]func(K, V, cacheset.RemovalReason), 0, len(s.callbacks))
+	for _, fn := range s.callbacks {
+		callbacks = append(callbacks, fn)
+	}
+	return callbacks
+}
+
+// set sets the value of the key for ttl, the default time to live of the cache if ttl is negative
+func (s *kv[K, V]) set(key K, value V, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if ttl < 0 {
+		err = s.keys.AddDefault(key)
+	} else {
+		err = s.keys.Add(key, ttl)
+	}
+	if err != nil {
+		return err
+	}
+	s.values[key] = slot[V]{value: value, ttl: ttl}
+	return nil
+}
+
+// get returns the value of the key and its entry, counting as an access
+func (s *kv[K, V]) get(key K) (slot[V], cacheset.Entry[K], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.keys.Lookup(key)
+	if !ok {
+		return slot[V]{}, e, false
+	}
+	v, ok := s.values[key]
+	return v, e, ok
+}
+
+// remove removes the key and returns true if it was in the cache
+func (s *kv[K, V]) remove(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	present := s.keys.Peek(key)
+	s.keys.Delete(key)
+	return present
+}
+
+// all returns the unexpired keys and their values
+func (s *kv[K, V]) all() map[K]V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make(map[K]V)
+	for _, key := range s.keys.Filter(func(K) bool { return true }) {
+		if v, ok := s.values[key]; ok {
+			values[key] = v.value
+		}
+	}
+	return values
+}
+
+// purge removes all keys and calls the callbacks with them as deleted
+func (s *kv[K, V]) purge() {
+	s.mu.Lock()
+	values := s.values
+	s.values = make(map[K]slot[V])
+	s.keys.Clear()
+	callbacks := s.snapshotCallbacks()
+	s.mu.Unlock()
+
+	for key, v := range values {
+		for _, fn := range callbacks {
+			fn(key, v.value, cacheset.RemovalDeleted)
+		}
+	}
+}
+
+// close stops the cache of the keys
+func (s *kv[K, V]) close() {
+	s.sub.Close()
+	s.keys.Close()
+}
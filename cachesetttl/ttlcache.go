@@ -0,0 +1,196 @@
+// Package cachesetttl
+//
+// Path: cachesetttl/ttlcache.go
+//
+// Description: ttlcache.go contains TTLCache, which has the main methods of the ttlcache.Cache type of
+// github.com/jellydator/ttlcache/v3, without the loaders and the options of Get.
+package cachesetttl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+const (
+	// NoTTL sets a value that never expires
+	NoTTL time.Duration = -1
+	// DefaultTTL sets a value for the default time to live of the cache, set with cacheset.WithDefaultTTL
+	DefaultTTL time.Duration = 0
+)
+
+// EvictionReason is the reason of the removal of a key, given to the OnEviction callbacks
+type EvictionReason int
+
+const (
+	// EvictionReasonDeleted means that the key was deleted
+	EvictionReasonDeleted EvictionReason = iota + 1
+	// EvictionReasonCapacityReached means that the key was evicted because the cache was full
+	EvictionReasonCapacityReached
+	// EvictionReasonExpired means that the key expired
+	EvictionReasonExpired
+)
+
+// evictionReason returns the eviction reason of a removal reason
+func evictionReason(reason cacheset.RemovalReason) EvictionReason {
+	switch reason {
+	case cacheset.RemovalEvicted:
+		return EvictionReasonCapacityReached
+	case cacheset.RemovalExpired:
+		return EvictionReasonExpired
+	default:
+		return EvictionReasonDeleted
+	}
+}
+
+// Item is a key of a TTLCache with its value
+type Item[K comparable, V any] struct {
+	key       K
+	value     V
+	ttl       time.Duration
+	expiresAt time.Time
+}
+
+// Key returns the key of the item
+func (i *Item[K, V]) Key() K {
+	return i.key
+}
+
+// Value returns the value of the item
+func (i *Item[K, V]) Value() V {
+	return i.value
+}
+
+// TTL returns the remaining time to live of the item when it was read, NoTTL if it never expires
+func (i *Item[K, V]) TTL() time.Duration {
+	if i.expiresAt.IsZero() {
+		return NoTTL
+	}
+	return i.ttl
+}
+
+// ExpiresAt returns the expiration time of the item, zero meaning never
+func (i *Item[K, V]) ExpiresAt() time.Time {
+	return i.expiresAt
+}
+
+// IsExpired returns true if the item has expired
+func (i *Item[K, V]) IsExpired() bool {
+	return !i.expiresAt.IsZero() && !time.Now().Before(i.expiresAt)
+}
+
+// TTLCache is a cache of values by key with the method set of the ttlcache caches
+type TTLCache[K comparable, V any] struct {
+	kv       *kv[K, V]
+	stop     chan struct{} // stop unblocks Start
+	stopOnce sync.Once
+}
+
+// NewTTLCache returns a cache whose keys are stored in a cache created with the given options,
+// cacheset.WithCapacity and cacheset.WithDefaultTTL replacing the options of ttlcache.New
+func NewTTLCache[K comparable, V any](opts ...cacheset.Option) *TTLCache[K, V] {
+	return &TTLCache[K, V]{kv: newKV[K, V](opts), stop: make(chan struct{})}
+}
+
+// Set sets the value of the key for ttl, which may be NoTTL or DefaultTTL, and returns its item
+func (c *TTLCache[K, V]) Set(key K, value V, ttl time.Duration) *Item[K, V] {
+	switch ttl {
+	case NoTTL:
+		ttl = 0
+	case DefaultTTL:
+		ttl = -1
+	}
+	if err := c.kv.set(key, value, ttl); err != nil {
+		return nil
+	}
+	return c.Get(key)
+}
+
+// Get returns the item of the key, or nil if it is not in the cache
+func (c *TTLCache[K, V]) Get(key K) *Item[K, V] {
+	v, e, ok := c.kv.get(key)
+	if !ok {
+		return nil
+	}
+	return &Item[K, V]{key: key, value: v.value, ttl: e.TTL(), expiresAt: e.ExpiresAt}
+}
+
+// Has returns true if the key is in the cache and has not expired, without counting as an access
+func (c *TTLCache[K, V]) Has(key K) bool {
+	return c.kv.keys.Peek(key)
+}
+
+// Touch resets the expiration of the key to the time to live it was set for
+func (c *TTLCache[K, V]) Touch(key K) {
+	c.kv.mu.Lock()
+	v, ok := c.kv.values[key]
+	c.kv.mu.Unlock()
+	if ok && c.Has(key) {
+		c.kv.set(key, v.value, v.ttl)
+	}
+}
+
+// Delete removes the key
+func (c *TTLCache[K, V]) Delete(key K) {
+	c.kv.remove(key)
+}
+
+// DeleteAll removes all keys, calling the eviction callbacks for each of them
+func (c *TTLCache[K, V]) DeleteAll() {
+	c.kv.purge()
+}
+
+// DeleteExpired removes the expired keys
+func (c *TTLCache[K, V]) DeleteExpired() {
+	c.kv.keys.ExpireAll()
+}
+
+// Keys returns the unexpired keys
+func (c *TTLCache[K, V]) Keys() []K {
+	return c.kv.keys.Filter(func(K) bool { return true })
+}
+
+// Items returns the items of the unexpired keys
+func (c *TTLCache[K, V]) Items() map[K]*Item[K, V] {
+	items := make(map[K]*Item[K, V])
+	for _, key := range c.Keys() {
+		if item := c.Get(key); item != nil {
+			items[key] = item
+		}
+	}
+	return items
+}
+
+// Len returns the number of keys
+func (c *TTLCache[K, V]) Len() int {
+	return c.kv.keys.Len()
+}
+
+// OnEviction registers a function called with the removed items, and returns the function unregistering it
+//
+// Description: The function is called from a goroutine of the cache, shortly after the removal.
+func (c *TTLCache[K, V]) OnEviction(fn func(ctx context.Context, reason EvictionReason, item *Item[K, V])) func() {
+	return c.kv.onRemoval(func(key K, value V, reason cacheset.RemovalReason) {
+		fn(context.Background(), evictionReason(reason), &Item[K, V]{key: key, value: value})
+	})
+}
+
+// Start blocks until Stop is called, the expired keys being removed by the cache in the background
+func (c *TTLCache[K, V]) Start() {
+	<-c.stop
+}
+
+// Stop unblocks Start
+func (c *TTLCache[K, V]) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// Close stops the cache, which must not be used anymore
+func (c *TTLCache[K, V]) Close() {
+	c.Stop()
+	c.kv.close()
+}
@@ -0,0 +1,79 @@
+package cachesetttl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+func TestTTLCache(t *testing.T) {
+	c := NewTTLCache[string, int](cacheset.WithDefaultTTL(time.Hour))
+	defer c.Close()
+
+	if item := c.Set("a", 1, DefaultTTL); item == nil || item.Value() != 1 || item.TTL() <= 59*time.Minute {
+		t.Errorf("Set(a, DefaultTTL) = %+v, want a value expiring in an hour", item)
+	}
+	if item := c.Set("b", 2, NoTTL); item == nil || item.TTL() != NoTTL || !item.ExpiresAt().IsZero() {
+		t.Errorf("Set(b, NoTTL) = %+v, want a value without expiration", item)
+	}
+	if item := c.Get("missing"); item != nil {
+		t.Errorf("Get(missing) = %+v, want nil", item)
+	}
+	if !c.Has("a") || c.Len() != 2 || len(c.Items()) != 2 {
+		t.Errorf("Has(a) = %v, Len() = %v, want true and 2", c.Has("a"), c.Len())
+	}
+
+	c.Delete("a")
+	if c.Has("a") {
+		t.Errorf("Has(a) = true after Delete")
+	}
+	c.DeleteAll()
+	if got := c.Keys(); len(got) != 0 {
+		t.Errorf("Keys() = %v after DeleteAll, want none", got)
+	}
+}
+
+func TestTTLCache_OnEviction(t *testing.T) {
+	c := NewTTLCache[string, int](cacheset.WithCapacity(1))
+	defer c.Close()
+
+	reasons := make(chan EvictionReason, 10)
+	unsubscribe := c.OnEviction(func(_ context.Context, reason EvictionReason, item *Item[string, int]) {
+		if item.Key() == "a" && item.Value() == 1 {
+			reasons <- reason
+		}
+	})
+	c.Set("a", 1, NoTTL)
+	c.Set("b", 2, NoTTL)
+	select {
+	case reason := <-reasons:
+		if reason != EvictionReasonCapacityReached {
+			t.Errorf("OnEviction() reason = %v, want %v", reason, EvictionReasonCapacityReached)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("OnEviction callback was not called")
+	}
+
+	unsubscribe()
+	c.Set("a", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	c.DeleteExpired()
+	select {
+	case reason := <-reasons:
+		t.Errorf("OnEviction callback called with %v after unsubscribing", reason)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestTTLCache_Start(t *testing.T) {
+	c := NewTTLCache[string, int]()
+	done := make(chan struct{})
+	go func() {
+		c.Start()
+		close(done)
+	}()
+	c.Close()
+	<-done
+}
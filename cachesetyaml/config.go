@@ -0,0 +1,37 @@
+// Package cachesetyaml reads the configuration of a cache from a YAML document.
+//
+// Path: cachesetyaml/config.go
+//
+// Description: config.go contains ParseConfig, the YAML counterpart of cacheset.ParseConfigJSON, kept
+// out of the cacheset package so that only the programs reading YAML files depend on gopkg.in/yaml.v3.
+// The keys of the document are the yaml tags of cacheset.Config, and the durations are strings such
+// as "5m".
+//
+// Usage:
+//
+//	data, err := os.ReadFile("cache.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	cfg, err := cachesetyaml.ParseConfig(data)
+//	if err != nil {
+//		return err
+//	}
+//	cache := cacheset.NewFromConfig[string](cfg)
+package cachesetyaml
+
+import (
+	"fmt"
+
+	cacheset "github.com/corentings/go-set"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseConfig returns the validated Config encoded in the given YAML document
+func ParseConfig(data []byte) (cacheset.Config, error) {
+	var cfg cacheset.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cacheset.Config{}, fmt.Errorf("cachesetyaml: parsing config: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
@@ -0,0 +1,45 @@
+package cachesetyaml
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+func TestParseConfig(t *testing.T) {
+	want := cacheset.Config{
+		CleanInterval:  cacheset.Duration(time.Minute),
+		DefaultTTL:     cacheset.Duration(10 * time.Minute),
+		Capacity:       100,
+		EvictionPolicy: cacheset.EvictSLRU,
+		NamespaceTTLs:  map[string]cacheset.Duration{"otp": cacheset.Duration(5 * time.Minute)},
+		Shards:         4,
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			data: "clean_interval: 1m\ndefault_ttl: 10m\ncapacity: 100\neviction_policy: SLRU\nnamespace_ttls:\n  otp: 5m\nshards: 4\n",
+		},
+		{name: "InvalidDuration", data: "default_ttl: soon\n", wantErr: true},
+		{name: "InvalidPolicy", data: "eviction_policy: MRU\n", wantErr: true},
+		{name: "Negative", data: "capacity: -1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConfig([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseConfig() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
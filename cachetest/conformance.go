@@ -0,0 +1,177 @@
+// Package cachetest
+//
+// Path: cachetest/conformance.go
+//
+// Description: conformance.go contains RunConformance, which checks that a backend implementing
+// cacheset.CacheSet behaves like cacheset.Cache, so that the callers can switch backends safely.
+//
+// Usage:
+//
+//	func TestConformance(t *testing.T) {
+//		cachetest.RunConformance(t, func() cacheset.CacheSet[string] {
+//			return mybackend.New[string]()
+//		}, cachetest.StringKey)
+//	}
+package cachetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// conformanceTTL is the time to live of the expiring elements added by RunConformance
+const conformanceTTL = 20 * time.Millisecond
+
+// RunConformance runs the conformance suite against the sets returned by factory, one new set per subtest
+//
+// Description: key returns the distinct elements of the tests, such as IntKey or StringKey. Each subtest
+// closes its set. The expirations are checked after ExpireAll, so that the suite does not depend on the
+// clean interval of the backend.
+func RunConformance[T comparable](t *testing.T, factory func() cacheset.CacheSet[T], key func(i int) T) {
+	t.Helper()
+
+	run := func(name string, test func(t *testing.T, s cacheset.CacheSet[T])) {
+		t.Run(name, func(t *testing.T) {
+			s := factory()
+			defer s.Close()
+			test(t, s)
+		})
+	}
+
+	run("AddContains", func(t *testing.T, s cacheset.CacheSet[T]) {
+		if s.Contains(key(0)) {
+			t.Errorf("Contains() = true on an empty set, want false")
+		}
+		if err := s.Add(key(0), 0); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if !s.Contains(key(0)) {
+			t.Errorf("Contains() = false after Add, want true")
+		}
+		if s.Contains(key(1)) {
+			t.Errorf("Contains() = true for an element never added, want false")
+		}
+	})
+
+	run("AddAgain", func(t *testing.T, s cacheset.CacheSet[T]) {
+		_ = s.Add(key(0), 0)
+		_ = s.Add(key(0), time.Hour)
+		if got := s.Len(); got != 1 {
+			t.Errorf("Len() = %v after adding an element twice, want %v", got, 1)
+		}
+	})
+
+	run("LenToSlice", func(t *testing.T, s cacheset.CacheSet[T]) {
+		var want []T
+		for i := range 100 {
+			_ = s.Add(key(i), time.Hour)
+			want = append(want, key(i))
+		}
+		if got := s.Len(); got != len(want) {
+			t.Errorf("Len() = %v, want %v", got, len(want))
+		}
+		got := s.ToSlice()
+		if len(got) != len(want) {
+			t.Fatalf("ToSlice() has %v elements, want %v", len(got), len(want))
+		}
+		for _, elem := range want {
+			if !slices.Contains(got, elem) {
+				t.Errorf("ToSlice() misses %v", elem)
+			}
+		}
+	})
+
+	run("Delete", func(t *testing.T, s cacheset.CacheSet[T]) {
+		_ = s.Add(key(0), 0)
+		_ = s.Add(key(1), 0)
+		s.Delete(key(0))
+		s.Delete(key(2))
+		if s.Contains(key(0)) || !s.Contains(key(1)) || s.Len() != 1 {
+			t.Errorf("ToSlice() = %v after Delete, want only %v", s.ToSlice(), key(1))
+		}
+	})
+
+	run("Clear", func(t *testing.T, s cacheset.CacheSet[T]) {
+		for i := range 10 {
+			_ = s.Add(key(i), 0)
+		}
+		s.Clear()
+		if s.Len() != 0 || s.Contains(key(0)) || len(s.ToSlice()) != 0 {
+			t.Errorf("ToSlice() = %v after Clear, want none", s.ToSlice())
+		}
+		_ = s.Add(key(0), 0)
+		if !s.Contains(key(0)) {
+			t.Errorf("Contains() = false after Clear and Add, want true")
+		}
+	})
+
+	run("Expiry", func(t *testing.T, s cacheset.CacheSet[T]) {
+		_ = s.Add(key(0), conformanceTTL)
+		_ = s.Add(key(1), 0)
+		_ = s.Add(key(2), time.Hour)
+		if !s.Contains(key(0)) {
+			t.Errorf("Contains() = false before the expiration, want true")
+		}
+		time.Sleep(2 * conformanceTTL)
+		s.ExpireAll()
+		if s.Contains(key(0)) {
+			t.Errorf("Contains() = true after the expiration, want false")
+		}
+		if !s.Contains(key(1)) || !s.Contains(key(2)) {
+			t.Errorf("ToSlice() = %v, want the unexpired elements kept", s.ToSlice())
+		}
+		if got := s.Len(); got != 2 {
+			t.Errorf("Len() = %v after ExpireAll, want %v", got, 2)
+		}
+	})
+
+	run("Stats", func(t *testing.T, s cacheset.CacheSet[T]) {
+		_ = s.Add(key(0), 0)
+		s.Contains(key(0))
+		s.Contains(key(1))
+		if st := s.Stats(); st.Len != 1 {
+			t.Errorf("Stats().Len = %v, want %v", st.Len, 1)
+		}
+	})
+
+	run("Concurrent", func(t *testing.T, s cacheset.CacheSet[T]) {
+		var wg sync.WaitGroup
+		for w := range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 100 {
+					elem := key(w*100 + i)
+					_ = s.Add(elem, time.Hour)
+					if !s.Contains(elem) {
+						t.Errorf("Contains(%v) = false right after Add, want true", elem)
+					}
+					if i%2 == 0 {
+						s.Delete(elem)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if got := s.Len(); got != 200 {
+			t.Errorf("Len() = %v, want %v", got, 200)
+		}
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		s := factory()
+		_ = s.Add(key(0), 0)
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown() error = %v, want nil", err)
+		}
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown() error = %v on a closed set, want nil", err)
+		}
+		s.Close()
+	})
+}
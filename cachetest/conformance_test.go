@@ -0,0 +1,32 @@
+package cachetest
+
+import (
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+func TestRunConformance(t *testing.T) {
+	tests := []struct {
+		name    string
+		factory func() cacheset.CacheSet[string]
+	}{
+		{"Cache", func() cacheset.CacheSet[string] { return cacheset.New[string](time.Minute) }},
+		{"Compact", func() cacheset.CacheSet[string] {
+			return cacheset.New[string](time.Minute, cacheset.WithCompactStorage())
+		}},
+		{"Sharded", func() cacheset.CacheSet[string] {
+			return cacheset.NewSharded[string](time.Minute, cacheset.WithShards(4))
+		}},
+		{"WriteBehind", func() cacheset.CacheSet[string] { return cacheset.NewWriteBehind[string](time.Minute) }},
+		{"Ordered", func() cacheset.CacheSet[string] {
+			return cacheset.New[string](time.Minute, cacheset.WithDeterministicIteration())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RunConformance(t, tt.factory, StringKey)
+		})
+	}
+}
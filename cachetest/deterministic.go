@@ -0,0 +1,223 @@
+// Package cachetest
+//
+// Path: cachetest/deterministic.go
+//
+// Description: deterministic.go contains Deterministic, an in-memory set whose time only advances
+// with AdvanceTime and which records its operations, so that the tests of the code using a set
+// never sleep.
+package cachetest
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpKind is the kind of an operation recorded by a Deterministic set
+type OpKind int
+
+const (
+	// OpAdd is a call of Add
+	OpAdd OpKind = iota
+	// OpContains is a call of Contains
+	OpContains
+	// OpDelete is a call of Delete
+	OpDelete
+	// OpClear is a call of Clear
+	OpClear
+	// OpExpire is the expiration of an element by AdvanceTime
+	OpExpire
+)
+
+// String returns the name of the operation kind
+func (k OpKind) String() string {
+	switch k {
+	case OpAdd:
+		return "Add"
+	case OpContains:
+		return "Contains"
+	case OpDelete:
+		return "Delete"
+	case OpClear:
+		return "Clear"
+	case OpExpire:
+		return "Expire"
+	default:
+		return "unknown"
+	}
+}
+
+// Op is an operation recorded by a Deterministic set
+type Op[T comparable] struct {
+	Kind  OpKind        // Kind is the kind of the operation
+	Elem  T             // Elem is the element of the operation, the zero value for OpClear
+	TTL   time.Duration // TTL is the time to live of an OpAdd
+	Found bool          // Found is the result of an OpContains, or whether the element was in the set for an OpDelete
+	At    time.Time     // At is the virtual time of the operation
+}
+
+// String returns the operation in the form of a call
+func (op Op[T]) String() string {
+	switch op.Kind {
+	case OpAdd:
+		return fmt.Sprintf("Add(%v, %v)", op.Elem, op.TTL)
+	case OpContains:
+		return fmt.Sprintf("Contains(%v) = %v", op.Elem, op.Found)
+	case OpDelete, OpExpire:
+		return fmt.Sprintf("%v(%v)", op.Kind, op.Elem)
+	default:
+		return op.Kind.String() + "()"
+	}
+}
+
+// Deterministic is an in-memory set whose time only advances with AdvanceTime, recording its operations
+//
+// Description: An element added for a positive ttl expires once AdvanceTime moves the virtual time
+// ttl past its addition, a ttl of 0 or less meaning no expiration. The expired elements are removed
+// by AdvanceTime, so they are never visible. A Deterministic set is safe for concurrent use, and
+// implements the method set of a cacheset.Cache used by most code, so that it can replace the cache
+// in the tests of that code.
+type Deterministic[T comparable] struct {
+	mu      sync.Mutex
+	now     time.Time       // now is the virtual time
+	expires map[T]time.Time // expires are the elements and their expiration times, zero meaning never
+	ops     []Op[T]         // ops are the recorded operations
+}
+
+// NewDeterministic returns an empty Deterministic set whose virtual time starts at the Unix epoch
+func NewDeterministic[T comparable]() *Deterministic[T] {
+	return &Deterministic[T]{
+		now:     time.Unix(0, 0).UTC(),
+		expires: make(map[T]time.Time),
+	}
+}
+
+// Add adds the given element for ttl, replacing its expiration if it is already in the set
+func (d *Deterministic[T]) Add(elem T, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = d.now.Add(ttl)
+	}
+	d.expires[elem] = expires
+	d.record(Op[T]{Kind: OpAdd, Elem: elem, TTL: ttl})
+	return nil
+}
+
+// Contains returns true if the given element is in the set
+func (d *Deterministic[T]) Contains(elem T) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, found := d.expires[elem]
+	d.record(Op[T]{Kind: OpContains, Elem: elem, Found: found})
+	return found
+}
+
+// Delete removes the given element from the set
+func (d *Deterministic[T]) Delete(elem T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, found := d.expires[elem]
+	delete(d.expires, elem)
+	d.record(Op[T]{Kind: OpDelete, Elem: elem, Found: found})
+}
+
+// Clear removes all elements from the set
+func (d *Deterministic[T]) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	clear(d.expires)
+	d.record(Op[T]{Kind: OpClear})
+}
+
+// Len returns the number of elements in the set
+func (d *Deterministic[T]) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.expires)
+}
+
+// ToSlice returns the elements of the set in no particular order
+func (d *Deterministic[T]) ToSlice() []T {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	elems := make([]T, 0, len(d.expires))
+	for elem := range d.expires {
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+// ExpiresAt returns the virtual expiration time of the given element, the zero time.Time meaning never,
+// and false if the element is not in the set
+func (d *Deterministic[T]) ExpiresAt(elem T) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	expires, ok := d.expires[elem]
+	return expires, ok
+}
+
+// Close does nothing, it lets the set replace a cache that is closed
+func (d *Deterministic[T]) Close() {}
+
+// Now returns the virtual time
+func (d *Deterministic[T]) Now() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.now
+}
+
+// AdvanceTime moves the virtual time forward by the given duration and removes the expired elements,
+// recording their expirations in the order of their expiration times, then of their printed forms
+func (d *Deterministic[T]) AdvanceTime(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.now = d.now.Add(max(duration, 0))
+
+	var expired []Op[T]
+	for elem, expires := range d.expires {
+		if !expires.IsZero() && !expires.After(d.now) {
+			delete(d.expires, elem)
+			expired = append(expired, Op[T]{Kind: OpExpire, Elem: elem, At: expires})
+		}
+	}
+	slices.SortFunc(expired, func(a, b Op[T]) int {
+		return cmp.Or(a.At.Compare(b.At), strings.Compare(fmt.Sprint(a.Elem), fmt.Sprint(b.Elem)))
+	})
+	d.ops = append(d.ops, expired...)
+}
+
+// Ops returns a copy of the recorded operations, oldest first
+func (d *Deterministic[T]) Ops() []Op[T] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.ops)
+}
+
+// ResetOps forgets the recorded operations
+func (d *Deterministic[T]) ResetOps() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ops = nil
+}
+
+// record records the given operation at the virtual time, the set must be locked
+func (d *Deterministic[T]) record(op Op[T]) {
+	op.At = d.now
+	d.ops = append(d.ops, op)
+}
@@ -0,0 +1,61 @@
+package cachetest
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeterministic_AdvanceTime(t *testing.T) {
+	d := NewDeterministic[string]()
+	d.Add("a", time.Second)
+	d.Add("b", 2*time.Second)
+	d.Add("c", 0)
+
+	d.AdvanceTime(time.Second - 1)
+	if !d.Contains("a") {
+		t.Errorf("Contains(a) = false before its expiration, want true")
+	}
+	d.AdvanceTime(1)
+	if d.Contains("a") {
+		t.Errorf("Contains(a) = true at its expiration, want false")
+	}
+	d.AdvanceTime(time.Hour)
+	if got, want := d.ToSlice(), []string{"c"}; !slices.Equal(got, want) {
+		t.Errorf("ToSlice() = %v, want %v", got, want)
+	}
+	if got, want := d.Now(), time.Unix(0, 0).Add(time.Hour+time.Second); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+}
+
+func TestDeterministic_Ops(t *testing.T) {
+	d := NewDeterministic[int]()
+	d.Add(1, time.Minute)
+	d.Add(2, time.Second)
+	d.AdvanceTime(time.Hour)
+	d.Contains(1)
+	d.Delete(3)
+	d.Clear()
+
+	var got []string
+	for _, op := range d.Ops() {
+		got = append(got, op.String())
+	}
+	want := []string{"Add(1, 1m0s)", "Add(2, 1s)", "Expire(2)", "Expire(1)", "Contains(1) = false", "Delete(3)", "Clear()"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Ops() = %v, want %v", got, want)
+	}
+	if at := d.Ops()[2].At; !at.Equal(time.Unix(1, 0)) {
+		t.Errorf("Ops()[2].At = %v, want %v", at, time.Unix(1, 0))
+	}
+
+	d.ResetOps()
+	if got := d.Ops(); len(got) != 0 {
+		t.Errorf("Ops() = %v after ResetOps, want none", got)
+	}
+}
+
+func TestDeterministic_Stress(t *testing.T) {
+	Stress(t, NewDeterministic[int](), IntKey, WithDuration(50*time.Millisecond), WithMaxTTL(time.Hour))
+}
@@ -0,0 +1,365 @@
+// Package cachetest provides helpers to test the implementations and the users of a cache set.
+//
+// Path: cachetest/stress.go
+//
+// Description: stress.go contains Stress, which hammers a set with randomized concurrent operations
+// while checking its invariants: an expired or deleted element is never visible, an added element is
+// visible until it expires, ToSlice has no duplicates, and Len agrees with the membership once the
+// operations stop. Each worker owns a disjoint part of the keys to know their exact expected state,
+// and reads the keys of the other workers to contend with them.
+//
+// Usage:
+//
+//	func TestStress(t *testing.T) {
+//		s := mybackend.New[int]()
+//		defer s.Close()
+//		cachetest.Stress(t, s, cachetest.IntKey, cachetest.WithSlack(10*time.Millisecond))
+//	}
+package cachetest
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Set is the method set of a cache set exercised by Stress, implemented by *cacheset.Cache and *cacheset.Sharded
+type Set[T comparable] interface {
+	Add(elem T, ttl time.Duration) error
+	Contains(elem T) bool
+	Delete(elem T)
+	Len() int
+	ToSlice() []T
+}
+
+// IntKey returns i, the keys of a Set[int]
+func IntKey(i int) int {
+	return i
+}
+
+// StringKey returns the decimal form of i, the keys of a Set[string]
+func StringKey(i int) string {
+	return fmt.Sprint(i)
+}
+
+// Option configures Stress
+type Option func(*config)
+
+// config is the configuration of Stress
+type config struct {
+	workers   int           // workers is the number of goroutines running operations
+	duration  time.Duration // duration is how long the operations run
+	keys      int           // keys is the number of distinct keys
+	maxTTL    time.Duration // maxTTL is the maximum time to live of the added keys
+	slack     time.Duration // slack is the tolerated imprecision of the expirations
+	seed      int64         // seed seeds the random operations
+	evictions bool          // evictions allows the set to remove unexpired keys
+	maxErrors int           // maxErrors is the number of reported violations before the others are dropped
+}
+
+// WithWorkers sets the number of goroutines running operations, 8 by default
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		c.workers = max(n, 1)
+	}
+}
+
+// WithDuration sets how long the operations run, 500 milliseconds by default
+func WithDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.duration = d
+	}
+}
+
+// WithKeys sets the number of distinct keys, 1024 by default
+func WithKeys(n int) Option {
+	return func(c *config) {
+		c.keys = max(n, 1)
+	}
+}
+
+// WithMaxTTL sets the maximum time to live of the added keys, 50 milliseconds by default
+//
+// Description: The times to live are drawn between 1 millisecond and the maximum, and a tenth of the
+// keys are added without expiration.
+func WithMaxTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.maxTTL = max(ttl, time.Millisecond)
+	}
+}
+
+// WithSlack sets the tolerated imprecision of the expirations, 1 millisecond by default
+//
+// Description: A key is only required to be invisible once its expiration time plus the slack is past,
+// and visible until its expiration time minus the slack. Sets removing the expired keys periodically,
+// like a cacheset.Cache whose Contains reports the keys until they are cleaned, need a slack larger than
+// their cleaning interval, and so do sets with a coarse clock or a time to live jitter.
+func WithSlack(d time.Duration) Option {
+	return func(c *config) {
+		c.slack = d
+	}
+}
+
+// WithSeed seeds the random operations, so that a failure can be investigated with the same operations
+func WithSeed(seed int64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+// WithEvictions allows the set to remove unexpired keys, for sets with a capacity
+func WithEvictions() Option {
+	return func(c *config) {
+		c.evictions = true
+	}
+}
+
+// Report counts the operations run by Stress
+type Report struct {
+	Adds     uint64 // Adds is the number of Add calls
+	Deletes  uint64 // Deletes is the number of Delete calls
+	Contains uint64 // Contains is the number of Contains calls
+	Scans    uint64 // Scans is the number of ToSlice calls
+}
+
+// String returns the report on a single line
+func (r Report) String() string {
+	return fmt.Sprintf("%d adds, %d deletes, %d contains, %d scans", r.Adds, r.Deletes, r.Contains, r.Scans)
+}
+
+// state is the expected state of a key, known by the worker owning it
+type state struct {
+	present bool      // present is true if the key was added and not deleted
+	unknown bool      // unknown is true if the last Add failed, so the state of the key is not known
+	lo, hi  time.Time // lo and hi bound the expiration time of a present key, zero meaning never
+}
+
+// visible returns whether the key must be visible, must be invisible, or may be either between
+// the times before and after the observation
+func (s state) visible(before, after time.Time, slack time.Duration) (must, mustNot bool) {
+	switch {
+	case s.unknown:
+		return false, false
+	case !s.present:
+		return false, true
+	case s.hi.IsZero():
+		return true, false
+	case before.After(s.hi.Add(slack)):
+		return false, true
+	case after.Before(s.lo.Add(-slack)):
+		return true, false
+	}
+	return false, false
+}
+
+// Stress runs randomized concurrent operations on s, whose keys are produced by key from 0 to the number
+// of keys, and reports the violations of its invariants with t.Errorf
+//
+// Description: The set must be empty and must not be used by anything else while Stress runs.
+func Stress[T comparable](t testing.TB, s Set[T], key func(i int) T, opts ...Option) Report {
+	t.Helper()
+	cfg := config{
+		workers:   8,
+		duration:  500 * time.Millisecond,
+		keys:      1024,
+		maxTTL:    50 * time.Millisecond,
+		slack:     time.Millisecond,
+		seed:      time.Now().UnixNano(),
+		maxErrors: 10,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	st := &stress[T]{t: t, s: s, cfg: cfg, index: make(map[T]int, cfg.keys)}
+	st.keys = make([]T, cfg.keys)
+	for i := range st.keys {
+		st.keys[i] = key(i)
+		st.index[st.keys[i]] = i
+	}
+	if len(st.index) != cfg.keys {
+		t.Fatalf("cachetest: key returned %d distinct keys for %d values", len(st.index), cfg.keys)
+	}
+
+	models := make([]map[int]state, cfg.workers)
+	deadline := time.Now().Add(cfg.duration)
+	var wg sync.WaitGroup
+	for w := range models {
+		models[w] = make(map[int]state)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st.work(w, models[w], deadline)
+		}()
+	}
+	wg.Wait()
+
+	st.verify(models)
+	return st.report
+}
+
+// stress is a run of Stress
+type stress[T comparable] struct {
+	t      testing.TB
+	s      Set[T]
+	cfg    config
+	keys   []T          // keys are the keys, by number
+	index  map[T]int    // index are the numbers of the keys
+	errors atomic.Int64 // errors is the number of violations
+	report Report       // report counts the operations of the workers that returned
+	mu     sync.Mutex   // mu guards report
+}
+
+// errorf reports a violation, dropping them once maxErrors are reported
+func (st *stress[T]) errorf(format string, args ...any) {
+	st.t.Helper()
+	if n := st.errors.Add(1); n <= int64(st.cfg.maxErrors) {
+		st.t.Errorf("cachetest: seed %d: "+format, append([]any{st.cfg.seed}, args...)...)
+	}
+}
+
+// work runs random operations until the deadline, on the keys owned by worker w whose expected
+// states are in model, and reads the other keys
+func (st *stress[T]) work(w int, model map[int]state, deadline time.Time) {
+	r := rand.New(rand.NewSource(st.cfg.seed + int64(w)))
+	workers := st.cfg.workers
+	owned := (st.cfg.keys - w + workers - 1) / workers
+	if owned == 0 {
+		return
+	}
+
+	var local Report
+	defer func() {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		st.report.Adds += local.Adds
+		st.report.Deletes += local.Deletes
+		st.report.Contains += local.Contains
+		st.report.Scans += local.Scans
+	}()
+
+	for time.Now().Before(deadline) {
+		// yielding interleaves the operations of the workers even with a single processor
+		runtime.Gosched()
+		k := w + workers*r.Intn(owned)
+		switch op := r.Intn(100); {
+		case op < 20:
+			ttl := time.Duration(0)
+			if r.Intn(10) != 0 {
+				ttl = time.Millisecond + time.Duration(r.Int63n(int64(st.cfg.maxTTL)))
+			}
+			before := time.Now()
+			err := st.s.Add(st.keys[k], ttl)
+			after := time.Now()
+			local.Adds++
+			switch {
+			case err != nil:
+				model[k] = state{unknown: true}
+			case ttl == 0:
+				model[k] = state{present: true}
+			default:
+				model[k] = state{present: true, lo: before.Add(ttl), hi: after.Add(ttl)}
+			}
+			st.check(k, model[k])
+		case op < 30:
+			st.s.Delete(st.keys[k])
+			local.Deletes++
+			model[k] = state{}
+			st.check(k, model[k])
+		case op < 80:
+			st.check(k, model[k])
+			local.Contains++
+		case op < 99:
+			st.s.Contains(st.keys[r.Intn(st.cfg.keys)])
+			local.Contains++
+		default:
+			st.scan()
+			local.Scans++
+		}
+	}
+}
+
+// check checks that the visibility of key k agrees with its expected state
+func (st *stress[T]) check(k int, s state) {
+	before := time.Now()
+	found := st.s.Contains(st.keys[k])
+	after := time.Now()
+
+	must, mustNot := s.visible(before, after, st.cfg.slack)
+	if found && mustNot {
+		st.errorf("Contains(%v) = true, want false: %s", st.keys[k], s.describe(before))
+	}
+	if !found && must && !st.cfg.evictions {
+		st.errorf("Contains(%v) = false, want true: %s", st.keys[k], s.describe(before))
+	}
+}
+
+// scan checks that ToSlice returns known keys without duplicates, and returns the set of their numbers
+func (st *stress[T]) scan() map[int]bool {
+	seen := make(map[int]bool)
+	for _, elem := range st.s.ToSlice() {
+		k, ok := st.index[elem]
+		if !ok {
+			st.errorf("ToSlice() returned %v, which was never added", elem)
+			continue
+		}
+		if seen[k] {
+			st.errorf("ToSlice() returned %v twice", elem)
+		}
+		seen[k] = true
+	}
+	return seen
+}
+
+// verify checks the membership and the length of the set once the operations stopped
+func (st *stress[T]) verify(models []map[int]state) {
+	st.t.Helper()
+	lenBefore := st.s.Len()
+	before := time.Now()
+	seen := st.scan()
+	after := time.Now()
+	lenAfter := st.s.Len()
+
+	var maybe int
+	for _, model := range models {
+		for k, s := range model {
+			must, mustNot := s.visible(before, after, st.cfg.slack)
+			if seen[k] && mustNot {
+				st.errorf("ToSlice() returned %v, want it absent: %s", st.keys[k], s.describe(before))
+			}
+			if !seen[k] && must && !st.cfg.evictions {
+				st.errorf("ToSlice() misses %v: %s", st.keys[k], s.describe(before))
+			}
+			if s.present || s.unknown {
+				maybe++
+			}
+		}
+	}
+
+	// Len may count the expired keys that were not removed yet, but never more than the added ones
+	if lenAfter < len(seen) && !st.cfg.evictions {
+		st.errorf("Len() = %d, but ToSlice() returned %d keys", lenAfter, len(seen))
+	}
+	if lenBefore > maybe {
+		st.errorf("Len() = %d, but at most %d keys were added and not deleted", lenBefore, maybe)
+	}
+}
+
+// describe returns the expected state of a key at now, for the error messages
+func (s state) describe(now time.Time) string {
+	switch {
+	case s.unknown:
+		return "its last Add failed"
+	case !s.present:
+		return "it was deleted or never added"
+	case s.hi.IsZero():
+		return "it was added without expiration"
+	case now.After(s.hi):
+		return fmt.Sprintf("it expired %v ago", now.Sub(s.hi))
+	default:
+		return fmt.Sprintf("it expires in %v", s.lo.Sub(now))
+	}
+}
@@ -0,0 +1,100 @@
+package cachetest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+func TestStress(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func() Set[int]
+		opts []Option
+	}{
+		{"Cache", func() Set[int] { return cacheset.New[int](time.Millisecond) }, nil},
+		{"Compact", func() Set[int] { return cacheset.New[int](time.Millisecond, cacheset.WithCompactStorage()) }, nil},
+		{"Sharded", func() Set[int] { return cacheset.NewSharded[int](time.Millisecond, cacheset.WithShards(4)) }, nil},
+		{"WriteBehind", func() Set[int] { return cacheset.NewWriteBehind[int](time.Millisecond) }, nil},
+		{"Capacity", func() Set[int] { return cacheset.New[int](time.Millisecond, cacheset.WithCapacity(100)) }, []Option{WithEvictions()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.set()
+			defer s.(interface{ Close() }).Close()
+
+			r := Stress(t, s, IntKey, append(tt.opts, WithDuration(200*time.Millisecond), WithSlack(50*time.Millisecond), WithSeed(1))...)
+			if r.Adds == 0 || r.Deletes == 0 || r.Contains == 0 {
+				t.Errorf("Stress() = %v, want all operations", r)
+			}
+		})
+	}
+}
+
+// recorder is a testing.TB recording the reported errors
+type recorder struct {
+	testing.TB
+	mu     sync.Mutex
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// immortal is a set ignoring the times to live
+type immortal struct {
+	mu    sync.Mutex
+	elems map[string]struct{}
+}
+
+func (s *immortal) Add(elem string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.elems[elem] = struct{}{}
+	return nil
+}
+
+func (s *immortal) Contains(elem string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.elems[elem]
+	return ok
+}
+
+func (s *immortal) Delete(elem string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.elems, elem)
+}
+
+func (s *immortal) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.elems)
+}
+
+func (s *immortal) ToSlice() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elems := make([]string, 0, len(s.elems))
+	for elem := range s.elems {
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+func TestStress_violation(t *testing.T) {
+	rec := &recorder{TB: t}
+	Stress[string](rec, &immortal{elems: make(map[string]struct{})}, StringKey, WithDuration(100*time.Millisecond), WithMaxTTL(5*time.Millisecond))
+	if len(rec.errors) == 0 {
+		t.Errorf("Stress() reported no error for a set ignoring the times to live")
+	}
+}
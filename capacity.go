@@ -0,0 +1,303 @@
+// Package cacheset
+//
+// Path: capacity.go
+//
+// Description: capacity.go contains the capacity limit of the cache and its overflow policies.
+package cacheset
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+)
+
+// OverflowPolicy is what happens when an element is added to a full cache
+type OverflowPolicy int
+
+const (
+	// OverflowEvict evicts an element chosen by the eviction policy to make room for the new one
+	OverflowEvict OverflowPolicy = iota
+	// OverflowReject rejects the new element, Add returns ErrCapacityExceeded
+	OverflowReject
+)
+
+// WithCapacity limits the number of elements in the cache, 0 meaning unlimited
+func WithCapacity(capacity int) Option {
+	return func(o *options) {
+		o.capacity = max(capacity, 0)
+	}
+}
+
+// WithEvictionPolicy sets the policy choosing the evicted elements when the cache is full, EvictLRU by default
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(o *options) {
+		o.evictionPolicy = policy
+	}
+}
+
+// WithSLRUProtected sets the fraction of the capacity reserved to the protected segment of EvictSLRU, 0.8 by default
+//
+// Description: The rest of the capacity is the probation segment, where new elements wait for a second access.
+// The fraction is clamped to [0, 1], the protected segment holds at least one element.
+func WithSLRUProtected(fraction float64) Option {
+	return func(o *options) {
+		o.slruProtected = math.Max(0, math.Min(1, fraction))
+	}
+}
+
+// WithOverflowPolicy sets what happens when an element is added to a full cache, OverflowEvict by default
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = policy
+	}
+}
+
+// WithOnFull sets a callback deciding what happens when the given element is added to a full cache
+//
+// Description: The callback overrides the overflow policy. It is called with the cache locked
+// and must not call the cache's methods. Its element type must match the cache's element type.
+func WithOnFull[T comparable](onFull func(elem T) OverflowPolicy) Option {
+	return func(o *options) {
+		o.onFull = onFull
+	}
+}
+
+// WithTinyLFU enables a TinyLFU admission filter on a cache with a capacity
+//
+// Description: The filter estimates the access frequency of elements, including the misses of Contains.
+// When the cache is full, a new element is only admitted if it is accessed more often than the element
+// the eviction policy would evict, otherwise Add returns ErrNotAdmitted. This keeps frequently used
+// elements in the cache when many elements are only seen once, as in a scan.
+func WithTinyLFU() Option {
+	return func(o *options) {
+		o.tinyLFU = true
+	}
+}
+
+// admit makes room for the given element if the cache is full, the cache must be locked
+func (c *Cache[T]) admit(elem T) error {
+	if c.closed && c.options.strict {
+		return ErrClosed
+	}
+	found := c.set.Contains(elem)
+	c.access(elem, found)
+	if found {
+		return nil
+	}
+	if c.tombstoned(elem) {
+		return ErrTombstoned
+	}
+
+	if c.policy != nil && c.set.Len() >= c.options.capacity {
+		c.expireDue()
+	}
+	if c.policy != nil && c.set.Len() >= c.options.capacity {
+		overflow := c.options.overflow
+		if c.onFull != nil {
+			overflow = c.onFull(elem)
+		}
+		if overflow == OverflowReject {
+			return ErrCapacityExceeded
+		}
+		if !c.admitted(elem) {
+			return ErrNotAdmitted
+		}
+		if !c.evict() {
+			return ErrCapacityExceeded
+		}
+	}
+	c.track(elem)
+
+	return nil
+}
+
+// shrink evicts elements until the cache respects its capacity, the cache must be locked
+func (c *Cache[T]) shrink() {
+	if c.policy == nil {
+		return
+	}
+	if c.set.Len() > c.options.capacity {
+		c.expireDue()
+	}
+	for c.set.Len() > c.options.capacity {
+		if !c.evict() {
+			return
+		}
+	}
+}
+
+// expireDue removes the expired elements of a full cache once the earliest deadline has passed, so that they
+// are neither counted against its capacity nor outlive the evicted elements, the cache must be locked
+//
+// Description: The elements are only scanned when one of them may have expired, then the earliest deadline
+// of the remaining ones is recorded, so that a full cache whose elements have not expired does not scan
+// them on each addition.
+func (c *Cache[T]) expireDue() {
+	if earliest := c.earliest.Load(); earliest == 0 || earliest > nanotime() {
+		return
+	}
+	for _, elem := range c.set.ExpireAll() {
+		c.remove(elem, RemovalExpired)
+	}
+	c.findEarliest()
+}
+
+// shed evicts up to n elements and returns the number of evicted elements
+//
+// Description: The elements are chosen by the eviction policy, or are the ones expiring first if the cache has no capacity.
+func (c *Cache[T]) shed(n int) int {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.policy != nil {
+		var shed int
+		for shed < n && c.evict() {
+			shed++
+		}
+		return shed
+	}
+
+	return c.evictExpiringFirst(n)
+}
+
+// evictExpiringFirst evicts up to n elements expiring first and returns the number of evicted elements,
+// the cache must be locked
+func (c *Cache[T]) evictExpiringFirst(n int) int {
+	victims := c.set.ExpiringFirst(n + len(c.pins))
+	victims = slices.DeleteFunc(victims, c.pinned)
+	victims = victims[:min(n, len(victims))]
+	for _, elem := range victims {
+		c.remove(elem, RemovalEvicted)
+	}
+	c.options.logger.Debug("cacheset: evicted elements expiring first", slog.Int("evicted", len(victims)))
+
+	return len(victims)
+}
+
+// evict evicts the element chosen by the eviction policy and returns false if there was none, the cache must be locked
+func (c *Cache[T]) evict() bool {
+	victim, ok := c.policy.victim()
+	if !ok {
+		return false
+	}
+
+	c.remove(victim, RemovalEvicted)
+	c.options.logger.Debug("cacheset: evicted element", slog.Any("elem", victim))
+
+	return true
+}
+
+// track records a new element in the lifetimes tracker, the insertion order, the versions, the negative lookup
+// filter and the eviction policy
+func (c *Cache[T]) track(elem T) {
+	if c.lifetimes != nil {
+		c.lifetimes.add(elem, nanotime())
+	}
+	if c.order != nil {
+		c.order.add(elem)
+	}
+	c.bump(elem)
+	if f := c.filter.Load(); f != nil {
+		f.add(elem)
+	}
+	if c.policy == nil {
+		return
+	}
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+
+	c.policy.add(elem)
+}
+
+// touch records a lookup of the given element in the lifetimes tracker if it was found, and in the admission
+// filter and the eviction policy, see access
+func (c *Cache[T]) touch(elem T, found bool) {
+	if found && c.lifetimes != nil {
+		c.lifetimes.use(elem)
+	}
+	c.access(elem, found)
+}
+
+// access records an access to the given element in the admission filter, and in the eviction policy if it
+// was found
+func (c *Cache[T]) access(elem T, found bool) {
+	if c.policy == nil {
+		return
+	}
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+
+	if c.admission != nil {
+		c.admission.record(elem)
+	}
+	if found {
+		c.policy.access(elem)
+	}
+}
+
+// admitted returns true if the admission filter lets the given element replace the eviction candidate
+func (c *Cache[T]) admitted(elem T) bool {
+	if c.admission == nil {
+		return true
+	}
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+
+	victim, ok := c.policy.victim()
+	return !ok || c.admission.admit(elem, victim)
+}
+
+// forget removes the given element from the trackers of the cache: the quotas, the versions, the contexts,
+// the pins, the tags, the insertion order, the negative lookup filter and the eviction policy, which is told
+// whether the element was evicted
+func (c *Cache[T]) forget(elem T, reason RemovalReason) {
+	delete(c.quotas, elem)
+	delete(c.versions, elem)
+	c.unbind(elem)
+	c.unpin(elem)
+	if c.tags != nil {
+		c.tags.remove(elem)
+	}
+	if c.order != nil {
+		c.order.remove(elem)
+	}
+	if f := c.filter.Load(); f != nil {
+		f.remove(elem)
+	}
+	if c.policy == nil {
+		return
+	}
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+
+	if reason == RemovalEvicted {
+		c.policy.evict(elem)
+		return
+	}
+	c.policy.remove(elem)
+}
+
+// forgetAll removes all elements from the trackers of the cache, see forget, and from the lifetimes tracker
+func (c *Cache[T]) forgetAll() {
+	c.quotas = nil
+	c.versions = nil
+	c.unbindAll()
+	c.unpinAll()
+	c.tags = nil
+	if c.order != nil {
+		c.order.clear()
+	}
+	if c.lifetimes != nil {
+		c.lifetimes.clear()
+	}
+	if f := c.filter.Load(); f != nil {
+		f.clear()
+	}
+	if c.policy == nil {
+		return
+	}
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+
+	c.policy.clear()
+}
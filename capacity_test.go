@@ -0,0 +1,140 @@
+package cacheset
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithCapacity(t *testing.T) {
+	c := New[int64](time.Minute, WithCapacity(2))
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, 0)
+	c.Contains(1)
+
+	evicted := c.Watch(2)
+	if err := c.Add(3, 0); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	t.Run("Evict", func(t *testing.T) {
+		if c.Len() != 2 || !c.Contains(1) || c.Contains(2) || !c.Contains(3) {
+			t.Errorf("Add() = %v, want the least recently used element evicted", c.ToSlice())
+		}
+		if ev := <-evicted; ev.Reason != RemovalEvicted {
+			t.Errorf("Watch() = %v, want %v", ev.Reason, RemovalEvicted)
+		}
+		if got := c.Stats().Evictions; got != 1 {
+			t.Errorf("Stats() evictions = %v, want %v", got, 1)
+		}
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		if err := c.Add(1, time.Minute); err != nil || c.Len() != 2 || !c.Contains(3) {
+			t.Errorf("Add() = %v, want no eviction when re-adding an element", c.ToSlice())
+		}
+	})
+}
+
+func TestWithOverflowPolicy(t *testing.T) {
+	c := New[int64](time.Minute, WithCapacity(1), WithOverflowPolicy(OverflowReject))
+	defer c.Close()
+	c.Add(1, 0)
+
+	t.Run("Reject", func(t *testing.T) {
+		if err := c.Add(2, 0); !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Add() error = %v, want %v", err, ErrCapacityExceeded)
+		}
+		if !c.Contains(1) || c.Contains(2) {
+			t.Errorf("Add() = %v, want %v", c.ToSlice(), []int64{1})
+		}
+	})
+}
+
+func TestWithCapacity_Expired(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"Reject": {WithCapacity(2), WithOverflowPolicy(OverflowReject)},
+		"Evict":  {WithCapacity(2)},
+		"Table":  {WithCapacity(2), WithCompactStorage()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := New[int64](time.Minute, opts...)
+			defer c.Close()
+			_ = c.Add(1, 0)
+			_ = c.Add(2, time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+
+			if err := c.Add(3, 0); err != nil {
+				t.Fatalf("Add() error = %v, want nil with an expired element", err)
+			}
+			if !c.Contains(1) || !c.Contains(3) || c.Len() != 2 {
+				t.Errorf("Add() = %v, want the expired element removed instead of %v", c.ToSlice(), 1)
+			}
+			if st := c.Stats(); st.Expirations != 1 || st.Evictions != 0 {
+				t.Errorf("Stats() = %v expirations, %v evictions, want 1, 0", st.Expirations, st.Evictions)
+			}
+		})
+	}
+}
+
+func TestWithOnFull(t *testing.T) {
+	c := New[int64](time.Minute, WithCapacity(1), WithOnFull(func(elem int64) OverflowPolicy {
+		if elem%2 == 0 {
+			return OverflowEvict
+		}
+		return OverflowReject
+	}))
+	defer c.Close()
+	c.Add(1, 0)
+
+	t.Run("Reject", func(t *testing.T) {
+		if err := c.Add(3, 0); !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Add() error = %v, want %v", err, ErrCapacityExceeded)
+		}
+	})
+
+	t.Run("Evict", func(t *testing.T) {
+		if err := c.Add(2, 0); err != nil || c.Contains(1) || !c.Contains(2) {
+			t.Errorf("Add() = %v, want %v", c.ToSlice(), []int64{2})
+		}
+	})
+}
+
+func TestWithTinyLFU(t *testing.T) {
+	c := New[int64](time.Minute, WithCapacity(10), WithTinyLFU())
+	defer c.Close()
+	for i := int64(0); i < 10; i++ {
+		c.Add(i, 0)
+		for j := 0; j < 5; j++ {
+			c.Contains(i)
+		}
+	}
+
+	var rejected int
+	for i := int64(100); i < 200; i++ {
+		if err := c.Add(i, 0); errors.Is(err, ErrNotAdmitted) {
+			rejected++
+		}
+	}
+
+	t.Run("Scan", func(t *testing.T) {
+		if rejected != 100 {
+			t.Errorf("Add() rejected = %v, want %v", rejected, 100)
+		}
+		for i := int64(0); i < 10; i++ {
+			if !c.Contains(i) {
+				t.Errorf("Contains(%v) = false, want the hot element kept", i)
+			}
+		}
+	})
+
+	t.Run("Frequent", func(t *testing.T) {
+		for j := 0; j < 10; j++ {
+			c.Contains(1000)
+		}
+		if err := c.Add(1000, 0); err != nil || !c.Contains(1000) {
+			t.Errorf("Add() error = %v, want the frequent element admitted", err)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+// Package cacheset
+//
+// Path: clock.go
+//
+// Description: clock.go contains the monotonic clock used for the expiration times.
+//
+// The expiration times are stored as monotonic readings, so that wall-clock jumps (NTP steps,
+// VM migrations, manual changes) neither expire elements early nor keep them alive too long.
+// They are converted to wall-clock times only at the API and persistence boundary.
+package cacheset
+
+import "time"
+
+// epoch is the origin of the monotonic readings returned by nanotime
+var epoch = time.Now()
+
+// nanotime returns the number of nanoseconds elapsed since epoch, measured with the monotonic clock
+func nanotime() int64 {
+	return int64(time.Since(epoch))
+}
+
+// toWall converts a monotonic expiration time to a wall-clock time in nanoseconds since the Unix epoch,
+// 0 meaning no expiration
+func toWall(expires int64) int64 {
+	if expires == 0 {
+		return 0
+	}
+	now := time.Now()
+	return now.UnixNano() + expires - int64(now.Sub(epoch))
+}
+
+// toTime converts a monotonic expiration time to a time.Time, the zero time.Time meaning no expiration
+func toTime(expires int64) time.Time {
+	if expires == 0 {
+		return time.Time{}
+	}
+	now := time.Now()
+	return now.Add(time.Duration(expires - int64(now.Sub(epoch))))
+}
+
+// fromTime converts a time.Time to a monotonic expiration time, the zero time.Time meaning no expiration
+func fromTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	now := time.Now()
+	return max(1, int64(now.Sub(epoch))+int64(t.Sub(now)))
+}
@@ -0,0 +1,22 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_toTime(t *testing.T) {
+	want := time.Now().Add(time.Hour)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		if got := toTime(fromTime(want)); got.Sub(want).Abs() > time.Millisecond {
+			t.Errorf("toTime(fromTime()) = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Zero", func(t *testing.T) {
+		if got := toTime(fromTime(time.Time{})); !got.IsZero() {
+			t.Errorf("toTime(fromTime()) = %v, want %v", got, time.Time{})
+		}
+	})
+}
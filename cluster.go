@@ -0,0 +1,262 @@
+// Package cacheset
+//
+// Path: cluster.go
+//
+// Description: cluster.go contains a client partitioning the elements across several cache nodes
+// with consistent hashing.
+package cacheset
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoNodes is returned by the methods of a Cluster without nodes
+var ErrNoNodes = errors.New("cacheset: cluster has no nodes")
+
+// ClusterNode is a cache node of a Cluster, usually a client of a remote cache such as the one returned
+// by cachesetgrpc.NewNode
+type ClusterNode[T comparable] interface {
+	// Add adds the given element to the node for the given duration, 0 meaning no expiration
+	Add(ctx context.Context, elem T, ttl time.Duration) error
+	// Contains returns true if the given element is in the node
+	Contains(ctx context.Context, elem T) (bool, error)
+	// Delete removes the given element from the node
+	Delete(ctx context.Context, elem T) error
+}
+
+// LocalNode returns a ClusterNode backed by the given in-process cache
+func LocalNode[T comparable](c *Cache[T]) ClusterNode[T] {
+	return localNode[T]{c: c}
+}
+
+// localNode is a ClusterNode backed by an in-process cache
+type localNode[T comparable] struct {
+	c *Cache[T]
+}
+
+// Add adds the given element to the cache
+func (n localNode[T]) Add(_ context.Context, elem T, ttl time.Duration) error {
+	return n.c.Add(elem, ttl)
+}
+
+// Contains returns true if the given element is in the cache
+func (n localNode[T]) Contains(_ context.Context, elem T) (bool, error) {
+	return n.c.Contains(elem), nil
+}
+
+// Delete removes the given element from the cache
+func (n localNode[T]) Delete(_ context.Context, elem T) error {
+	n.c.Delete(elem)
+	return nil
+}
+
+// ClusterOption configures a Cluster
+type ClusterOption func(*clusterConfig)
+
+// clusterConfig is the configuration of a Cluster
+type clusterConfig struct {
+	virtualNodes int // virtualNodes is the number of points of each node on the ring
+	hasher       any // hasher is the func(T) uint64 placing an element on the ring
+}
+
+// WithVirtualNodes sets the number of points of each node on the ring, 128 by default
+//
+// Description: More points spread the elements more evenly across the nodes, at the cost of a larger ring.
+func WithVirtualNodes(n int) ClusterOption {
+	return func(c *clusterConfig) {
+		c.virtualNodes = max(n, 1)
+	}
+}
+
+// WithClusterHasher sets the function placing an element on the ring, which must return the same
+// value in every process routing to the cluster
+//
+// Description: By default, elements are hashed with FNV-1a over their fmt.Sprint representation, which
+// suits the strings and the integers only: it allocates on every call, and the distinct elements printed
+// the same way, such as two pointers to equal structs, land on the same node. Other element types should
+// set a hasher. The hasher's element type must match the cluster's element type.
+func WithClusterHasher[T comparable](hasher func(elem T) uint64) ClusterOption {
+	return func(c *clusterConfig) {
+		c.hasher = hasher
+	}
+}
+
+// point is a virtual node on the ring
+type point struct {
+	hash uint64 // hash is the position of the point on the ring
+	node string // node is the name of the node owning the point
+}
+
+// Cluster routes the elements to a set of cache nodes with consistent hashing
+//
+// Description: Each node owns several points (virtual nodes) on a hash ring and an element belongs
+// to the node owning the first point after its hash. Adding or removing a node only moves the
+// elements between its points and their predecessors, about 1/n of the elements for n nodes.
+type Cluster[T comparable] struct {
+	mu           sync.RWMutex
+	nodes        map[string]ClusterNode[T] // nodes are the nodes by name
+	ring         []point                   // ring are the points of the nodes sorted by hash
+	virtualNodes int                       // virtualNodes is the number of points of each node
+	hasher       func(T) uint64            // hasher places an element on the ring
+}
+
+// NewCluster returns a cluster without nodes
+func NewCluster[T comparable](opts ...ClusterOption) *Cluster[T] {
+	cfg := clusterConfig{virtualNodes: 128}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c := &Cluster[T]{
+		nodes:        make(map[string]ClusterNode[T]),
+		virtualNodes: cfg.virtualNodes,
+		hasher:       hashSprint[T],
+	}
+	if cfg.hasher != nil {
+		hasher, ok := cfg.hasher.(func(T) uint64)
+		if !ok {
+			panic(fmt.Sprintf("cacheset: WithClusterHasher element type %T does not match the cluster's", cfg.hasher))
+		}
+		c.hasher = hasher
+	}
+	return c
+}
+
+// AddNode adds or replaces the node with the given name, which must be the same in every process routing to the cluster
+func (c *Cluster[T]) AddNode(name string, node ClusterNode[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.nodes[name]; !ok {
+		for i := range c.virtualNodes {
+			c.ring = append(c.ring, point{hash: hashString(name + "#" + strconv.Itoa(i)), node: name})
+		}
+		slices.SortFunc(c.ring, comparePoints)
+	}
+	c.nodes[name] = node
+}
+
+// RemoveNode removes the node with the given name, its elements are routed to the next nodes on the ring
+func (c *Cluster[T]) RemoveNode(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.nodes[name]; !ok {
+		return
+	}
+	delete(c.nodes, name)
+	c.ring = slices.DeleteFunc(c.ring, func(p point) bool { return p.node == name })
+}
+
+// Nodes returns the sorted names of the nodes
+func (c *Cluster[T]) Nodes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.nodes))
+	for name := range c.nodes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Locate returns the name of the node owning the given element and false if the cluster has no nodes
+func (c *Cluster[T]) Locate(elem T) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	name, _, err := c.locate(elem)
+	return name, err == nil
+}
+
+// Add adds the given element to the node owning it
+func (c *Cluster[T]) Add(ctx context.Context, elem T, ttl time.Duration) error {
+	node, err := c.node(elem)
+	if err != nil {
+		return err
+	}
+	return node.Add(ctx, elem, ttl)
+}
+
+// Contains returns true if the given element is in the node owning it
+func (c *Cluster[T]) Contains(ctx context.Context, elem T) (bool, error) {
+	node, err := c.node(elem)
+	if err != nil {
+		return false, err
+	}
+	return node.Contains(ctx, elem)
+}
+
+// Delete removes the given element from the node owning it
+func (c *Cluster[T]) Delete(ctx context.Context, elem T) error {
+	node, err := c.node(elem)
+	if err != nil {
+		return err
+	}
+	return node.Delete(ctx, elem)
+}
+
+// node returns the node owning the given element, the call to the node is made without the lock
+func (c *Cluster[T]) node(elem T) (ClusterNode[T], error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, node, err := c.locate(elem)
+	return node, err
+}
+
+// locate returns the node owning the given element, the cluster must be locked
+func (c *Cluster[T]) locate(elem T) (string, ClusterNode[T], error) {
+	if len(c.ring) == 0 {
+		return "", nil, ErrNoNodes
+	}
+	hash := c.hasher(elem)
+	i, _ := slices.BinarySearchFunc(c.ring, hash, func(p point, hash uint64) int {
+		return cmp.Compare(p.hash, hash)
+	})
+	if i == len(c.ring) {
+		i = 0
+	}
+	name := c.ring[i].node
+	return name, c.nodes[name], nil
+}
+
+// comparePoints orders the points by hash, then by node name so that collisions are ordered the same everywhere
+func comparePoints(a, b point) int {
+	return cmp.Or(cmp.Compare(a.hash, b.hash), strings.Compare(a.node, b.node))
+}
+
+// hashString hashes the given string with FNV-1a
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return fmix64(h.Sum64())
+}
+
+// hashSprint hashes the fmt.Sprint representation of the given element with FNV-1a, see WithClusterHasher
+// for its limits
+func hashSprint[T comparable](elem T) uint64 {
+	h := fnv.New64a()
+	fmt.Fprint(h, elem)
+	return fmix64(h.Sum64())
+}
+
+// fmix64 is the finalizer of MurmurHash3, spreading the FNV-1a hashes of similar strings over the ring
+func fmix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
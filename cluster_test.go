@@ -0,0 +1,92 @@
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCluster(t *testing.T) {
+	ctx := context.Background()
+	caches := map[string]*Cache[string]{}
+	cluster := NewCluster[string]()
+	for _, name := range []string{"a", "b", "c"} {
+		caches[name] = New[string](time.Minute)
+		defer caches[name].Close()
+		cluster.AddNode(name, LocalNode(caches[name]))
+	}
+
+	elems := make([]string, 1000)
+	owners := make(map[string]string, len(elems))
+	for i := range elems {
+		elems[i] = fmt.Sprintf("elem-%d", i)
+		if err := cluster.Add(ctx, elems[i], 0); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		owners[elems[i]], _ = cluster.Locate(elems[i])
+	}
+
+	t.Run("routing", func(t *testing.T) {
+		for name, c := range caches {
+			if n := c.Len(); n < 200 || n > 500 {
+				t.Errorf("node %s Len() = %v, want about %v", name, n, len(elems)/3)
+			}
+		}
+		for _, elem := range elems {
+			if !caches[owners[elem]].Contains(elem) {
+				t.Fatalf("node %s does not contain %s", owners[elem], elem)
+			}
+			if ok, err := cluster.Contains(ctx, elem); !ok || err != nil {
+				t.Fatalf("Contains(%s) = %v, %v, want true, nil", elem, ok, err)
+			}
+		}
+	})
+
+	t.Run("minimal movement", func(t *testing.T) {
+		d := New[string](time.Minute)
+		defer d.Close()
+		cluster.AddNode("d", LocalNode(d))
+		for _, elem := range elems {
+			owner, _ := cluster.Locate(elem)
+			if owner != owners[elem] && owner != "d" {
+				t.Fatalf("Locate(%s) = %s, want %s or d", elem, owner, owners[elem])
+			}
+		}
+		cluster.RemoveNode("d")
+		for _, elem := range elems {
+			if owner, _ := cluster.Locate(elem); owner != owners[elem] {
+				t.Fatalf("Locate(%s) = %s after RemoveNode, want %s", elem, owner, owners[elem])
+			}
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := cluster.Delete(ctx, elems[0]); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if ok, _ := cluster.Contains(ctx, elems[0]); ok {
+			t.Errorf("Contains() = true after Delete")
+		}
+	})
+}
+
+func TestCluster_NoNodes(t *testing.T) {
+	cluster := NewCluster[int]()
+	if err := cluster.Add(context.Background(), 1, 0); !errors.Is(err, ErrNoNodes) {
+		t.Errorf("Add() error = %v, want %v", err, ErrNoNodes)
+	}
+	if _, ok := cluster.Locate(1); ok {
+		t.Errorf("Locate() = true, want false")
+	}
+}
+
+func TestWithClusterHasher(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewCluster() did not panic on a mismatched hasher")
+		}
+	}()
+	NewCluster[int](WithClusterHasher(func(string) uint64 { return 0 }))
+}
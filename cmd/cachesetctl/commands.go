@@ -0,0 +1,296 @@
+// Command cachesetctl
+//
+// Path: cmd/cachesetctl/commands.go
+//
+// Description: commands.go contains the commands and the formats of the snapshot files.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+	"github.com/corentings/go-set/cachesetpb"
+)
+
+// command runs the given command on snapshots of elements of type T
+func command[T comparable](cmd string, args []string, key []byte, out io.Writer) error {
+	t := tool[T]{key: key, out: out}
+	switch cmd {
+	case "stats":
+		return t.stats(args)
+	case "list":
+		return t.list(args)
+	case "compact":
+		return t.compact(args)
+	case "convert":
+		return t.convert(args)
+	case "merge":
+		return t.merge(args)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+}
+
+// tool runs the commands on snapshots of elements of type T
+type tool[T comparable] struct {
+	key []byte    // key is the AES key of encrypted snapshots, nil if they are not encrypted
+	out io.Writer // out receives the output of the commands
+}
+
+// stats prints the number of elements of a snapshot and their expirations
+func (t tool[T]) stats(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	c, err := t.load(args[0], "binary")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	now := time.Now()
+	var permanent, hour, day int
+	var first, last time.Time
+	for _, expires := range c.CopySet() {
+		if expires == 0 {
+			permanent++
+			continue
+		}
+		at := time.Unix(0, expires)
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+		if at.Sub(now) <= time.Hour {
+			hour++
+		}
+		if at.Sub(now) <= 24*time.Hour {
+			day++
+		}
+	}
+
+	fmt.Fprintf(t.out, "elements:            %d\n", c.Len())
+	fmt.Fprintf(t.out, "permanent:           %d\n", permanent)
+	fmt.Fprintf(t.out, "expiring within 1h:  %d\n", hour)
+	fmt.Fprintf(t.out, "expiring within 24h: %d\n", day)
+	if !first.IsZero() {
+		fmt.Fprintf(t.out, "first expiration:    %s\n", first.Format(time.RFC3339))
+		fmt.Fprintf(t.out, "last expiration:     %s\n", last.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// list prints the elements of a snapshot matching the optional regular expression, sorted
+func (t tool[T]) list(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	grep := flags.String("grep", "", "regular expression the printed elements match")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+	re, err := regexp.Compile(*grep)
+	if err != nil {
+		return err
+	}
+	c, err := t.load(flags.Arg(0), "binary")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var lines []string
+	for _, elem := range c.ToSlice() {
+		if line := fmt.Sprint(elem); re.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	slices.Sort(lines)
+	for _, line := range lines {
+		fmt.Fprintln(t.out, line)
+	}
+	return nil
+}
+
+// compact rewrites a snapshot without its expired elements
+func (t tool[T]) compact(args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errUsage
+	}
+	c, err := t.load(args[0], "binary")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return t.save(c, args[len(args)-1], "binary")
+}
+
+// convert converts a snapshot between the binary, json and proto formats
+func (t tool[T]) convert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	from := flags.String("from", "binary", "format of the input: binary, json or proto")
+	to := flags.String("to", "json", "format of the output: binary, json or proto")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+	c, err := t.load(flags.Arg(0), *from)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return t.save(c, flags.Arg(1), *to)
+}
+
+// merge merges two snapshots, keeping the later expiration of the common elements
+func (t tool[T]) merge(args []string) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+	a, err := t.load(args[0], "binary")
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	b, err := t.load(args[1], "binary")
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	a.Merge(b, func(x, y time.Time) time.Time {
+		if x.IsZero() || y.IsZero() {
+			return time.Time{}
+		}
+		if x.After(y) {
+			return x
+		}
+		return y
+	})
+	return t.save(a, args[2], "binary")
+}
+
+// options returns the options of the caches holding the snapshots
+func (t tool[T]) options() []cacheset.Option {
+	if t.key == nil {
+		return nil
+	}
+	return []cacheset.Option{cacheset.WithSnapshotEncryption(anyKey(t.key))}
+}
+
+// load reads the snapshot file at path in the given format
+func (t tool[T]) load(path, format string) (*cacheset.Cache[T], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := cacheset.New[T](time.Hour, t.options()...)
+	switch format {
+	case "binary":
+		err = c.Restore(bytes.NewReader(data))
+	case "json":
+		err = loadJSON(data, c)
+	case "proto":
+		err = cachesetpb.Unmarshal(data, c)
+	default:
+		err = fmt.Errorf("%w: unknown format %q", errUsage, format)
+	}
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// save writes the elements of the cache to the file at path in the given format
+func (t tool[T]) save(c *cacheset.Cache[T], path, format string) error {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "binary":
+		err = c.Snapshot(&buf, cacheset.SnapshotAbsolute)
+	case "json":
+		err = saveJSON(c, &buf)
+	case "proto":
+		var data []byte
+		data, err = cachesetpb.Marshal(c)
+		buf.Write(data)
+	default:
+		err = fmt.Errorf("%w: unknown format %q", errUsage, format)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// jsonElement is an element of a snapshot in the json format
+type jsonElement[T comparable] struct {
+	Elem      T          `json:"elem"`                 // Elem is the element
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // ExpiresAt is the expiration time, absent if the element does not expire
+}
+
+// saveJSON writes the elements of the cache as a json array sorted by element
+func saveJSON[T comparable](c *cacheset.Cache[T], w io.Writer) error {
+	elems := make([]jsonElement[T], 0, c.Len())
+	for elem, expires := range c.CopySet() {
+		e := jsonElement[T]{Elem: elem}
+		if expires != 0 {
+			at := time.Unix(0, expires).UTC()
+			e.ExpiresAt = &at
+		}
+		elems = append(elems, e)
+	}
+	slices.SortFunc(elems, func(a, b jsonElement[T]) int {
+		return strings.Compare(fmt.Sprint(a.Elem), fmt.Sprint(b.Elem))
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(elems)
+}
+
+// loadJSON adds the unexpired elements of a json array to the cache
+func loadJSON[T comparable](data []byte, c *cacheset.Cache[T]) error {
+	var elems []jsonElement[T]
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return err
+	}
+	for _, e := range elems {
+		var ttl time.Duration
+		if e.ExpiresAt != nil {
+			if ttl = time.Until(*e.ExpiresAt); ttl <= 0 {
+				continue
+			}
+		}
+		if err := c.Add(e.Elem, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anyKey is a KeyProvider decrypting the snapshots with the given key, whatever its ID
+type anyKey []byte
+
+// CurrentKey returns the key with the ID "cachesetctl"
+func (k anyKey) CurrentKey() (string, []byte, error) {
+	return "cachesetctl", k, nil
+}
+
+// Key returns the key
+func (k anyKey) Key(string) ([]byte, error) {
+	return k, nil
+}
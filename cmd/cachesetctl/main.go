@@ -0,0 +1,85 @@
+// Command cachesetctl inspects and manipulates the snapshot files written by cacheset caches.
+//
+// Path: cmd/cachesetctl/main.go
+//
+// Description: main.go contains the parsing of the command line. The snapshots are loaded into a
+// cache of the element type given by -type, so the expired elements are dropped on load.
+//
+// Usage:
+//
+//	cachesetctl [-type string|int|int64|uint64] [-key-file path] <command> [arguments]
+//
+// Commands:
+//
+//	stats FILE                      print the number of elements and their expirations
+//	list [-grep REGEXP] FILE        print the elements, one per line
+//	compact FILE [OUT]              drop the expired elements, in place unless OUT is given
+//	convert [-from F] [-to F] IN OUT convert between the binary, json and proto formats
+//	merge A B OUT                   merge two snapshots, keeping the later expiration of the common elements
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// usage is printed on invalid command lines
+const usage = `usage: cachesetctl [-type string|int|int64|uint64] [-key-file path] <command> [arguments]
+
+commands:
+  stats FILE                        print the number of elements and their expirations
+  list [-grep REGEXP] FILE          print the elements, one per line
+  compact FILE [OUT]                drop the expired elements, in place unless OUT is given
+  convert [-from F] [-to F] IN OUT  convert between the binary, json and proto formats
+  merge A B OUT                     merge two snapshots, keeping the later expiration of the common elements
+`
+
+// errUsage is returned on invalid command lines
+var errUsage = errors.New("invalid command line")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "cachesetctl:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the command line and runs the command, writing its output to out
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("cachesetctl", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	elemType := flags.String("type", "string", "element type of the snapshots")
+	keyFile := flags.String("key-file", "", "file holding the AES key of encrypted snapshots")
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return errUsage
+	}
+
+	var key []byte
+	if *keyFile != "" {
+		var err error
+		if key, err = os.ReadFile(*keyFile); err != nil {
+			return err
+		}
+	}
+
+	cmd, cmdArgs := flags.Arg(0), flags.Args()[1:]
+	switch *elemType {
+	case "string":
+		return command[string](cmd, cmdArgs, key, out)
+	case "int":
+		return command[int](cmd, cmdArgs, key, out)
+	case "int64":
+		return command[int64](cmd, cmdArgs, key, out)
+	case "uint64":
+		return command[uint64](cmd, cmdArgs, key, out)
+	default:
+		return fmt.Errorf("%w: unknown type %q", errUsage, *elemType)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	cacheset "github.com/corentings/go-set"
+)
+
+// writeSnapshot writes a snapshot of the given elements to a file of dir and returns its path
+func writeSnapshot(t *testing.T, dir, name string, ttl time.Duration, elems ...string) string {
+	t.Helper()
+	c := cacheset.New[string](time.Hour)
+	defer c.Close()
+	for _, elem := range elems {
+		if err := c.Add(elem, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf, cacheset.SnapshotAbsolute); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readSnapshot returns the sorted elements of the snapshot file at path
+func readSnapshot(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cacheset.New[string](time.Hour)
+	defer c.Close()
+	if err := c.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	elems := c.ToSlice()
+	slices.Sort(elems)
+	return elems
+}
+
+func Test_run(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSnapshot(t, dir, "a", 0, "apple", "banana", "cherry")
+	b := writeSnapshot(t, dir, "b", time.Minute, "banana", "date")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"stats", []string{"stats", b}, "elements:            2\npermanent:           0\nexpiring within 1h:  2\n"},
+		{"list", []string{"list", a}, "apple\nbanana\ncherry\n"},
+		{"grep", []string{"list", "-grep", "an", a}, "banana\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(tt.args, &out); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if got := out.String(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("run() = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_run_merge(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSnapshot(t, dir, "a", 0, "apple", "banana")
+	b := writeSnapshot(t, dir, "b", time.Minute, "banana", "date")
+	out := filepath.Join(dir, "out")
+
+	if err := run([]string{"merge", a, b, out}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if got, want := readSnapshot(t, out), []string{"apple", "banana", "date"}; !slices.Equal(got, want) {
+		t.Errorf("merge = %v, want %v", got, want)
+	}
+}
+
+func Test_run_compact(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSnapshot(t, dir, "a", 10*time.Millisecond, "apple")
+	time.Sleep(20 * time.Millisecond)
+
+	if err := run([]string{"compact", path}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if got := readSnapshot(t, path); len(got) != 0 {
+		t.Errorf("compact = %v, want no elements", got)
+	}
+}
+
+func Test_run_convert(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSnapshot(t, dir, "a", time.Hour, "apple", "banana")
+	for _, format := range []string{"json", "proto"} {
+		t.Run(format, func(t *testing.T) {
+			converted := filepath.Join(dir, "a."+format)
+			back := filepath.Join(dir, "a."+format+".bin")
+			if err := run([]string{"convert", "-to", format, path, converted}, &bytes.Buffer{}); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if err := run([]string{"convert", "-from", format, "-to", "binary", converted, back}, &bytes.Buffer{}); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if got, want := readSnapshot(t, back), []string{"apple", "banana"}; !slices.Equal(got, want) {
+				t.Errorf("convert = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_run_usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"unknown"}, {"-type", "float", "stats", "x"}, {"merge", "a"}} {
+		if err := run(args, &bytes.Buffer{}); !errors.Is(err, errUsage) {
+			t.Errorf("run(%q) error = %v, want %v", args, err, errUsage)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package cacheset
+//
+// Path: coalesce.go
+//
+// Description: coalesce.go contains the coalesced cleaning, which lets the cleaning goroutine of a mostly
+// idle cache sleep until an element can expire instead of waking up every clean interval.
+package cacheset
+
+import "time"
+
+// WithCoalescedCleaning skips the cleanings while no element can expire
+//
+// Description: By default, the cleaning goroutine wakes up every clean interval, even if the cache is empty
+// or its elements expire in hours. With coalesced cleaning, it sleeps until the earliest deadline of the
+// elements and cleans at most once per clean interval, so that the elements expiring within an interval
+// are removed by a single cleaning. Adding an element expiring before the next cleaning wakes it up.
+// Finding the earliest deadline after a cleaning visits all the elements, so coalescing suits the caches
+// that are mostly idle. It is ignored with WithMemoryPressure, which reads the heap at every interval,
+// and by Sharded caches, whose shards are cleaned together.
+// HealthStatus.WakeupsPerHour measures the effect.
+func WithCoalescedCleaning() Option {
+	return func(o *options) {
+		o.coalesce = true
+	}
+}
+
+// coalescing returns true if the cleanings are coalesced
+func (o options) coalescing() bool {
+	return o.coalesce && o.memoryThreshold == 0 && !o.external
+}
+
+// due records the deadline of the given element and wakes the coalesced cleaning goroutine if the element
+// expires before its next cleaning, the cache must be locked
+//
+// Description: A cache with a capacity records the deadlines too, see expireDue.
+func (c *Cache[T]) due(elem T) {
+	if c.rearm == nil && c.policy == nil {
+		return
+	}
+	e, ok := c.set.Get(elem)
+	if !ok {
+		return
+	}
+	deadline := e.deadline()
+	if deadline == 0 {
+		return
+	}
+	if earliest := c.earliest.Load(); earliest != 0 && earliest <= deadline {
+		return
+	}
+	c.earliest.Store(deadline)
+	if c.rearm == nil {
+		return
+	}
+	if next := c.nextClean.Load(); next == 0 || deadline < next {
+		select {
+		case c.rearm <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// findEarliest records the earliest deadline of the elements, the cache must be locked for reading
+func (c *Cache[T]) findEarliest() {
+	var earliest int64
+	for _, e := range c.set.All() {
+		if deadline := e.deadline(); deadline != 0 && (earliest == 0 || deadline < earliest) {
+			earliest = deadline
+		}
+	}
+	c.earliest.Store(earliest)
+}
+
+// coalesce runs the cleaning goroutine of a cache with WithCoalescedCleaning until the cache is closed
+func (c *Cache[T]) coalesce(snapshots <-chan time.Time) {
+	timer := time.NewTimer(time.Hour) // timer fires at the next cleaning, stopped while none is scheduled
+	timer.Stop()
+	defer timer.Stop()
+	defer c.nextClean.Store(0)
+
+	last := nanotime() // last is the time of the last cleaning, or of the start
+	schedule := func() {
+		c.RLock()
+		interval := c.cleanInterval
+		earliest := c.earliest.Load()
+		c.RUnlock()
+
+		if earliest == 0 {
+			timer.Stop()
+			c.nextClean.Store(0)
+			return
+		}
+		next := max(earliest, last+int64(interval))
+		c.nextClean.Store(next)
+		timer.Reset(time.Duration(next - nanotime()))
+	}
+
+	c.RLock()
+	c.findEarliest()
+	c.RUnlock()
+	schedule()
+
+	for {
+		select {
+		case <-c.close:
+			return
+		case <-timer.C:
+			c.health.wakeup()
+			c.clean()
+			last = nanotime()
+			c.RLock()
+			c.findEarliest()
+			c.RUnlock()
+			schedule()
+		case <-c.rearm:
+			schedule()
+		case <-snapshots:
+			c.checkpoint()
+		}
+	}
+}
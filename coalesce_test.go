@@ -0,0 +1,73 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithCoalescedCleaning(t *testing.T) {
+	t.Run("Idle", func(t *testing.T) {
+		ticking := New[int64](time.Millisecond)
+		defer ticking.Close()
+		coalesced := New[int64](time.Millisecond, WithCoalescedCleaning())
+		defer coalesced.Close()
+		coalesced.Add(1, 0)
+		time.Sleep(30 * time.Millisecond)
+
+		if h := ticking.Health(); h.Wakeups == 0 || h.WakeupsPerHour() == 0 {
+			t.Errorf("Health() = %+v, want wakeups without coalescing", h)
+		}
+		if h := coalesced.Health(); h.Wakeups != 0 || h.WakeupsPerHour() != 0 || !h.NextSweep.IsZero() || !h.Healthy() {
+			t.Errorf("Health() = %+v, want a healthy cleaning goroutine without wakeups", h)
+		}
+	})
+
+	t.Run("Expiring", func(t *testing.T) {
+		c := New[int64](5*time.Millisecond, WithCoalescedCleaning())
+		defer c.Close()
+		for i := int64(0); i < 10; i++ {
+			c.Add(i, time.Duration(10+i)*time.Millisecond/10)
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		h := c.Health()
+		if c.Len() != 0 {
+			t.Errorf("Len() = %v, want %v", c.Len(), 0)
+		}
+		if h.Wakeups == 0 || h.Wakeups > 3 || !h.NextSweep.IsZero() || !h.Healthy() {
+			t.Errorf("Health() = %+v, want the expirations coalesced in one or two wakeups", h)
+		}
+	})
+
+	t.Run("FarFuture", func(t *testing.T) {
+		c := New[int64](time.Millisecond, WithCoalescedCleaning())
+		defer c.Close()
+		c.Add(1, time.Hour)
+		c.Add(2, 2*time.Hour)
+		time.Sleep(10 * time.Millisecond)
+
+		h := c.Health()
+		if h.Wakeups != 0 || !h.Healthy() {
+			t.Errorf("Health() = %+v, want no wakeups", h)
+		}
+		if until := time.Until(h.NextSweep); until < 59*time.Minute || until > time.Hour {
+			t.Errorf("Health().NextSweep in %v, want in about %v", until, time.Hour)
+		}
+
+		c.Add(3, time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		if h := c.Health(); h.Wakeups != 1 || c.Contains(3) || c.Len() != 2 {
+			t.Errorf("Health() = %+v, Len() = %v, want the earlier element to wake the cleaning goroutine", h, c.Len())
+		}
+	})
+
+	t.Run("MemoryPressure", func(t *testing.T) {
+		c := New[int64](time.Millisecond, WithCoalescedCleaning(), WithMemoryPressure(0.9, 0.1))
+		defer c.Close()
+		time.Sleep(10 * time.Millisecond)
+
+		if h := c.Health(); h.Coalesced || h.Wakeups == 0 {
+			t.Errorf("Health() = %+v, want the cleanings not coalesced", h)
+		}
+	})
+}
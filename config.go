@@ -0,0 +1,214 @@
+// Package cacheset
+//
+// Path: config.go
+//
+// Description: config.go contains the Config type building a cache from a configuration file.
+package cacheset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration written as a string such as "5m" in configuration files
+type Duration time.Duration
+
+// MarshalText returns the duration formatted by time.Duration.String
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses the duration with time.ParseDuration
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("cacheset: invalid duration: %w", err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Config is the configuration of a cache, read from a JSON file, or a YAML file with cachesetyaml
+//
+// Description: The zero values keep the defaults of New: no expiration by default, no capacity,
+// no persistence, and an automatic number of shards for NewShardedFromConfig.
+//
+//	clean_interval: 1m
+//	default_ttl: 10m
+//	capacity: 10000
+//	eviction_policy: SLRU
+type Config struct {
+	CleanInterval   Duration            `json:"clean_interval" yaml:"clean_interval"`     // CleanInterval is the interval between two cleanings, 1 minute by default
+	DefaultTTL      Duration            `json:"default_ttl" yaml:"default_ttl"`           // DefaultTTL is the duration of the elements added with AddDefault
+	TTLJitter       float64             `json:"ttl_jitter" yaml:"ttl_jitter"`             // TTLJitter is the fraction by which the durations given to Add are randomized
+	Capacity        int                 `json:"capacity" yaml:"capacity"`                 // Capacity is the maximum number of elements, 0 meaning unlimited
+	EvictionPolicy  EvictionPolicy      `json:"eviction_policy" yaml:"eviction_policy"`   // EvictionPolicy chooses the evicted elements when the cache is full
+	NamespaceTTLs   map[string]Duration `json:"namespace_ttls" yaml:"namespace_ttls"`     // NamespaceTTLs are the default TTLs of the namespaces set with WithNamespace
+	Shards          int                 `json:"shards" yaml:"shards"`                     // Shards is the number of shards of NewShardedFromConfig, 0 meaning automatic
+	PersistencePath string              `json:"persistence_path" yaml:"persistence_path"` // PersistencePath is the snapshot file restored on New and written on Close
+}
+
+// defaultCleanInterval is the clean interval of a Config without one
+const defaultCleanInterval = time.Minute
+
+// ParseConfigJSON returns the validated Config encoded in the given JSON document
+func ParseConfigJSON(data []byte) (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("cacheset: parsing config: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate returns an error if a setting of the Config is out of range
+func (cfg Config) Validate() error {
+	var errs []error
+	if cfg.CleanInterval < 0 {
+		errs = append(errs, errors.New("cacheset: clean_interval must not be negative"))
+	}
+	if cfg.DefaultTTL < 0 {
+		errs = append(errs, errors.New("cacheset: default_ttl must not be negative"))
+	}
+	if cfg.TTLJitter < 0 || cfg.TTLJitter > 1 {
+		errs = append(errs, errors.New("cacheset: ttl_jitter must be between 0 and 1"))
+	}
+	for namespace, ttl := range cfg.NamespaceTTLs {
+		if ttl < 0 {
+			errs = append(errs, fmt.Errorf("cacheset: the default TTL of namespace %q must not be negative", namespace))
+		}
+	}
+	if cfg.Capacity < 0 {
+		errs = append(errs, errors.New("cacheset: capacity must not be negative"))
+	}
+	if cfg.Shards < 0 {
+		errs = append(errs, errors.New("cacheset: shards must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Options returns the options applying the Config, the clean interval and the shards aside
+func (cfg Config) Options() []Option {
+	opts := []Option{
+		WithDefaultTTL(time.Duration(cfg.DefaultTTL)),
+		WithTTLJitter(cfg.TTLJitter),
+	}
+	for namespace, ttl := range cfg.NamespaceTTLs {
+		opts = append(opts, WithNamespaceTTL(namespace, time.Duration(ttl)))
+	}
+	if cfg.Capacity > 0 {
+		opts = append(opts, WithCapacity(cfg.Capacity), WithEvictionPolicy(cfg.EvictionPolicy))
+	}
+	if cfg.PersistencePath != "" {
+		opts = append(opts, WithDurability(cfg.PersistencePath))
+	}
+	return opts
+}
+
+// cleanInterval returns the clean interval of the Config
+func (cfg Config) cleanInterval() time.Duration {
+	if cfg.CleanInterval <= 0 {
+		return defaultCleanInterval
+	}
+	return time.Duration(cfg.CleanInterval)
+}
+
+// NewFromConfig creates a new cache configured by the given Config, then by the given options
+//
+// Description: The Shards setting is ignored, see NewShardedFromConfig.
+func NewFromConfig[T comparable](cfg Config, opts ...Option) *Cache[T] {
+	return New[T](cfg.cleanInterval(), append(cfg.Options(), opts...)...)
+}
+
+// NewShardedFromConfig creates a new sharded cache configured by the given Config, then by the given options
+func NewShardedFromConfig[T comparable](cfg Config, opts ...Option) *Sharded[T] {
+	return NewSharded[T](cfg.cleanInterval(), append(append(cfg.Options(), WithShards(cfg.Shards)), opts...)...)
+}
+
+// ApplyConfig adjusts the clean interval, the capacity, the eviction policy, the default TTLs and
+// the TTL jitter of the cache to the given Config
+//
+// Description: The Config is validated first and nothing is changed if it is invalid. When the capacity
+// or the eviction policy changes, the policy forgets the accesses recorded so far and starts from the
+// elements ordered by expiration, so the elements expiring first are evicted first if the new capacity
+// is smaller. The shards and the persistence path cannot be changed.
+//
+// ApplyConfig replaces each setting it covers, like NewFromConfig: a zero or empty field resets its
+// setting to the default of New, even if the setting was given as an option. A Config without capacity
+// removes the capacity set with WithCapacity, and one without namespace TTLs removes those set with
+// WithNamespaceTTL. To change a few settings, apply the Config the cache was built from, modified.
+func (c *Cache[T]) ApplyConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	c.options.defaultTTL = time.Duration(cfg.DefaultTTL)
+	c.options.ttlJitter = cfg.TTLJitter
+	c.options.namespaceTTLs = make(map[string]time.Duration, len(cfg.NamespaceTTLs))
+	for namespace, ttl := range cfg.NamespaceTTLs {
+		c.options.namespaceTTLs[namespace] = time.Duration(ttl)
+	}
+
+	if interval := cfg.cleanInterval(); interval != c.cleanInterval {
+		c.health.mu.Lock()
+		c.cleanInterval = interval
+		c.health.mu.Unlock()
+		if c.ticker != nil {
+			c.ticker.Reset(interval)
+		}
+		if c.rearm != nil {
+			select {
+			case c.rearm <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	if cfg.Capacity != c.options.capacity || cfg.EvictionPolicy != c.options.evictionPolicy {
+		c.options.capacity = cfg.Capacity
+		c.options.evictionPolicy = cfg.EvictionPolicy
+		c.rebuildPolicy()
+	}
+
+	return nil
+}
+
+// rebuildPolicy replaces the eviction policy after a change of capacity and shrinks the cache, the cache must be locked
+func (c *Cache[T]) rebuildPolicy() {
+	c.policyMu.Lock()
+	c.policy, c.admission = nil, nil
+	if c.options.capacity > 0 {
+		c.policy = newPolicy[T](c.options.evictionPolicy, c.options)
+		if c.options.tinyLFU {
+			c.admission = newTinyLFU[T](c.options.capacity)
+		}
+		for _, elem := range c.set.ExpiringFirst(c.set.Len()) {
+			c.policy.add(elem)
+		}
+		c.findEarliest()
+	}
+	c.policyMu.Unlock()
+
+	c.shrink()
+}
+
+// ApplyConfig adjusts the settings of every shard to the given Config, dividing the capacity among them
+func (s *Sharded[T]) ApplyConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Capacity > 0 {
+		cfg.Capacity = max(1, (cfg.Capacity+len(s.shards)-1)/len(s.shards))
+	}
+	for _, shard := range s.shards {
+		if err := shard.ApplyConfig(cfg); err != nil {
+			return err
+		}
+	}
+	s.ticker.Reset(cfg.cleanInterval())
+	return nil
+}
@@ -0,0 +1,161 @@
+package cacheset
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	want := Config{
+		CleanInterval:  Duration(time.Minute),
+		DefaultTTL:     Duration(10 * time.Minute),
+		Capacity:       100,
+		EvictionPolicy: EvictSLRU,
+		NamespaceTTLs:  map[string]Duration{"otp": Duration(5 * time.Minute)},
+		Shards:         4,
+	}
+
+	tests := []struct {
+		name    string
+		parse   func([]byte) (Config, error)
+		data    string
+		wantErr bool
+	}{
+		{
+			name:  "JSON",
+			parse: ParseConfigJSON,
+			data:  `{"clean_interval": "1m", "default_ttl": "10m", "capacity": 100, "eviction_policy": "slru", "namespace_ttls": {"otp": "5m"}, "shards": 4}`,
+		},
+		{name: "InvalidDuration", parse: ParseConfigJSON, data: `{"default_ttl": "soon"}`, wantErr: true},
+		{name: "InvalidPolicy", parse: ParseConfigJSON, data: `{"eviction_policy": "MRU"}`, wantErr: true},
+		{name: "Negative", parse: ParseConfigJSON, data: `{"capacity": -1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, want) {
+				t.Errorf("parse() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	cfg := Config{DefaultTTL: Duration(time.Hour), Capacity: 2, Shards: 2}
+
+	t.Run("Cache", func(t *testing.T) {
+		c := NewFromConfig[int64](cfg)
+		defer c.Close()
+		c.AddDefault(1)
+		c.AddDefault(2)
+		c.AddDefault(3)
+		if got := c.Len(); got != 2 {
+			t.Errorf("NewFromConfig() Len = %v, want %v", got, 2)
+		}
+		if e, _ := c.Lookup(3); e.TTL() < 59*time.Minute {
+			t.Errorf("AddDefault() TTL = %v, want %v", e.TTL(), time.Hour)
+		}
+	})
+
+	t.Run("Sharded", func(t *testing.T) {
+		s := NewShardedFromConfig[int64](cfg)
+		defer s.Close()
+		if got := len(s.Stats().ShardLens); got != 2 {
+			t.Errorf("NewShardedFromConfig() shards = %v, want %v", got, 2)
+		}
+	})
+}
+
+func TestCache_ApplyConfig(t *testing.T) {
+	c := New[int64](time.Hour)
+	defer c.Close()
+	for i := int64(0); i < 10; i++ {
+		c.Add(i, time.Duration(i+1)*time.Minute)
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		if err := c.ApplyConfig(Config{Capacity: -1}); err == nil {
+			t.Errorf("ApplyConfig() error = %v, want an error", err)
+		}
+	})
+
+	cfg := Config{
+		CleanInterval: Duration(10 * time.Millisecond),
+		DefaultTTL:    Duration(time.Millisecond),
+		Capacity:      5,
+	}
+	if err := c.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+
+	t.Run("Capacity", func(t *testing.T) {
+		if got := c.Len(); got != 5 {
+			t.Errorf("Len() = %v, want %v", got, 5)
+		}
+		if c.Contains(0) || !c.Contains(9) {
+			t.Errorf("ApplyConfig() did not evict the elements expiring first")
+		}
+	})
+
+	t.Run("CleanInterval", func(t *testing.T) {
+		c.AddDefault(100)
+		time.Sleep(50 * time.Millisecond)
+		if c.Contains(100) {
+			t.Errorf("ApplyConfig() did not apply the clean interval and the default TTL")
+		}
+		if got := c.Health().CleanInterval; got != 10*time.Millisecond {
+			t.Errorf("Health().CleanInterval = %v, want %v", got, 10*time.Millisecond)
+		}
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		c.ApplyConfig(Config{})
+		for i := int64(200); i < 220; i++ {
+			c.Add(i, 0)
+		}
+		if got := c.Len(); got != 24 {
+			t.Errorf("Len() = %v, want %v", got, 24)
+		}
+	})
+}
+
+func TestCache_ApplyConfig_Reset(t *testing.T) {
+	c := New[string](time.Hour, WithCapacity(2), WithNamespace(func(elem string) string { return elem[:1] }),
+		WithNamespaceTTL("a", time.Minute))
+	defer c.Close()
+
+	if err := c.ApplyConfig(Config{CleanInterval: Duration(time.Hour)}); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	for _, elem := range []string{"a1", "b1", "c1"} {
+		if err := c.AddDefault(elem); err != nil {
+			t.Errorf("AddDefault() error = %v, want the capacity removed", err)
+		}
+	}
+	if e, _ := c.Lookup("a1"); !e.IsPermanent() || c.Len() != 3 {
+		t.Errorf("Lookup() = %v, want the namespace TTL and the capacity reset by an empty Config", e)
+	}
+}
+
+func TestCache_ApplyConfig_Clone(t *testing.T) {
+	c := New[int](time.Hour)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 20 {
+			_ = c.ApplyConfig(Config{CleanInterval: Duration(time.Duration(i+1) * time.Minute)})
+		}
+	}()
+	for range 20 {
+		c.Clone().Close()
+	}
+	wg.Wait()
+}
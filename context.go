@@ -0,0 +1,63 @@
+// Package cacheset
+//
+// Path: context.go
+//
+// Description: context.go contains the variants of the operations taking a context, which stop the long
+// operations once it is done.
+package cacheset
+
+import (
+	"context"
+	"io"
+	"slices"
+	"time"
+)
+
+// ctxChunk is the number of elements checked by the context variants of the bulk operations between two
+// checks of their context
+const ctxChunk = 1024
+
+// AddCtx adds the given element to the cache like Add, unless ctx is already done
+//
+// Description: The addition itself is not interrupted, the context is checked before the cache is locked.
+// The wrappers of the cache, such as cachesetotel, read the trace context from ctx.
+func (c *Cache[T]) AddCtx(ctx context.Context, elem T, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Add(elem, ttl)
+}
+
+// ContainsCtx returns true if the given element is in the cache like Contains, unless ctx is already done
+func (c *Cache[T]) ContainsCtx(ctx context.Context, elem T) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Contains(elem), nil
+}
+
+// ContainsBatchCtx reports whether each of the given elements is in the cache like ContainsBatch, one
+// chunk of elements at a time, and returns the error of ctx if it is done before all chunks were checked
+func (c *Cache[T]) ContainsBatchCtx(ctx context.Context, elems []T) ([]bool, error) {
+	found := make([]bool, 0, len(elems))
+	for chunk := range slices.Chunk(elems, ctxChunk) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		found = append(found, c.ContainsBatch(chunk)...)
+	}
+	return found, nil
+}
+
+// SnapshotCtx writes a snapshot of the cache to w like Snapshot, and stops with the error of ctx once it is done
+func (c *Cache[T]) SnapshotCtx(ctx context.Context, w io.Writer, mode SnapshotMode) error {
+	return c.Snapshot(ctxWriter{ctx: ctx, w: w}, mode)
+}
+
+// RestoreCtx restores a snapshot like Restore, and stops with the error of ctx once it is done
+//
+// Description: The snapshot is read before the cache is locked, so a restore stopped by ctx leaves the
+// cache unchanged.
+func (c *Cache[T]) RestoreCtx(ctx context.Context, r io.Reader) error {
+	return c.Restore(ctxReader{ctx: ctx, r: r})
+}
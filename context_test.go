@@ -0,0 +1,69 @@
+package cacheset
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCache_Ctx(t *testing.T) {
+	c := New[int](time.Minute)
+	defer c.Close()
+
+	ctx := context.Background()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	t.Run("AddCtx", func(t *testing.T) {
+		if err := c.AddCtx(ctx, 1, 0); err != nil {
+			t.Errorf("AddCtx() error = %v, want nil", err)
+		}
+		if err := c.AddCtx(cancelled, 2, 0); !errors.Is(err, context.Canceled) || c.Contains(2) {
+			t.Errorf("AddCtx() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("ContainsCtx", func(t *testing.T) {
+		if found, err := c.ContainsCtx(ctx, 1); !found || err != nil {
+			t.Errorf("ContainsCtx() = %v, %v, want %v, nil", found, err, true)
+		}
+		if _, err := c.ContainsCtx(cancelled, 1); !errors.Is(err, context.Canceled) {
+			t.Errorf("ContainsCtx() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("ContainsBatchCtx", func(t *testing.T) {
+		elems := make([]int, 3000)
+		elems[2999] = 1
+		found, err := c.ContainsBatchCtx(ctx, elems)
+		if err != nil || len(found) != len(elems) || !found[2999] || found[0] {
+			t.Errorf("ContainsBatchCtx() = %v, %v, want the last element found", len(found), err)
+		}
+		if _, err := c.ContainsBatchCtx(cancelled, elems); !errors.Is(err, context.Canceled) {
+			t.Errorf("ContainsBatchCtx() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("SnapshotCtx", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := c.SnapshotCtx(cancelled, &buf, SnapshotAbsolute); !errors.Is(err, context.Canceled) {
+			t.Errorf("SnapshotCtx() error = %v, want %v", err, context.Canceled)
+		}
+		buf.Reset()
+		if err := c.SnapshotCtx(ctx, &buf, SnapshotAbsolute); err != nil {
+			t.Fatalf("SnapshotCtx() error = %v", err)
+		}
+
+		r := New[int](time.Minute)
+		defer r.Close()
+		if err := r.RestoreCtx(cancelled, bytes.NewReader(buf.Bytes())); !errors.Is(err, context.Canceled) || r.Len() != 0 {
+			t.Errorf("RestoreCtx() error = %v, want %v", err, context.Canceled)
+		}
+		if err := r.RestoreCtx(ctx, &buf); err != nil || !slices.Equal(r.ToSlice(), []int{1}) {
+			t.Errorf("RestoreCtx() = %v, %v, want %v", r.ToSlice(), err, []int{1})
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Package cacheset
+//
+// Path: debug.go
+//
+// Description: debug.go contains the human-readable descriptions of a cache.
+package cacheset
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// String returns a concise summary of the cache: its size, its eviction policy and its next expiration
+func (c *Cache[T]) String() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	policy := "none"
+	if c.policy != nil {
+		policy = c.options.evictionPolicy.String()
+	}
+
+	var next int64
+	for _, e := range c.set.All() {
+		if d := e.deadline(); d != 0 && (next == 0 || d < next) {
+			next = d
+		}
+	}
+
+	return fmt.Sprintf("Cache[len=%d policy=%s next_expiry=%s]", c.set.Len(), policy, remaining(next, nanotime()))
+}
+
+// DebugDump writes a table of up to limit elements with their remaining time to live, soonest expiration first
+//
+// Description: A zero or negative limit writes all elements. The remaining idle time is only
+// shown for the elements added with a maximum idle duration.
+func (c *Cache[T]) DebugDump(w io.Writer, limit int) error {
+	type row struct {
+		elem     T
+		deadline int64
+		idle     int64
+	}
+
+	c.RLock()
+	now := nanotime()
+	rows := make([]row, 0, c.set.Len())
+	for k, e := range c.set.All() {
+		r := row{elem: k, deadline: e.deadline()}
+		if e.idle != nil {
+			r.idle = e.lastAccess() + e.maxIdle()
+		}
+		rows = append(rows, r)
+	}
+	c.RUnlock()
+
+	slices.SortFunc(rows, func(a, b row) int {
+		return compareDeadlines(a.deadline, b.deadline)
+	})
+
+	more := 0
+	if limit > 0 && len(rows) > limit {
+		more = len(rows) - limit
+		rows = rows[:limit]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ELEMENT\tTTL\tIDLE")
+	for _, r := range rows {
+		idle := "-"
+		if r.idle != 0 {
+			idle = remaining(r.idle, now)
+		}
+		fmt.Fprintf(tw, "%v\t%s\t%s\n", r.elem, remaining(r.deadline, now), idle)
+	}
+	if more > 0 {
+		fmt.Fprintf(tw, "... %d more\t\t\n", more)
+	}
+	return tw.Flush()
+}
+
+// remaining returns the human-readable duration until the given monotonic expiration time
+func remaining(expires, now int64) string {
+	switch {
+	case expires == 0:
+		return "never"
+	case expired(expires, now):
+		return "expired"
+	default:
+		return time.Duration(expires - now).Round(time.Millisecond).String()
+	}
+}
@@ -0,0 +1,54 @@
+package cacheset
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_String(t *testing.T) {
+	c := New[int64](time.Minute, WithCapacity(10))
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, time.Hour)
+
+	want := "Cache[len=2 policy=LRU next_expiry="
+	if got := c.String(); !strings.HasPrefix(got, want) || strings.Contains(got, "never") {
+		t.Errorf("String() = %v, want %v<about 1h>]", got, want)
+	}
+}
+
+func TestCache_DebugDump(t *testing.T) {
+	c := New[string](time.Minute)
+	defer c.Close()
+	c.Add("forever", 0)
+	c.Add("soon", time.Minute)
+	c.Add("later", time.Hour)
+	c.AddWithIdle("idle", 2*time.Hour, time.Hour)
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{name: "All", limit: 0, want: []string{"ELEMENT", "soon", "later", "idle", "forever"}},
+		{name: "Limit", limit: 2, want: []string{"ELEMENT", "soon", "later", "... 2 more"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := c.DebugDump(&b, tt.limit); err != nil {
+				t.Fatalf("DebugDump() error = %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("DebugDump() = %q, want %v lines", b.String(), len(tt.want))
+			}
+			for i, prefix := range tt.want {
+				if !strings.HasPrefix(lines[i], prefix) {
+					t.Errorf("DebugDump() line %v = %q, want prefix %q", i, lines[i], prefix)
+				}
+			}
+		})
+	}
+}
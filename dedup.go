@@ -0,0 +1,80 @@
+// Package cacheset
+//
+// Path: dedup.go
+//
+// Description: dedup.go contains the deduplication helpers built on a cache.
+package cacheset
+
+import (
+	"context"
+	"time"
+)
+
+// DedupChan forwards the elements received from in that were not received during the last ttl
+//
+// Description: The returned channel is unbuffered and closed once in is closed. The elements seen
+// are kept in a cache created with the given options, which is closed with the returned channel.
+// An element that the cache refuses, for instance because it is full, is forwarded.
+// The caller must drain the returned channel until it is closed, or the forwarding goroutine leaks.
+func DedupChan[T comparable](in <-chan T, ttl time.Duration, opts ...Option) <-chan T {
+	out := make(chan T)
+	seen := New[T](dedupCleanInterval(ttl), opts...)
+
+	go func() {
+		defer seen.Close()
+		defer close(out)
+
+		for elem := range in {
+			if added, err := seen.GetOrAdd(elem, ttl); added || err != nil {
+				out <- elem
+			}
+		}
+	}()
+
+	return out
+}
+
+// dedupCleanInterval returns the clean interval of a deduplication cache for the given ttl
+func dedupCleanInterval(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return time.Minute
+	}
+	return ttl
+}
+
+// Deduper suppresses the redelivered messages of an at-least-once consumer
+//
+// Description: The identifiers of the processed messages are kept in a cache persisted to a
+// snapshot file, restored by NewDeduper, written every snapshot interval and on Close, so that
+// duplicates are still suppressed after a restart. The messages processed after the last snapshot
+// of a crashed process may be processed again.
+type Deduper struct {
+	seen *Cache[string] // seen holds the identifiers of the processed messages
+}
+
+// NewDeduper returns a Deduper persisted to the snapshot file at path every interval
+//
+// Description: The interval is also the clean interval of the cache, created with the given options.
+func NewDeduper(path string, interval time.Duration, opts ...Option) *Deduper {
+	opts = append(opts, WithDurability(path), WithSnapshotInterval(interval))
+	return &Deduper{seen: New[string](dedupCleanInterval(interval), opts...)}
+}
+
+// Dedupe records the given message identifier for ttl and returns true if it was not seen during the last ttl
+//
+// Description: If the identifier cannot be recorded, for instance because the cache is full, it is
+// reported as seen for the first time, so that a message is never dropped.
+func (d *Deduper) Dedupe(id string, ttl time.Duration) (firstTime bool) {
+	added, err := d.seen.GetOrAdd(id, ttl)
+	return added || err != nil
+}
+
+// Cache returns the cache holding the identifiers of the processed messages
+func (d *Deduper) Cache() *Cache[string] {
+	return d.seen
+}
+
+// Close writes the final snapshot and stops the Deduper
+func (d *Deduper) Close() error {
+	return d.seen.Shutdown(context.Background())
+}
@@ -0,0 +1,66 @@
+package cacheset
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDedupChan(t *testing.T) {
+	in := make(chan int64)
+	out := DedupChan(in, 20*time.Millisecond)
+
+	go func() {
+		defer close(in)
+		for _, elem := range []int64{1, 2, 1, 3, 2} {
+			in <- elem
+		}
+		time.Sleep(30 * time.Millisecond)
+		in <- 1
+	}()
+
+	var got []int64
+	for elem := range out {
+		got = append(got, elem)
+	}
+
+	if want := []int64{1, 2, 3, 1}; !slices.Equal(got, want) {
+		t.Errorf("DedupChan() = %v, want %v", got, want)
+	}
+}
+
+func TestDeduper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.snapshot")
+
+	d := NewDeduper(path, 10*time.Millisecond)
+	t.Run("FirstTime", func(t *testing.T) {
+		if !d.Dedupe("a", time.Hour) {
+			t.Errorf("Dedupe() = %v, want %v", false, true)
+		}
+		if d.Dedupe("a", time.Hour) {
+			t.Errorf("Dedupe() = %v, want %v", true, false)
+		}
+	})
+
+	t.Run("Periodic", func(t *testing.T) {
+		time.Sleep(50 * time.Millisecond)
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Dedupe() did not write a periodic snapshot: %v", err)
+		}
+	})
+
+	d.Dedupe("b", time.Hour)
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	t.Run("Restart", func(t *testing.T) {
+		d := NewDeduper(path, time.Minute)
+		defer d.Close()
+		if d.Dedupe("a", time.Hour) || d.Dedupe("b", time.Hour) {
+			t.Errorf("Dedupe() after a restart = %v, want %v", true, false)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+// Package cacheset
+//
+// Path: delta.go
+//
+// Description: delta.go contains the delta snapshots, holding only the changes since the previous snapshot.
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// ErrBrokenDeltaChain is returned by RestoreLayers when a delta was not written right after the previous layer
+var ErrBrokenDeltaChain = errors.New("cacheset: delta does not follow the previous snapshot")
+
+// WithDeltaSnapshots tracks the elements changed between two snapshots, so that SnapshotDelta writes only them
+//
+// Description: With WithDurability and WithSnapshotInterval, one periodic snapshot out of every is a full
+// snapshot and the others are deltas written next to the snapshot file, in files suffixed with
+// ".delta-000001", ".delta-000002", and so on, or stored with these names in the SnapshotSink. A full snapshot, including the final one written on Close,
+// compacts the deltas: they are deleted once it is written. A delta always follows the last snapshot
+// written by the cache, so the snapshots written elsewhere break the chain of the delta files. The access times of the elements expiring
+// after a maximum idle duration are not tracked, they are only updated by the full snapshots.
+func WithDeltaSnapshots(every int) Option {
+	return func(o *options) {
+		o.deltaEvery = max(every, 0)
+	}
+}
+
+// SnapshotDelta writes the elements added, changed and removed since the previous Snapshot or SnapshotDelta to w
+//
+// Description: The cache must have been created with WithDeltaSnapshots. The delta is applied over the
+// previous snapshot with RestoreLayers, or with Restore. Like Snapshot, the cache is not locked while
+// the elements are encoded. If the delta cannot be written, its changes are part of the next one.
+func (c *Cache[T]) SnapshotDelta(w io.Writer, mode SnapshotMode) error {
+	if c.options.deltaEvery == 0 {
+		return errors.New("cacheset: delta snapshots are not enabled, see WithDeltaSnapshots")
+	}
+
+	start := time.Now()
+	n, err := c.snapshot(w, mode, true)
+	if err != nil {
+		c.options.logger.Warn("cacheset: delta snapshot failed", slog.Any("error", err))
+		return err
+	}
+
+	c.options.logger.Debug("cacheset: wrote delta snapshot",
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("len", n),
+	)
+	return nil
+}
+
+// RestoreLayers restores a full snapshot, then the deltas written after it, in order
+//
+// Description: Each delta must have been written right after the previous layer, otherwise
+// ErrBrokenDeltaChain is returned and the following deltas are not applied.
+func (c *Cache[T]) RestoreLayers(base io.Reader, deltas ...io.Reader) error {
+	prev, _, err := c.restore(base, func(h snapshotHeader) error {
+		if h.Delta {
+			return fmt.Errorf("%w: the base is a delta", ErrBrokenDeltaChain)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, r := range deltas {
+		if prev, _, err = c.restore(r, follows(prev)); err != nil {
+			return fmt.Errorf("cacheset: restoring delta %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// follows returns a function accepting the header of a delta written right after the given snapshot
+func follows(prev snapshotHeader) func(snapshotHeader) error {
+	return func(h snapshotHeader) error {
+		if !h.Delta || h.Since != prev.Taken {
+			return ErrBrokenDeltaChain
+		}
+		return nil
+	}
+}
+
+// markDirty records a change of the given element for the next delta, the cache must be locked
+func (c *Cache[T]) markDirty(elem T) {
+	if c.dirty != nil {
+		c.dirty[elem] = struct{}{}
+	}
+}
+
+// markCleared records a Clear for the next delta, the cache must be locked
+func (c *Cache[T]) markCleared() {
+	if c.dirty != nil {
+		clear(c.dirty)
+		c.dirtyCleared = true
+	}
+}
+
+// deltaName returns the name of the i-th delta of the snapshot with the given name
+func deltaName(name string, i int) string {
+	return fmt.Sprintf("%s.delta-%06d", name, i)
+}
+
+// checkpointDelta writes the next delta of a cache with durability
+func (c *Cache[T]) checkpointDelta(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.options.durabilityTimeout)
+	defer cancel()
+
+	if err := c.writeSnapshot(ctx, deltaName(c.options.snapshotName, c.deltaFiles+1), true); err != nil {
+		return err
+	}
+	c.deltaFiles++
+	return nil
+}
+
+// removeDeltas removes the deltas of the snapshot of a cache with durability, including the stale ones found on restore
+func (c *Cache[T]) removeDeltas(ctx context.Context) error {
+	var errs []error
+	for i := 1; i <= max(c.deltaFiles, c.staleDeltas); i++ {
+		if err := c.options.sink.Remove(ctx, deltaName(c.options.snapshotName, i)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	c.deltaFiles, c.staleDeltas = 0, 0
+	return nil
+}
+
+// restoreDeltas applies the deltas following the snapshot with the given header, up to the first missing or stale one
+//
+// Description: The deltas left over by a crash before the deltas of an older snapshot were removed are stale:
+// they are counted to be removed by the next full snapshot.
+func (c *Cache[T]) restoreDeltas(ctx context.Context, prev snapshotHeader) error {
+	stale := false
+	for i := 1; ; i++ {
+		r, err := c.options.sink.Open(ctx, deltaName(c.options.snapshotName, i))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cacheset: opening delta: %w", err)
+		}
+		c.staleDeltas = i
+		if !stale {
+			prev, _, err = c.restore(r, follows(prev))
+			stale = errors.Is(err, ErrBrokenDeltaChain)
+		}
+		r.Close()
+		if err != nil && !stale {
+			return err
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package cacheset
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCache_SnapshotDelta(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		c := New[int](time.Minute)
+		defer c.Close()
+		if err := c.SnapshotDelta(&bytes.Buffer{}, SnapshotAbsolute); err == nil {
+			t.Errorf("SnapshotDelta() error = nil, want an error")
+		}
+	})
+
+	c := New[int](time.Minute, WithDeltaSnapshots(1))
+	defer c.Close()
+	for elem := range 5 {
+		c.Add(elem, 0)
+	}
+	var base, delta1, delta2 bytes.Buffer
+	if err := c.Snapshot(&base, SnapshotAbsolute); err != nil {
+		t.Fatal(err)
+	}
+	c.Delete(0)
+	c.Add(1, time.Hour)
+	c.Add(5, 0)
+	if err := c.SnapshotDelta(&delta1, SnapshotAbsolute); err != nil {
+		t.Fatal(err)
+	}
+	c.Clear()
+	c.Add(6, 0)
+	if err := c.SnapshotDelta(&delta2, SnapshotAbsolute); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("layers", func(t *testing.T) {
+		r := New[int](time.Minute)
+		defer r.Close()
+		if err := r.RestoreLayers(bytes.NewReader(base.Bytes()), bytes.NewReader(delta1.Bytes())); err != nil {
+			t.Fatalf("RestoreLayers() error = %v", err)
+		}
+		if got, want := sorted(r.ToSlice()), []int{1, 2, 3, 4, 5}; !slices.Equal(got, want) {
+			t.Errorf("ToSlice() = %v, want %v", got, want)
+		}
+		if e, _ := r.Lookup(1); e.IsPermanent() {
+			t.Errorf("Lookup(1).IsPermanent() = true, want the TTL of the delta")
+		}
+		if err := r.RestoreLayers(bytes.NewReader(base.Bytes()), bytes.NewReader(delta1.Bytes()), bytes.NewReader(delta2.Bytes())); err != nil {
+			t.Fatalf("RestoreLayers() error = %v", err)
+		}
+		if got, want := r.ToSlice(), []int{6}; !slices.Equal(got, want) {
+			t.Errorf("ToSlice() = %v after a cleared delta, want %v", got, want)
+		}
+	})
+
+	t.Run("broken chain", func(t *testing.T) {
+		r := New[int](time.Minute)
+		defer r.Close()
+		err := r.RestoreLayers(bytes.NewReader(base.Bytes()), bytes.NewReader(delta2.Bytes()))
+		if !errors.Is(err, ErrBrokenDeltaChain) {
+			t.Errorf("RestoreLayers() error = %v, want %v", err, ErrBrokenDeltaChain)
+		}
+	})
+}
+
+func TestWithDeltaSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	c := New[int](time.Minute, WithDurability(path), WithSnapshotInterval(10*time.Millisecond), WithDeltaSnapshots(100))
+	for elem := range 10 {
+		c.Add(elem, 0)
+	}
+	deltas := func() []string {
+		matches, _ := filepath.Glob(path + ".delta-*")
+		return matches
+	}
+	for deadline := time.Now().Add(time.Second); len(deltas()) < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	c.Delete(0)
+	c.Add(10, 0)
+	for n := len(deltas()); len(deltas()) == n; {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// a crash before Close restores the full snapshot and its deltas
+	restored := New[int](time.Minute, WithDurability(path), WithErrorHandler(func(err error) { t.Errorf("restore error = %v", err) }))
+	defer restored.Close()
+	if got, want := sorted(restored.ToSlice()), []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !slices.Equal(got, want) {
+		t.Errorf("ToSlice() = %v, want %v", got, want)
+	}
+
+	if err := c.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if got := deltas(); len(got) != 0 {
+		t.Errorf("delta files %v left after Close", got)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Stat() error = %v", err)
+	}
+}
+
+// sorted returns the given elements sorted
+func sorted(elems []int) []int {
+	slices.Sort(elems)
+	return elems
+}
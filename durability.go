@@ -0,0 +1,157 @@
+// Package cacheset
+//
+// Path: durability.go
+//
+// Description: durability.go contains the restoration of the cache on New and its final snapshot on Close.
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// WithDurability restores the cache from the snapshot file at path on New and writes a final snapshot on Close
+//
+// Description: A missing file is not an error. The final snapshot is written to a temporary file
+// renamed over path, so a failed write never corrupts the previous snapshot. It must be written
+// before the durability timeout, 10 seconds by default. Errors are reported to the error handler.
+// The durability is not inherited by the caches returned by Clone.
+func WithDurability(path string) Option {
+	return WithSnapshotSink(FileSink{}, path)
+}
+
+// WithSnapshotSink restores the cache from the snapshot with the given name in sink on New and writes
+// a final snapshot on Close, like WithDurability with a file
+func WithSnapshotSink(sink SnapshotSink, name string) Option {
+	return func(o *options) {
+		o.sink = sink
+		o.snapshotName = name
+	}
+}
+
+// WithDurabilityTimeout sets the deadline of the final snapshot written on Close, 10 seconds by default
+func WithDurabilityTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.durabilityTimeout = timeout
+	}
+}
+
+// WithSnapshotInterval periodically writes a snapshot of a cache with durability, between New and Close
+//
+// Description: The snapshots are written by the cleaning goroutine like the final snapshot, so that
+// a crash loses at most the changes of the last interval. Errors are reported to the error handler.
+func WithSnapshotInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.snapshotInterval = interval
+	}
+}
+
+// checkpoint writes a periodic snapshot of a cache with durability, or a delta with WithDeltaSnapshots
+func (c *Cache[T]) checkpoint() {
+	defer c.recoverPanic()
+
+	full := c.options.deltaEvery == 0 || c.checkpoints%c.options.deltaEvery == 0
+	c.checkpoints++
+	if full {
+		if err := c.flush(context.Background()); err != nil {
+			c.report(fmt.Errorf("cacheset: periodic snapshot failed: %w", err))
+		}
+		return
+	}
+	if err := c.checkpointDelta(context.Background()); err != nil {
+		c.report(fmt.Errorf("cacheset: periodic delta snapshot failed: %w", err))
+	}
+}
+
+// restoreSink restores the cache from its snapshot and its deltas, a missing snapshot is not an error
+func (c *Cache[T]) restoreSink(ctx context.Context) error {
+	r, err := c.options.sink.Open(ctx, c.options.snapshotName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cacheset: opening snapshot: %w", err)
+	}
+	defer r.Close()
+
+	header, n, err := c.restore(ctxReader{ctx: ctx, r: r}, nil)
+	if err != nil {
+		c.options.logger.Warn("cacheset: restore failed", slog.Any("error", err))
+		return err
+	}
+	c.options.logger.Debug("cacheset: restored snapshot", slog.Int("len", n))
+
+	return c.restoreDeltas(ctx, header)
+}
+
+// writeSnapshot writes a snapshot of the cache, or a delta, with the given name in the sink before ctx is done
+//
+// Description: The snapshot only replaces the previous one once it is committed, so a failed write never
+// corrupts the previous snapshot.
+func (c *Cache[T]) writeSnapshot(ctx context.Context, name string, delta bool) (err error) {
+	w, err := c.options.sink.Create(ctx, name)
+	if err != nil {
+		return fmt.Errorf("cacheset: creating snapshot: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = w.Abort()
+		}
+	}()
+
+	write := c.Snapshot
+	if delta {
+		write = c.SnapshotDelta
+	}
+	if err := write(ctxWriter{ctx: ctx, w: w}, SnapshotAbsolute); err != nil {
+		return err
+	}
+	if err := w.Commit(); err != nil {
+		return fmt.Errorf("cacheset: committing snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// flush writes a full snapshot of a cache with durability before ctx is done or the durability timeout,
+// and removes the delta files it compacts
+func (c *Cache[T]) flush(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.options.durabilityTimeout)
+	defer cancel()
+
+	if err := c.writeSnapshot(ctx, c.options.snapshotName, false); err != nil {
+		return err
+	}
+	return c.removeDeltas(ctx)
+}
+
+// ctxWriter is an io.Writer failing once its context is done
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w ctxWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// ctxReader is an io.Reader failing once its context is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
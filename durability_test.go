@@ -0,0 +1,33 @@
+package cacheset
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithDurability(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	var errs []error
+	handler := WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	c := New[string](time.Minute, WithDurability(path), handler)
+	c.Add("foo", time.Hour)
+	c.Add("bar", 0)
+	c.Close()
+
+	restored := New[string](time.Minute, WithDurability(path), handler)
+	defer restored.Close()
+
+	t.Run("Restore", func(t *testing.T) {
+		if len(errs) != 0 {
+			t.Fatalf("WithDurability() errors = %v", errs)
+		}
+		if !restored.Contains("foo") || !restored.Contains("bar") {
+			t.Errorf("WithDurability() = %v, want %v", restored.ToSlice(), []string{"foo", "bar"})
+		}
+	})
+}
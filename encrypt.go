@@ -0,0 +1,255 @@
+// Package cacheset
+//
+// Path: encrypt.go
+//
+// Description: encrypt.go contains the encryption of the snapshots with AES-GCM.
+//
+// An encrypted snapshot starts with a header naming the key it was encrypted with, followed by the
+// snapshot split in chunks of 64 KiB, each sealed with AES-GCM. The nonce of a chunk is made of a
+// random prefix, the index of the chunk and a flag marking the last chunk, and the header is
+// authenticated with every chunk, so that the chunks cannot be reordered, truncated or moved to
+// another snapshot without failing the restoration.
+package cacheset
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedMagic starts the encrypted snapshots, it cannot start a gob stream
+const encryptedMagic = "\x00cachesetenc\x01"
+
+// encryptedChunk is the size of the plaintext of the chunks of an encrypted snapshot
+const encryptedChunk = 64 << 10
+
+// noncePrefixSize is the size of the random prefix of the nonces
+const noncePrefixSize = 7
+
+// KeyProvider provides the AES keys encrypting the snapshots, identified by IDs written in the snapshots
+//
+// Description: The keys must be 16, 24 or 32 bytes long, selecting AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the key encrypting the new snapshots and its ID
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given ID, to decrypt a snapshot
+	Key(id string) ([]byte, error)
+}
+
+// StaticKey returns a KeyProvider with a single key
+func StaticKey(id string, key []byte) KeyProvider {
+	return Keyring{Current: id, Keys: map[string][]byte{id: key}}
+}
+
+// Keyring is a KeyProvider holding the current key and the previous ones, so that the snapshots
+// encrypted before a rotation can still be restored
+type Keyring struct {
+	Current string            // Current is the ID of the key encrypting the new snapshots
+	Keys    map[string][]byte // Keys are the keys by ID
+}
+
+// CurrentKey returns the current key and its ID
+func (k Keyring) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+// Key returns the key with the given ID
+func (k Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("cacheset: unknown snapshot key %q", id)
+	}
+	return key, nil
+}
+
+// WithSnapshotEncryption encrypts the snapshots and the deltas with AES-GCM, using the current key of keys
+//
+// Description: Restore reads the ID of the key from the snapshot and asks keys for it. The snapshots
+// written without encryption are still restored, so that an existing snapshot can be migrated.
+func WithSnapshotEncryption(keys KeyProvider) Option {
+	return func(o *options) {
+		o.snapshotKeys = keys
+	}
+}
+
+// sealer encrypts a snapshot chunk by chunk
+type sealer struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte // header is the header of the snapshot, authenticated with every chunk
+	prefix []byte // prefix is the random prefix of the nonces
+	buf    []byte // buf is the plaintext of the current chunk
+	index  uint32 // index is the index of the current chunk
+}
+
+// newSealer writes the header of an encrypted snapshot to w and returns a writer encrypting the snapshot,
+// which must be closed to write the last chunk
+func newSealer(w io.Writer, keys KeyProvider) (*sealer, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("cacheset: snapshot key ID %q is longer than 255 bytes", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	header := append([]byte(encryptedMagic), byte(len(id)))
+	header = append(append(header, id...), prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &sealer{w: w, aead: aead, header: header, prefix: prefix, buf: make([]byte, 0, encryptedChunk)}, nil
+}
+
+// Write encrypts p, writing the full chunks
+func (s *sealer) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		m := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf = s.buf[:len(s.buf)+m]
+		p, n = p[m:], n+m
+		if len(s.buf) == cap(s.buf) {
+			if err := s.seal(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the last chunk
+func (s *sealer) Close() error {
+	return s.seal(true)
+}
+
+// seal encrypts and writes the current chunk
+func (s *sealer) seal(last bool) error {
+	flag := byte(0)
+	if last {
+		flag = 1
+	}
+	out := make([]byte, 5, 5+len(s.buf)+s.aead.Overhead())
+	out[0] = flag
+	out = s.aead.Seal(out, chunkNonce(s.prefix, s.index, flag), s.buf, s.header)
+	binary.BigEndian.PutUint32(out[1:5], uint32(len(out)-5))
+	if _, err := s.w.Write(out); err != nil {
+		return err
+	}
+	s.buf = s.buf[:0]
+	s.index++
+	return nil
+}
+
+// opener decrypts an encrypted snapshot chunk by chunk
+type opener struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte // header is the header of the snapshot, authenticated with every chunk
+	prefix []byte // prefix is the random prefix of the nonces
+	buf    []byte // buf is the plaintext of the current chunk not read yet
+	index  uint32 // index is the index of the next chunk
+	last   bool   // last is true once the last chunk was decrypted
+}
+
+// decrypt returns a reader of the snapshot read from r, decrypted with keys if it is encrypted
+func decrypt(r io.Reader, keys KeyProvider) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(encryptedMagic))
+	if err != nil || !bytes.Equal(magic, []byte(encryptedMagic)) {
+		// a plaintext snapshot, the decoder reports the read errors
+		return br, nil
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("%w: the snapshot is encrypted, see WithSnapshotEncryption", ErrInvalidSnapshot)
+	}
+
+	header := make([]byte, len(encryptedMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	rest := make([]byte, int(header[len(header)-1])+noncePrefixSize)
+	if _, err := io.ReadFull(br, rest); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	header = append(header, rest...)
+	id := string(rest[:len(rest)-noncePrefixSize])
+
+	key, err := keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &opener{r: br, aead: aead, header: header, prefix: rest[len(rest)-noncePrefixSize:]}, nil
+}
+
+// Read decrypts the chunks as needed
+func (o *opener) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		if o.last {
+			return 0, io.EOF
+		}
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk
+func (o *opener) open() error {
+	var head [5]byte
+	if _, err := io.ReadFull(o.r, head[:]); err != nil {
+		return fmt.Errorf("cacheset: reading encrypted snapshot: %w", io.ErrUnexpectedEOF)
+	}
+	size := binary.BigEndian.Uint32(head[1:])
+	if size > encryptedChunk+uint32(o.aead.Overhead()) {
+		return errors.New("cacheset: reading encrypted snapshot: chunk too large")
+	}
+	ciphertext := make([]byte, size)
+	if _, err := io.ReadFull(o.r, ciphertext); err != nil {
+		return fmt.Errorf("cacheset: reading encrypted snapshot: %w", io.ErrUnexpectedEOF)
+	}
+	plaintext, err := o.aead.Open(ciphertext[:0], chunkNonce(o.prefix, o.index, head[0]), ciphertext, o.header)
+	if err != nil {
+		return fmt.Errorf("cacheset: decrypting snapshot: %w", err)
+	}
+	o.buf, o.index, o.last = plaintext, o.index+1, head[0] == 1
+	return nil
+}
+
+// newAEAD returns AES-GCM with the given key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cacheset: snapshot key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of the chunk with the given index
+func chunkNonce(prefix []byte, index uint32, flag byte) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, index)
+	return append(nonce, flag)
+}
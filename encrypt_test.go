@@ -0,0 +1,73 @@
+package cacheset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWithSnapshotEncryption(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16)
+	c := New[string](time.Minute, WithSnapshotEncryption(StaticKey("2024", oldKey)))
+	defer c.Close()
+	for i := range 10000 {
+		c.Add(fmt.Sprintf("user-%d@example.com", i), 0)
+	}
+	var encrypted bytes.Buffer
+	if err := c.Snapshot(&encrypted, SnapshotAbsolute); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted.Bytes(), []byte("@example.com")) {
+		t.Fatalf("Snapshot() wrote the elements in plaintext")
+	}
+
+	restore := func(data []byte, opts ...Option) (*Cache[string], error) {
+		r := New[string](time.Minute, opts...)
+		return r, r.Restore(bytes.NewReader(data))
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		keys := Keyring{Current: "2025", Keys: map[string][]byte{"2024": oldKey, "2025": newKey}}
+		r, err := restore(encrypted.Bytes(), WithSnapshotEncryption(keys))
+		defer r.Close()
+		if err != nil || r.Len() != 10000 {
+			t.Fatalf("Restore() = %v with %d elements, want nil with %d", err, r.Len(), 10000)
+		}
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		r, err := restore(encrypted.Bytes())
+		defer r.Close()
+		if !errors.Is(err, ErrInvalidSnapshot) {
+			t.Errorf("Restore() error = %v, want %v", err, ErrInvalidSnapshot)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		for name, data := range map[string][]byte{
+			"flipped":   append(bytes.Clone(encrypted.Bytes()[:1000]), append([]byte{encrypted.Bytes()[1000] ^ 1}, encrypted.Bytes()[1001:]...)...),
+			"truncated": encrypted.Bytes()[:encrypted.Len()-100],
+		} {
+			r, err := restore(data, WithSnapshotEncryption(StaticKey("2024", oldKey)))
+			if err == nil || r.Len() != 0 {
+				t.Errorf("Restore() of a %s snapshot = %v with %d elements, want an error", name, err, r.Len())
+			}
+			r.Close()
+		}
+	})
+
+	t.Run("plaintext", func(t *testing.T) {
+		var plain bytes.Buffer
+		p := New[string](time.Minute)
+		p.Add("a", 0)
+		p.Snapshot(&plain, SnapshotAbsolute)
+		p.Close()
+		r, err := restore(plain.Bytes(), WithSnapshotEncryption(StaticKey("2024", oldKey)))
+		defer r.Close()
+		if err != nil || !r.Contains("a") {
+			t.Errorf("Restore() of a plaintext snapshot error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+// Package cacheset
+//
+// Path: errors.go
+//
+// Description: errors.go contains the errors reported by the cache.
+package cacheset
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+// ErrCapacityExceeded is returned by Add when the cache is full and the overflow policy rejects the element,
+// or all its elements are pinned
+var ErrCapacityExceeded = errors.New("cacheset: capacity exceeded")
+
+// ErrClosed is returned by WaitFor when the cache is closed before the element is added, and by Add once the
+// cache is closed in strict mode
+var ErrClosed = errors.New("cacheset: cache closed")
+
+// ErrInvalidDuration is returned by Add for a negative duration in strict mode, see WithStrict
+var ErrInvalidDuration = errors.New("cacheset: invalid duration")
+
+// ErrNotAdmitted is returned by Add when the cache is full and the admission filter rejects the element
+var ErrNotAdmitted = errors.New("cacheset: element not admitted")
+
+// PanicError is reported to the error handler when a panic is recovered in the cleaning goroutine
+type PanicError struct {
+	Value any    // Value is the value passed to panic
+	Stack []byte // Stack is the stack trace of the goroutine that panicked
+}
+
+// Error returns the panic value formatted as an error message
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("cacheset: recovered panic: %v", e.Value)
+}
+
+// recoverPanic recovers a panic and reports it as a PanicError, it must be deferred
+func (c *Cache[T]) recoverPanic() {
+	if r := recover(); r != nil {
+		c.report(&PanicError{Value: r, Stack: debug.Stack()})
+	}
+}
+
+// report logs the given error and passes it to the error handler
+func (c *Cache[T]) report(err error) {
+	c.options.logger.Warn("cacheset: internal error", slog.Any("error", err))
+	if c.options.errorHandler != nil {
+		c.options.errorHandler(err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package cacheset
+//
+// Path: events.go
+//
+// Description: events.go contains the stream of the additions and removals of a cache.
+package cacheset
+
+import (
+	"sync"
+	"time"
+)
+
+// EventKind is the kind of change described by an Event
+type EventKind int
+
+const (
+	// EventAdded means that the element was added or its expiration was changed
+	EventAdded EventKind = iota
+	// EventRemoved means that the element was removed, see the Reason of the event
+	EventRemoved
+	// EventCleared means that all elements were removed with Clear, the Elem of the event is the zero value
+	EventCleared
+	// EventSynced follows the replayed additions of a subscription made with WithSyncMarker
+	EventSynced
+)
+
+// String returns the name of the event kind
+func (k EventKind) String() string {
+	switch k {
+	case EventAdded:
+		return "added"
+	case EventRemoved:
+		return "removed"
+	case EventCleared:
+		return "cleared"
+	case EventSynced:
+		return "synced"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change of a cache
+type Event[T comparable] struct {
+	Kind          EventKind     // Kind is the kind of change
+	Elem          T             // Elem is the added or removed element
+	ExpiresAt     time.Time     // ExpiresAt is the expiration time of an added element, zero meaning never
+	Reason        RemovalReason // Reason is the reason of the removal of a removed element
+	Replayed      bool          // Replayed is true for the synthetic additions replaying the elements of the cache
+	EntryMetadata               // EntryMetadata describes a removed element, zero without WithEntryMetadata
+}
+
+// SubscribeOption configures a subscription
+type SubscribeOption func(*subscribeOptions)
+
+// subscribeOptions are the settings of a subscription
+type subscribeOptions struct {
+	replay bool // replay sends the elements of the cache as synthetic additions first
+	marker bool // marker sends an EventSynced after the replayed additions
+}
+
+// WithReplay starts the subscription with a synthetic EventAdded for each unexpired element of the cache,
+// so that a mirror can bootstrap from the subscription and then stay in sync
+func WithReplay() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.replay = true
+	}
+}
+
+// WithSyncMarker replays the elements of the cache like WithReplay and then sends an EventSynced,
+// even if the cache is empty, so that a mirror knows which of its elements were removed in the meantime
+func WithSyncMarker() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.replay = true
+		o.marker = true
+	}
+}
+
+// Subscription receives the events of a cache until it is closed or the cache is closed
+type Subscription[T comparable] struct {
+	c         *Cache[T]           // c is the cache the subscription listens to
+	pred      func(Event[T]) bool // pred selects the events sent to the subscription, nil meaning all
+	out       chan Event[T]       // out is the channel returned by Events
+	wake      chan struct{}       // wake signals the forwarding goroutine that events were queued
+	done      chan struct{}       // done stops the forwarding goroutine
+	closeOnce sync.Once           // closeOnce ensures that done is closed once
+	queue     []Event[T]          // queue are the events not forwarded yet
+	ended     bool                // ended is true once the cache is closed, the queue is then drained
+	mu        sync.Mutex
+}
+
+// Subscribe returns a subscription receiving all the events of the cache
+func (c *Cache[T]) Subscribe(opts ...SubscribeOption) *Subscription[T] {
+	return c.SubscribeFunc(nil, opts...)
+}
+
+// SubscribeFunc returns a subscription receiving the events of the cache for which pred returns true
+//
+// Description: pred is called while the cache is locked and must not use the cache. Events are queued
+// without limit, so a subscriber that stops reading makes the queue grow: it must read the channel
+// until it is closed, or call Close. The events are received in the order of the changes.
+func (c *Cache[T]) SubscribeFunc(pred func(Event[T]) bool, opts ...SubscribeOption) *Subscription[T] {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Subscription[T]{
+		c:    c,
+		pred: pred,
+		out:  make(chan Event[T]),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go s.forward()
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.closed {
+		s.end()
+		return s
+	}
+	if o.replay {
+		now := nanotime()
+		for elem, e := range c.set.All() {
+			if !e.expired(now) {
+				s.push(Event[T]{Kind: EventAdded, Elem: elem, ExpiresAt: toTime(e.deadline()), Replayed: true})
+			}
+		}
+	}
+	if o.marker {
+		s.push(Event[T]{Kind: EventSynced, Replayed: true})
+	}
+	c.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Events returns the channel receiving the events, closed once the subscription or the cache is closed
+func (s *Subscription[T]) Events() <-chan Event[T] {
+	return s.out
+}
+
+// Close stops the subscription and closes its channel, dropping the events not received yet
+func (s *Subscription[T]) Close() {
+	s.c.Lock()
+	delete(s.c.subscribers, s)
+	s.c.Unlock()
+
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// push queues the given event if the subscription selects it
+func (s *Subscription[T]) push(ev Event[T]) {
+	if s.pred != nil && !s.pred(ev) {
+		return
+	}
+	s.mu.Lock()
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// end closes the channel once the queued events are forwarded
+func (s *Subscription[T]) end() {
+	s.mu.Lock()
+	s.ended = true
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// forward sends the queued events to the channel until the subscription is closed or ended
+func (s *Subscription[T]) forward() {
+	defer close(s.out)
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			ended := s.ended
+			s.queue = nil
+			s.mu.Unlock()
+			if ended {
+				return
+			}
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		ev := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- ev:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// publish sends the given event to the subscriptions, the cache must be locked
+func (c *Cache[T]) publish(ev Event[T]) {
+	for s := range c.subscribers {
+		s.push(ev)
+	}
+}
+
+// publishAdded sends an EventAdded for the given element to the subscriptions, the cache must be locked
+func (c *Cache[T]) publishAdded(elem T) {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	var expires time.Time
+	if e, ok := c.set.Get(elem); ok {
+		expires = toTime(e.deadline())
+	}
+	c.publish(Event[T]{Kind: EventAdded, Elem: elem, ExpiresAt: expires})
+}
+
+// endSubscriptions ends all subscriptions once their queued events are received, the cache must be locked
+func (c *Cache[T]) endSubscriptions() {
+	for s := range c.subscribers {
+		delete(c.subscribers, s)
+		s.end()
+	}
+}
@@ -0,0 +1,81 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Subscribe(t *testing.T) {
+	c := New[int64](time.Minute)
+	c.Add(1, 0)
+	c.Add(2, time.Hour)
+
+	all := c.Subscribe(WithReplay())
+	odd := c.SubscribeFunc(func(ev Event[int64]) bool { return ev.Elem%2 == 1 })
+
+	c.Add(3, 0)
+	c.Add(4, 0)
+	c.Delete(1)
+	c.Clear()
+	c.Close()
+
+	tests := []struct {
+		name string
+		sub  *Subscription[int64]
+		want []Event[int64]
+	}{
+		{
+			name: "Replay",
+			sub:  all,
+			want: []Event[int64]{
+				{Kind: EventAdded, Replayed: true},
+				{Kind: EventAdded, Replayed: true},
+				{Kind: EventAdded, Elem: 3},
+				{Kind: EventAdded, Elem: 4},
+				{Kind: EventRemoved, Elem: 1, Reason: RemovalDeleted},
+				{Kind: EventCleared},
+			},
+		},
+		{
+			name: "Filtered",
+			sub:  odd,
+			want: []Event[int64]{
+				{Kind: EventAdded, Elem: 3},
+				{Kind: EventRemoved, Elem: 1, Reason: RemovalDeleted},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Event[int64]
+			for ev := range tt.sub.Events() {
+				got = append(got, ev)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Events() = %v, want %v", got, tt.want)
+			}
+			for i, want := range tt.want {
+				ev := got[i]
+				if ev.Kind != want.Kind || ev.Replayed != want.Replayed || ev.Reason != want.Reason || (!want.Replayed && ev.Elem != want.Elem) {
+					t.Errorf("Events()[%v] = %+v, want %+v", i, ev, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscription_Close(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+
+	s := c.Subscribe()
+	c.Add(1, 0)
+	s.Close()
+	c.Add(2, 0)
+
+	for ev := range s.Events() {
+		if ev.Elem == 2 {
+			t.Errorf("Events() = %+v after Close", ev)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package cacheset
+//
+// Path: expirenegative.go
+//
+// Description: expirenegative.go contains the opt-in semantic of the negative durations, which expire
+// the elements instead of keeping them forever.
+package cacheset
+
+import "time"
+
+// WithNegativeExpiry makes a negative duration given to Add mean that the element has already expired
+//
+// Description: By default, like 0, a negative duration means that the element never expires, which
+// surprises the callers computing a duration from a deadline that has already passed. With
+// WithNegativeExpiry, every addition with a negative TTL or maximum idle duration, through Add and its
+// variants, GetOrAdd, Warm, WriteBehind or TryAcquire, does not add the element, and remove it with RemovalExpired if it was in the cache, as if it had expired
+// at once. The removal is counted as an expiration, not as an addition. The strict mode accepts the
+// negative durations then, see WithStrict.
+func WithNegativeExpiry() Option {
+	return func(o *options) {
+		o.negativeExpiry = true
+	}
+}
+
+// accept prepares the addition of the given element for ttl and maxIdle, and returns false if it must not
+// be put: the strict mode may reject the durations, with WithNegativeExpiry a negative duration removes the
+// element instead, and the element must be admitted by the capacity of the cache. Every addition goes
+// through accept before put, the cache must be locked.
+func (c *Cache[T]) accept(elem T, ttl, maxIdle time.Duration) (bool, error) {
+	if err := c.validate(ttl, maxIdle); err != nil {
+		return false, err
+	}
+	if c.expireNegative(elem, ttl, maxIdle) {
+		return false, nil
+	}
+	if err := c.admit(elem); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// expireNegative removes the given element with RemovalExpired and returns true if ttl or maxIdle is negative
+// with WithNegativeExpiry, the cache must be locked
+func (c *Cache[T]) expireNegative(elem T, ttl, maxIdle time.Duration) bool {
+	if !c.options.negativeExpiry || (ttl >= 0 && maxIdle >= 0) {
+		return false
+	}
+	if c.set.Contains(elem) {
+		c.remove(elem, RemovalExpired)
+	}
+	return true
+}
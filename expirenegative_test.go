@@ -0,0 +1,88 @@
+package cacheset
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCache_NegativeExpiry(t *testing.T) {
+	c := New[int](time.Minute, WithNegativeExpiry(), WithStrict())
+	defer c.Close()
+
+	_ = c.Add(1, 0)
+	watch := c.Watch(1)
+
+	tests := []struct {
+		name string
+		add  func() error
+		elem int
+	}{
+		{name: "Present", add: func() error { return c.Add(1, -time.Second) }, elem: 1},
+		{name: "Missing", add: func() error { return c.Add(2, -time.Second) }, elem: 2},
+		{name: "Idle", add: func() error { return c.AddWithIdle(3, time.Minute, -time.Second) }, elem: 3},
+		{name: "GetOrAdd", add: func() error { _, err := c.GetOrAdd(4, -time.Second); return err }, elem: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.add(); err != nil {
+				t.Errorf("Add() error = %v, want nil", err)
+			}
+			if c.Contains(tt.elem) {
+				t.Errorf("Contains(%v) = true after a negative duration, want false", tt.elem)
+			}
+		})
+	}
+	if ev := <-watch; ev.Reason != RemovalExpired {
+		t.Errorf("Watch() reason = %v, want %v", ev.Reason, RemovalExpired)
+	}
+	if s := c.Stats(); s.Adds != 1 || s.Expirations != 1 {
+		t.Errorf("Stats() = %+v, want %v add and %v expiration", s, 1, 1)
+	}
+
+	plain := New[int](time.Minute)
+	defer plain.Close()
+	_ = plain.Add(1, -time.Second)
+	if !plain.Contains(1) {
+		t.Errorf("Contains() = false without WithNegativeExpiry, want true")
+	}
+}
+
+func TestCache_NegativeExpiry_Paths(t *testing.T) {
+	t.Run("Warm", func(t *testing.T) {
+		c := New[int](time.Minute, WithNegativeExpiry())
+		defer c.Close()
+		_ = c.Add(1, 0)
+
+		if err := c.Warm(context.Background(), SeedSlice([]int{1, 2}, -time.Second)); err != nil {
+			t.Fatalf("Warm() error = %v", err)
+		}
+		if c.Contains(1) || c.Contains(2) {
+			t.Errorf("ToSlice() = %v after warming with a negative duration, want none", c.ToSlice())
+		}
+	})
+
+	t.Run("WriteBehind", func(t *testing.T) {
+		w := NewWriteBehind[int](time.Minute, WithNegativeExpiry())
+		defer w.Close()
+		_ = w.Add(1, 0)
+		_ = w.Add(1, -time.Second)
+		_ = w.Add(2, -time.Second)
+		if w.Contains(1) || w.Contains(2) {
+			t.Errorf("Contains() = true for a pending negative duration, want false")
+		}
+
+		w.Sync()
+		if got := w.Cache().ToSlice(); len(got) != 0 {
+			t.Errorf("ToSlice() = %v after a negative duration, want none", got)
+		}
+	})
+
+	t.Run("TryAcquire", func(t *testing.T) {
+		c := New[int](time.Minute, WithNegativeExpiry())
+		defer c.Close()
+		if c.TryAcquire(1, 10, -time.Second) || c.Contains(1) {
+			t.Errorf("TryAcquire() = true with a negative window, want false")
+		}
+	})
+}
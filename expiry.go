@@ -0,0 +1,138 @@
+// Package cacheset
+//
+// Path: expiry.go
+//
+// Description: expiry.go contains the storage wrapper keeping an expiration index of the elements,
+// so that the cleanings only visit the elements the index reports as due. The indexes are the
+// expiration buckets of WithExpirationBuckets and the timing wheel of WithTimingWheel.
+package cacheset
+
+import "time"
+
+// expiryIndex indexes elements by expiration time
+type expiryIndex[T comparable] interface {
+	// add indexes the given element, which expires at the given monotonic time
+	add(elem T, expires int64)
+	// remove removes the given element from the index
+	remove(elem T)
+	// due returns the elements that may have expired at now, the others being kept indexed
+	due(now int64) []T
+	// clear removes all elements from the index
+	clear()
+	// empty returns an empty index with the same settings
+	empty() expiryIndex[T]
+}
+
+// indexed is a storage indexing the elements of another storage by expiration time
+//
+// Description: The elements with a maximum idle duration, whose expiration moves with their accesses,
+// are kept in idle and checked at each cleaning, and the elements without expiration are not indexed.
+type indexed[T comparable] struct {
+	store[T]                // store holds the elements and serves the methods not changing expirations
+	index    expiryIndex[T] // index indexes the elements with an expiration time and no maximum idle duration
+	idle     map[T]struct{} // idle are the elements with a maximum idle duration
+}
+
+// newIndexed returns a storage indexing the elements of s in the given empty index
+func newIndexed[T comparable](s store[T], index expiryIndex[T]) *indexed[T] {
+	x := &indexed[T]{store: s, index: index, idle: make(map[T]struct{})}
+	for elem := range s.All() {
+		x.reindex(elem)
+	}
+	return x
+}
+
+// reindex indexes the given element by its current expiration time
+func (x *indexed[T]) reindex(elem T) {
+	x.unindex(elem)
+	e, ok := x.store.Get(elem)
+	switch {
+	case !ok:
+	case e.idle != nil:
+		x.idle[elem] = struct{}{}
+	case e.expires != 0:
+		x.index.add(elem, e.expires)
+	}
+}
+
+// unindex removes the given element from the index
+func (x *indexed[T]) unindex(elem T) {
+	delete(x.idle, elem)
+	x.index.remove(elem)
+}
+
+// ExpireAll removes the expired elements reported by the index and the expired elements with
+// a maximum idle duration, and returns them
+func (x *indexed[T]) ExpireAll() []T {
+	var removed []T
+	for _, elem := range x.index.due(nanotime()) {
+		if x.store.Expired(elem) {
+			removed = append(removed, elem)
+		} else {
+			x.reindex(elem)
+		}
+	}
+	for elem := range x.idle {
+		if x.store.Expired(elem) {
+			removed = append(removed, elem)
+		}
+	}
+
+	for _, elem := range removed {
+		x.Delete(elem)
+	}
+	return removed
+}
+
+// Expire removes the given element if it has expired and returns true if it was removed
+func (x *indexed[T]) Expire(elem T) bool {
+	if !x.store.Expire(elem) {
+		return false
+	}
+	x.unindex(elem)
+	return true
+}
+
+// Copy returns a copy of the storage with its own index
+func (x *indexed[T]) Copy() store[T] {
+	return newIndexed(x.store.Copy(), x.index.empty())
+}
+
+// Merge merges other into the storage like set.Merge and indexes the merged elements
+func (x *indexed[T]) Merge(other store[T], resolve func(a, b int64) int64) []T {
+	added := x.store.Merge(other, resolve)
+	for elem := range other.All() {
+		x.reindex(elem)
+	}
+	return added
+}
+
+// Add adds the given element and indexes it
+func (x *indexed[T]) Add(elem T, duration time.Duration) {
+	x.AddWithIdle(elem, duration, 0)
+}
+
+// AddWithIdle adds the given element and indexes it
+func (x *indexed[T]) AddWithIdle(elem T, ttl, maxIdle time.Duration) {
+	x.store.AddWithIdle(elem, ttl, maxIdle)
+	x.reindex(elem)
+}
+
+// Set sets the entry of the given element and indexes it
+func (x *indexed[T]) Set(elem T, e *entry) {
+	x.store.Set(elem, e)
+	x.reindex(elem)
+}
+
+// Delete removes the given element and its index
+func (x *indexed[T]) Delete(elem T) {
+	x.store.Delete(elem)
+	x.unindex(elem)
+}
+
+// Clear removes all elements and the index
+func (x *indexed[T]) Clear() {
+	x.store.Clear()
+	x.index.clear()
+	clear(x.idle)
+}
@@ -0,0 +1,48 @@
+// Package cacheset
+//
+// Path: expvar.go
+//
+// Description: expvar.go contains the publication of the cache statistics with the expvar package.
+package cacheset
+
+import (
+	"expvar"
+	"sync"
+)
+
+// expvarMu serializes the publications, so that two caches published under the same name concurrently
+// cannot both pass the check and make expvar.Publish panic
+var expvarMu sync.Mutex
+
+// PublishExpvar publishes the size and the statistics of the cache as an expvar variable with the given name
+//
+// Description: The variable is served as JSON under /debug/vars. Since expvar variables cannot be
+// removed, a closed cache keeps being published with a size of 0. ErrAlreadyRegistered is returned
+// if a variable with this name is already published.
+func (c *Cache[T]) PublishExpvar(name string) error {
+	return publishExpvar(name, c)
+}
+
+// publishExpvar publishes the statistics of the given cache under the given name
+func publishExpvar(name string, c AnyCache) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	if expvar.Get(name) != nil {
+		return ErrAlreadyRegistered
+	}
+	expvar.Publish(name, expvar.Func(func() any {
+		s := c.Stats()
+		return map[string]any{
+			"len":         s.Len,
+			"hits":        s.Hits,
+			"misses":      s.Misses,
+			"hit_ratio":   s.HitRatio(),
+			"adds":        s.Adds,
+			"deletes":     s.Deletes,
+			"expirations": s.Expirations,
+			"evictions":   s.Evictions,
+		}
+	}))
+	return nil
+}
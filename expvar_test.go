@@ -0,0 +1,68 @@
+package cacheset
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// expvarRuns numbers the runs of the tests, since the expvar variables cannot be removed between them
+var expvarRuns atomic.Int64
+
+// expvarName returns a name never published before by the tests
+func expvarName(t *testing.T) string {
+	return fmt.Sprintf("%s_%d", t.Name(), expvarRuns.Add(1))
+}
+
+func TestCache_PublishExpvar(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+	c.Add(1, 0)
+	c.Contains(1)
+	c.Contains(2)
+
+	name := expvarName(t)
+	if err := c.PublishExpvar(name); err != nil {
+		t.Fatalf("PublishExpvar() error = %v", err)
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		var got map[string]float64
+		if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+			t.Fatalf("PublishExpvar() invalid JSON: %v", err)
+		}
+		if got["len"] != 1 || got["hits"] != 1 || got["misses"] != 1 || got["hit_ratio"] != 0.5 {
+			t.Errorf("PublishExpvar() = %v, want len 1, 1 hit and 1 miss", got)
+		}
+	})
+
+	t.Run("AlreadyRegistered", func(t *testing.T) {
+		if err := c.PublishExpvar(name); !errors.Is(err, ErrAlreadyRegistered) {
+			t.Errorf("PublishExpvar() error = %v, want %v", err, ErrAlreadyRegistered)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		name := expvarName(t)
+		var wg sync.WaitGroup
+		var published atomic.Int64
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if c.PublishExpvar(name) == nil {
+					published.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if got := published.Load(); got != 1 {
+			t.Errorf("PublishExpvar() succeeded %v times under the same name, want %v", got, 1)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+// Package cacheset
+//
+// Path: forecast.go
+//
+// Description: forecast.go contains the counts of the upcoming expirations, which let the capacity
+// planning forecast the churn of the cache.
+package cacheset
+
+import "time"
+
+// CountExpiringBetween returns the number of elements expiring from from included to to excluded
+//
+// Description: The elements without expiration are not counted, the elements with a maximum idle duration
+// are counted at their current expiration time, and the expired elements that were not cleaned yet are
+// counted if from is in the past. The count visits all the elements under a read lock: the expiration
+// indexes of WithExpirationBuckets and WithTimingWheel are too coarse to answer for any interval.
+func (c *Cache[T]) CountExpiringBetween(from, to time.Time) int {
+	return c.ExpirationForecast(from, to.Sub(from), 1)[0]
+}
+
+// ExpirationForecast returns the number of elements expiring in each of n consecutive periods of the given
+// width starting at from, such as the next 24 hours with ExpirationForecast(time.Now(), time.Hour, 24)
+//
+// Description: The periods include their start and exclude their end, see CountExpiringBetween. It returns
+// n zeros if width is not positive, and nil if n is not positive.
+func (c *Cache[T]) ExpirationForecast(from time.Time, width time.Duration, n int) []int {
+	if n <= 0 {
+		return nil
+	}
+	counts := make([]int, n)
+	if width <= 0 {
+		return counts
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	start := fromTime(from)
+	for _, e := range c.set.All() {
+		deadline := e.deadline()
+		if deadline == 0 || deadline < start {
+			continue
+		}
+		if i := (deadline - start) / int64(width); i < int64(n) {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// CountExpiringBetween returns the number of elements of all shards expiring from from included to to
+// excluded, see Cache.CountExpiringBetween
+func (s *Sharded[T]) CountExpiringBetween(from, to time.Time) int {
+	return s.ExpirationForecast(from, to.Sub(from), 1)[0]
+}
+
+// ExpirationForecast returns the number of elements of all shards expiring in each period, one shard at a
+// time, see Cache.ExpirationForecast
+func (s *Sharded[T]) ExpirationForecast(from time.Time, width time.Duration, n int) []int {
+	var total []int
+	for _, shard := range s.shards {
+		counts := shard.ExpirationForecast(from, width, n)
+		if total == nil {
+			total = counts
+			continue
+		}
+		for i, count := range counts {
+			total[i] += count
+		}
+	}
+	return total
+}
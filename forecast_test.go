@@ -0,0 +1,40 @@
+package cacheset
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache_ExpirationForecast(t *testing.T) {
+	c := New[int](time.Hour)
+	defer c.Close()
+
+	now := time.Now()
+	for i, ttl := range []time.Duration{30 * time.Minute, 90 * time.Minute, 100 * time.Minute, 5 * time.Hour, 0} {
+		_ = c.Add(i, ttl)
+	}
+	_ = c.AddWithIdle(10, 0, 150*time.Minute)
+
+	if got := c.ExpirationForecast(now, time.Hour, 3); !reflect.DeepEqual(got, []int{1, 2, 1}) {
+		t.Errorf("ExpirationForecast() = %v, want %v", got, []int{1, 2, 1})
+	}
+	if got := c.CountExpiringBetween(now.Add(time.Hour), now.Add(2*time.Hour)); got != 2 {
+		t.Errorf("CountExpiringBetween() = %v, want %v", got, 2)
+	}
+	if got := c.CountExpiringBetween(now, now.Add(24*time.Hour)); got != 5 {
+		t.Errorf("CountExpiringBetween() = %v, want %v", got, 5)
+	}
+	if got := c.ExpirationForecast(now, 0, 2); !reflect.DeepEqual(got, []int{0, 0}) {
+		t.Errorf("ExpirationForecast() = %v for a zero width, want %v", got, []int{0, 0})
+	}
+
+	s := NewSharded[int](time.Hour, WithShards(4))
+	defer s.Close()
+	for i := range 8 {
+		_ = s.Add(i, 30*time.Minute)
+	}
+	if got := s.CountExpiringBetween(now, now.Add(time.Hour)); got != 8 {
+		t.Errorf("Sharded.CountExpiringBetween() = %v, want %v", got, 8)
+	}
+}
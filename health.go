@@ -0,0 +1,121 @@
+// Package cacheset
+//
+// Path: health.go
+//
+// Description: health.go contains the health reporting of the cleaning goroutine.
+package cacheset
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthStatus describes the state of the cache's cleaning goroutine
+type HealthStatus struct {
+	Started           time.Time     // Started is the time the cleaning goroutine was started
+	LastSweep         time.Time     // LastSweep is the time the last successful cleaning started, zero if none
+	NextSweep         time.Time     // NextSweep is the time of the next coalesced cleaning, zero if none is scheduled
+	CleanInterval     time.Duration // CleanInterval is the interval between two cleanings
+	LastSweepDuration time.Duration // LastSweepDuration is the duration of the last successful cleaning
+	LastRemoved       int           // LastRemoved is the number of elements removed by the last successful cleaning
+	Sweeps            uint64        // Sweeps is the number of successful cleanings
+	Wakeups           uint64        // Wakeups is the number of times the cleaning goroutine woke up to clean the cache
+	Alive             bool          // Alive is true while the cleaning goroutine is running
+	Coalesced         bool          // Coalesced is true if the cleanings are skipped while no element can expire
+}
+
+// Healthy returns true if the cleaning goroutine is running and has cleaned the cache in the last two intervals
+//
+// Description: With WithCoalescedCleaning, the cleaning goroutine may sleep for longer: it is healthy
+// unless its next cleaning is overdue by two intervals.
+func (h HealthStatus) Healthy() bool {
+	if !h.Alive {
+		return false
+	}
+	if h.Coalesced {
+		return h.NextSweep.IsZero() || time.Since(h.NextSweep) <= 2*h.CleanInterval
+	}
+	last := h.Started
+	if h.LastSweep.After(last) {
+		last = h.LastSweep
+	}
+	return time.Since(last) <= 2*h.CleanInterval
+}
+
+// WakeupsPerHour returns the average number of wakeups of the cleaning goroutine per hour since its start
+func (h HealthStatus) WakeupsPerHour() float64 {
+	elapsed := time.Since(h.Started)
+	if h.Started.IsZero() || elapsed <= 0 {
+		return 0
+	}
+	return float64(h.Wakeups) / elapsed.Hours()
+}
+
+// health records the activity of the cleaning goroutine
+type health struct {
+	started  time.Time
+	last     time.Time
+	duration time.Duration
+	removed  int
+	sweeps   uint64
+	wakeups  uint64
+	mu       sync.Mutex
+	alive    atomic.Bool
+}
+
+// start records the start of a cleaning goroutine, forgetting the previous cleanings
+func (h *health) start(started time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.started = started
+	h.last = time.Time{}
+	h.duration = 0
+	h.removed = 0
+	h.sweeps = 0
+	h.wakeups = 0
+}
+
+// wakeup records a wakeup of the cleaning goroutine
+func (h *health) wakeup() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.wakeups++
+}
+
+// sweep records a successful cleaning
+func (h *health) sweep(start time.Time, duration time.Duration, removed int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last = start
+	h.duration = duration
+	h.removed = removed
+	h.sweeps++
+}
+
+// Health returns the state of the cache's cleaning goroutine
+func (c *Cache[T]) Health() HealthStatus {
+	var next time.Time
+	if n := c.nextClean.Load(); n != 0 {
+		next = toTime(n)
+	}
+
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+
+	return HealthStatus{
+		Started:           c.health.started,
+		LastSweep:         c.health.last,
+		NextSweep:         next,
+		CleanInterval:     c.cleanInterval,
+		LastSweepDuration: c.health.duration,
+		LastRemoved:       c.health.removed,
+		Sweeps:            c.health.sweeps,
+		Wakeups:           c.health.wakeups,
+		Alive:             c.health.alive.Load(),
+		Coalesced:         c.options.coalescing(),
+	}
+}
@@ -0,0 +1,130 @@
+// Package cacheset
+//
+// Path: history.go
+//
+// Description: history.go contains the rolling history of the counters of the cache, which gives the
+// trends of the lightweight deployments without any external metrics system.
+package cacheset
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// StatsBucket holds the counters of the cache over a period of time
+type StatsBucket struct {
+	Start       time.Time // Start is the start of the period
+	Hits        uint64    // Hits is the number of Contains calls that found the element during the period
+	Misses      uint64    // Misses is the number of Contains calls that did not find the element during the period
+	Adds        uint64    // Adds is the number of elements added during the period
+	Deletes     uint64    // Deletes is the number of elements removed with Delete or Clear during the period
+	Expirations uint64    // Expirations is the number of elements removed because they expired during the period
+	Evictions   uint64    // Evictions is the number of elements evicted during the period
+}
+
+// WithStatsHistory keeps in Stats.History the counters of the last n periods of the given width, such as
+// the last 60 minutes with WithStatsHistory(time.Minute, 60)
+//
+// Description: A goroutine per cache closes a period every width, by taking the difference of the counters
+// since the previous one, so the hot paths pay nothing. The last bucket is the current period, which is not
+// over yet. Values of n below 1 and widths of 0 or less disable the history.
+func WithStatsHistory(width time.Duration, n int) Option {
+	return func(o *options) {
+		o.historyWidth, o.historyBuckets = width, n
+		if width <= 0 || n < 1 {
+			o.historyWidth, o.historyBuckets = 0, 0
+		}
+	}
+}
+
+// statsHistory is the rolling history of the counters of a cache
+type statsHistory struct {
+	mu      sync.Mutex
+	n       int               // n is the number of buckets kept, the current one included
+	buckets []StatsBucket     // buckets are the closed periods, oldest first
+	start   time.Time         // start is the start of the current period
+	base    [statCount]uint64 // base are the counters at the start of the current period
+}
+
+// newStatsHistory returns an empty history of n buckets whose current period starts at start
+func newStatsHistory(n int, start time.Time) *statsHistory {
+	return &statsHistory{n: n, buckets: make([]StatsBucket, 0, n), start: start}
+}
+
+// roll closes the current period at now, given the current counters
+func (h *statsHistory) roll(now time.Time, counts [statCount]uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.n > 1 {
+		if len(h.buckets) == h.n-1 {
+			h.buckets = slices.Delete(h.buckets, 0, 1)
+		}
+		h.buckets = append(h.buckets, h.bucket(counts))
+	}
+	h.start, h.base = now, counts
+}
+
+// bucket returns the current period given the current counters, the history must be locked
+func (h *statsHistory) bucket(counts [statCount]uint64) StatsBucket {
+	return StatsBucket{
+		Start:       h.start,
+		Hits:        counts[statHits] - h.base[statHits],
+		Misses:      counts[statMisses] - h.base[statMisses],
+		Adds:        counts[statAdds] - h.base[statAdds],
+		Deletes:     counts[statDeletes] - h.base[statDeletes],
+		Expirations: counts[statExpirations] - h.base[statExpirations],
+		Evictions:   counts[statEvictions] - h.base[statEvictions],
+	}
+}
+
+// snapshot returns the closed periods and the current one, oldest first, given the current counters
+func (h *statsHistory) snapshot(counts [statCount]uint64) []StatsBucket {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append(slices.Clone(h.buckets), h.bucket(counts))
+}
+
+// counts returns the current value of each counter
+func (s *stats) counts() [statCount]uint64 {
+	var counts [statCount]uint64
+	for i := range counts {
+		counts[i] = s.load(i)
+	}
+	return counts
+}
+
+// recordHistory closes a period of the history every width until the cache is closed
+func (c *Cache[T]) recordHistory(history *statsHistory, width time.Duration, closed <-chan struct{}) {
+	ticker := time.NewTicker(width)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case now := <-ticker.C:
+			history.roll(now, c.stats.counts())
+		}
+	}
+}
+
+// mergeHistory adds the buckets of b to the buckets of a, aligned on the current periods, and returns the sum
+func mergeHistory(a, b []StatsBucket) []StatsBucket {
+	if len(b) > len(a) {
+		a, b = b, a
+	}
+	sum := slices.Clone(a)
+	for i := range b {
+		s, o := &sum[len(sum)-1-i], b[len(b)-1-i]
+		s.Hits += o.Hits
+		s.Misses += o.Misses
+		s.Adds += o.Adds
+		s.Deletes += o.Deletes
+		s.Expirations += o.Expirations
+		s.Evictions += o.Evictions
+	}
+	return sum
+}
@@ -0,0 +1,61 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsHistory_Roll(t *testing.T) {
+	start := time.Now()
+	h := newStatsHistory(3, start)
+
+	var counts [statCount]uint64
+	for i := range 4 {
+		counts[statAdds] += uint64(i + 1)
+		h.roll(start.Add(time.Duration(i+1)*time.Minute), counts)
+	}
+	counts[statAdds] += 10
+
+	got := h.snapshot(counts)
+	want := []uint64{3, 4, 10}
+	if len(got) != len(want) {
+		t.Fatalf("snapshot() = %+v, want %v buckets", got, len(want))
+	}
+	for i := range want {
+		if got[i].Adds != want[i] {
+			t.Errorf("snapshot()[%v].Adds = %v, want %v", i, got[i].Adds, want[i])
+		}
+	}
+	if !got[2].Start.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("snapshot()[2].Start = %v, want %v", got[2].Start, start.Add(4*time.Minute))
+	}
+}
+
+func TestCache_StatsHistory(t *testing.T) {
+	c := New[int](time.Hour, WithStatsHistory(20*time.Millisecond, 60))
+	defer c.Close()
+
+	_ = c.Add(1, 0)
+	c.Contains(1)
+	time.Sleep(50 * time.Millisecond)
+	_ = c.Add(2, 0)
+
+	history := c.Stats().History
+	if len(history) < 2 {
+		t.Fatalf("Stats().History = %+v, want at least %v buckets", history, 2)
+	}
+	var adds, hits uint64
+	for _, b := range history {
+		adds += b.Adds
+		hits += b.Hits
+	}
+	if adds != 2 || hits != 1 || history[len(history)-1].Adds != 1 {
+		t.Errorf("Stats().History = %+v, want %v adds and %v hit, the last add in the current bucket", history, 2, 1)
+	}
+
+	plain := New[int](time.Hour)
+	defer plain.Close()
+	if got := plain.Stats().History; got != nil {
+		t.Errorf("Stats().History = %v without WithStatsHistory, want nil", got)
+	}
+}
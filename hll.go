@@ -0,0 +1,111 @@
+// Package cacheset
+//
+// Path: hll.go
+//
+// Description: hll.go contains the HyperLogLog counter of the distinct elements added to the cache.
+package cacheset
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// hllPrecision is the number of bits of the hash selecting a register, giving a standard error of about 0.8%
+const hllPrecision = 14
+
+// WithUniqueAddsCounter counts approximately the distinct elements added to the cache, reported by ApproxUniqueAdds
+//
+// Description: The counter uses a HyperLogLog sketch of 16 KiB whatever the number of distinct elements.
+// It is reset every period, a period of 0 or less meaning never.
+func WithUniqueAddsCounter(period time.Duration) Option {
+	return func(o *options) {
+		o.uniqueAdds = true
+		o.uniqueAddsPeriod = period
+	}
+}
+
+// ApproxUniqueAdds returns the approximate number of distinct elements added since the last reset of the counter
+//
+// Description: ApproxUniqueAdds returns 0 unless the cache was created with WithUniqueAddsCounter.
+func (c *Cache[T]) ApproxUniqueAdds() uint64 {
+	if c.unique == nil {
+		return 0
+	}
+	return c.unique.estimate()
+}
+
+// recordUnique records the addition of the given element in the distinct elements counter
+func (c *Cache[T]) recordUnique(elem T) {
+	if c.unique != nil {
+		c.unique.add(elem)
+	}
+}
+
+// hyperLogLog is a HyperLogLog sketch of the distinct elements added since its last reset
+type hyperLogLog[T comparable] struct {
+	registers []uint8      // registers hold the maximum rank seen for each register
+	seed      maphash.Seed // seed is the seed of the elements' hashes
+	reset     int64        // reset is the monotonic time of the last reset
+	period    int64        // period is the duration between two resets in nanoseconds, 0 meaning never
+	mu        sync.Mutex
+}
+
+// newHyperLogLog returns a new HyperLogLog sketch reset every period
+func newHyperLogLog[T comparable](period time.Duration) *hyperLogLog[T] {
+	return &hyperLogLog[T]{
+		registers: make([]uint8, 1<<hllPrecision),
+		seed:      maphash.MakeSeed(),
+		reset:     nanotime(),
+		period:    max(int64(period), 0),
+	}
+}
+
+// expire resets the sketch if its period has elapsed, h.mu must be locked
+func (h *hyperLogLog[T]) expire() {
+	if now := nanotime(); h.period > 0 && now-h.reset >= h.period {
+		clear(h.registers)
+		h.reset = now
+	}
+}
+
+// add records the given element
+func (h *hyperLogLog[T]) add(elem T) {
+	x := maphash.Comparable(h.seed, elem)
+	idx := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire()
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// estimate returns the estimated number of distinct elements
+func (h *hyperLogLog[T]) estimate() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire()
+
+	m := float64(len(h.registers))
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
@@ -0,0 +1,44 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_ApproxUniqueAdds(t *testing.T) {
+	c := New[int64](time.Minute, WithUniqueAddsCounter(0))
+	defer c.Close()
+
+	for i := int64(0); i < 100_000; i++ {
+		c.Add(i%50_000, 0)
+	}
+
+	t.Run("Estimate", func(t *testing.T) {
+		if got := c.ApproxUniqueAdds(); got < 48_000 || got > 52_000 {
+			t.Errorf("ApproxUniqueAdds() = %v, want about %v", got, 50_000)
+		}
+	})
+
+	t.Run("Small", func(t *testing.T) {
+		d := New[string](time.Minute, WithUniqueAddsCounter(0))
+		defer d.Close()
+		d.Add("foo", 0)
+		d.Add("bar", 0)
+		d.Add("foo", 0)
+		if got := d.ApproxUniqueAdds(); got != 2 {
+			t.Errorf("ApproxUniqueAdds() = %v, want %v", got, 2)
+		}
+	})
+}
+
+func Test_hyperLogLog_reset(t *testing.T) {
+	h := newHyperLogLog[int64](10 * time.Millisecond)
+	h.add(1)
+	time.Sleep(20 * time.Millisecond)
+
+	t.Run("Reset", func(t *testing.T) {
+		if got := h.estimate(); got != 0 {
+			t.Errorf("estimate() = %v, want %v", got, 0)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+// Package cacheset
+//
+// Path: hotkeys.go
+//
+// Description: hotkeys.go contains the sampling-based tracking of the most accessed elements.
+package cacheset
+
+import (
+	"container/heap"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyFreq is an element and its estimated number of accesses
+type KeyFreq[T comparable] struct {
+	Elem  T      // Elem is the element
+	Count uint64 // Count is the estimated number of accesses, it may overestimate the real number
+}
+
+// WithHotKeys tracks the k most accessed elements over a rolling window, reported by TopK
+//
+// Description: Accesses are Contains, Exists and Add calls. The tracking uses the Space-Saving algorithm,
+// which keeps k counters whatever the number of distinct elements. The counts cover the current window
+// and the previous one, so they always span at least one window.
+func WithHotKeys(k int, window time.Duration) Option {
+	return func(o *options) {
+		o.hotKeys = max(k, 0)
+		o.hotKeysWindow = window
+	}
+}
+
+// WithHotKeySampling records only one access out of rate on average, 1 by default
+//
+// Description: The counts reported by TopK are scaled back by rate.
+func WithHotKeySampling(rate int) Option {
+	return func(o *options) {
+		o.hotKeysSampling = max(rate, 1)
+	}
+}
+
+// TopK returns the n most accessed elements with their estimated number of accesses, most accessed first
+//
+// Description: TopK returns nil unless the cache was created with WithHotKeys.
+func (c *Cache[T]) TopK(n int) []KeyFreq[T] {
+	if c.hot == nil {
+		return nil
+	}
+	return c.hot.topK(n)
+}
+
+// recordHot records an access to the given element in the hot keys tracker
+func (c *Cache[T]) recordHot(elem T) {
+	if c.hot != nil {
+		c.hot.record(elem)
+	}
+}
+
+// hotKeys tracks the most accessed elements over a rolling window
+type hotKeys[T comparable] struct {
+	current     *spaceSaving[T] // current counts the accesses of the current window
+	previous    *spaceSaving[T] // previous counts the accesses of the previous window
+	windowStart int64           // windowStart is the monotonic start time of the current window
+	window      int64           // window is the duration of a window in nanoseconds
+	k           int             // k is the number of counters of each window
+	sampling    int             // sampling is the average number of accesses per recorded access
+	mu          sync.Mutex
+}
+
+// newHotKeys returns a tracker of the k most accessed elements
+func newHotKeys[T comparable](k int, window time.Duration, sampling int) *hotKeys[T] {
+	return &hotKeys[T]{
+		current:     newSpaceSaving[T](k),
+		previous:    newSpaceSaving[T](k),
+		windowStart: nanotime(),
+		window:      int64(window),
+		k:           k,
+		sampling:    max(sampling, 1),
+	}
+}
+
+// record records an access to the given element, subject to sampling
+func (h *hotKeys[T]) record(elem T) {
+	if h.sampling > 1 && rand.IntN(h.sampling) != 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if now := nanotime(); h.window > 0 && now-h.windowStart >= h.window {
+		h.previous, h.current = h.current, newSpaceSaving[T](h.k)
+		if now-h.windowStart >= 2*h.window {
+			h.previous = newSpaceSaving[T](h.k)
+		}
+		h.windowStart = now
+	}
+	h.current.add(elem)
+}
+
+// topK returns the n most accessed elements of the current and previous windows
+func (h *hotKeys[T]) topK(n int) []KeyFreq[T] {
+	h.mu.Lock()
+	counts := make(map[T]uint64, 2*h.k)
+	for _, w := range []*spaceSaving[T]{h.previous, h.current} {
+		for _, c := range w.counters {
+			counts[c.elem] += c.count
+		}
+	}
+	h.mu.Unlock()
+
+	top := make([]KeyFreq[T], 0, len(counts))
+	for elem, count := range counts {
+		top = append(top, KeyFreq[T]{Elem: elem, Count: count * uint64(h.sampling)})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].Count > top[j].Count
+	})
+
+	return top[:min(max(n, 0), len(top))]
+}
+
+// ssCounter is a counter of the Space-Saving algorithm
+type ssCounter[T comparable] struct {
+	elem  T
+	count uint64
+	index int
+}
+
+// spaceSaving counts the most frequent elements with a fixed number of counters
+//
+// Description: When all counters are used, the counter with the smallest count is given to the new element,
+// which inherits its count. The counters form a min-heap on their count.
+type spaceSaving[T comparable] struct {
+	elems    map[T]*ssCounter[T]
+	counters []*ssCounter[T]
+	k        int
+}
+
+// newSpaceSaving returns a Space-Saving counter with k counters
+func newSpaceSaving[T comparable](k int) *spaceSaving[T] {
+	return &spaceSaving[T]{
+		elems: make(map[T]*ssCounter[T], k),
+		k:     k,
+	}
+}
+
+// add counts an occurrence of the given element
+func (s *spaceSaving[T]) add(elem T) {
+	if c, ok := s.elems[elem]; ok {
+		c.count++
+		heap.Fix(s, c.index)
+		return
+	}
+	if len(s.counters) < s.k {
+		c := &ssCounter[T]{elem: elem, count: 1}
+		s.elems[elem] = c
+		heap.Push(s, c)
+		return
+	}
+
+	c := s.counters[0]
+	delete(s.elems, c.elem)
+	c.elem = elem
+	c.count++
+	s.elems[elem] = c
+	heap.Fix(s, 0)
+}
+
+func (s *spaceSaving[T]) Len() int           { return len(s.counters) }
+func (s *spaceSaving[T]) Less(i, j int) bool { return s.counters[i].count < s.counters[j].count }
+
+func (s *spaceSaving[T]) Swap(i, j int) {
+	s.counters[i], s.counters[j] = s.counters[j], s.counters[i]
+	s.counters[i].index = i
+	s.counters[j].index = j
+}
+
+func (s *spaceSaving[T]) Push(x any) {
+	c := x.(*ssCounter[T])
+	c.index = len(s.counters)
+	s.counters = append(s.counters, c)
+}
+
+func (s *spaceSaving[T]) Pop() any {
+	c := s.counters[len(s.counters)-1]
+	s.counters = s.counters[:len(s.counters)-1]
+	return c
+}
@@ -0,0 +1,50 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_TopK(t *testing.T) {
+	c := New[int64](time.Minute, WithHotKeys(10, time.Minute))
+	defer c.Close()
+
+	for i := int64(0); i < 1000; i++ {
+		c.Contains(i)
+		c.Contains(1)
+		if i%2 == 0 {
+			c.Contains(2)
+		}
+	}
+
+	t.Run("TopK", func(t *testing.T) {
+		top := c.TopK(2)
+		if len(top) != 2 || top[0].Elem != 1 || top[1].Elem != 2 {
+			t.Fatalf("TopK() = %v, want elements %v and %v first", top, 1, 2)
+		}
+		if top[0].Count < 1000 {
+			t.Errorf("TopK() count = %v, want at least %v", top[0].Count, 1000)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		d := New[int64](time.Minute)
+		defer d.Close()
+		if top := d.TopK(2); top != nil {
+			t.Errorf("TopK() = %v, want nil", top)
+		}
+	})
+}
+
+func Test_hotKeys_window(t *testing.T) {
+	h := newHotKeys[int64](10, 10*time.Millisecond, 1)
+	h.record(1)
+	time.Sleep(25 * time.Millisecond)
+	h.record(2)
+
+	t.Run("Rotate", func(t *testing.T) {
+		if top := h.topK(10); len(top) != 1 || top[0].Elem != 2 {
+			t.Errorf("topK() = %v, want only %v", top, 2)
+		}
+	})
+}
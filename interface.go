@@ -0,0 +1,40 @@
+// Package cacheset
+//
+// Path: interface.go
+//
+// Description: interface.go contains the CacheSet interface, the method set shared by the caches of the
+// package and expected from the alternative backends.
+package cacheset
+
+import (
+	"context"
+	"time"
+)
+
+// CacheSet is the method set of a set whose elements expire, implemented by Cache, Sharded, WriteBehind and
+// Tiered
+//
+// Description: Code depending on CacheSet rather than on a concrete type can switch backends, and
+// cachetest.RunConformance checks that a backend behaves like Cache: an element is visible from its
+// addition until it expires, is deleted or the set is cleared, a duration of 0 means no expiration,
+// and ExpireAll removes the expired elements at once. The counters of Stats a backend does not track
+// are left at 0.
+type CacheSet[T comparable] interface {
+	Add(elem T, ttl time.Duration) error
+	Contains(elem T) bool
+	Delete(elem T)
+	Clear()
+	Len() int
+	ToSlice() []T
+	ExpireAll()
+	Stats() Stats
+	Close()
+	Shutdown(ctx context.Context) error
+}
+
+var (
+	_ CacheSet[int] = (*Cache[int])(nil)
+	_ CacheSet[int] = (*Sharded[int])(nil)
+	_ CacheSet[int] = (*WriteBehind[int])(nil)
+	_ CacheSet[int] = (*Tiered[int])(nil)
+)
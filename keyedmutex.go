@@ -0,0 +1,143 @@
+// Package cacheset
+//
+// Path: keyedmutex.go
+//
+// Description: keyedmutex.go contains the KeyedMutex type providing a lock per key.
+package cacheset
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyedMutex is a set of mutexes indexed by key, guarding per-key critical sections
+//
+// Description: The mutex of a key is created on its first use and released once it has not been
+// used for the idle duration given to NewKeyedMutex. The zero value is not usable.
+//
+//	locks := cacheset.NewKeyedMutex[string](time.Minute)
+//	defer locks.Close()
+//	locks.Lock("user-42")
+//	defer locks.Unlock("user-42")
+type KeyedMutex[T comparable] struct {
+	locks     map[T]*keyedLock // locks are the mutexes of the keys in use or recently used
+	close     chan struct{}    // close stops the cleaning goroutine
+	closeOnce sync.Once        // closeOnce ensures that the cleaning goroutine is stopped once
+	idle      int64            // idle is the duration in nanoseconds after which an unused mutex is released
+	mu        sync.Mutex
+}
+
+// keyedLock is the mutex of a key
+type keyedLock struct {
+	mu       sync.Mutex // mu is the mutex of the key
+	refs     int        // refs is the number of goroutines holding or waiting for the mutex
+	lastUsed int64      // lastUsed is the monotonic time of the last Unlock
+}
+
+// NewKeyedMutex returns a KeyedMutex releasing the mutexes not used for the given duration
+//
+// Description: A goroutine releases the idle mutexes every idle duration until Close is called.
+func NewKeyedMutex[T comparable](idle time.Duration) *KeyedMutex[T] {
+	if idle <= 0 {
+		idle = time.Minute
+	}
+	m := &KeyedMutex[T]{
+		locks: make(map[T]*keyedLock),
+		close: make(chan struct{}),
+		idle:  int64(idle),
+	}
+
+	ticker := time.NewTicker(idle)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.close:
+				return
+			case <-ticker.C:
+				m.clean()
+			}
+		}
+	}()
+
+	return m
+}
+
+// acquire returns the mutex of the given key, creating it if needed, the KeyedMutex must be locked
+func (m *KeyedMutex[T]) acquire(key T) *keyedLock {
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	return l
+}
+
+// Lock locks the mutex of the given key, blocking until it is available
+func (m *KeyedMutex[T]) Lock(key T) {
+	m.mu.Lock()
+	l := m.acquire(key)
+	l.refs++
+	m.mu.Unlock()
+
+	l.mu.Lock()
+}
+
+// TryLock tries to lock the mutex of the given key without blocking and returns true if it succeeded
+func (m *KeyedMutex[T]) TryLock(key T) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l := m.acquire(key)
+	if !l.mu.TryLock() {
+		return false
+	}
+	l.refs++
+
+	return true
+}
+
+// Unlock unlocks the mutex of the given key
+//
+// Description: Like sync.Mutex, it is a run-time error if the mutex of the key is not locked.
+func (m *KeyedMutex[T]) Unlock(key T) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok || l.refs == 0 {
+		m.mu.Unlock()
+		panic("cacheset: unlock of unlocked key")
+	}
+	l.refs--
+	l.lastUsed = nanotime()
+	m.mu.Unlock()
+
+	l.mu.Unlock()
+}
+
+// Len returns the number of mutexes currently allocated
+func (m *KeyedMutex[T]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.locks)
+}
+
+// Close stops the goroutine releasing the idle mutexes
+func (m *KeyedMutex[T]) Close() {
+	m.closeOnce.Do(func() {
+		close(m.close)
+	})
+}
+
+// clean releases the mutexes not used for the idle duration
+func (m *KeyedMutex[T]) clean() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := nanotime()
+	for key, l := range m.locks {
+		if l.refs == 0 && now-l.lastUsed >= m.idle {
+			delete(m.locks, key)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cacheset
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	m := NewKeyedMutex[string](10 * time.Millisecond)
+	defer m.Close()
+
+	t.Run("Exclusive", func(t *testing.T) {
+		var wg sync.WaitGroup
+		counters := map[string]*int{"a": new(int), "b": new(int)}
+		for i := 0; i < 100; i++ {
+			for key, counter := range counters {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					m.Lock(key)
+					defer m.Unlock(key)
+					*counter++
+				}()
+			}
+		}
+		wg.Wait()
+		if *counters["a"] != 100 || *counters["b"] != 100 {
+			t.Errorf("Lock() counters = %v, %v, want 100 each", *counters["a"], *counters["b"])
+		}
+	})
+
+	t.Run("TryLock", func(t *testing.T) {
+		m.Lock("a")
+		if m.TryLock("a") {
+			t.Errorf("TryLock() = %v, want %v", true, false)
+		}
+		if !m.TryLock("c") {
+			t.Errorf("TryLock() = %v, want %v", false, true)
+		}
+		m.Unlock("a")
+		m.Unlock("c")
+	})
+
+	t.Run("Idle", func(t *testing.T) {
+		m.Lock("held")
+		time.Sleep(50 * time.Millisecond)
+		if got := m.Len(); got != 1 {
+			t.Errorf("Len() = %v, want %v", got, 1)
+		}
+		m.Unlock("held")
+	})
+
+	t.Run("Unlocked", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Unlock() did not panic")
+			}
+		}()
+		m.Unlock("missing")
+	})
+}
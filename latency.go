@@ -0,0 +1,92 @@
+// Package cacheset
+//
+// Path: latency.go
+//
+// Description: latency.go contains the sampled latency histograms of the operations of the cache, which
+// show when the contention on its lock degrades the tail latencies.
+package cacheset
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBucketBounds is the number of bounds of the latency histograms
+const latencyBucketBounds = 27
+
+// latencyBounds are the upper bounds of the buckets of the latency histograms, doubling from 64ns to about 4s,
+// which keeps the relative error of each bucket under a factor of 2 like an HDR histogram of low precision
+var latencyBounds = func() []time.Duration {
+	bounds := make([]time.Duration, latencyBucketBounds)
+	for i := range bounds {
+		bounds[i] = 64 << i
+	}
+	return bounds
+}()
+
+// WithLatencyHistograms reports in Stats the latencies of one in sampling calls of Add and Contains, and of
+// all the cleanings
+//
+// Description: The latencies include the time spent waiting for the lock, so a growing tail hints at contention.
+// Measuring a call reads the clock twice, sampling keeps the overhead low on the hot paths: 1 measures every
+// call, and values below 1 are raised to 1. The histograms are exported by cachesetprom.
+func WithLatencyHistograms(sampling int) Option {
+	return func(o *options) {
+		o.latencySampling = max(sampling, 1)
+	}
+}
+
+// latencies are the latency histograms of the operations of a cache
+type latencies struct {
+	sampling uint32           // sampling is the average number of calls per measured call
+	add      latencyHistogram // add are the latencies of Add
+	contains latencyHistogram // contains are the latencies of Contains
+	sweep    latencyHistogram // sweep are the durations of the cleanings
+}
+
+// latencyHistogram is a histogram of latencies updated without locking
+type latencyHistogram struct {
+	counts [latencyBucketBounds + 1]atomic.Uint64 // counts are the numbers of latencies in each bucket, one more than the bounds
+	sum    atomic.Int64                           // sum is the sum of the latencies in nanoseconds
+}
+
+// newLatencies returns empty latency histograms measuring one in sampling calls
+func newLatencies(sampling int) *latencies {
+	return &latencies{sampling: uint32(sampling)}
+}
+
+// sample returns the start time of the call to measure, 0 if the call is not sampled
+func (l *latencies) sample() int64 {
+	if l == nil || (l.sampling > 1 && rand.Uint32N(l.sampling) != 0) {
+		return 0
+	}
+	return nanotime()
+}
+
+// observe records the latency of the call started at start, if it was sampled
+func (h *latencyHistogram) observe(start int64) {
+	if start == 0 {
+		return
+	}
+	h.record(time.Duration(nanotime() - start))
+}
+
+// record records the given latency
+func (h *latencyHistogram) record(d time.Duration) {
+	i, _ := slices.BinarySearch(latencyBounds, d)
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// histogram returns a copy of the latencies
+func (h *latencyHistogram) histogram() Histogram {
+	out := Histogram{Bounds: latencyBounds, Counts: make([]uint64, len(h.counts))}
+	for i := range h.counts {
+		out.Counts[i] = h.counts[i].Load()
+		out.Count += out.Counts[i]
+	}
+	out.Sum = time.Duration(h.sum.Load())
+	return out
+}
@@ -0,0 +1,68 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_LatencyHistograms(t *testing.T) {
+	c := New[int](time.Minute, WithLatencyHistograms(1))
+	defer c.Close()
+
+	for i := range 100 {
+		_ = c.Add(i, 0)
+		c.Contains(i)
+	}
+	c.clean()
+
+	st := c.Stats()
+	if st.AddLatency.Count != 100 || st.ContainsLatency.Count != 100 || st.SweepLatency.Count != 1 {
+		t.Errorf("Stats() latency counts = %v, %v, %v, want %v, %v, %v",
+			st.AddLatency.Count, st.ContainsLatency.Count, st.SweepLatency.Count, 100, 100, 1)
+	}
+	if st.AddLatency.Mean() <= 0 || len(st.AddLatency.Counts) != len(latencyBounds)+1 {
+		t.Errorf("Stats().AddLatency = %+v, want a positive mean", st.AddLatency)
+	}
+
+	t.Run("Sampling", func(t *testing.T) {
+		s := New[int](time.Minute, WithLatencyHistograms(10))
+		defer s.Close()
+		for i := range 10000 {
+			s.Contains(i)
+		}
+		if n := s.Stats().ContainsLatency.Count; n < 500 || n > 2000 {
+			t.Errorf("Stats().ContainsLatency.Count = %v, want about %v", n, 1000)
+		}
+	})
+
+	t.Run("Sharded", func(t *testing.T) {
+		s := NewSharded[int](time.Minute, WithShards(4), WithLatencyHistograms(1))
+		defer s.Close()
+		for i := range 100 {
+			_ = s.Add(i, 0)
+		}
+		if n := s.Stats().AddLatency.Count; n != 100 {
+			t.Errorf("Stats().AddLatency.Count = %v, want %v", n, 100)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		d := New[int](time.Minute)
+		defer d.Close()
+		_ = d.Add(1, 0)
+		if d.Stats().AddLatency.Bounds != nil {
+			t.Errorf("Stats().AddLatency = %+v, want empty", d.Stats().AddLatency)
+		}
+	})
+}
+
+func Test_latencyHistogram_record(t *testing.T) {
+	var h latencyHistogram
+	h.record(64)
+	h.record(65)
+	h.record(time.Hour)
+	got := h.histogram()
+	if got.Counts[0] != 1 || got.Counts[1] != 1 || got.Counts[len(latencyBounds)] != 1 {
+		t.Errorf("histogram() = %v, want one latency in the first, second and last buckets", got.Counts)
+	}
+}
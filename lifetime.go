@@ -0,0 +1,165 @@
+// Package cacheset
+//
+// Path: lifetime.go
+//
+// Description: lifetime.go contains the histograms of the remaining times to live of the elements and of
+// the lifetimes of the removed elements.
+package cacheset
+
+import (
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// histogramBounds are the upper bounds of the buckets of a Histogram
+var histogramBounds = []time.Duration{
+	time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond,
+	time.Second, 10 * time.Second, time.Minute, 10 * time.Minute,
+	time.Hour, 6 * time.Hour, 24 * time.Hour,
+}
+
+// Histogram is a distribution of durations
+type Histogram struct {
+	Bounds []time.Duration // Bounds are the inclusive upper bounds of the buckets but the last one, which has none
+	Counts []uint64        // Counts are the numbers of durations in each bucket, one more than the bounds
+	Count  uint64          // Count is the number of durations
+	Sum    time.Duration   // Sum is the sum of the durations
+}
+
+// newHistogram returns an empty histogram with the default bounds
+func newHistogram() Histogram {
+	return Histogram{Bounds: histogramBounds, Counts: make([]uint64, len(histogramBounds)+1)}
+}
+
+// Mean returns the mean of the durations, 0 if there is none
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// observe adds the given duration to the histogram
+func (h *Histogram) observe(d time.Duration) {
+	i, _ := slices.BinarySearch(h.Bounds, d)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+// merge adds the durations of the given histogram, which must have the same bounds
+func (h *Histogram) merge(o Histogram) {
+	if o.Bounds == nil {
+		return
+	}
+	if h.Bounds == nil {
+		*h = Histogram{Bounds: o.Bounds, Counts: make([]uint64, len(o.Counts))}
+	}
+	for i, n := range o.Counts {
+		h.Counts[i] += n
+	}
+	h.Count += o.Count
+	h.Sum += o.Sum
+}
+
+// clone returns a copy of the histogram
+func (h Histogram) clone() Histogram {
+	h.Counts = slices.Clone(h.Counts)
+	return h
+}
+
+// WithTTLHistograms reports in Stats the distribution of the remaining times to live of the elements, and of
+// the lifetimes of the evicted and expired elements
+//
+// Description: Elements evicted long before their expiration hint at times to live longer than the capacity
+// allows, while elements expiring without having been looked up hint at times to live too short to be useful.
+// The addition time of each element is tracked, and Stats visits all the elements to compute the distribution
+// of the remaining times to live.
+func WithTTLHistograms() Option {
+	return func(o *options) {
+		o.ttlHistograms = true
+	}
+}
+
+// lifetimes tracks the addition time and the use of the elements, and the lifetimes of the removed ones
+//
+// Description: The ages are added and removed with the cache locked, and their hits are counted with the
+// cache locked for reading.
+type lifetimes[T comparable] struct {
+	ages    map[T]*age // ages are the addition time and the use of each element
+	evicted Histogram  // evicted are the lifetimes of the evicted elements
+	expired Histogram  // expired are the lifetimes of the expired elements
+	unused  uint64     // unused is the number of elements which expired without being looked up
+}
+
+// age is the addition time and the use of an element
+type age struct {
+	added int64         // added is the time the element was added
+	ttl   time.Duration // ttl is the duration the element was last added for, 0 meaning no expiration
+	hits  atomic.Uint64 // hits is the number of lookups that found the element
+}
+
+// newLifetimes returns an empty lifetimes tracker
+func newLifetimes[T comparable]() *lifetimes[T] {
+	return &lifetimes[T]{ages: make(map[T]*age), evicted: newHistogram(), expired: newHistogram()}
+}
+
+// add records the addition of the given element, the cache must be locked
+func (l *lifetimes[T]) add(elem T, now int64) {
+	if _, ok := l.ages[elem]; !ok {
+		l.ages[elem] = &age{added: now}
+	}
+}
+
+// use records a lookup of the given element, the cache must be locked for reading
+func (l *lifetimes[T]) use(elem T) {
+	if a, ok := l.ages[elem]; ok {
+		a.hits.Add(1)
+	}
+}
+
+// expire records the duration the given element was added for, the cache must be locked
+func (l *lifetimes[T]) expire(elem T, ttl time.Duration) {
+	if a, ok := l.ages[elem]; ok {
+		a.ttl = ttl
+	}
+}
+
+// remove records the removal of the given element for the given reason, the cache must be locked
+func (l *lifetimes[T]) remove(elem T, reason RemovalReason, now int64) {
+	a, ok := l.ages[elem]
+	if !ok {
+		return
+	}
+	delete(l.ages, elem)
+
+	switch reason {
+	case RemovalEvicted:
+		l.evicted.observe(time.Duration(now - a.added))
+	case RemovalExpired:
+		l.expired.observe(time.Duration(now - a.added))
+		if a.hits.Load() == 0 {
+			l.unused++
+		}
+	}
+}
+
+// clear forgets all elements, the cache must be locked
+func (l *lifetimes[T]) clear() {
+	clear(l.ages)
+}
+
+// ttlStats fills the histograms of the given stats, the cache must be locked for reading
+func (c *Cache[T]) ttlStats(st *Stats) {
+	now := nanotime()
+	st.RemainingTTLs = newHistogram()
+	for _, e := range c.set.All() {
+		if deadline := e.deadline(); deadline != 0 && !expired(deadline, now) {
+			st.RemainingTTLs.observe(time.Duration(deadline - now))
+		}
+	}
+	st.EvictedLifetimes = c.lifetimes.evicted.clone()
+	st.ExpiredLifetimes = c.lifetimes.expired.clone()
+	st.ExpiredUnused = c.lifetimes.unused
+}
@@ -0,0 +1,62 @@
+package cacheset
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithTTLHistograms(t *testing.T) {
+	c := New[int](time.Hour, WithTTLHistograms(), WithCapacity(3))
+	defer c.Close()
+
+	c.Add(1, time.Millisecond)
+	c.Add(2, time.Millisecond)
+	c.Add(3, time.Hour)
+	c.Contains(2)
+	time.Sleep(5 * time.Millisecond)
+	c.ExpireAll()
+	c.Add(4, time.Minute)
+	c.Add(5, 0)
+	c.Add(6, 10*time.Second)
+
+	st := c.Stats()
+	if st.ExpiredLifetimes.Count != 2 || st.ExpiredUnused != 1 {
+		t.Errorf("Stats() ExpiredLifetimes = %+v, ExpiredUnused = %v, want %v lifetimes and %v unused", st.ExpiredLifetimes, st.ExpiredUnused, 2, 1)
+	}
+	if mean := st.ExpiredLifetimes.Mean(); mean < time.Millisecond || mean > time.Second {
+		t.Errorf("Stats() ExpiredLifetimes.Mean() = %v, want about %v", mean, 5*time.Millisecond)
+	}
+	if st.EvictedLifetimes.Count != 1 {
+		t.Errorf("Stats() EvictedLifetimes = %+v, want %v lifetime", st.EvictedLifetimes, 1)
+	}
+
+	// the remaining elements are 4, 5 and 6, of which 5 never expires
+	want := make([]uint64, len(histogramBounds)+1)
+	want[4], want[5] = 1, 1
+	if h := st.RemainingTTLs; h.Count != 2 || !slices.Equal(h.Counts, want) {
+		t.Errorf("Stats() RemainingTTLs = %+v, want counts %v", h, want)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		c := New[int](time.Hour)
+		defer c.Close()
+		if st := c.Stats(); st.RemainingTTLs.Bounds != nil {
+			t.Errorf("Stats() RemainingTTLs = %+v, want an empty histogram", st.RemainingTTLs)
+		}
+	})
+}
+
+func TestHistogram_merge(t *testing.T) {
+	var h Histogram
+	a := newHistogram()
+	a.observe(time.Second)
+	a.observe(2 * time.Hour)
+	h.merge(a)
+	h.merge(a)
+	h.merge(Histogram{})
+
+	if h.Count != 4 || h.Sum != 2*(time.Second+2*time.Hour) || h.Counts[3] != 2 || h.Counts[8] != 2 {
+		t.Errorf("merge() = %+v", h)
+	}
+}
@@ -0,0 +1,155 @@
+// Package cacheset
+//
+// Path: loader.go
+//
+// Description: loader.go contains the read-through mode, in which the cache asks a loader whether the
+// elements it misses exist, and serves the stale elements while they are refreshed.
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoLoader is returned by GetOrLoad when the cache was created without WithLoader
+var ErrNoLoader = errors.New("cacheset: no loader")
+
+// WithLoader enables the read-through mode, in which GetOrLoad asks load whether a missing element exists
+//
+// Description: An element that exists is added for its default TTL, see AddDefault, while a missing one
+// is not remembered, see NegativeCache. The concurrent GetOrLoad calls for an element share a single call
+// to load, made with the context of the first caller. The loader's element type must match the cache's
+// element type.
+func WithLoader[T comparable](load func(ctx context.Context, elem T) (bool, error)) Option {
+	return func(o *options) {
+		o.loader = load
+	}
+}
+
+// WithMaxStale lets GetOrLoad serve an element for up to maxStale after it expired, while it is reloaded
+//
+// Description: The loaded elements are added for their default TTL plus maxStale, and are stale during
+// the last maxStale: GetOrLoad then returns true at once and reloads the element in the background, so that
+// a slow loader does not delay the callers. A reload finding that the element does not exist anymore
+// removes it, and its errors are reported to the error handler. The other methods of the cache see the
+// stale elements as present.
+func WithMaxStale(maxStale time.Duration) Option {
+	return func(o *options) {
+		o.maxStale = max(maxStale, 0)
+	}
+}
+
+// loader runs the loader of the read-through mode, one call at a time for each element
+type loader[T comparable] struct {
+	load  func(context.Context, T) (bool, error) // load returns true if the element exists
+	mu    sync.Mutex                             // mu protects calls
+	calls map[T]*loadCall                        // calls are the running calls to load
+}
+
+// loadCall is a running call to the loader
+type loadCall struct {
+	done   chan struct{} // done is closed once the call returned
+	exists bool          // exists is the result of the call
+	err    error         // err is the error of the call or of the addition of the element
+}
+
+// newLoader returns a loader calling load
+func newLoader[T comparable](load func(context.Context, T) (bool, error)) *loader[T] {
+	return &loader[T]{load: load, calls: make(map[T]*loadCall)}
+}
+
+// GetOrLoad returns true if the given element is in the cache, or else if the loader finds that it exists
+//
+// Description: An element found by the loader is added to the cache, the errors of the loader and of the
+// addition are returned. With WithMaxStale, a stale element is served while it is reloaded in the background.
+// GetOrLoad returns ErrNoLoader if the cache was created without WithLoader.
+func (c *Cache[T]) GetOrLoad(ctx context.Context, elem T) (bool, error) {
+	if c.loads == nil {
+		return false, ErrNoLoader
+	}
+
+	if e, ok := c.Lookup(elem); ok {
+		if c.stale(e) {
+			go c.reload(elem)
+		}
+		return true, nil
+	}
+
+	call, shared := c.loads.start(elem)
+	if !shared {
+		c.runLoad(ctx, elem, call, false)
+	}
+	select {
+	case <-call.done:
+		return call.exists, call.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// stale returns true if the given loaded entry has expired but is still served for the max stale duration
+func (c *Cache[T]) stale(e Entry[T]) bool {
+	return c.options.maxStale > 0 && !e.IsPermanent() && time.Until(e.ExpiresAt) <= c.options.maxStale
+}
+
+// reload reloads the given stale element, unless it is already being loaded
+func (c *Cache[T]) reload(elem T) {
+	call, shared := c.loads.start(elem)
+	if shared {
+		return
+	}
+	c.runLoad(context.Background(), elem, call, true)
+	if call.err != nil && !errors.Is(call.err, ErrBreakerOpen) {
+		c.report(call.err)
+	}
+}
+
+// runLoad calls the loader for the given element and adds it if it exists, or removes it on a reload
+func (c *Cache[T]) runLoad(ctx context.Context, elem T, call *loadCall, reload bool) {
+	defer c.loads.finish(elem, call)
+
+	if c.breaker != nil && !c.breaker.allow() {
+		call.err = ErrBreakerOpen
+		return
+	}
+	call.exists, call.err = c.loads.load(ctx, elem)
+	if c.breaker != nil {
+		c.breaker.record(call.err)
+	}
+	switch {
+	case call.err != nil:
+	case call.exists:
+		c.RLock()
+		ttl := c.defaultTTL(elem)
+		c.RUnlock()
+		if ttl > 0 {
+			ttl += c.options.maxStale
+		}
+		call.err = c.Add(elem, ttl)
+	case reload:
+		c.Delete(elem)
+	}
+}
+
+// start returns the running call for the given element, or a new one and false if there is none
+func (l *loader[T]) start(elem T) (call *loadCall, shared bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if call, ok := l.calls[elem]; ok {
+		return call, true
+	}
+	call = &loadCall{done: make(chan struct{})}
+	l.calls[elem] = call
+	return call, false
+}
+
+// finish ends the given call for the given element
+func (l *loader[T]) finish(elem T, call *loadCall) {
+	l.mu.Lock()
+	delete(l.calls, elem)
+	l.mu.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,108 @@
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_GetOrLoad(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	load := func(ctx context.Context, elem int64) (bool, error) {
+		calls.Add(1)
+		<-release
+		if elem < 0 {
+			return false, errors.New("negative")
+		}
+		return elem%2 == 0, nil
+	}
+	c := New[int64](time.Hour, WithLoader(load), WithDefaultTTL(time.Hour))
+	defer c.Close()
+
+	t.Run("Shared", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if found, err := c.GetOrLoad(context.Background(), 2); !found || err != nil {
+					t.Errorf("GetOrLoad() = %v, %v, want %v, nil", found, err, true)
+				}
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if n := calls.Load(); n != 1 || !c.Contains(2) {
+			t.Errorf("loader calls = %v, Contains() = %v, want %v, %v", n, c.Contains(2), 1, true)
+		}
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		if found, err := c.GetOrLoad(context.Background(), 2); !found || err != nil || calls.Load() != 1 {
+			t.Errorf("GetOrLoad() = %v, %v with %v calls, want %v, nil with %v", found, err, calls.Load(), true, 1)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		if found, err := c.GetOrLoad(context.Background(), 3); found || err != nil || c.Contains(3) {
+			t.Errorf("GetOrLoad() = %v, %v, want %v, nil", found, err, false)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		if found, err := c.GetOrLoad(context.Background(), -1); found || err == nil {
+			t.Errorf("GetOrLoad() = %v, %v, want %v and an error", found, err, false)
+		}
+	})
+
+	t.Run("NoLoader", func(t *testing.T) {
+		c := New[int64](time.Hour)
+		defer c.Close()
+		if _, err := c.GetOrLoad(context.Background(), 1); !errors.Is(err, ErrNoLoader) {
+			t.Errorf("GetOrLoad() error = %v, want %v", err, ErrNoLoader)
+		}
+	})
+}
+
+func TestWithMaxStale(t *testing.T) {
+	var exists atomic.Bool
+	exists.Store(true)
+	reloaded := make(chan struct{}, 1)
+	load := func(ctx context.Context, elem string) (bool, error) {
+		time.Sleep(5 * time.Millisecond)
+		defer func() {
+			select {
+			case reloaded <- struct{}{}:
+			default:
+			}
+		}()
+		return exists.Load(), nil
+	}
+	c := New[string](time.Hour, WithLoader(load), WithDefaultTTL(10*time.Millisecond), WithMaxStale(time.Hour))
+	defer c.Close()
+
+	if found, err := c.GetOrLoad(context.Background(), "a"); !found || err != nil {
+		t.Fatalf("GetOrLoad() = %v, %v, want %v, nil", found, err, true)
+	}
+	<-reloaded
+	time.Sleep(15 * time.Millisecond)
+
+	exists.Store(false)
+	start := time.Now()
+	if found, err := c.GetOrLoad(context.Background(), "a"); !found || err != nil || time.Since(start) > 5*time.Millisecond {
+		t.Errorf("GetOrLoad() = %v, %v after %v, want the stale answer at once", found, err, time.Since(start))
+	}
+	<-reloaded
+	for deadline := time.Now().Add(100 * time.Millisecond); c.Contains("a") && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if c.Contains("a") {
+		t.Errorf("Contains() = %v after the reload, want %v", true, false)
+	}
+}
@@ -0,0 +1,81 @@
+// Package cacheset
+//
+// Path: lookup.go
+//
+// Description: lookup.go contains the Entry type describing an element of a cache.
+package cacheset
+
+import "time"
+
+// Entry describes an element of a cache and its expiration
+//
+// Description: New fields may be added to Entry, so it should not be compared or constructed
+// outside of the package.
+type Entry[T comparable] struct {
+	Elem       T             // Elem is the element
+	ExpiresAt  time.Time     // ExpiresAt is the time the element expires if it is not accessed anymore, zero meaning never
+	MaxIdle    time.Duration // MaxIdle is the maximum duration between two accesses, 0 meaning no limit
+	LastAccess time.Time     // LastAccess is the time of the last access, only tracked with a MaxIdle
+}
+
+// IsPermanent returns true if the element never expires
+func (e Entry[T]) IsPermanent() bool {
+	return e.ExpiresAt.IsZero()
+}
+
+// TTL returns the remaining time to live of the element, 0 meaning that it never expires
+func (e Entry[T]) TTL() time.Duration {
+	if e.IsPermanent() {
+		return 0
+	}
+	return max(time.Until(e.ExpiresAt), time.Nanosecond)
+}
+
+// EntryInfo describes an element of a cache for the administration tools
+type EntryInfo[T comparable] struct {
+	Entry[T]               // Entry is the element and its expiration
+	Metadata EntryMetadata // Metadata describes the life of the element, zero without WithEntryMetadata
+	Pinned   bool          // Pinned is true if the element is pinned, see Pin
+	Version  uint64        // Version is the version of the element, 0 without WithVersions
+}
+
+// Lookup returns the entry of the given element and false if it is not in the cache or has expired
+//
+// Description: Like Contains, Lookup counts as an access to the element.
+func (c *Cache[T]) Lookup(elem T) (Entry[T], bool) {
+	if !c.mayContain(elem) {
+		c.stats.hit(false)
+		return Entry[T]{}, false
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	now := nanotime()
+	e, ok := c.set.Get(elem)
+	if ok && e.expired(now) {
+		ok = false
+	}
+	c.stats.hit(ok)
+	c.recordHot(elem)
+	c.touch(elem, ok)
+	if !ok {
+		return Entry[T]{}, false
+	}
+	e.touch(now)
+
+	return newLookupEntry(elem, e), true
+}
+
+// newLookupEntry returns the description of the given element and its entry
+func newLookupEntry[T comparable](elem T, e *entry) Entry[T] {
+	entry := Entry[T]{
+		Elem:      elem,
+		ExpiresAt: toTime(e.deadline()),
+		MaxIdle:   time.Duration(e.maxIdle()),
+	}
+	if e.idle != nil {
+		entry.LastAccess = toTime(e.lastAccess())
+	}
+	return entry
+}
@@ -0,0 +1,51 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Lookup(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+	c.Add(1, 0)
+	c.Add(2, time.Hour)
+	c.AddWithIdle(3, time.Hour, time.Minute)
+	c.Add(4, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		name      string
+		elem      int64
+		found     bool
+		permanent bool
+		ttl       time.Duration
+		maxIdle   time.Duration
+	}{
+		{name: "Permanent", elem: 1, found: true, permanent: true},
+		{name: "TTL", elem: 2, found: true, ttl: time.Hour},
+		{name: "Idle", elem: 3, found: true, ttl: time.Minute, maxIdle: time.Minute},
+		{name: "Expired", elem: 4, found: false},
+		{name: "Missing", elem: 5, found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, found := c.Lookup(tt.elem)
+			if found != tt.found {
+				t.Fatalf("Lookup() found = %v, want %v", found, tt.found)
+			}
+			if !found {
+				return
+			}
+			if e.Elem != tt.elem || e.IsPermanent() != tt.permanent || e.MaxIdle != tt.maxIdle {
+				t.Errorf("Lookup() = %+v, want element %v, permanent %v and max idle %v", e, tt.elem, tt.permanent, tt.maxIdle)
+			}
+			if got := e.TTL(); got > tt.ttl || got < tt.ttl-time.Second {
+				t.Errorf("Lookup() TTL = %v, want about %v", got, tt.ttl)
+			}
+			if tt.maxIdle > 0 && e.LastAccess.IsZero() {
+				t.Errorf("Lookup() LastAccess is zero, want the time of the last access")
+			}
+		})
+	}
+}
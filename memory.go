@@ -0,0 +1,63 @@
+// Package cacheset
+//
+// Path: memory.go
+//
+// Description: memory.go contains the evictions triggered by the memory pressure of the Go runtime.
+package cacheset
+
+import (
+	"log/slog"
+	"runtime/metrics"
+)
+
+// heapMetrics are the runtime metrics read to measure the memory pressure
+var heapMetrics = []string{"/gc/heap/live:bytes", "/gc/heap/goal:bytes"}
+
+// readHeap returns the live heap and the heap goal in bytes, replaced in tests
+var readHeap = func() (live, goal uint64) {
+	samples := []metrics.Sample{{Name: heapMetrics[0]}, {Name: heapMetrics[1]}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() != metrics.KindUint64 || samples[1].Value.Kind() != metrics.KindUint64 {
+		return 0, 0
+	}
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64()
+}
+
+// WithMemoryPressure evicts the given fraction of the elements, expiring first, when the heap is under pressure
+//
+// Description: At each cleaning, the live heap is compared to the heap goal of the garbage collector.
+// The goal grows with the live heap (GOGC) but is capped by the soft memory limit (GOMEMLIMIT), so a
+// ratio approaching 1 means that the program is close to its limit. When the ratio is above threshold,
+// the fraction of the elements expiring first is evicted, the elements without expiration last.
+// Evictions are reported with RemovalEvicted. A threshold of 0.9 and a fraction of 0.1 are reasonable.
+func WithMemoryPressure(threshold, fraction float64) Option {
+	return func(o *options) {
+		o.memoryThreshold = max(threshold, 0)
+		o.memoryShed = min(max(fraction, 0), 1)
+	}
+}
+
+// relieve evicts elements if the heap is under pressure
+func (c *Cache[T]) relieve() {
+	if c.options.memoryThreshold == 0 || c.options.memoryShed == 0 {
+		return
+	}
+	live, goal := readHeap()
+	if goal == 0 {
+		return
+	}
+	ratio := float64(live) / float64(goal)
+	if ratio < c.options.memoryThreshold {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	n := int(float64(c.set.Len())*c.options.memoryShed + 0.5)
+	evicted := c.evictExpiringFirst(max(n, 1))
+	c.options.logger.Warn("cacheset: evicted elements under memory pressure",
+		slog.Float64("heap_ratio", ratio),
+		slog.Int("evicted", evicted),
+	)
+}
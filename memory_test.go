@@ -0,0 +1,42 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithMemoryPressure(t *testing.T) {
+	read := readHeap
+	defer func() { readHeap = read }()
+
+	if live, goal := readHeap(); goal == 0 || live > goal*2 {
+		t.Errorf("readHeap() = %v, %v, want a live heap and a heap goal", live, goal)
+	}
+
+	readHeap = func() (uint64, uint64) { return 50, 100 }
+
+	c := New[int64](time.Hour, WithMemoryPressure(0.9, 0.25))
+	defer c.Close()
+	for i := int64(0); i < 8; i++ {
+		c.Add(i, time.Duration(i+1)*time.Minute)
+	}
+
+	t.Run("Low", func(t *testing.T) {
+		c.clean()
+		if got := c.Len(); got != 8 {
+			t.Errorf("Len() = %v, want %v", got, 8)
+		}
+	})
+
+	readHeap = func() (uint64, uint64) { return 95, 100 }
+
+	t.Run("High", func(t *testing.T) {
+		c.clean()
+		if got := c.Len(); got != 6 {
+			t.Errorf("Len() = %v, want %v", got, 6)
+		}
+		if c.Contains(0) || c.Contains(1) || !c.Contains(2) {
+			t.Errorf("WithMemoryPressure() did not evict the elements expiring first")
+		}
+	})
+}
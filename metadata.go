@@ -0,0 +1,41 @@
+// Package cacheset
+//
+// Path: metadata.go
+//
+// Description: metadata.go contains the metadata of the elements, reported with their removals so that
+// the eviction analytics can tell more than the removed element.
+package cacheset
+
+import "time"
+
+// EntryMetadata describes the life of an element in the cache
+type EntryMetadata struct {
+	TTL  time.Duration // TTL is the duration the element was last added for, after the jitter, 0 meaning no expiration
+	Age  time.Duration // Age is the time elapsed since the element was first added
+	Hits uint64        // Hits is the number of lookups that found the element
+}
+
+// WithEntryMetadata tracks the addition time, the TTL and the number of hits of each element, and reports
+// them in the RemovalEvent sent to the watchers and in the EventRemoved sent to the subscriptions
+//
+// Description: Adding an element again keeps its addition time and hits but records its new TTL. The hits
+// are counted by the lookups such as Contains, under the read lock with an atomic counter per element. The
+// tracking costs a small allocation per element.
+func WithEntryMetadata() Option {
+	return func(o *options) {
+		o.entryMetadata = true
+	}
+}
+
+// metadata returns the metadata of the given element at now, the zero EntryMetadata without
+// WithEntryMetadata, the cache must be locked for reading
+func (c *Cache[T]) metadata(elem T, now int64) EntryMetadata {
+	if !c.options.entryMetadata {
+		return EntryMetadata{}
+	}
+	a, ok := c.lifetimes.ages[elem]
+	if !ok {
+		return EntryMetadata{}
+	}
+	return EntryMetadata{TTL: a.ttl, Age: time.Duration(now - a.added), Hits: a.hits.Load()}
+}
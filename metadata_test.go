@@ -0,0 +1,45 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_EntryMetadata(t *testing.T) {
+	c := New[int](time.Hour, WithEntryMetadata())
+	defer c.Close()
+
+	_ = c.Add(1, time.Minute)
+	sub := c.Subscribe()
+	defer sub.Close()
+	watch := c.Watch(1)
+
+	time.Sleep(5 * time.Millisecond)
+	c.Contains(1)
+	c.Contains(1)
+	_ = c.Add(1, time.Hour)
+	c.Delete(1)
+
+	ev := <-watch
+	if ev.TTL != time.Hour || ev.Hits != 2 || ev.Age < 5*time.Millisecond {
+		t.Errorf("Watch() = %+v, want a TTL of %v, %v hits and an age of at least %v", ev, time.Hour, 2, 5*time.Millisecond)
+	}
+	for got := range sub.Events() {
+		if got.Kind != EventRemoved {
+			continue
+		}
+		if got.EntryMetadata != ev.EntryMetadata {
+			t.Errorf("Subscribe() = %+v, want %+v", got.EntryMetadata, ev.EntryMetadata)
+		}
+		break
+	}
+
+	plain := New[int](time.Hour)
+	defer plain.Close()
+	_ = plain.Add(1, time.Minute)
+	watch = plain.Watch(1)
+	plain.Delete(1)
+	if ev := <-watch; ev.EntryMetadata != (EntryMetadata{}) {
+		t.Errorf("Watch() = %+v without WithEntryMetadata, want no metadata", ev)
+	}
+}
@@ -0,0 +1,61 @@
+// Package cacheset
+//
+// Path: namespace.go
+//
+// Description: namespace.go contains the default TTLs of the namespaces of the elements.
+package cacheset
+
+import (
+	"maps"
+	"strings"
+	"time"
+)
+
+// WithNamespace sets the function returning the namespace of an element, used to choose its default TTL
+//
+// Description: The namespace of an element added with AddDefault selects its TTL among the ones set
+// with WithNamespaceTTL, falling back to WithDefaultTTL. The function must match the element type
+// of the cache, or New panics.
+func WithNamespace[T comparable](namespace func(elem T) string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithNamespaceTTL sets the default TTL of the elements of the given namespace
+func WithNamespaceTTL(namespace string, ttl time.Duration) Option {
+	return func(o *options) {
+		o.namespaceTTLs = maps.Clone(o.namespaceTTLs)
+		if o.namespaceTTLs == nil {
+			o.namespaceTTLs = make(map[string]time.Duration)
+		}
+		o.namespaceTTLs[namespace] = max(ttl, 0)
+	}
+}
+
+// PrefixNamespace returns a namespace function for WithNamespace reading the namespace of a string
+// before the first occurrence of sep, such as "sessions" in "sessions:42"
+func PrefixNamespace(sep string) func(elem string) string {
+	return func(elem string) string {
+		namespace, _, ok := strings.Cut(elem, sep)
+		if !ok {
+			return ""
+		}
+		return namespace
+	}
+}
+
+// defaultTTL returns the default TTL of the given element, the cache must be locked for reading
+func (c *Cache[T]) defaultTTL(elem T) time.Duration {
+	if c.ttlPolicy != nil {
+		if ttl, ok := c.ttlPolicy(elem); ok {
+			return ttl
+		}
+	}
+	if c.namespace != nil {
+		if ttl, ok := c.options.namespaceTTLs[c.namespace(elem)]; ok {
+			return ttl
+		}
+	}
+	return c.options.defaultTTL
+}
@@ -0,0 +1,53 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithNamespaceTTL(t *testing.T) {
+	c := New[string](time.Minute,
+		WithNamespace(PrefixNamespace(":")),
+		WithNamespaceTTL("sessions", 30*time.Minute),
+		WithNamespaceTTL("otp", 5*time.Minute),
+		WithDefaultTTL(time.Hour),
+	)
+	defer c.Close()
+
+	tests := []struct {
+		name string
+		elem string
+		want time.Duration
+	}{
+		{name: "Sessions", elem: "sessions:42", want: 30 * time.Minute},
+		{name: "OTP", elem: "otp:42", want: 5 * time.Minute},
+		{name: "Unknown", elem: "users:42", want: time.Hour},
+		{name: "NoNamespace", elem: "42", want: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.AddDefault(tt.elem)
+			e, _ := c.Lookup(tt.elem)
+			if got := e.TTL(); got > tt.want || got < tt.want-time.Second {
+				t.Errorf("AddDefault() TTL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ApplyConfig", func(t *testing.T) {
+		c.ApplyConfig(Config{NamespaceTTLs: map[string]Duration{"otp": Duration(time.Minute)}})
+		c.AddDefault("otp:43")
+		if e, _ := c.Lookup("otp:43"); e.TTL() > time.Minute {
+			t.Errorf("AddDefault() TTL = %v, want %v", e.TTL(), time.Minute)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("New() did not panic")
+			}
+		}()
+		New[int64](time.Minute, WithNamespace(PrefixNamespace(":")))
+	})
+}
@@ -0,0 +1,87 @@
+// Package cacheset
+//
+// Path: negative.go
+//
+// Description: negative.go contains the NegativeCache type, which remembers the elements known not to exist
+// next to the ones known to exist.
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// NegativeCache remembers for how long an element is known to exist or known not to exist upstream
+//
+// Description: The positive and the negative answers are stored in two caches created with the same options,
+// and expire after their own duration: a missing element is usually remembered for a shorter time, so that
+// its creation upstream is noticed quickly. Recording an answer replaces the opposite one. WithDurability is ignored.
+type NegativeCache[T comparable] struct {
+	positive    *Cache[T]     // positive holds the elements known to exist
+	negative    *Cache[T]     // negative holds the elements known not to exist
+	positiveTTL time.Duration // positiveTTL is the duration of the positive answers, 0 meaning no expiration
+	negativeTTL time.Duration // negativeTTL is the duration of the negative answers, 0 meaning no expiration
+}
+
+// NewNegativeCache creates a new negative cache whose answers expire after positiveTTL or negativeTTL
+func NewNegativeCache[T comparable](cleanInterval, positiveTTL, negativeTTL time.Duration, opts ...Option) *NegativeCache[T] {
+	o := newOptions(opts)
+	o.sink = nil
+	return &NegativeCache[T]{
+		positive:    newCache[T](cleanInterval, o),
+		negative:    newCache[T](cleanInterval, o),
+		positiveTTL: positiveTTL,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// Check returns whether the existence of the given element is known, and if so whether it exists
+func (n *NegativeCache[T]) Check(elem T) (known, exists bool) {
+	if _, ok := n.positive.Lookup(elem); ok {
+		return true, true
+	}
+	if _, ok := n.negative.Lookup(elem); ok {
+		return true, false
+	}
+	return false, false
+}
+
+// SetExists records that the given element exists for the positive duration
+func (n *NegativeCache[T]) SetExists(elem T) error {
+	n.negative.Delete(elem)
+	return n.positive.Add(elem, n.positiveTTL)
+}
+
+// SetMissing records that the given element does not exist for the negative duration
+func (n *NegativeCache[T]) SetMissing(elem T) error {
+	n.positive.Delete(elem)
+	return n.negative.Add(elem, n.negativeTTL)
+}
+
+// Forget forgets whether the given element exists
+func (n *NegativeCache[T]) Forget(elem T) {
+	n.positive.Delete(elem)
+	n.negative.Delete(elem)
+}
+
+// Positive returns the cache of the elements known to exist
+func (n *NegativeCache[T]) Positive() *Cache[T] {
+	return n.positive
+}
+
+// Negative returns the cache of the elements known not to exist
+func (n *NegativeCache[T]) Negative() *Cache[T] {
+	return n.negative
+}
+
+// Close stops the cleaning goroutines of both caches
+func (n *NegativeCache[T]) Close() {
+	n.positive.Close()
+	n.negative.Close()
+}
+
+// Shutdown gracefully closes both caches before ctx is done, see Cache.Shutdown
+func (n *NegativeCache[T]) Shutdown(ctx context.Context) error {
+	return errors.Join(n.positive.Shutdown(ctx), n.negative.Shutdown(ctx))
+}
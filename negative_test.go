@@ -0,0 +1,53 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNegativeCache(t *testing.T) {
+	n := NewNegativeCache[string](time.Hour, time.Hour, 5*time.Millisecond)
+	defer n.Close()
+
+	n.SetExists("a")
+	n.SetMissing("b")
+	n.SetMissing("c")
+	n.SetExists("c")
+
+	tests := []struct {
+		elem         string
+		known, found bool
+	}{
+		{"a", true, true},
+		{"b", true, false},
+		{"c", true, true},
+		{"d", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.elem, func(t *testing.T) {
+			if known, exists := n.Check(tt.elem); known != tt.known || exists != tt.found {
+				t.Errorf("Check(%v) = %v, %v, want %v, %v", tt.elem, known, exists, tt.known, tt.found)
+			}
+		})
+	}
+
+	t.Run("Expired", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		if known, _ := n.Check("b"); known {
+			t.Errorf("Check(%v) known = %v, want %v", "b", known, false)
+		}
+		if known, exists := n.Check("a"); !known || !exists {
+			t.Errorf("Check(%v) = %v, %v, want %v, %v", "a", known, exists, true, true)
+		}
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		n.Forget("a")
+		if known, _ := n.Check("a"); known {
+			t.Errorf("Check(%v) known = %v, want %v", "a", known, false)
+		}
+		if n.Positive().Len() != 1 || n.Negative().Len() != 1 {
+			t.Errorf("Len() = %v, %v, want %v, %v", n.Positive().Len(), n.Negative().Len(), 1, 1)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+// Package cacheset
+//
+// Path: options.go
+//
+// Description: options.go contains the options used to configure a cache.
+package cacheset
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Option configures a cache
+type Option func(*options)
+
+// options are the settings of a cache
+type options struct {
+	logger            *slog.Logger             // logger receives the cache's log records
+	errorHandler      func(error)              // errorHandler receives the errors that cannot be returned to the caller
+	sink              SnapshotSink             // sink stores the snapshot restored on New and written on Close, nil meaning no durability
+	snapshotKeys      KeyProvider              // snapshotKeys encrypt the snapshots, nil meaning no encryption
+	snapshotName      string                   // snapshotName is the name of the snapshot in sink
+	durabilityTimeout time.Duration            // durabilityTimeout is the deadline of the snapshot written on Close
+	defaultTTL        time.Duration            // defaultTTL is the duration of the elements added with AddDefault
+	snapshotInterval  time.Duration            // snapshotInterval is the duration between two periodic snapshots, 0 meaning disabled
+	uniqueAddsPeriod  time.Duration            // uniqueAddsPeriod is the duration between two resets of the distinct elements counter
+	hotKeysWindow     time.Duration            // hotKeysWindow is the duration of the windows of the hot keys tracker
+	bucketWidth       time.Duration            // bucketWidth is the width of the expiration buckets, 0 meaning no buckets
+	wheelTick         time.Duration            // wheelTick is the granularity of the timing wheel, 0 meaning no wheel
+	maxStale          time.Duration            // maxStale is the duration for which GetOrLoad serves an expired element while reloading it
+	tombstoneWindow   time.Duration            // tombstoneWindow is the duration of the tombstones of the deleted elements, 0 meaning none
+	historyWidth      time.Duration            // historyWidth is the width of the periods of the stats history, 0 meaning no history
+	breakerCooldown   time.Duration            // breakerCooldown is the duration for which the loader breaker stays open
+	hasher            any                      // hasher is the func(T) uint64 choosing the shard of an element
+	namespace         any                      // namespace is the func(T) string returning the namespace of an element
+	namespaceTTLs     map[string]time.Duration // namespaceTTLs are the default TTLs of the namespaces
+	onFull            any                      // onFull is the func(T) OverflowPolicy deciding what to do when the cache is full
+	loader            any                      // loader is the func(context.Context, T) (bool, error) of the read-through mode, nil meaning disabled
+	sortOrder         any                      // sortOrder is the func(a, b T) int sorting the elements returned by ToSlice, nil meaning unsorted
+	ttlPolicies       []any                    // ttlPolicies are the func(T) time.Duration returning the default TTLs of the elements
+	ttlJitter         float64                  // ttlJitter is the fraction by which the durations given to Add are randomized
+	memoryThreshold   float64                  // memoryThreshold is the live heap to heap goal ratio above which elements are evicted, 0 meaning disabled
+	memoryShed        float64                  // memoryShed is the fraction of the elements evicted when memoryThreshold is crossed
+	slruProtected     float64                  // slruProtected is the fraction of the capacity reserved to the protected segment of EvictSLRU
+	shards            int                      // shards is the number of shards of a Sharded cache, 0 meaning automatic
+	hotKeys           int                      // hotKeys is the number of elements tracked by the hot keys tracker, 0 meaning disabled
+	hotKeysSampling   int                      // hotKeysSampling is the average number of accesses per recorded access
+	deltaEvery        int                      // deltaEvery is the number of periodic snapshots per full snapshot, 0 meaning no delta snapshots
+	auditLog          int                      // auditLog is the number of mutations recorded by the audit log, 0 meaning disabled
+	bloomFilter       int                      // bloomFilter is the number of elements the negative lookup filter is sized for, 0 meaning disabled
+	capacity          int                      // capacity is the maximum number of elements in the cache, 0 meaning unlimited
+	latencySampling   int                      // latencySampling is the average number of calls per measured call, 0 meaning no latency histograms
+	historyBuckets    int                      // historyBuckets is the number of periods kept by the stats history
+	amortizedExpiry   int                      // amortizedExpiry is the maximum number of expired elements removed by each addition, 0 meaning none
+	breakerFailures   int                      // breakerFailures is the number of consecutive loader failures opening the breaker, 0 meaning no breaker
+	ttlRule           TTLRule                  // ttlRule combines the durations of the TTL policies
+	budget            *Budget                  // budget is the capacity budget shared with other caches, nil meaning none
+	evictionPolicy    EvictionPolicy           // evictionPolicy chooses the evicted elements when the cache is full
+	replace           ReplacePolicy            // replace decides the expiration of an element added again
+	overflow          OverflowPolicy           // overflow is what happens when an element is added to a full cache
+	compactStorage    bool                     // compactStorage stores the elements in a table instead of a map
+	tinyLFU           bool                     // tinyLFU enables the TinyLFU admission filter
+	uniqueAdds        bool                     // uniqueAdds enables the distinct elements counter
+	negativeExpiry    bool                     // negativeExpiry makes the negative durations expire the elements instead of keeping them forever
+	strict            bool                     // strict rejects the invalid durations and the additions to a closed cache
+	entryMetadata     bool                     // entryMetadata tracks the addition time, the TTL and the hits of each element
+	ttlHistograms     bool                     // ttlHistograms enables the histograms of the remaining times to live and of the lifetimes
+	versions          bool                     // versions tracks a version of each element
+	pinnedNoExpiry    bool                     // pinnedNoExpiry keeps the pinned elements from expiring
+	coalesce          bool                     // coalesce skips the cleanings while no element can expire
+	insertionOrder    bool                     // insertionOrder returns the elements in insertion order from ToSlice
+	external          bool                     // external leaves the cleanings to the owner of the cache, the shards of a Sharded cache
+}
+
+// newOptions returns the default options with the given options applied
+func newOptions(opts []Option) options {
+	o := options{
+		logger:            slog.New(discardHandler{}),
+		slruProtected:     0.8,
+		durabilityTimeout: 10 * time.Second,
+		hotKeysSampling:   1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithLogger sets the logger used to report the cache's cleanings and failures
+//
+// Description: Cleanings are logged at debug level and failures at warn level. By default, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithErrorHandler sets a function receiving the errors of the cleaning goroutine and of the durability
+//
+// Description: Panics recovered in the cleaning goroutine are reported as a *PanicError.
+// The handler may be called from the cleaning goroutine and must not block.
+func WithErrorHandler(handler func(error)) Option {
+	return func(o *options) {
+		o.errorHandler = handler
+	}
+}
+
+// WithTTLJitter randomizes the duration given to each Add by ±fraction
+//
+// Description: With a fraction of 0.1, an element added for 10 minutes expires between 9 and 11 minutes later.
+// Elements added at the same time with the same duration then expire over several cleanings instead of one.
+// The fraction is clamped to [0, 1], elements added without expiration are not affected.
+func WithTTLJitter(fraction float64) Option {
+	return func(o *options) {
+		o.ttlJitter = math.Max(0, math.Min(1, fraction))
+	}
+}
+
+// WithDefaultTTL sets the duration of the elements added with AddDefault, 0 meaning no expiration
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.defaultTTL = max(ttl, 0)
+	}
+}
+
+// jitter returns the given duration randomized according to the ttlJitter option
+func (o options) jitter(duration time.Duration) time.Duration {
+	if o.ttlJitter == 0 || duration <= 0 {
+		return duration
+	}
+
+	jittered := duration + time.Duration(float64(duration)*o.ttlJitter*(2*rand.Float64()-1))
+	if jittered <= 0 {
+		return 1
+	}
+	return jittered
+}
+
+// discardHandler is a slog.Handler that discards all records
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
@@ -0,0 +1,139 @@
+// Package cacheset
+//
+// Path: order.go
+//
+// Description: order.go contains the deterministic iteration orders of the elements, which make the output
+// of ToSlice and Range reproducible.
+package cacheset
+
+import (
+	"cmp"
+	"slices"
+	"sync/atomic"
+)
+
+// insertions numbers the insertions of all caches, so that the elements of the shards of a Sharded cache
+// can be ordered together
+var insertions atomic.Uint64
+
+// WithDeterministicIteration returns the elements in insertion order from ToSlice, Filter, Partition and Range
+//
+// Description: By default, the elements are returned in the random order of a map. An element added again
+// keeps its position, while an element removed and added again moves to the end. The position of each
+// element is tracked, which costs a map entry per element.
+func WithDeterministicIteration() Option {
+	return func(o *options) {
+		o.insertionOrder = true
+	}
+}
+
+// WithSortedIteration returns the elements sorted by compare from ToSlice, Filter, Partition and Range
+//
+// Description: compare returns a negative number when a sorts before b, a positive number when it sorts after
+// b and 0 when they are equal, like cmp.Compare. It takes precedence over WithDeterministicIteration.
+// Its element type must match the cache's element type.
+func WithSortedIteration[T comparable](compare func(a, b T) int) Option {
+	return func(o *options) {
+		o.sortOrder = compare
+	}
+}
+
+// insertionOrder tracks the position of each element in insertion order, the cache must be locked
+type insertionOrder[T comparable] struct {
+	positions map[T]uint64 // positions are the insertion numbers of the elements
+}
+
+// newInsertionOrder returns an empty insertion order
+func newInsertionOrder[T comparable]() *insertionOrder[T] {
+	return &insertionOrder[T]{positions: make(map[T]uint64)}
+}
+
+// add records the insertion of the given element, unless it is already tracked
+func (o *insertionOrder[T]) add(elem T) {
+	if _, ok := o.positions[elem]; !ok {
+		o.positions[elem] = insertions.Add(1)
+	}
+}
+
+// remove forgets the position of the given element
+func (o *insertionOrder[T]) remove(elem T) {
+	delete(o.positions, elem)
+}
+
+// clear forgets all positions
+func (o *insertionOrder[T]) clear() {
+	clear(o.positions)
+}
+
+// sort sorts the given elements in insertion order
+func (o *insertionOrder[T]) sort(elems []T) {
+	slices.SortStableFunc(elems, func(a, b T) int {
+		return cmp.Compare(o.positions[a], o.positions[b])
+	})
+}
+
+// ordered sorts the given elements in the iteration order of the cache, the cache must be locked for reading
+func (c *Cache[T]) ordered(elems []T) []T {
+	switch {
+	case c.sorted != nil:
+		slices.SortStableFunc(elems, c.sorted)
+	case c.order != nil:
+		c.order.sort(elems)
+	}
+	return elems
+}
+
+// Range calls fn for each element in the cache in its iteration order, until fn returns false
+//
+// Description: Range iterates over a copy of the elements taken under a single read lock, so fn may call
+// the cache's methods. Like ToSlice, it includes the expired elements that were not cleaned yet.
+func (c *Cache[T]) Range(fn func(elem T) bool) {
+	for _, elem := range c.ToSlice() {
+		if !fn(elem) {
+			return
+		}
+	}
+}
+
+// positioned is an element with its insertion number
+type positioned[T comparable] struct {
+	elem     T      // elem is the element
+	position uint64 // position is the insertion number of the element
+}
+
+// positions returns the elements in the cache with their insertion numbers, the cache must be locked for reading
+func (c *Cache[T]) positions() []positioned[T] {
+	elems := make([]positioned[T], 0, c.set.Len())
+	for elem := range c.set.All() {
+		elems = append(elems, positioned[T]{elem: elem, position: c.order.positions[elem]})
+	}
+	return elems
+}
+
+// orderedSlice returns the elements of all shards in their iteration order
+func (s *Sharded[T]) orderedSlice() []T {
+	first := s.shards[0]
+	if first.sorted != nil {
+		var slice []T
+		for _, shard := range s.shards {
+			slice = append(slice, shard.ToSlice()...)
+		}
+		slices.SortStableFunc(slice, first.sorted)
+		return slice
+	}
+
+	var all []positioned[T]
+	for _, shard := range s.shards {
+		shard.RLock()
+		all = append(all, shard.positions()...)
+		shard.RUnlock()
+	}
+	slices.SortFunc(all, func(a, b positioned[T]) int {
+		return cmp.Compare(a.position, b.position)
+	})
+	slice := make([]T, len(all))
+	for i, p := range all {
+		slice[i] = p.elem
+	}
+	return slice
+}
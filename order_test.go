@@ -0,0 +1,80 @@
+package cacheset
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCache_DeterministicIteration(t *testing.T) {
+	t.Run("Insertion", func(t *testing.T) {
+		c := New[int](time.Minute, WithDeterministicIteration())
+		defer c.Close()
+
+		for _, elem := range []int{5, 3, 9, 1, 7} {
+			_ = c.Add(elem, 0)
+		}
+		_ = c.Add(3, time.Hour)
+		c.Delete(9)
+		_ = c.Add(9, 0)
+
+		want := []int{5, 3, 1, 7, 9}
+		if got := c.ToSlice(); !slices.Equal(got, want) {
+			t.Errorf("ToSlice() = %v, want %v", got, want)
+		}
+		if got := c.Filter(func(elem int) bool { return elem > 3 }); !slices.Equal(got, []int{5, 7, 9}) {
+			t.Errorf("Filter() = %v, want %v", got, []int{5, 7, 9})
+		}
+		if got := c.Clone().ToSlice(); !slices.Equal(got, want) {
+			t.Errorf("Clone().ToSlice() = %v, want %v", got, want)
+		}
+
+		var ranged []int
+		c.Range(func(elem int) bool {
+			ranged = append(ranged, elem)
+			return len(ranged) < 3
+		})
+		if !slices.Equal(ranged, want[:3]) {
+			t.Errorf("Range() = %v, want %v", ranged, want[:3])
+		}
+	})
+
+	t.Run("Sorted", func(t *testing.T) {
+		c := New[int](time.Minute, WithSortedIteration(cmp.Compare[int]))
+		defer c.Close()
+
+		for _, elem := range []int{5, 3, 9, 1, 7} {
+			_ = c.Add(elem, 0)
+		}
+		if got, want := c.ToSlice(), []int{1, 3, 5, 7, 9}; !slices.Equal(got, want) {
+			t.Errorf("ToSlice() = %v, want %v", got, want)
+		}
+		in, out := c.Partition(func(elem int) bool { return elem%3 == 0 })
+		if !slices.Equal(in, []int{3, 9}) || !slices.Equal(out, []int{1, 5, 7}) {
+			t.Errorf("Partition() = %v, %v, want %v, %v", in, out, []int{3, 9}, []int{1, 5, 7})
+		}
+	})
+
+	t.Run("Sharded", func(t *testing.T) {
+		s := NewSharded[int](time.Minute, WithShards(4), WithDeterministicIteration())
+		defer s.Close()
+
+		want := []int{42, 7, 19, 3, 88, 0, 61}
+		for _, elem := range want {
+			_ = s.Add(elem, 0)
+		}
+		if got := s.ToSlice(); !slices.Equal(got, want) {
+			t.Errorf("ToSlice() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("New() did not panic with a mismatched WithSortedIteration")
+			}
+		}()
+		New[int](time.Minute, WithSortedIteration(cmp.Compare[string]))
+	})
+}
@@ -0,0 +1,255 @@
+// Package cacheset
+//
+// Path: orset.go
+//
+// Description: orset.go contains an observed-remove set with expiration times (OR-Set CRDT).
+//
+// Each addition is tagged with a unique dot (replica, epoch, sequence number). A removal tombstones the
+// dots it observed, so a concurrent addition on another replica survives the removal (add-wins).
+// The replicas exchange deltas or full states with Merge, in any order and any number of times,
+// and converge to the same elements once they have seen the same deltas.
+//
+// Expiration times are wall-clock times, since they are exchanged between hosts: the clocks of
+// the replicas are expected to be synchronized within a fraction of the times to live.
+package cacheset
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Dot identifies one addition of an element to an ORSet
+type Dot struct {
+	Replica string // Replica is the identifier of the replica that made the addition
+	Epoch   uint64 // Epoch is the random identifier of the incarnation of the replica, renewed on each NewORSet
+	Seq     uint64 // Seq is the sequence number of the addition in the epoch
+}
+
+// ORSetAdd is the addition of an element in an ORSetDelta
+type ORSetAdd[T comparable] struct {
+	Elem      T         // Elem is the added element
+	Dot       Dot       // Dot identifies the addition
+	ExpiresAt time.Time // ExpiresAt is the expiration time of the addition, the zero time meaning no expiration
+}
+
+// ORSetRemove is the tombstone of an addition in an ORSetDelta
+type ORSetRemove struct {
+	Dot       Dot       // Dot identifies the removed addition
+	ExpiresAt time.Time // ExpiresAt is the expiration time of the removed addition, after which the tombstone is collected
+}
+
+// ORSetDelta is a part of the state of an ORSet, exchanged between replicas with Merge
+//
+// Description: The fields are exported so that the delta can be encoded with encoding/json,
+// encoding/gob or any other encoding supported by T.
+type ORSetDelta[T comparable] struct {
+	Adds    []ORSetAdd[T] // Adds are the additions
+	Removes []ORSetRemove // Removes are the tombstones
+}
+
+// Empty returns true if the delta carries no change
+func (d ORSetDelta[T]) Empty() bool {
+	return len(d.Adds) == 0 && len(d.Removes) == 0
+}
+
+// ORSet is an observed-remove set whose elements expire, replicated without coordination
+//
+// Description: The tombstone of an addition is kept until the addition expires, after which
+// every replica drops the addition on its own: the tombstones of elements added with a time
+// to live are therefore collected by Compact, while those of permanent elements are kept.
+type ORSet[T comparable] struct {
+	mu      sync.RWMutex
+	replica string                  // replica is the identifier of this replica, unique among the replicas
+	epoch   uint64                  // epoch distinguishes the dots of this incarnation from those made before a restart
+	seq     uint64                  // seq is the last sequence number used in the epoch
+	adds    map[T]map[Dot]time.Time // adds are the live additions of each element with their expiration times
+	elems   map[Dot]T               // elems are the elements of the live additions
+	removed map[Dot]time.Time       // removed are the tombstones with the expiration times of the removed additions
+}
+
+// NewORSet returns an empty ORSet for the given replica, whose identifier must be unique among the replicas
+//
+// Description: The set starts a new random epoch, so that a replica restarted without its state does not
+// reuse the dots of its previous additions, which the other replicas may have tombstoned.
+func NewORSet[T comparable](replica string) *ORSet[T] {
+	return &ORSet[T]{
+		replica: replica,
+		epoch:   rand.Uint64(),
+		adds:    make(map[T]map[Dot]time.Time),
+		elems:   make(map[Dot]T),
+		removed: make(map[Dot]time.Time),
+	}
+}
+
+// Add adds the given element for the given duration, 0 meaning no expiration, and returns the delta to send to the other replicas
+func (s *ORSet[T]) Add(elem T, ttl time.Duration) ORSetDelta[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	s.seq++
+	add := ORSetAdd[T]{Elem: elem, Dot: Dot{Replica: s.replica, Epoch: s.epoch, Seq: s.seq}, ExpiresAt: expires}
+	s.insert(add)
+	return ORSetDelta[T]{Adds: []ORSetAdd[T]{add}}
+}
+
+// Remove removes the given element and returns the delta to send to the other replicas
+//
+// Description: Only the additions observed by this replica are removed, an addition made concurrently
+// on another replica is kept once merged.
+func (s *ORSet[T]) Remove(elem T) ORSetDelta[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var delta ORSetDelta[T]
+	for dot, expires := range s.adds[elem] {
+		s.removed[dot] = expires
+		delete(s.elems, dot)
+		delta.Removes = append(delta.Removes, ORSetRemove{Dot: dot, ExpiresAt: expires})
+	}
+	delete(s.adds, elem)
+	return delta
+}
+
+// Contains returns true if the given element has an addition that is neither removed nor expired
+func (s *ORSet[T]) Contains(elem T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, expires := range s.adds[elem] {
+		if live(expires, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToSlice returns the elements of the set
+func (s *ORSet[T]) ToSlice() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	elems := make([]T, 0, len(s.adds))
+	for elem, dots := range s.adds {
+		for _, expires := range dots {
+			if live(expires, now) {
+				elems = append(elems, elem)
+				break
+			}
+		}
+	}
+	return elems
+}
+
+// Len returns the number of elements of the set
+func (s *ORSet[T]) Len() int {
+	return len(s.ToSlice())
+}
+
+// State returns the full state of the set, to send to a replica that joins or missed deltas
+func (s *ORSet[T]) State() ORSetDelta[T] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var delta ORSetDelta[T]
+	for elem, dots := range s.adds {
+		for dot, expires := range dots {
+			delta.Adds = append(delta.Adds, ORSetAdd[T]{Elem: elem, Dot: dot, ExpiresAt: expires})
+		}
+	}
+	for dot, expires := range s.removed {
+		delta.Removes = append(delta.Removes, ORSetRemove{Dot: dot, ExpiresAt: expires})
+	}
+	return delta
+}
+
+// Merge applies a delta or a full state received from another replica
+//
+// Description: Merge is idempotent, commutative and associative: the deltas can be received
+// late, duplicated or out of order.
+func (s *ORSet[T]) Merge(delta ORSetDelta[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, rm := range delta.Removes {
+		if !live(rm.ExpiresAt, now) {
+			continue
+		}
+		s.removed[rm.Dot] = rm.ExpiresAt
+		s.drop(rm.Dot)
+	}
+	for _, add := range delta.Adds {
+		if !live(add.ExpiresAt, now) {
+			continue
+		}
+		if _, ok := s.removed[add.Dot]; ok {
+			continue
+		}
+		s.insert(add)
+	}
+}
+
+// Compact drops the expired additions and the tombstones of expired additions, and returns the number of entries dropped
+func (s *ORSet[T]) Compact() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for elem, dots := range s.adds {
+		for dot, expires := range dots {
+			if !live(expires, now) {
+				delete(dots, dot)
+				delete(s.elems, dot)
+				n++
+			}
+		}
+		if len(dots) == 0 {
+			delete(s.adds, elem)
+		}
+	}
+	for dot, expires := range s.removed {
+		if !live(expires, now) {
+			delete(s.removed, dot)
+			n++
+		}
+	}
+	return n
+}
+
+// insert records the given addition, the set must be locked
+func (s *ORSet[T]) insert(add ORSetAdd[T]) {
+	dots, ok := s.adds[add.Elem]
+	if !ok {
+		dots = make(map[Dot]time.Time)
+		s.adds[add.Elem] = dots
+	}
+	dots[add.Dot] = add.ExpiresAt
+	s.elems[add.Dot] = add.Elem
+}
+
+// drop removes the addition identified by the given dot if it is live, the set must be locked
+func (s *ORSet[T]) drop(dot Dot) {
+	elem, ok := s.elems[dot]
+	if !ok {
+		return
+	}
+	delete(s.elems, dot)
+	dots := s.adds[elem]
+	delete(dots, dot)
+	if len(dots) == 0 {
+		delete(s.adds, elem)
+	}
+}
+
+// live returns true if the given expiration time, the zero time meaning no expiration, is after now
+func live(expires, now time.Time) bool {
+	return expires.IsZero() || expires.After(now)
+}
@@ -0,0 +1,101 @@
+package cacheset
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestORSet_Merge(t *testing.T) {
+	a, b, c := NewORSet[string]("a"), NewORSet[string]("b"), NewORSet[string]("c")
+	sync := func(deltas ...ORSetDelta[string]) {
+		for _, s := range []*ORSet[string]{a, b, c} {
+			for _, d := range deltas {
+				s.Merge(d)
+			}
+		}
+	}
+
+	sync(a.Add("x", 0), b.Add("y", 0))
+	for name, s := range map[string]*ORSet[string]{"a": a, "b": b, "c": c} {
+		got := s.ToSlice()
+		slices.Sort(got)
+		if !slices.Equal(got, []string{"x", "y"}) {
+			t.Errorf("%s.ToSlice() = %v, want %v", name, got, []string{"x", "y"})
+		}
+	}
+
+	t.Run("add wins", func(t *testing.T) {
+		rm := a.Remove("x")
+		add := c.Add("x", 0)
+		sync(rm, add)
+		for name, s := range map[string]*ORSet[string]{"a": a, "b": b, "c": c} {
+			if !s.Contains("x") {
+				t.Errorf("%s.Contains(x) = false, want true", name)
+			}
+		}
+	})
+
+	t.Run("observed remove", func(t *testing.T) {
+		sync(b.Remove("x"), b.Remove("y"))
+		for name, s := range map[string]*ORSet[string]{"a": a, "b": b, "c": c} {
+			if got := s.Len(); got != 0 {
+				t.Errorf("%s.Len() = %v, want %v", name, got, 0)
+			}
+		}
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		d := NewORSet[string]("d")
+		d.Merge(b.Remove("missing"))
+		d.Merge(a.State())
+		d.Merge(a.State())
+		if got := d.Len(); got != 0 {
+			t.Errorf("Len() = %v, want %v", got, 0)
+		}
+	})
+}
+
+func TestORSet_Compact(t *testing.T) {
+	a, b := NewORSet[int]("a"), NewORSet[int]("b")
+	b.Merge(a.Add(1, 20*time.Millisecond))
+	b.Merge(a.Add(2, 0))
+	a.Merge(b.Remove(1))
+
+	if got := len(a.State().Removes); got != 1 {
+		t.Fatalf("len(State().Removes) = %v, want %v", got, 1)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := a.Compact(); got != 1 {
+		t.Errorf("Compact() = %v, want %v", got, 1)
+	}
+	if got := a.State(); len(got.Removes) != 0 || len(got.Adds) != 1 {
+		t.Errorf("State() = %v, want only the addition of 2", got)
+	}
+	if !a.Contains(2) {
+		t.Errorf("Contains(2) = false, want true")
+	}
+}
+
+func TestORSet_Restart(t *testing.T) {
+	a, b := NewORSet[int]("a"), NewORSet[int]("b")
+	b.Merge(a.Add(1, 0))
+	a.Merge(b.Remove(1))
+
+	// the replica restarts without its state, its sequence numbers starting over
+	restarted := NewORSet[int]("a")
+	add := restarted.Add(1, 0)
+	b.Merge(add)
+
+	t.Run("NewDot", func(t *testing.T) {
+		if got := add.Adds[0].Dot; slices.ContainsFunc(b.State().Removes, func(rm ORSetRemove) bool { return rm.Dot == got }) {
+			t.Errorf("Add().Dot = %v, want a dot not tombstoned before the restart", got)
+		}
+	})
+
+	t.Run("Added", func(t *testing.T) {
+		if !b.Contains(1) {
+			t.Errorf("Contains(1) = false, want true")
+		}
+	})
+}
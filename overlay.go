@@ -0,0 +1,309 @@
+// Package cacheset
+//
+// Path: overlay.go
+//
+// Description: overlay.go contains the copy-on-write storage used while a snapshot is written.
+//
+// While Snapshot encodes the elements of a cache, the storage of the cache is frozen and wrapped
+// in an overlay recording the changes made in the meantime, so that the cache is only locked to
+// swap the storages, not to encode the elements. The overlay is merged back into the frozen
+// storage once the snapshot is written.
+package cacheset
+
+import (
+	"iter"
+	"slices"
+	"time"
+)
+
+// overlay is a storage recording the changes made over a frozen storage
+//
+// Description: An element of base shadowed by added is also recorded in deleted, so that the visible
+// entry of an element is its entry in added, or else its entry in base unless deleted or cleared.
+// The entries of base are never written, except for the atomic access times, since they are read by
+// the snapshot without the lock of the cache.
+type overlay[T comparable] struct {
+	base    store[T]       // base is the frozen storage
+	added   set[T]         // added are the entries written since base was frozen
+	deleted map[T]struct{} // deleted are the elements of base removed or shadowed by added
+	cleared bool           // cleared is true if all elements of base were removed
+	n       int            // n is the number of visible elements
+}
+
+// newOverlay returns an overlay over the given frozen storage
+func newOverlay[T comparable](base store[T]) *overlay[T] {
+	return &overlay[T]{
+		base:    base,
+		added:   newSet[T](),
+		deleted: make(map[T]struct{}),
+		n:       base.Len(),
+	}
+}
+
+// merge applies the changes to the frozen storage and returns it, the overlay must not be used anymore
+func (o *overlay[T]) merge() store[T] {
+	if o.cleared {
+		o.base.Clear()
+	}
+	for elem := range o.deleted {
+		o.base.Delete(elem)
+	}
+	for elem, e := range o.added {
+		o.base.Set(elem, e)
+	}
+	return o.base
+}
+
+// inBase returns the entry of the given element in base if it is visible
+func (o *overlay[T]) inBase(elem T) (*entry, bool) {
+	if o.cleared {
+		return nil, false
+	}
+	if _, ok := o.deleted[elem]; ok {
+		return nil, false
+	}
+	return o.base.Get(elem)
+}
+
+// shadow hides the entry of the given element in base before it is written in added
+func (o *overlay[T]) shadow(elem T) {
+	if !o.cleared && o.base.Contains(elem) {
+		o.deleted[elem] = struct{}{}
+	}
+}
+
+// own returns the entry of the given element in added, copying it from base first, and false if it is not visible
+func (o *overlay[T]) own(elem T) (*entry, bool) {
+	if e, ok := o.added[elem]; ok {
+		return e, true
+	}
+	e, ok := o.inBase(elem)
+	if !ok {
+		return nil, false
+	}
+	o.shadow(elem)
+	o.added[elem] = e.copy()
+	return o.added[elem], true
+}
+
+// Get returns the entry of the given element
+func (o *overlay[T]) Get(elem T) (*entry, bool) {
+	if e, ok := o.added[elem]; ok {
+		return e, true
+	}
+	return o.inBase(elem)
+}
+
+// Set stores the given entry as the entry of the given element
+func (o *overlay[T]) Set(elem T, e *entry) {
+	if _, ok := o.added[elem]; !ok {
+		if _, ok := o.inBase(elem); !ok {
+			o.n++
+		}
+		o.shadow(elem)
+	}
+	o.added.Set(elem, e)
+}
+
+// Contains returns true if the given element is in the overlay
+func (o *overlay[T]) Contains(elem T) bool {
+	_, ok := o.Get(elem)
+	return ok
+}
+
+// Touch records an access to the given element and returns true if it is in the overlay
+func (o *overlay[T]) Touch(elem T) bool {
+	e, ok := o.Get(elem)
+	if ok {
+		e.touch(nanotime())
+	}
+	return ok
+}
+
+// Delete removes the given element from the overlay
+func (o *overlay[T]) Delete(elem T) {
+	if !o.Contains(elem) {
+		return
+	}
+	o.added.Delete(elem)
+	o.shadow(elem)
+	o.n--
+}
+
+// Expired returns true if the given element has expired
+func (o *overlay[T]) Expired(elem T) bool {
+	e, ok := o.Get(elem)
+	return ok && e.expired(nanotime())
+}
+
+// Expire removes the given element from the overlay if it has expired and returns true if it was removed
+func (o *overlay[T]) Expire(elem T) bool {
+	if o.Expired(elem) {
+		o.Delete(elem)
+		return true
+	}
+	return false
+}
+
+// ExpireAll removes all expired elements from the overlay and returns them
+func (o *overlay[T]) ExpireAll() []T {
+	var removed []T
+	now := nanotime()
+	for elem, e := range o.All() {
+		if e.expired(now) {
+			removed = append(removed, elem)
+		}
+	}
+	for _, elem := range removed {
+		o.Delete(elem)
+	}
+	return removed
+}
+
+// Add adds the given element to the overlay with the given expiration time
+func (o *overlay[T]) Add(elem T, duration time.Duration) {
+	o.AddWithIdle(elem, duration, 0)
+}
+
+// AddWithIdle adds the given element to the overlay, expiring after ttl or after maxIdle without access
+func (o *overlay[T]) AddWithIdle(elem T, ttl, maxIdle time.Duration) {
+	now := nanotime()
+	if e, ok := o.own(elem); ok {
+		e.reset(ttl, maxIdle, now)
+		return
+	}
+	o.Set(elem, newEntry(ttl, maxIdle, now))
+}
+
+// Clear removes all elements from the overlay
+func (o *overlay[T]) Clear() {
+	o.added.Clear()
+	clear(o.deleted)
+	o.cleared = true
+	o.n = 0
+}
+
+// Len returns the number of elements in the overlay
+func (o *overlay[T]) Len() int {
+	return o.n
+}
+
+// Probe returns an iterator over the elements of the overlay and their entries, ignoring the position
+func (o *overlay[T]) Probe(_ *int) iter.Seq2[T, *entry] {
+	return o.All()
+}
+
+// All returns an iterator over the elements of the overlay and their entries
+func (o *overlay[T]) All() iter.Seq2[T, *entry] {
+	return func(yield func(T, *entry) bool) {
+		for elem, e := range o.added {
+			if !yield(elem, e) {
+				return
+			}
+		}
+		if o.cleared {
+			return
+		}
+		for elem, e := range o.base.All() {
+			if _, ok := o.deleted[elem]; ok {
+				continue
+			}
+			if !yield(elem, e) {
+				return
+			}
+		}
+	}
+}
+
+// Copy returns a copy of the overlay, of the kind of the frozen storage
+func (o *overlay[T]) Copy() store[T] {
+	c := o.base.Copy()
+	if o.cleared {
+		c.Clear()
+	}
+	for elem := range o.deleted {
+		c.Delete(elem)
+	}
+	for elem, e := range o.added {
+		c.Set(elem, e.copy())
+	}
+	return c
+}
+
+// Merge adds all unexpired elements of other to the overlay and returns the elements that were not in the overlay
+//
+// Description: When an element exists in both, resolve is called with both expiration times
+// and its result is stored as the new expiration time.
+func (o *overlay[T]) Merge(other store[T], resolve func(a, b int64) int64) []T {
+	var added []T
+	now := nanotime()
+	for elem, v := range other.All() {
+		if v.expired(now) {
+			continue
+		}
+		if e, ok := o.Get(elem); ok && !e.expired(now) {
+			e, _ = o.own(elem)
+			e.expires = resolve(e.expires, v.expires)
+			continue
+		}
+		if !o.Contains(elem) {
+			added = append(added, elem)
+		}
+		o.Set(elem, v.copy())
+	}
+	return added
+}
+
+// Expirations returns a map of the overlay's elements to their expiration times in nanoseconds since the Unix epoch,
+// 0 meaning no expiration
+func (o *overlay[T]) Expirations() map[T]int64 {
+	m := make(map[T]int64, o.n)
+	for elem, e := range o.All() {
+		m[elem] = toWall(e.deadline())
+	}
+	return m
+}
+
+// ExpiringFirst returns up to n elements of the overlay, the ones expiring first, the elements without expiration last
+func (o *overlay[T]) ExpiringFirst(n int) []T {
+	elems := o.ToSlice()
+	deadlines := make(map[T]int64, len(elems))
+	for elem, e := range o.All() {
+		deadlines[elem] = e.deadline()
+	}
+	slices.SortFunc(elems, func(a, b T) int {
+		return compareDeadlines(deadlines[a], deadlines[b])
+	})
+	return elems[:min(n, len(elems))]
+}
+
+// ToSlice returns a slice of the overlay's elements
+func (o *overlay[T]) ToSlice() []T {
+	slice := make([]T, 0, o.n)
+	for elem := range o.All() {
+		slice = append(slice, elem)
+	}
+	return slice
+}
+
+// Filter returns a slice of the overlay's unexpired elements for which pred returns true
+func (o *overlay[T]) Filter(pred func(T) bool) []T {
+	in, _ := o.Partition(pred)
+	return in
+}
+
+// Partition splits the overlay's unexpired elements into those for which pred returns true and the others
+func (o *overlay[T]) Partition(pred func(T) bool) (in []T, out []T) {
+	now := nanotime()
+	for elem, e := range o.All() {
+		if e.expired(now) {
+			continue
+		}
+		if pred(elem) {
+			in = append(in, elem)
+		} else {
+			out = append(out, elem)
+		}
+	}
+	return in, out
+}
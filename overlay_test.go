@@ -0,0 +1,120 @@
+package cacheset
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOverlay(t *testing.T) {
+	for name, base := range map[string]store[int]{"set": newSet[int](), "table": newTable[int]()} {
+		t.Run(name, func(t *testing.T) {
+			for elem := range 4 {
+				base.Add(elem, 0)
+			}
+			o := newOverlay(base)
+			o.Delete(0)
+			o.Add(1, time.Hour)
+			o.Add(4, 0)
+			o.Delete(4)
+			o.Add(5, 0)
+
+			want := []int{1, 2, 3, 5}
+			if got := o.ToSlice(); !equalUnordered(got, want) {
+				t.Errorf("ToSlice() = %v, want %v", got, want)
+			}
+			if got := o.Len(); got != len(want) {
+				t.Errorf("Len() = %v, want %v", got, len(want))
+			}
+			if e, _ := base.Get(1); e.expires != 0 {
+				t.Errorf("Add() wrote the frozen entry")
+			}
+			if got := base.Len(); got != 4 {
+				t.Errorf("base.Len() = %v, want %v", got, 4)
+			}
+
+			merged := o.merge()
+			if got := merged.ToSlice(); !equalUnordered(got, want) {
+				t.Errorf("merge().ToSlice() = %v, want %v", got, want)
+			}
+			if e, _ := merged.Get(1); e.expires == 0 {
+				t.Errorf("merge() lost the expiration of 1")
+			}
+		})
+	}
+
+	t.Run("Clear", func(t *testing.T) {
+		base := newSet[int]()
+		base.Add(1, 0)
+		o := newOverlay[int](base)
+		o.Clear()
+		o.Add(2, 0)
+		if got := o.merge().ToSlice(); !slices.Equal(got, []int{2}) {
+			t.Errorf("merge().ToSlice() = %v, want %v", got, []int{2})
+		}
+	})
+}
+
+// blockingWriter blocks its first write until unblock is closed
+type blockingWriter struct {
+	w       io.Writer
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.started:
+	default:
+		close(w.started)
+		<-w.unblock
+	}
+	return w.w.Write(p)
+}
+
+func TestCache_SnapshotConcurrentMutations(t *testing.T) {
+	c := New[int](time.Minute)
+	defer c.Close()
+	for elem := range 100 {
+		c.Add(elem, 0)
+	}
+
+	var buf bytes.Buffer
+	w := &blockingWriter{w: &buf, started: make(chan struct{}), unblock: make(chan struct{})}
+	done := make(chan error)
+	go func() { done <- c.Snapshot(w, SnapshotAbsolute) }()
+	<-w.started
+
+	// the cache is not locked while the snapshot is written
+	c.Delete(0)
+	c.Add(100, 0)
+	if got := c.Len(); got != 100 {
+		t.Errorf("Len() = %v during the snapshot, want %v", got, 100)
+	}
+	close(w.unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	if c.Contains(0) || !c.Contains(100) || c.Len() != 100 {
+		t.Errorf("the changes made during the snapshot were lost")
+	}
+	restored := New[int](time.Minute)
+	defer restored.Close()
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !restored.Contains(0) || restored.Contains(100) || restored.Len() != 100 {
+		t.Errorf("the snapshot is not the state of the cache when it started")
+	}
+}
+
+// equalUnordered returns true if both slices hold the same elements
+func equalUnordered(a, b []int) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
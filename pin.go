@@ -0,0 +1,144 @@
+// Package cacheset
+//
+// Path: pin.go
+//
+// Description: pin.go contains the pinning of the elements, which exempts them from the evictions and
+// optionally from the expiration.
+package cacheset
+
+// WithPinnedNoExpiry keeps the pinned elements from expiring until they are unpinned
+//
+// Description: While an element is pinned, its entry is replaced by one without expiration, and its own
+// expiration is set aside: an element added again while pinned updates the expiration set aside. Unpin
+// restores it, so that an element whose time to live elapsed while it was pinned expires at the next
+// cleaning. The snapshots and the clones record the pinned elements as never expiring.
+func WithPinnedNoExpiry() Option {
+	return func(o *options) {
+		o.pinnedNoExpiry = true
+	}
+}
+
+// Pin exempts the given element from the evictions until it is unpinned or removed, and returns false
+// if it is not in the cache
+//
+// Description: The pinned elements are skipped by the eviction policy and by the evictions under memory
+// pressure, so a cache whose elements are all pinned rejects the new ones with ErrCapacityExceeded. They
+// still expire, unless the cache was created with WithPinnedNoExpiry. Pinning a pinned element does nothing.
+// The number of pinned elements is reported in Stats.
+func (c *Cache[T]) Pin(elem T) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.set.Get(elem)
+	if !ok || e.expired(nanotime()) {
+		return false
+	}
+	if _, ok := c.pins[elem]; ok {
+		return true
+	}
+	if c.pins == nil {
+		c.pins = make(map[T]*entry)
+	}
+	c.pins[elem] = nil
+	c.pinCount.Add(1)
+	if c.policy != nil {
+		c.policyMu.Lock()
+		c.policy.remove(elem)
+		c.policyMu.Unlock()
+	}
+	c.hold(elem)
+
+	return true
+}
+
+// Unpin lets the given element be evicted and expire again, and returns false if it was not pinned
+func (c *Cache[T]) Unpin(elem T) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	held, ok := c.pins[elem]
+	if !ok {
+		return false
+	}
+	delete(c.pins, elem)
+	c.pinCount.Add(-1)
+	if held != nil {
+		c.set.Set(elem, held)
+		c.markDirty(elem)
+		c.due(elem)
+	}
+	if c.policy != nil {
+		c.policyMu.Lock()
+		c.policy.add(elem)
+		c.policyMu.Unlock()
+	}
+	c.shrink()
+
+	return true
+}
+
+// Pinned returns true if the given element is pinned
+func (c *Cache[T]) Pinned(elem T) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	_, ok := c.pins[elem]
+	return ok
+}
+
+// pinned returns true if the given element is pinned, the cache must be locked
+func (c *Cache[T]) pinned(elem T) bool {
+	_, ok := c.pins[elem]
+	return ok
+}
+
+// hold sets aside the expiration of the given pinned element with WithPinnedNoExpiry, and replaces its entry
+// by one without expiration, the cache must be locked
+func (c *Cache[T]) hold(elem T) {
+	if !c.options.pinnedNoExpiry || !c.pinned(elem) {
+		return
+	}
+	e, ok := c.set.Get(elem)
+	if !ok {
+		return
+	}
+	if held := c.pins[elem]; held != nil {
+		held.release()
+	}
+	c.pins[elem] = e.copy()
+	c.set.Set(elem, newEntry(0, 0, nanotime()))
+}
+
+// unpin forgets the pin of the given removed element, the cache must be locked
+func (c *Cache[T]) unpin(elem T) {
+	held, ok := c.pins[elem]
+	if !ok {
+		return
+	}
+	if held != nil {
+		held.release()
+	}
+	delete(c.pins, elem)
+	c.pinCount.Add(-1)
+}
+
+// unpinAll forgets all pins, the cache must be locked
+func (c *Cache[T]) unpinAll() {
+	for _, held := range c.pins {
+		if held != nil {
+			held.release()
+		}
+	}
+	c.pins = nil
+	c.pinCount.Store(0)
+}
+
+// Pin exempts the given element from the evictions of its shard, see Cache.Pin
+func (s *Sharded[T]) Pin(elem T) bool {
+	return s.shard(elem).Pin(elem)
+}
+
+// Unpin lets the given element be evicted and expire again, see Cache.Unpin
+func (s *Sharded[T]) Unpin(elem T) bool {
+	return s.shard(elem).Unpin(elem)
+}
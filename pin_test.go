@@ -0,0 +1,131 @@
+package cacheset
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_Pin(t *testing.T) {
+	t.Run("Eviction", func(t *testing.T) {
+		c := New[int](time.Minute, WithCapacity(2))
+		defer c.Close()
+
+		_ = c.Add(1, 0)
+		_ = c.Add(2, 0)
+		if !c.Pin(1) {
+			t.Fatalf("Pin() = false, want true")
+		}
+		_ = c.Add(3, 0)
+		if !c.Contains(1) || c.Contains(2) || !c.Contains(3) {
+			t.Errorf("ToSlice() = %v, want the pinned %v kept and %v evicted", c.ToSlice(), 1, 2)
+		}
+
+		c.Pin(3)
+		if err := c.Add(4, 0); !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Add() error = %v, want %v", err, ErrCapacityExceeded)
+		}
+		if got := c.Stats().Pinned; got != 2 {
+			t.Errorf("Stats().Pinned = %v, want %v", got, 2)
+		}
+
+		if !c.Unpin(1) || c.Unpin(1) {
+			t.Errorf("Unpin() did not unpin %v once", 1)
+		}
+		if err := c.Add(4, 0); err != nil {
+			t.Fatalf("Add() error = %v, want nil", err)
+		}
+		if c.Contains(1) || !c.Contains(3) {
+			t.Errorf("ToSlice() = %v, want the unpinned %v evicted", c.ToSlice(), 1)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		c := New[int](time.Minute)
+		defer c.Close()
+
+		if c.Pin(1) {
+			t.Errorf("Pin() = true, want false")
+		}
+		_ = c.Add(1, 0)
+		c.Pin(1)
+		c.Delete(1)
+		if c.Pinned(1) || c.Stats().Pinned != 0 {
+			t.Errorf("Pinned() = true after Delete, want false")
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		c := New[int](time.Minute)
+		defer c.Close()
+
+		_ = c.Add(1, 0)
+		_ = c.Add(2, 0)
+		c.Pin(1)
+		c.Pin(2)
+		c.Lock()
+		got := make(chan int)
+		go func() { got <- c.Stats().Pinned }()
+		select {
+		case n := <-got:
+			if n != 2 {
+				t.Errorf("Stats().Pinned = %v, want %v", n, 2)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Stats() blocked on the lock of the cache")
+			c.Unlock()
+			<-got
+			return
+		}
+		c.Unlock()
+
+		c.Clear()
+		if got := c.Stats().Pinned; got != 0 {
+			t.Errorf("Stats().Pinned = %v after Clear, want %v", got, 0)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		c := New[int](time.Minute)
+		defer c.Close()
+
+		_ = c.Add(1, 10*time.Millisecond)
+		c.Pin(1)
+		time.Sleep(20 * time.Millisecond)
+		c.ExpireAll()
+		if c.Contains(1) {
+			t.Errorf("Contains() = true, want the pinned element expired without WithPinnedNoExpiry")
+		}
+	})
+
+	t.Run("NoExpiry", func(t *testing.T) {
+		c := New[int](time.Minute, WithPinnedNoExpiry())
+		defer c.Close()
+
+		_ = c.Add(1, 10*time.Millisecond)
+		c.Pin(1)
+		time.Sleep(20 * time.Millisecond)
+		c.ExpireAll()
+		if !c.Contains(1) {
+			t.Fatalf("Contains() = false, want the pinned element kept")
+		}
+
+		c.Unpin(1)
+		c.ExpireAll()
+		if c.Contains(1) {
+			t.Errorf("Contains() = true, want the unpinned element expired")
+		}
+	})
+
+	t.Run("MemoryPressure", func(t *testing.T) {
+		c := New[int](time.Minute)
+		defer c.Close()
+
+		_ = c.Add(1, time.Second)
+		_ = c.Add(2, time.Minute)
+		c.Pin(1)
+		if n := c.shed(1); n != 1 || !c.Contains(1) || c.Contains(2) {
+			t.Errorf("shed() = %v, want the pinned element skipped", n)
+		}
+	})
+}
@@ -0,0 +1,420 @@
+// Package cacheset
+//
+// Path: policy.go
+//
+// Description: policy.go contains the eviction policies of the cache.
+package cacheset
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+// EvictionPolicy chooses the element evicted when an element is added to a full cache
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the least recently used element
+	EvictLRU EvictionPolicy = iota
+	// EvictSLRU is a segmented LRU: new elements enter a probation segment and are promoted
+	// to a protected segment when accessed again, the least recently used element on probation is evicted
+	EvictSLRU
+	// Evict2Q keeps new elements in a FIFO queue and remembers the recently evicted ones,
+	// elements added again shortly after their eviction enter an LRU queue of frequently used elements
+	Evict2Q
+	// EvictCLOCK approximates LRU with a reference bit per element, giving a second chance
+	// to the elements accessed since the clock hand last passed them
+	EvictCLOCK
+)
+
+// String returns the name of the eviction policy
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictLRU:
+		return "LRU"
+	case EvictSLRU:
+		return "SLRU"
+	case Evict2Q:
+		return "2Q"
+	case EvictCLOCK:
+		return "CLOCK"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText returns the name of the eviction policy
+func (p EvictionPolicy) MarshalText() ([]byte, error) {
+	if p.String() == "unknown" {
+		return nil, fmt.Errorf("cacheset: unknown eviction policy %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText sets the eviction policy from its case-insensitive name: LRU, SLRU, 2Q or CLOCK
+func (p *EvictionPolicy) UnmarshalText(text []byte) error {
+	for _, policy := range []EvictionPolicy{EvictLRU, EvictSLRU, Evict2Q, EvictCLOCK} {
+		if strings.EqualFold(string(text), policy.String()) {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("cacheset: unknown eviction policy %q", text)
+}
+
+// policy tracks the accesses to the elements of a cache and chooses which one to evict
+type policy[T comparable] interface {
+	add(elem T)        // add records a new element
+	access(elem T)     // access records an access to an element
+	remove(elem T)     // remove forgets an element deleted or expired
+	evict(elem T)      // evict forgets an element evicted by the cache
+	victim() (T, bool) // victim returns the element to evict, false if there is none
+	clear()            // clear forgets all elements
+}
+
+// newPolicy returns the implementation of the given eviction policy
+func newPolicy[T comparable](p EvictionPolicy, o options) policy[T] {
+	switch p {
+	case EvictSLRU:
+		return newSLRU[T](max(1, int(float64(o.capacity)*o.slruProtected)))
+	case Evict2Q:
+		return newTwoQueue[T](o.capacity)
+	case EvictCLOCK:
+		return newClock[T]()
+	default:
+		return newLRU[T]()
+	}
+}
+
+// lru is a least recently used eviction policy
+type lru[T comparable] struct {
+	elems map[T]*list.Element // elems are the elements of the list indexed by value
+	order *list.List          // order lists the elements from the most to the least recently used
+}
+
+// newLRU returns a new least recently used eviction policy
+func newLRU[T comparable]() *lru[T] {
+	return &lru[T]{
+		elems: make(map[T]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (l *lru[T]) add(elem T) {
+	if e, ok := l.elems[elem]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.elems[elem] = l.order.PushFront(elem)
+}
+
+func (l *lru[T]) access(elem T) {
+	if e, ok := l.elems[elem]; ok {
+		l.order.MoveToFront(e)
+	}
+}
+
+func (l *lru[T]) evict(elem T) {
+	l.remove(elem)
+}
+
+func (l *lru[T]) remove(elem T) {
+	if e, ok := l.elems[elem]; ok {
+		l.order.Remove(e)
+		delete(l.elems, elem)
+	}
+}
+
+func (l *lru[T]) victim() (T, bool) {
+	e := l.order.Back()
+	if e == nil {
+		var zero T
+		return zero, false
+	}
+	return e.Value.(T), true
+}
+
+func (l *lru[T]) clear() {
+	l.elems = make(map[T]*list.Element)
+	l.order.Init()
+}
+
+// slruEntry is an element of a segmented LRU
+type slruEntry[T comparable] struct {
+	elem      T
+	protected bool
+}
+
+// slru is a segmented least recently used eviction policy
+type slru[T comparable] struct {
+	elems        map[T]*list.Element // elems are the elements of both segments indexed by value
+	probation    *list.List          // probation lists the elements accessed once, most recent first
+	protected    *list.List          // protected lists the elements accessed more than once, most recent first
+	protectedCap int                 // protectedCap is the maximum number of elements in the protected segment
+}
+
+// newSLRU returns a new segmented least recently used eviction policy
+func newSLRU[T comparable](protectedCap int) *slru[T] {
+	return &slru[T]{
+		elems:        make(map[T]*list.Element),
+		probation:    list.New(),
+		protected:    list.New(),
+		protectedCap: protectedCap,
+	}
+}
+
+func (s *slru[T]) add(elem T) {
+	if _, ok := s.elems[elem]; ok {
+		s.access(elem)
+		return
+	}
+	s.elems[elem] = s.probation.PushFront(&slruEntry[T]{elem: elem})
+}
+
+func (s *slru[T]) access(elem T) {
+	e, ok := s.elems[elem]
+	if !ok {
+		return
+	}
+	entry := e.Value.(*slruEntry[T])
+	if entry.protected {
+		s.protected.MoveToFront(e)
+		return
+	}
+
+	s.probation.Remove(e)
+	entry.protected = true
+	s.elems[elem] = s.protected.PushFront(entry)
+
+	if s.protected.Len() > s.protectedCap {
+		demoted := s.protected.Back()
+		s.protected.Remove(demoted)
+		entry := demoted.Value.(*slruEntry[T])
+		entry.protected = false
+		s.elems[entry.elem] = s.probation.PushFront(entry)
+	}
+}
+
+func (s *slru[T]) evict(elem T) {
+	s.remove(elem)
+}
+
+func (s *slru[T]) remove(elem T) {
+	e, ok := s.elems[elem]
+	if !ok {
+		return
+	}
+	if e.Value.(*slruEntry[T]).protected {
+		s.protected.Remove(e)
+	} else {
+		s.probation.Remove(e)
+	}
+	delete(s.elems, elem)
+}
+
+func (s *slru[T]) victim() (T, bool) {
+	if e := s.probation.Back(); e != nil {
+		return e.Value.(*slruEntry[T]).elem, true
+	}
+	if e := s.protected.Back(); e != nil {
+		return e.Value.(*slruEntry[T]).elem, true
+	}
+	var zero T
+	return zero, false
+}
+
+func (s *slru[T]) clear() {
+	s.elems = make(map[T]*list.Element)
+	s.probation.Init()
+	s.protected.Init()
+}
+
+// twoQueueEntry is an element of a 2Q policy
+type twoQueueEntry[T comparable] struct {
+	elem     T
+	frequent bool
+}
+
+// twoQueue is a 2Q eviction policy
+type twoQueue[T comparable] struct {
+	elems      map[T]*list.Element // elems are the elements of recent and frequent indexed by value
+	ghosts     map[T]*list.Element // ghosts are the elements of evicted indexed by value
+	recent     *list.List          // recent lists the elements seen once in FIFO order, newest first
+	frequent   *list.List          // frequent lists the elements added again after their removal, most recent first
+	evicted    *list.List          // evicted lists the elements recently removed from recent, newest first
+	recentCap  int                 // recentCap is the length above which recent is evicted before frequent
+	evictedCap int                 // evictedCap is the maximum number of remembered evicted elements
+}
+
+// newTwoQueue returns a new 2Q eviction policy for the given capacity
+func newTwoQueue[T comparable](capacity int) *twoQueue[T] {
+	return &twoQueue[T]{
+		elems:      make(map[T]*list.Element),
+		ghosts:     make(map[T]*list.Element),
+		recent:     list.New(),
+		frequent:   list.New(),
+		evicted:    list.New(),
+		recentCap:  max(1, capacity/4),
+		evictedCap: max(1, capacity/2),
+	}
+}
+
+func (q *twoQueue[T]) add(elem T) {
+	if _, ok := q.elems[elem]; ok {
+		q.access(elem)
+		return
+	}
+	if g, ok := q.ghosts[elem]; ok {
+		q.evicted.Remove(g)
+		delete(q.ghosts, elem)
+		q.elems[elem] = q.frequent.PushFront(&twoQueueEntry[T]{elem: elem, frequent: true})
+		return
+	}
+	q.elems[elem] = q.recent.PushFront(&twoQueueEntry[T]{elem: elem})
+}
+
+func (q *twoQueue[T]) access(elem T) {
+	if e, ok := q.elems[elem]; ok && e.Value.(*twoQueueEntry[T]).frequent {
+		q.frequent.MoveToFront(e)
+	}
+}
+
+func (q *twoQueue[T]) remove(elem T) {
+	q.drop(elem)
+}
+
+// evict forgets the given element, remembering it as a ghost if it was seen once, so that it is added
+// to frequent if it comes back soon: the deleted and expired elements are not remembered
+func (q *twoQueue[T]) evict(elem T) {
+	if !q.drop(elem) {
+		return
+	}
+	q.ghosts[elem] = q.evicted.PushFront(elem)
+	if q.evicted.Len() > q.evictedCap {
+		oldest := q.evicted.Back()
+		q.evicted.Remove(oldest)
+		delete(q.ghosts, oldest.Value.(T))
+	}
+}
+
+// drop removes the given element from its queue and returns true if it was in recent
+func (q *twoQueue[T]) drop(elem T) bool {
+	e, ok := q.elems[elem]
+	if !ok {
+		return false
+	}
+	delete(q.elems, elem)
+	if e.Value.(*twoQueueEntry[T]).frequent {
+		q.frequent.Remove(e)
+		return false
+	}
+	q.recent.Remove(e)
+	return true
+}
+
+func (q *twoQueue[T]) victim() (T, bool) {
+	if e := q.recent.Back(); e != nil && (q.recent.Len() > q.recentCap || q.frequent.Len() == 0) {
+		return e.Value.(*twoQueueEntry[T]).elem, true
+	}
+	if e := q.frequent.Back(); e != nil {
+		return e.Value.(*twoQueueEntry[T]).elem, true
+	}
+	var zero T
+	return zero, false
+}
+
+func (q *twoQueue[T]) clear() {
+	q.elems = make(map[T]*list.Element)
+	q.ghosts = make(map[T]*list.Element)
+	q.recent.Init()
+	q.frequent.Init()
+	q.evicted.Init()
+}
+
+// clockEntry is a slot of a CLOCK policy
+type clockEntry[T comparable] struct {
+	elem       T
+	referenced bool
+	used       bool
+}
+
+// clock is a CLOCK (second chance) eviction policy
+type clock[T comparable] struct {
+	elems map[T]int       // elems are the slots of the elements indexed by value
+	slots []clockEntry[T] // slots is the ring of elements swept by the hand
+	free  []int           // free are the indexes of the unused slots
+	hand  int             // hand is the index of the next slot to inspect
+}
+
+// newClock returns a new CLOCK eviction policy
+func newClock[T comparable]() *clock[T] {
+	return &clock[T]{elems: make(map[T]int)}
+}
+
+func (c *clock[T]) add(elem T) {
+	if _, ok := c.elems[elem]; ok {
+		c.access(elem)
+		return
+	}
+
+	entry := clockEntry[T]{elem: elem, used: true}
+	if n := len(c.free); n > 0 {
+		i := c.free[n-1]
+		c.free = c.free[:n-1]
+		c.slots[i] = entry
+		c.elems[elem] = i
+		return
+	}
+	c.slots = append(c.slots, entry)
+	c.elems[elem] = len(c.slots) - 1
+}
+
+func (c *clock[T]) access(elem T) {
+	if i, ok := c.elems[elem]; ok {
+		c.slots[i].referenced = true
+	}
+}
+
+func (c *clock[T]) evict(elem T) {
+	c.remove(elem)
+}
+
+func (c *clock[T]) remove(elem T) {
+	i, ok := c.elems[elem]
+	if !ok {
+		return
+	}
+	delete(c.elems, elem)
+	c.slots[i] = clockEntry[T]{}
+	c.free = append(c.free, i)
+}
+
+// victim moves the hand to the first unreferenced element, clearing the reference bits on its way
+func (c *clock[T]) victim() (T, bool) {
+	if len(c.elems) == 0 {
+		var zero T
+		return zero, false
+	}
+	for {
+		if c.hand >= len(c.slots) {
+			c.hand = 0
+		}
+		slot := &c.slots[c.hand]
+		switch {
+		case !slot.used:
+		case slot.referenced:
+			slot.referenced = false
+		default:
+			return slot.elem, true
+		}
+		c.hand++
+	}
+}
+
+func (c *clock[T]) clear() {
+	c.elems = make(map[T]int)
+	c.slots = nil
+	c.free = nil
+	c.hand = 0
+}
@@ -0,0 +1,83 @@
+package cacheset
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchPolicies are the eviction policies compared by the hit ratio benchmarks
+var benchPolicies = []EvictionPolicy{EvictLRU, EvictSLRU, Evict2Q, EvictCLOCK}
+
+// BenchmarkPolicy_Zipf compares the hit ratio of the eviction policies on a zipfian trace
+func BenchmarkPolicy_Zipf(b *testing.B) {
+	z := rand.NewZipf(rand.New(rand.NewSource(42)), 1.1, 1, 100_000)
+	trace := make([]string, 200_000)
+	for i := range trace {
+		trace[i] = strconv.FormatUint(z.Uint64(), 10)
+	}
+	benchmarkPolicies(b, trace, 1000)
+}
+
+// BenchmarkPolicy_Trace compares the hit ratio of the eviction policies on the trace file
+// named by the CACHESET_TRACE environment variable, whose lines start with the accessed key.
+// The capacity is read from CACHESET_TRACE_CAPACITY and defaults to 1000.
+//
+//	CACHESET_TRACE=trace.txt go test -run=^$ -bench=Policy_Trace
+func BenchmarkPolicy_Trace(b *testing.B) {
+	path := os.Getenv("CACHESET_TRACE")
+	if path == "" {
+		b.Skip("CACHESET_TRACE is not set")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	var trace []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			trace = append(trace, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		b.Fatal(err)
+	}
+
+	capacity := 1000
+	if v := os.Getenv("CACHESET_TRACE_CAPACITY"); v != "" {
+		if _, err := fmt.Sscan(v, &capacity); err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchmarkPolicies(b, trace, capacity)
+}
+
+// benchmarkPolicies replays the trace on a cache with the given capacity for each eviction policy,
+// adding the missed keys, and reports the hit ratio
+func benchmarkPolicies(b *testing.B, trace []string, capacity int) {
+	for _, policy := range benchPolicies {
+		b.Run(policy.String(), func(b *testing.B) {
+			var s Stats
+			for i := 0; i < b.N; i++ {
+				c := New[string](time.Hour, WithCapacity(capacity), WithEvictionPolicy(policy))
+				for _, key := range trace {
+					if !c.Contains(key) {
+						c.Add(key, 0)
+					}
+				}
+				s = c.Stats()
+				c.Close()
+			}
+			b.ReportMetric(s.HitRatio(), "hit-ratio")
+		})
+	}
+}
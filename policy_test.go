@@ -0,0 +1,111 @@
+package cacheset
+
+import "testing"
+
+func Test_lru(t *testing.T) {
+	p := newLRU[int64]()
+	p.add(1)
+	p.add(2)
+	p.add(3)
+	p.access(1)
+
+	t.Run("victim", func(t *testing.T) {
+		if got, _ := p.victim(); got != 2 {
+			t.Errorf("victim() = %v, want %v", got, 2)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		p.remove(2)
+		if got, _ := p.victim(); got != 3 {
+			t.Errorf("victim() = %v, want %v", got, 3)
+		}
+	})
+}
+
+func Test_slru(t *testing.T) {
+	p := newSLRU[int64](1)
+	p.add(1)
+	p.add(2)
+	p.add(3)
+	p.access(1)
+
+	t.Run("probation", func(t *testing.T) {
+		if got, _ := p.victim(); got != 2 {
+			t.Errorf("victim() = %v, want %v", got, 2)
+		}
+	})
+
+	t.Run("demote", func(t *testing.T) {
+		p.access(3)
+		p.remove(2)
+		if got, _ := p.victim(); got != 1 {
+			t.Errorf("victim() = %v, want %v", got, 1)
+		}
+	})
+
+	t.Run("protected", func(t *testing.T) {
+		p.remove(1)
+		if got, ok := p.victim(); !ok || got != 3 {
+			t.Errorf("victim() = %v, want %v", got, 3)
+		}
+	})
+}
+
+func Test_twoQueue(t *testing.T) {
+	p := newTwoQueue[int64](4)
+	p.add(1)
+	p.add(2)
+
+	t.Run("recent", func(t *testing.T) {
+		if got, _ := p.victim(); got != 1 {
+			t.Errorf("victim() = %v, want %v", got, 1)
+		}
+	})
+
+	t.Run("frequent", func(t *testing.T) {
+		p.evict(1)
+		p.add(1)
+		p.add(3)
+		if got, _ := p.victim(); got != 2 {
+			t.Errorf("victim() = %v, want %v", got, 2)
+		}
+		p.remove(2)
+		p.remove(3)
+		if got, _ := p.victim(); got != 1 {
+			t.Errorf("victim() = %v, want %v", got, 1)
+		}
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		p.add(4)
+		p.remove(4)
+		p.add(4)
+		if e := p.elems[4].Value.(*twoQueueEntry[int64]); e.frequent {
+			t.Errorf("add() put a deleted element back in frequent, want recent")
+		}
+	})
+}
+
+func Test_clock(t *testing.T) {
+	p := newClock[int64]()
+	p.add(1)
+	p.add(2)
+	p.add(3)
+	p.access(1)
+
+	t.Run("second chance", func(t *testing.T) {
+		if got, _ := p.victim(); got != 2 {
+			t.Errorf("victim() = %v, want %v", got, 2)
+		}
+	})
+
+	t.Run("reuse", func(t *testing.T) {
+		p.remove(2)
+		p.add(4)
+		p.remove(3)
+		if got, _ := p.victim(); got != 4 {
+			t.Errorf("victim() = %v, want %v", got, 4)
+		}
+	})
+}
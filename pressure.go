@@ -0,0 +1,92 @@
+// Package cacheset
+//
+// Path: pressure.go
+//
+// Description: pressure.go contains the back-pressure signal raised when the cleaning falls behind the
+// additions, and the amortized expiry keeping the backlog of expired elements bounded.
+package cacheset
+
+// pressureSample is the number of elements sampled by Pressure
+const pressureSample = 64
+
+// amortizedProbes is the number of elements probed per element removed by the amortized expiry
+const amortizedProbes = 4
+
+// WithAmortizedExpiry makes each addition remove up to n expired elements itself
+//
+// Description: When the elements are added faster than the cleaning goroutine removes the expired ones,
+// the expired elements pile up between two cleanings, see Pressure. With WithAmortizedExpiry, each addition
+// probes up to 4n elements and removes the expired ones, at most n, with RemovalExpired, so the work of the
+// cleaning is spread over the additions. The probes of an addition resume where the previous ones stopped, or
+// start at a random element with the default storage, so that the whole storage is probed over the additions.
+// A small n, such as 1 or 2, is enough to keep the backlog bounded. Values below 1 disable the mode.
+func WithAmortizedExpiry(n int) Option {
+	return func(o *options) {
+		o.amortizedExpiry = max(n, 0)
+	}
+}
+
+// Pressure returns the estimated fraction of the elements of the cache that have expired but were not cleaned
+// yet, between 0 and 1
+//
+// Description: The fraction is estimated on up to 64 elements, following the ones sampled by the previous
+// call, like the probes of WithAmortizedExpiry. A pressure growing over time means that the
+// cleaning cannot keep up with the additions: shorten the clean interval or enable WithAmortizedExpiry.
+func (c *Cache[T]) Pressure() float64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := nanotime()
+	pos := int(c.probe.Load())
+	sampled, expired := 0, 0
+	for _, e := range c.set.Probe(&pos) {
+		sampled++
+		if e.expired(now) {
+			expired++
+		}
+		if sampled == pressureSample {
+			break
+		}
+	}
+	c.probe.Store(int64(pos))
+	if sampled == 0 {
+		return 0
+	}
+	return float64(expired) / float64(sampled)
+}
+
+// amortize removes up to amortizedExpiry expired elements, other than the element just added, with
+// WithAmortizedExpiry, the cache must be locked
+func (c *Cache[T]) amortize(added T) {
+	n := c.options.amortizedExpiry
+	if n == 0 {
+		return
+	}
+
+	now := nanotime()
+	pos := int(c.probe.Load())
+	probes := n * amortizedProbes
+	var expired []T
+	for elem, e := range c.set.Probe(&pos) {
+		probes--
+		if elem != added && e.expired(now) {
+			expired = append(expired, elem)
+		}
+		if probes == 0 || len(expired) == n {
+			break
+		}
+	}
+	c.probe.Store(int64(pos))
+	for _, elem := range expired {
+		c.remove(elem, RemovalExpired)
+	}
+}
+
+// Pressure returns the average pressure of the shards, see Cache.Pressure
+func (s *Sharded[T]) Pressure() float64 {
+	var sum float64
+	for _, shard := range s.shards {
+		sum += shard.Pressure()
+	}
+	return sum / float64(len(s.shards))
+}
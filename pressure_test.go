@@ -0,0 +1,98 @@
+package cacheset
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCache_Pressure(t *testing.T) {
+	c := New[int](time.Hour)
+	defer c.Close()
+
+	if got := c.Pressure(); got != 0 {
+		t.Errorf("Pressure() = %v on an empty cache, want %v", got, 0)
+	}
+	for i := range 10 {
+		_ = c.Add(i, time.Millisecond)
+	}
+	for i := 10; i < 20; i++ {
+		_ = c.Add(i, 0)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if got := c.Pressure(); got != 0.5 {
+		t.Errorf("Pressure() = %v, want %v", got, 0.5)
+	}
+	c.ExpireAll()
+	if got := c.Pressure(); got != 0 {
+		t.Errorf("Pressure() = %v after ExpireAll, want %v", got, 0)
+	}
+}
+
+func TestCache_AmortizedExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want uint64
+	}{
+		{name: "Disabled", want: 0},
+		{name: "Enabled", opts: []Option{WithAmortizedExpiry(64)}, want: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New[int](time.Hour, tt.opts...)
+			defer c.Close()
+
+			for i := range 50 {
+				_ = c.Add(i, 20*time.Millisecond)
+			}
+			time.Sleep(30 * time.Millisecond)
+			_ = c.Add(50, 0)
+			if got := c.Stats().Expirations; got != tt.want {
+				t.Errorf("Stats().Expirations = %v, want %v", got, tt.want)
+			}
+			if got := c.Len(); got != 51-int(tt.want) {
+				t.Errorf("Len() = %v, want %v", got, 51-int(tt.want))
+			}
+		})
+	}
+}
+
+func TestCache_Probe_Table(t *testing.T) {
+	t.Run("AmortizedExpiry", func(t *testing.T) {
+		c := New[int](time.Hour, WithCompactStorage(), WithAmortizedExpiry(1))
+		defer c.Close()
+
+		for i := range 200 {
+			_ = c.Add(i, time.Millisecond)
+		}
+		time.Sleep(5 * time.Millisecond)
+		for i := 200; i < 600; i++ {
+			_ = c.Add(i, 0)
+		}
+		if got := c.Stats().Expirations; got != 200 {
+			t.Errorf("Stats().Expirations = %v, want %v", got, 200)
+		}
+	})
+
+	t.Run("Pressure", func(t *testing.T) {
+		c := New[int](time.Hour, WithCompactStorage())
+		defer c.Close()
+
+		for i := range 640 {
+			ttl := time.Duration(0)
+			if i%10 == 0 {
+				ttl = time.Millisecond
+			}
+			_ = c.Add(i, ttl)
+		}
+		time.Sleep(5 * time.Millisecond)
+		var sum float64
+		for range 10 {
+			sum += c.Pressure()
+		}
+		if got := sum / 10; math.Abs(got-0.1) > 1e-9 {
+			t.Errorf("Pressure() = %v on average over the table, want %v", got, 0.1)
+		}
+	})
+}
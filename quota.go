@@ -0,0 +1,47 @@
+// Package cacheset
+//
+// Path: quota.go
+//
+// Description: quota.go contains the quotas limiting the acquisitions of a key per time window.
+package cacheset
+
+import "time"
+
+// TryAcquire counts an acquisition of key and returns true if it is within the limit of its current window
+//
+// Description: The first acquisition of a key adds it to the cache for window, which starts its window,
+// and the acquisitions are counted until it expires: at most limit of them succeed, so that TryAcquire(user,
+// 100, time.Minute) allows 100 requests per user per minute. The windows are fixed, not sliding, and a window
+// of 0 never ends, as does a negative one unless WithNegativeExpiry makes TryAcquire return false for it.
+// The key is an element of the cache during its window, so a cache is best dedicated to quotas. A full
+// cache rejecting the key makes TryAcquire return false. The windows are exact, WithTTLJitter does not
+// apply to them.
+func (c *Cache[T]) TryAcquire(key T, limit int, window time.Duration) bool {
+	if limit <= 0 {
+		return false
+	}
+
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.set.Get(key); ok && !e.expired(nanotime()) {
+		n := c.quotas[key]
+		if n >= limit {
+			return false
+		}
+		c.quotas[key] = n + 1
+		return true
+	}
+
+	if ok, _ := c.accept(key, window, 0); !ok {
+		return false
+	}
+	c.putExact(key, window, 0)
+	c.stats.add(statAdds, 1)
+	if c.quotas == nil {
+		c.quotas = make(map[T]int)
+	}
+	c.quotas[key] = 1
+	return true
+}
@@ -0,0 +1,56 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_TryAcquire(t *testing.T) {
+	c := New[string](time.Hour)
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if !c.TryAcquire("alice", 3, 20*time.Millisecond) {
+			t.Errorf("TryAcquire() #%v = %v, want %v", i, false, true)
+		}
+	}
+	if c.TryAcquire("alice", 3, 20*time.Millisecond) {
+		t.Errorf("TryAcquire() over the limit = %v, want %v", true, false)
+	}
+	if !c.TryAcquire("bob", 3, 20*time.Millisecond) {
+		t.Errorf("TryAcquire() of another key = %v, want %v", false, true)
+	}
+	if c.TryAcquire("carol", 0, time.Minute) {
+		t.Errorf("TryAcquire() with a limit of 0 = %v, want %v", true, false)
+	}
+
+	t.Run("NextWindow", func(t *testing.T) {
+		time.Sleep(25 * time.Millisecond)
+		if !c.TryAcquire("alice", 3, 20*time.Millisecond) {
+			t.Errorf("TryAcquire() in the next window = %v, want %v", false, true)
+		}
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		c.TryAcquire("dave", 1, time.Minute)
+		c.Delete("dave")
+		if !c.TryAcquire("dave", 1, time.Minute) {
+			t.Errorf("TryAcquire() after Delete = %v, want %v", false, true)
+		}
+	})
+}
+
+func TestCache_TryAcquire_Jitter(t *testing.T) {
+	c := New[int](time.Hour, WithTTLJitter(0.5))
+	defer c.Close()
+
+	for key := range 20 {
+		if !c.TryAcquire(key, 1, time.Hour) {
+			t.Fatalf("TryAcquire(%v) = %v, want %v", key, false, true)
+		}
+		e, ok := c.Lookup(key)
+		if ttl := e.TTL(); !ok || ttl > time.Hour || ttl < time.Hour-time.Second {
+			t.Errorf("TTL() = %v for a window of %v, want the window without jitter", ttl, time.Hour)
+		}
+	}
+}
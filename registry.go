@@ -0,0 +1,84 @@
+// Package cacheset
+//
+// Path: registry.go
+//
+// Description: registry.go contains the global registry of named caches.
+package cacheset
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrAlreadyRegistered is returned by Register and PublishExpvar when the name is already used
+var ErrAlreadyRegistered = errors.New("cacheset: a cache is already registered with this name")
+
+// AnyCache is implemented by every Cache regardless of its element type
+type AnyCache interface {
+	Len() int
+	Stats() Stats
+}
+
+// CacheInfo describes a registered cache
+type CacheInfo struct {
+	Name  string // Name is the name the cache was registered with
+	Stats Stats  // Stats is a snapshot of the cache's counters
+}
+
+// registry is the global registry of named caches
+var registry = struct {
+	caches map[string]AnyCache
+	sync.RWMutex
+}{caches: make(map[string]AnyCache)}
+
+// Register adds the given cache to the global registry under the given name
+//
+// Description: A registered Cache is removed from the registry when it is closed.
+func Register(name string, c AnyCache) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.caches[name]; ok {
+		return ErrAlreadyRegistered
+	}
+	registry.caches[name] = c
+
+	return nil
+}
+
+// Unregister removes the cache registered under the given name from the global registry
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	delete(registry.caches, name)
+}
+
+// Registered returns the name and stats of every registered cache, sorted by name
+func Registered() []CacheInfo {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	infos := make([]CacheInfo, 0, len(registry.caches))
+	for name, c := range registry.caches {
+		infos = append(infos, CacheInfo{Name: name, Stats: c.Stats()})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos
+}
+
+// unregisterCache removes every registration of the given cache from the global registry
+func unregisterCache(c AnyCache) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for name, registered := range registry.caches {
+		if registered == c {
+			delete(registry.caches, name)
+		}
+	}
+}
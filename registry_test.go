@@ -0,0 +1,51 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegister(t *testing.T) {
+	// other tests may register caches, so the test only looks for its own name
+	name := t.Name()
+	find := func() (CacheInfo, bool) {
+		for _, info := range Registered() {
+			if info.Name == name {
+				return info, true
+			}
+		}
+		return CacheInfo{}, false
+	}
+
+	c := New[string](time.Minute)
+	c.Add("foo", 0)
+	c.Contains("foo")
+	c.Contains("bar")
+
+	if err := Register(name, c); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	t.Run("AlreadyRegistered", func(t *testing.T) {
+		if err := Register(name, c); err != ErrAlreadyRegistered {
+			t.Errorf("Register() error = %v, want %v", err, ErrAlreadyRegistered)
+		}
+	})
+
+	t.Run("Registered", func(t *testing.T) {
+		info, ok := find()
+		if !ok {
+			t.Fatalf("Registered() = %v, want a cache named %v", Registered(), name)
+		}
+		if s := info.Stats; s.Len != 1 || s.Hits != 1 || s.Misses != 1 {
+			t.Errorf("Registered() stats = %+v", s)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		c.Close()
+		if _, ok := find(); ok {
+			t.Errorf("Registered() = %v, want no cache named %v after Close", Registered(), name)
+		}
+	})
+}
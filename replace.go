@@ -0,0 +1,81 @@
+// Package cacheset
+//
+// Path: replace.go
+//
+// Description: replace.go contains the policies deciding the expiration of an element added again.
+package cacheset
+
+import "time"
+
+// ReplacePolicy decides the expiration of an element added while it is already in the cache
+type ReplacePolicy int
+
+const (
+	// ReplaceOverwriteTTL expires the element after the new duration
+	ReplaceOverwriteTTL ReplacePolicy = iota
+	// ReplaceKeepExisting keeps the expiration of the element, so it expires after its first addition
+	ReplaceKeepExisting
+	// ReplaceKeepLonger keeps the latest of the current and the new expirations
+	ReplaceKeepLonger
+)
+
+// WithReplacePolicy sets the expiration of the elements added again before they expire, ReplaceOverwriteTTL by default
+//
+// Description: An element that has expired but was not cleaned yet is always added as a new one.
+func WithReplacePolicy(policy ReplacePolicy) Option {
+	return func(o *options) {
+		o.replace = policy
+	}
+}
+
+// put sets the expiration of the given element, according to the replace policy if it is already in the cache,
+// the cache must be locked
+func (c *Cache[T]) put(elem T, ttl, maxIdle time.Duration) {
+	c.putExact(elem, c.options.jitter(ttl), maxIdle)
+}
+
+// putExact is put without the jitter of WithTTLJitter, for the durations that must be exact, the cache must
+// be locked
+func (c *Cache[T]) putExact(elem T, ttl, maxIdle time.Duration) {
+	if c.options.replace != ReplaceOverwriteTTL {
+		now := nanotime()
+		if e, ok := c.set.Get(elem); ok && !e.expired(now) && c.keep(e, ttl, maxIdle, now) {
+			return
+		}
+	}
+	c.set.AddWithIdle(elem, ttl, maxIdle)
+	if c.lifetimes != nil {
+		c.lifetimes.expire(elem, ttl)
+	}
+	c.hold(elem)
+	c.bump(elem)
+	c.markDirty(elem)
+	c.due(elem)
+	c.publishAdded(elem)
+	c.audit(MutationAdd, elem, 0)
+	c.amortize(elem)
+}
+
+// keep returns true if the replace policy keeps the given entry rather than the expiration after ttl or maxIdle
+func (c *Cache[T]) keep(e *entry, ttl, maxIdle time.Duration, now int64) bool {
+	switch c.options.replace {
+	case ReplaceKeepExisting:
+		return true
+	case ReplaceKeepLonger:
+		return compareDeadlines(e.deadline(), newEntryDeadline(ttl, maxIdle, now)) >= 0
+	default:
+		return false
+	}
+}
+
+// newEntryDeadline returns the deadline of an entry added at now for ttl and maxIdle, 0 meaning never
+func newEntryDeadline(ttl, maxIdle time.Duration, now int64) int64 {
+	var deadline int64
+	if ttl > 0 {
+		deadline = now + int64(ttl)
+	}
+	if maxIdle > 0 && (deadline == 0 || now+int64(maxIdle) < deadline) {
+		deadline = now + int64(maxIdle)
+	}
+	return deadline
+}
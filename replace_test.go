@@ -0,0 +1,47 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithReplacePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ReplacePolicy
+		first  time.Duration
+		second time.Duration
+		want   time.Duration
+	}{
+		{name: "OverwriteShorter", policy: ReplaceOverwriteTTL, first: time.Hour, second: time.Minute, want: time.Minute},
+		{name: "KeepExisting", policy: ReplaceKeepExisting, first: time.Minute, second: time.Hour, want: time.Minute},
+		{name: "KeepLongerShorter", policy: ReplaceKeepLonger, first: time.Hour, second: time.Minute, want: time.Hour},
+		{name: "KeepLongerLonger", policy: ReplaceKeepLonger, first: time.Minute, second: time.Hour, want: time.Hour},
+		{name: "KeepLongerPermanent", policy: ReplaceKeepLonger, first: time.Minute, second: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New[int64](time.Minute, WithReplacePolicy(tt.policy))
+			defer c.Close()
+
+			c.Add(1, tt.first)
+			c.Add(1, tt.second)
+			e, _ := c.Lookup(1)
+			if got := e.TTL(); got > tt.want || got < tt.want-time.Second {
+				t.Errorf("Add() TTL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Expired", func(t *testing.T) {
+		c := New[int64](time.Minute, WithReplacePolicy(ReplaceKeepExisting))
+		defer c.Close()
+
+		c.Add(1, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		c.Add(1, time.Hour)
+		if !c.Contains(1) || c.Expired(1) {
+			t.Errorf("Add() kept the expiration of an expired element")
+		}
+	})
+}
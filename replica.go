@@ -0,0 +1,118 @@
+// Package cacheset
+//
+// Path: replica.go
+//
+// Description: replica.go contains a read-only follower applying the events of a primary cache.
+package cacheset
+
+import (
+	"sync"
+	"time"
+)
+
+// Replica is a read-only copy of a primary cache, kept in sync by applying its events
+//
+// Description: The events come from a subscription of the primary made with WithSyncMarker, usually
+// through a transport such as cachesetgrpc. On failover, Promote returns the underlying cache
+// for read-write use.
+type Replica[T comparable] struct {
+	c        *Cache[T]
+	mu       sync.Mutex
+	seen     map[T]struct{} // seen are the elements replayed since the start of the current replay, nil outside a replay
+	promoted bool           // promoted is true once Promote was called, the events are then ignored
+}
+
+// NewReplica returns an empty replica whose cache is created with the given clean interval and options
+func NewReplica[T comparable](cleanInterval time.Duration, opts ...Option) *Replica[T] {
+	return &Replica[T]{c: New[T](cleanInterval, opts...)}
+}
+
+// Apply applies an event of the primary
+//
+// Description: A replay, from the first replayed addition to the following EventSynced, replaces
+// the elements of the replica: the elements that were not replayed are removed once it ends, and
+// the replica keeps serving its previous elements in the meantime.
+func (r *Replica[T]) Apply(ev Event[T]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.promoted {
+		return
+	}
+	switch ev.Kind {
+	case EventAdded:
+		if ev.Replayed {
+			if r.seen == nil {
+				r.seen = make(map[T]struct{})
+			}
+			r.seen[ev.Elem] = struct{}{}
+		}
+		var ttl time.Duration
+		if !ev.ExpiresAt.IsZero() {
+			ttl = time.Until(ev.ExpiresAt)
+			if ttl <= 0 {
+				r.c.Delete(ev.Elem)
+				return
+			}
+		}
+		_ = r.c.Add(ev.Elem, ttl)
+	case EventRemoved:
+		r.c.Delete(ev.Elem)
+	case EventCleared:
+		r.c.Clear()
+	case EventSynced:
+		seen := r.seen
+		r.seen = nil
+		r.c.DeleteFunc(func(elem T) bool {
+			_, ok := seen[elem]
+			return !ok
+		})
+	}
+}
+
+// AbortReplay forgets an interrupted replay, it must be called before following a new subscription
+func (r *Replica[T]) AbortReplay() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seen = nil
+}
+
+// Contains returns true if the given element is in the replica
+func (r *Replica[T]) Contains(elem T) bool {
+	return r.c.Contains(elem)
+}
+
+// Lookup returns the entry of the given element and false if it is not in the replica
+func (r *Replica[T]) Lookup(elem T) (Entry[T], bool) {
+	return r.c.Lookup(elem)
+}
+
+// ToSlice returns the elements of the replica
+func (r *Replica[T]) ToSlice() []T {
+	return r.c.ToSlice()
+}
+
+// Len returns the number of elements in the replica
+func (r *Replica[T]) Len() int {
+	return r.c.Len()
+}
+
+// Promote stops applying the events of the primary and returns the underlying cache, owned by the caller from now on
+func (r *Replica[T]) Promote() *Cache[T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.promoted = true
+	return r.c
+}
+
+// Close closes the underlying cache, unless the replica was promoted
+func (r *Replica[T]) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.promoted {
+		r.c.Close()
+	}
+}
@@ -0,0 +1,86 @@
+package cacheset
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// follow applies the events of the subscription to the replica until the subscription is closed
+func follow[T comparable](r *Replica[T], sub *Subscription[T]) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ev := range sub.Events() {
+			r.Apply(ev)
+		}
+	}()
+	return done
+}
+
+func TestReplica_Apply(t *testing.T) {
+	primary := New[string](time.Minute)
+	primary.Add("a", 0)
+	primary.Add("b", time.Hour)
+
+	r := NewReplica[string](time.Minute)
+	defer r.Close()
+	r.Apply(Event[string]{Kind: EventAdded, Elem: "stale", Replayed: true})
+	r.AbortReplay()
+
+	sub := primary.Subscribe(WithSyncMarker())
+	done := follow(r, sub)
+	primary.Add("c", 0)
+	primary.Delete("a")
+	primary.Close()
+	<-done
+
+	got := r.ToSlice()
+	slices.Sort(got)
+	if want := []string{"b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("ToSlice() = %v, want %v", got, want)
+	}
+	if e, ok := r.Lookup("b"); !ok || e.IsPermanent() {
+		t.Errorf("Lookup(b) = %v, %v, want an expiring entry", e, ok)
+	}
+}
+
+func TestReplica_Resync(t *testing.T) {
+	r := NewReplica[int](time.Minute)
+	defer r.Close()
+	for _, elem := range []int{1, 2, 3} {
+		r.Apply(Event[int]{Kind: EventAdded, Elem: elem})
+	}
+
+	r.Apply(Event[int]{Kind: EventAdded, Elem: 2, Replayed: true})
+	if got := r.Len(); got != 3 {
+		t.Errorf("Len() = %v during the replay, want %v", got, 3)
+	}
+	r.Apply(Event[int]{Kind: EventSynced, Replayed: true})
+	if got := r.ToSlice(); !slices.Equal(got, []int{2}) {
+		t.Errorf("ToSlice() = %v, want %v", got, []int{2})
+	}
+
+	r.Apply(Event[int]{Kind: EventSynced, Replayed: true})
+	if got := r.Len(); got != 0 {
+		t.Errorf("Len() = %v after an empty replay, want %v", got, 0)
+	}
+}
+
+func TestReplica_Promote(t *testing.T) {
+	r := NewReplica[int](time.Minute)
+	r.Apply(Event[int]{Kind: EventAdded, Elem: 1})
+	c := r.Promote()
+	defer c.Close()
+
+	r.Apply(Event[int]{Kind: EventAdded, Elem: 2})
+	r.Close()
+	if err := c.Add(3, 0); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	got := c.ToSlice()
+	slices.Sort(got)
+	if !slices.Equal(got, []int{1, 3}) {
+		t.Errorf("ToSlice() = %v, want %v", got, []int{1, 3})
+	}
+}
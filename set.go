@@ -5,44 +5,115 @@
 // Description: set.go contains the set type and its methods.
 package cacheset
 
-import "time"
+import (
+	"cmp"
+	"iter"
+	"maps"
+	"slices"
+	"time"
+)
 
 // set is a map with expiration times
-type set[T comparable] map[T]int64
+type set[T comparable] map[T]*entry
 
-// Expire removes the given element from the set if it has expired
-func (s set[T]) Expire(elem T) {
+// Expire removes the given element from the set if it has expired and returns true if it was removed
+func (s set[T]) Expire(elem T) bool {
 	if s.Expired(elem) {
 		s.Delete(elem)
+		return true
 	}
+	return false
 }
 
 // Copy returns a copy of the set
-func (s set[T]) Copy() set[T] {
+func (s set[T]) Copy() store[T] {
 	c := newSet[T]()
 	for k, v := range s {
-		c[k] = v
+		c[k] = v.copy()
 	}
 	return c
 }
 
-// ExpireAll removes all expired elements from the set
-func (s set[T]) ExpireAll() {
-	for k := range s {
-		s.Expire(k)
+// Expirations returns a map of the set's elements to their expiration times in nanoseconds since the Unix epoch,
+// 0 meaning no expiration
+//
+// Description: The expiration time of an element with a maximum idle duration assumes that it is not accessed anymore.
+func (s set[T]) Expirations() map[T]int64 {
+	m := make(map[T]int64, len(s))
+	for k, v := range s {
+		m[k] = toWall(v.deadline())
 	}
+	return m
+}
+
+// ExpireAll removes all expired elements from the set and returns them
+func (s set[T]) ExpireAll() []T {
+	var removed []T
+	now := nanotime()
+	for k, v := range s {
+		if v.expired(now) {
+			delete(s, k)
+			v.release()
+			removed = append(removed, k)
+		}
+	}
+	return removed
 }
 
 // Expired returns true if the given element has expired
 func (s set[T]) Expired(elem T) bool {
-	expires, ok := s[elem]
+	e, ok := s[elem]
 	if !ok {
 		return false
 	}
-	if expires > 0 && expires < time.Now().UnixNano() {
-		return true
+	return e.expired(nanotime())
+}
+
+// Merge adds all unexpired elements of other to the set and returns the elements that were not in the set
+//
+// Description: When an element exists in both sets, resolve is called with both expiration times
+// and its result is stored as the new expiration time.
+func (s set[T]) Merge(other store[T], resolve func(a, b int64) int64) []T {
+	var added []T
+	now := nanotime()
+	for k, v := range other.All() {
+		if v.expired(now) {
+			continue
+		}
+		if e, ok := s[k]; ok && !e.expired(now) {
+			e.expires = resolve(e.expires, v.expires)
+			continue
+		}
+		if e, ok := s[k]; ok {
+			e.release()
+		} else {
+			added = append(added, k)
+		}
+		s[k] = v.copy()
 	}
-	return false
+	return added
+}
+
+// ExpiringFirst returns up to n elements of the set, the ones expiring first, the elements without expiration last
+func (s set[T]) ExpiringFirst(n int) []T {
+	type candidate struct {
+		elem     T
+		deadline int64
+	}
+
+	candidates := make([]candidate, 0, len(s))
+	for k, v := range s {
+		candidates = append(candidates, candidate{elem: k, deadline: v.deadline()})
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return compareDeadlines(a.deadline, b.deadline)
+	})
+
+	elems := make([]T, 0, min(n, len(candidates)))
+	for _, c := range candidates[:min(n, len(candidates))] {
+		elems = append(elems, c.elem)
+	}
+	return elems
 }
 
 // ToSlice returns a slice of the set's elements
@@ -54,21 +125,48 @@ func (s set[T]) ToSlice() []T {
 	return slice
 }
 
+// Filter returns a slice of the set's unexpired elements for which pred returns true
+func (s set[T]) Filter(pred func(T) bool) []T {
+	in, _ := s.Partition(pred)
+	return in
+}
+
+// Partition splits the set's unexpired elements into those for which pred returns true and the others
+func (s set[T]) Partition(pred func(T) bool) (in []T, out []T) {
+	now := nanotime()
+	for k, v := range s {
+		if v.expired(now) {
+			continue
+		}
+		if pred(k) {
+			in = append(in, k)
+		} else {
+			out = append(out, k)
+		}
+	}
+	return in, out
+}
+
 // Add adds the given element to the set with the given expiration time
 func (s set[T]) Add(elem T, duration time.Duration) {
-	var expires int64
-	if duration > 0 {
-		expires = time.Now().Add(duration).UnixNano()
-	} else {
-		expires = 0
+	s.AddWithIdle(elem, duration, 0)
+}
+
+// AddWithIdle adds the given element to the set, expiring after ttl or after maxIdle without access
+func (s set[T]) AddWithIdle(elem T, ttl, maxIdle time.Duration) {
+	now := nanotime()
+	if e, ok := s[elem]; ok {
+		e.reset(ttl, maxIdle, now)
+		return
 	}
-	s[elem] = expires
+	s[elem] = newEntry(ttl, maxIdle, now)
 }
 
 // Clear removes all elements from the set
 func (s set[T]) Clear() {
-	for k := range s {
+	for k, v := range s {
 		delete(s, k)
+		v.release()
 	}
 }
 
@@ -78,9 +176,46 @@ func (s set[T]) Contains(elem T) bool {
 	return ok
 }
 
+// Touch records an access to the given element and returns true if it is in the set
+func (s set[T]) Touch(elem T) bool {
+	e, ok := s[elem]
+	if ok {
+		e.touch(nanotime())
+	}
+	return ok
+}
+
 // Delete removes the given element from the set
 func (s set[T]) Delete(elem T) {
-	delete(s, elem)
+	if e, ok := s[elem]; ok {
+		delete(s, elem)
+		e.release()
+	}
+}
+
+// Get returns the entry of the given element
+func (s set[T]) Get(elem T) (*entry, bool) {
+	e, ok := s[elem]
+	return e, ok
+}
+
+// Set stores the given entry as the entry of the given element
+func (s set[T]) Set(elem T, e *entry) {
+	if old, ok := s[elem]; ok && old != e {
+		old.release()
+	}
+	s[elem] = e
+}
+
+// All returns an iterator over the elements of the set and their entries
+func (s set[T]) All() iter.Seq2[T, *entry] {
+	return maps.All(s)
+}
+
+// Probe returns an iterator over the elements of the set and their entries, ignoring the position since the
+// iterations of a map start at a random element
+func (s set[T]) Probe(_ *int) iter.Seq2[T, *entry] {
+	return s.All()
 }
 
 // Len returns the number of elements in the set
@@ -88,6 +223,25 @@ func (s set[T]) Len() int {
 	return len(s)
 }
 
+// expired returns true if the given expiration time is before now
+func expired(expires, now int64) bool {
+	return expires > 0 && expires < now
+}
+
+// compareDeadlines orders two expiration times, the ones without expiration last
+func compareDeadlines(a, b int64) int {
+	switch {
+	case a == b:
+		return 0
+	case a == 0:
+		return 1
+	case b == 0:
+		return -1
+	default:
+		return cmp.Compare(a, b)
+	}
+}
+
 // New returns a new set
 func newSet[T comparable]() set[T] {
 	return make(set[T])
@@ -14,7 +14,7 @@ func Test_newSet(t *testing.T) {
 	tests := []testCase[int64]{
 		{
 			name: "test",
-			want: make(map[int64]int64),
+			want: make(map[int64]*entry),
 		},
 	}
 	for _, tt := range tests {
@@ -218,3 +218,85 @@ func Test_set_ToSlice(t *testing.T) {
 		}
 	})
 }
+
+func Test_set_Merge(t *testing.T) {
+	s := newSet[int64]()
+	s.Add(1, 0)
+	s.Add(2, 0)
+
+	other := newSet[int64]()
+	other.Add(2, time.Minute)
+	other.Add(3, 0)
+
+	s.Merge(other, func(a, b int64) int64 {
+		return a
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		if got := s.Len(); got != 3 {
+			t.Errorf("Merge() = %v, want %v", got, 3)
+		}
+		if got := s[2].expires; got != 0 {
+			t.Errorf("Merge() = %v, want %v", got, 0)
+		}
+	})
+}
+
+func Test_set_Partition(t *testing.T) {
+	t.Parallel()
+	s := newSet[int64]()
+	s.Add(1, 0)
+	s.Add(2, 0)
+	s.Add(3, 1*time.Second)
+	s.Add(4, 0)
+
+	time.Sleep(2 * time.Second)
+
+	in, out := s.Partition(func(elem int64) bool {
+		return elem%2 == 0
+	})
+
+	t.Run("Partition", func(t *testing.T) {
+		if len(in) != 2 {
+			t.Errorf("Partition() in = %v, want %v", in, []int64{2, 4})
+		}
+	})
+
+	t.Run("Partition", func(t *testing.T) {
+		if !reflect.DeepEqual(out, []int64{1}) {
+			t.Errorf("Partition() out = %v, want %v", out, []int64{1})
+		}
+	})
+}
+
+func Test_set_AddWithIdle(t *testing.T) {
+	t.Parallel()
+	s := newSet[int64]()
+	s.AddWithIdle(1, 0, 1*time.Second)
+	s.AddWithIdle(2, 0, 1*time.Second)
+	s.AddWithIdle(3, 1*time.Second, 1*time.Minute)
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(500 * time.Millisecond)
+		s.Touch(2)
+		s.Touch(3)
+	}
+
+	t.Run("Idle", func(t *testing.T) {
+		if !s.Expired(1) {
+			t.Errorf("AddWithIdle() = %v, want %v expired", s, 1)
+		}
+	})
+
+	t.Run("Touched", func(t *testing.T) {
+		if s.Expired(2) {
+			t.Errorf("AddWithIdle() = %v, want %v not expired", s, 2)
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		if !s.Expired(3) {
+			t.Errorf("AddWithIdle() = %v, want %v expired", s, 3)
+		}
+	})
+}
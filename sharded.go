@@ -0,0 +1,270 @@
+// Package cacheset
+//
+// Path: sharded.go
+//
+// Description: sharded.go contains the Sharded type, a cache split into independently locked shards.
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sharded is a cache split into shards, each with its own lock, to reduce lock contention
+//
+// Description: Each element is stored in the shard chosen by hashing it. A capacity given with WithCapacity
+// is divided evenly between the shards, and each shard evicts its own elements. WithDurability is ignored.
+// The shards are cleaned together every clean interval by a pool of one worker per processor, each
+// locking a single shard at a time, so that a cleaning lasts as long as the largest shards rather than
+// all of them.
+type Sharded[T comparable] struct {
+	hasher   func(T) uint64 // hasher hashes an element to choose its shard
+	shards   []*Cache[T]    // shards are the caches storing the elements
+	ticker   *time.Ticker   // ticker ticks every clean interval
+	close    chan struct{}  // close stops the sweeping goroutine
+	done     chan struct{}  // done is closed when the sweeping goroutine returns
+	stopOnce sync.Once      // stopOnce ensures that the sweeping goroutine is stopped once
+}
+
+// WithShards sets the number of shards of a Sharded cache
+//
+// Description: A number of 0 or less, the default, picks a number of shards from GOMAXPROCS.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithHasher sets the function hashing the elements of a Sharded cache to choose their shard
+//
+// Description: Elements with the same hash are stored in the same shard, which allows co-locating related elements.
+// By default, elements are hashed with hash/maphash. The hasher's element type must match the cache's element type.
+func WithHasher[T comparable](hasher func(elem T) uint64) Option {
+	return func(o *options) {
+		o.hasher = hasher
+	}
+}
+
+// autoShards returns the number of shards picked from GOMAXPROCS, four per processor rounded up to a power of two
+func autoShards() int {
+	return 1 << bits.Len(uint(4*runtime.GOMAXPROCS(0)-1))
+}
+
+// NewSharded creates a new sharded cache whose shards asynchronously clean
+func NewSharded[T comparable](cleanInterval time.Duration, opts ...Option) *Sharded[T] {
+	o := newOptions(opts)
+
+	n := o.shards
+	if n <= 0 {
+		n = autoShards()
+	}
+
+	s := &Sharded[T]{shards: make([]*Cache[T], n)}
+	if o.hasher != nil {
+		hasher, ok := o.hasher.(func(T) uint64)
+		if !ok {
+			panic("cacheset: the WithHasher function does not match the cache's element type")
+		}
+		s.hasher = hasher
+	} else {
+		seed := maphash.MakeSeed()
+		s.hasher = func(elem T) uint64 {
+			return maphash.Comparable(seed, elem)
+		}
+	}
+
+	shard := o
+	shard.sink = nil
+	shard.external = true
+	if o.capacity > 0 {
+		shard.capacity = max(1, (o.capacity+n-1)/n)
+	}
+	for i := range s.shards {
+		s.shards[i] = newCache[T](cleanInterval, shard)
+	}
+
+	s.ticker = time.NewTicker(cleanInterval)
+	s.close = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.sweep()
+
+	return s
+}
+
+// sweep cleans the shards every clean interval until the cache is closed
+func (s *Sharded[T]) sweep() {
+	defer close(s.done)
+	defer s.ticker.Stop()
+
+	for {
+		select {
+		case <-s.close:
+			return
+		case <-s.ticker.C:
+			s.parallel(func(shard *Cache[T]) {
+				shard.health.wakeup()
+				shard.clean()
+			})
+		}
+	}
+}
+
+// parallel calls f for each shard from a pool of one worker per processor, and returns once all calls returned
+func (s *Sharded[T]) parallel(f func(shard *Cache[T])) {
+	workers := min(runtime.GOMAXPROCS(0), len(s.shards))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := next.Add(1) - 1; i < int64(len(s.shards)); i = next.Add(1) - 1 {
+				f(s.shards[i])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// stop stops the sweeping goroutine and waits for its current cleaning
+func (s *Sharded[T]) stop() {
+	s.stopOnce.Do(func() {
+		close(s.close)
+		<-s.done
+	})
+}
+
+// shard returns the shard of the given element
+func (s *Sharded[T]) shard(elem T) *Cache[T] {
+	return s.shards[s.hasher(elem)%uint64(len(s.shards))]
+}
+
+// Add adds the given element to its shard
+func (s *Sharded[T]) Add(elem T, duration time.Duration) error {
+	return s.shard(elem).Add(elem, duration)
+}
+
+// AddWithIdle adds the given element to its shard until ttl elapses or it is not accessed for maxIdle
+func (s *Sharded[T]) AddWithIdle(elem T, ttl, maxIdle time.Duration) error {
+	return s.shard(elem).AddWithIdle(elem, ttl, maxIdle)
+}
+
+// Contains returns true if the given element is in the cache
+func (s *Sharded[T]) Contains(elem T) bool {
+	return s.shard(elem).Contains(elem)
+}
+
+// Delete removes the given element from the cache
+func (s *Sharded[T]) Delete(elem T) {
+	s.shard(elem).Delete(elem)
+}
+
+// TryDelete removes the given element from its shard and returns true if it was there, see Cache.TryDelete
+func (s *Sharded[T]) TryDelete(elem T) bool {
+	return s.shard(elem).TryDelete(elem)
+}
+
+// DeleteFunc removes all elements for which pred returns true, one shard at a time, and returns their number
+func (s *Sharded[T]) DeleteFunc(pred func(T) bool) int {
+	var deleted int
+	for _, shard := range s.shards {
+		deleted += shard.DeleteFunc(pred)
+	}
+	return deleted
+}
+
+// Watch returns a channel that receives a single RemovalEvent when the given element is removed from the cache
+func (s *Sharded[T]) Watch(elem T) <-chan RemovalEvent[T] {
+	return s.shard(elem).Watch(elem)
+}
+
+// Len returns the number of elements in the cache
+func (s *Sharded[T]) Len() int {
+	var n int
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// ToSlice returns a slice of all elements in the cache
+func (s *Sharded[T]) ToSlice() []T {
+	if first := s.shards[0]; first.sorted != nil || first.order != nil {
+		return s.orderedSlice()
+	}
+	var slice []T
+	for _, shard := range s.shards {
+		slice = append(slice, shard.ToSlice()...)
+	}
+	return slice
+}
+
+// Clear clears the cache
+func (s *Sharded[T]) Clear() {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+}
+
+// ExpireAll expires all elements in the cache, the shards being cleaned in parallel
+func (s *Sharded[T]) ExpireAll() {
+	s.parallel(func(shard *Cache[T]) {
+		shard.ExpireAll()
+	})
+}
+
+// Stats returns the sum of the shards' counters, with the number of elements of each shard in ShardLens
+// and the duration of its last cleaning in ShardSweepDurations
+func (s *Sharded[T]) Stats() Stats {
+	var total Stats
+	total.ShardLens = make([]int, len(s.shards))
+	total.ShardSweepDurations = make([]time.Duration, len(s.shards))
+	for i, shard := range s.shards {
+		st := shard.Stats()
+		total.Len += st.Len
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Adds += st.Adds
+		total.Deletes += st.Deletes
+		total.Expirations += st.Expirations
+		total.Evictions += st.Evictions
+		total.Pinned += st.Pinned
+		total.ShardLens[i] = st.Len
+		total.ShardSweepDurations[i] = shard.Health().LastSweepDuration
+		total.RemainingTTLs.merge(st.RemainingTTLs)
+		total.EvictedLifetimes.merge(st.EvictedLifetimes)
+		total.ExpiredLifetimes.merge(st.ExpiredLifetimes)
+		total.ExpiredUnused += st.ExpiredUnused
+		total.AddLatency.merge(st.AddLatency)
+		total.ContainsLatency.merge(st.ContainsLatency)
+		total.SweepLatency.merge(st.SweepLatency)
+		total.History = mergeHistory(total.History, st.History)
+	}
+	return total
+}
+
+// Close stops the cleaning goroutines of the shards
+func (s *Sharded[T]) Close() {
+	unregisterCache(s)
+	s.stop()
+	for _, shard := range s.shards {
+		shard.Close()
+	}
+}
+
+// Shutdown gracefully closes the shards before ctx is done, see Cache.Shutdown
+func (s *Sharded[T]) Shutdown(ctx context.Context) error {
+	unregisterCache(s)
+	s.stop()
+	var errs []error
+	for _, shard := range s.shards {
+		errs = append(errs, shard.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
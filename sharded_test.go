@@ -0,0 +1,87 @@
+package cacheset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSharded(t *testing.T) {
+	t.Run("Auto", func(t *testing.T) {
+		s := NewSharded[int64](time.Minute)
+		defer s.Close()
+		if n := len(s.shards); n < 4 || n&(n-1) != 0 {
+			t.Errorf("NewSharded() shards = %v, want a power of two of at least 4", n)
+		}
+	})
+
+	t.Run("Hasher", func(t *testing.T) {
+		s := NewSharded[int64](time.Minute, WithShards(4), WithHasher(func(elem int64) uint64 {
+			return uint64(elem / 10)
+		}))
+		defer s.Close()
+		for i := int64(10); i < 20; i++ {
+			s.Add(i, 0)
+		}
+
+		lens := s.Stats().ShardLens
+		if len(lens) != 4 || lens[1] != 10 {
+			t.Errorf("Stats() ShardLens = %v, want all elements in shard 1", lens)
+		}
+	})
+}
+
+func TestSharded(t *testing.T) {
+	s := NewSharded[int64](time.Minute, WithShards(8))
+	defer s.Close()
+	for i := int64(0); i < 100; i++ {
+		s.Add(i, 0)
+	}
+	s.Delete(0)
+
+	t.Run("Len", func(t *testing.T) {
+		if got := s.Len(); got != 99 {
+			t.Errorf("Len() = %v, want %v", got, 99)
+		}
+	})
+
+	t.Run("Contains", func(t *testing.T) {
+		if s.Contains(0) || !s.Contains(1) {
+			t.Errorf("Contains() = %v, %v, want false, true", s.Contains(0), s.Contains(1))
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		if st := s.Stats(); st.Adds != 100 || st.Deletes != 1 || st.Len != 99 {
+			t.Errorf("Stats() = %+v", st)
+		}
+	})
+}
+
+func TestSharded_Sweep(t *testing.T) {
+	s := NewSharded[int64](5*time.Millisecond, WithShards(8))
+	defer s.Close()
+	for i := int64(0); i < 1000; i++ {
+		s.Add(i, time.Millisecond)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	st := s.Stats()
+	if st.Len != 0 || st.Expirations != 1000 {
+		t.Errorf("Stats() = %+v, want all elements expired", st)
+	}
+	if len(st.ShardSweepDurations) != 8 {
+		t.Errorf("Stats() ShardSweepDurations = %v, want %v durations", st.ShardSweepDurations, 8)
+	}
+	for i, shard := range s.shards {
+		if h := shard.Health(); h.Sweeps == 0 || !h.Healthy() {
+			t.Errorf("shard %v Health() = %+v, want a healthy shard cleaned by the sweeper", i, h)
+		}
+	}
+
+	s.Close()
+	for i, shard := range s.shards {
+		if h := shard.Health(); h.Alive {
+			t.Errorf("shard %v Health() = %+v, want a closed shard", i, h)
+		}
+	}
+}
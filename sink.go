@@ -0,0 +1,87 @@
+// Package cacheset
+//
+// Path: sink.go
+//
+// Description: sink.go contains the storages of the snapshots of the caches with durability.
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SnapshotSink stores the snapshots of a cache with durability
+//
+// Description: The cachesets3 package provides a sink storing the snapshots in an S3-compatible object store.
+type SnapshotSink interface {
+	// Create returns a writer of the snapshot with the given name, replacing the previous one once committed
+	Create(ctx context.Context, name string) (SnapshotWriter, error)
+	// Open returns a reader of the snapshot with the given name, or an error wrapping fs.ErrNotExist if there is none
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// Remove removes the snapshot with the given name, a missing snapshot is not an error
+	Remove(ctx context.Context, name string) error
+}
+
+// SnapshotWriter writes a snapshot to a SnapshotSink
+type SnapshotWriter interface {
+	io.Writer
+	// Commit makes the written snapshot replace the previous one atomically
+	Commit() error
+	// Abort discards the written snapshot, keeping the previous one
+	Abort() error
+}
+
+// FileSink is a SnapshotSink storing the snapshots in files, the names of the snapshots being file paths
+//
+// Description: A snapshot is written to a temporary file in the same directory, synced and renamed
+// over the previous one on Commit.
+type FileSink struct{}
+
+// Create returns a writer of a temporary file renamed to name on Commit
+func (FileSink) Create(_ context.Context, name string) (SnapshotWriter, error) {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &fileWriter{File: f, name: name}, nil
+}
+
+// Open opens the file name
+func (FileSink) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+// Remove removes the file name
+func (FileSink) Remove(_ context.Context, name string) error {
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// fileWriter writes a snapshot to a temporary file
+type fileWriter struct {
+	*os.File
+	name string // name is the path of the snapshot
+}
+
+// Commit syncs and closes the temporary file, then renames it over the snapshot
+func (w *fileWriter) Commit() error {
+	if err := w.Sync(); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.Name(), w.name)
+}
+
+// Abort closes and removes the temporary file
+func (w *fileWriter) Abort() error {
+	_ = w.Close()
+	return os.Remove(w.Name())
+}
@@ -0,0 +1,101 @@
+package cacheset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memSink is a SnapshotSink keeping the snapshots in memory
+type memSink struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (s *memSink) Create(_ context.Context, name string) (SnapshotWriter, error) {
+	return &memWriter{sink: s, name: name}, nil
+}
+
+func (s *memSink) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[name]
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w", name, fs.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memSink) Remove(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, name)
+	return nil
+}
+
+func (s *memSink) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+type memWriter struct {
+	bytes.Buffer
+	sink *memSink
+	name string
+}
+
+func (w *memWriter) Commit() error {
+	w.sink.mu.Lock()
+	defer w.sink.mu.Unlock()
+	w.sink.files[w.name] = w.Bytes()
+	return nil
+}
+
+func (w *memWriter) Abort() error {
+	return nil
+}
+
+// blockSink is a memSink whose Create ignores its context and blocks until release is closed
+type blockSink struct {
+	memSink
+	release chan struct{}
+}
+
+func (s *blockSink) Create(ctx context.Context, name string) (SnapshotWriter, error) {
+	<-s.release
+	return s.memSink.Create(ctx, name)
+}
+
+func TestWithSnapshotSink(t *testing.T) {
+	sink := &memSink{files: map[string][]byte{}}
+	c := New[string](time.Minute, WithSnapshotSink(sink, "members"), WithSnapshotInterval(5*time.Millisecond), WithDeltaSnapshots(1000))
+	c.Add("a", 0)
+	for deadline := time.Now().Add(time.Second); len(sink.names()) < 3 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if got := sink.names(); len(got) < 3 || got[0] != "members" || got[1] != "members.delta-000001" {
+		t.Fatalf("names() = %v, want members and its deltas", got)
+	}
+
+	restored := New[string](time.Minute, WithSnapshotSink(sink, "members"))
+	if !restored.Contains("a") {
+		t.Errorf("Contains(a) = false after restoring from the sink")
+	}
+	restored.Close()
+	c.Close()
+	if got := sink.names(); !slices.Equal(got, []string{"members"}) {
+		t.Errorf("names() = %v after Close, want %v", got, []string{"members"})
+	}
+}
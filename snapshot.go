@@ -0,0 +1,327 @@
+// Package cacheset
+//
+// Path: snapshot.go
+//
+// Description: snapshot.go contains the serialization of the cache's elements and expiration times.
+package cacheset
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"time"
+)
+
+// SnapshotMode selects how expiration times are written in a snapshot
+type SnapshotMode int
+
+const (
+	// SnapshotAbsolute writes wall-clock expiration times, a snapshot restored later
+	// only keeps the elements that have not expired in the meantime
+	SnapshotAbsolute SnapshotMode = iota
+	// SnapshotRelative writes the remaining time to live of each element, a snapshot restored
+	// on another host is not affected by the clock skew between both hosts, but the time elapsed
+	// between the snapshot and the restoration does not count
+	SnapshotRelative
+)
+
+// snapshotMagic identifies the snapshots written by Snapshot
+const snapshotMagic = "cacheset"
+
+// snapshotVersion is the version of the snapshot format, version 2 added the delta snapshots
+// and version 3 the elements written in their text form
+const snapshotVersion = 3
+
+// ErrInvalidSnapshot is returned by Restore when the snapshot was not written by Snapshot
+var ErrInvalidSnapshot = errors.New("cacheset: invalid snapshot")
+
+// snapshotHeader is the first value of a snapshot
+type snapshotHeader struct {
+	Magic   string       // Magic is always snapshotMagic
+	Version int          // Version is the version of the snapshot format
+	Mode    SnapshotMode // Mode is how the expiration times are written
+	Taken   int64        // Taken is the wall-clock time of the snapshot in nanoseconds since the Unix epoch
+	Len     int          // Len is the number of records following the header
+	Delta   bool         // Delta is true if the records are the changes since the snapshot taken at Since
+	Since   int64        // Since is the Taken time of the snapshot a delta applies to
+	Cleared bool         // Cleared is true if the cache was cleared before the changes of a delta
+	Text    bool         // Text is true if the records are textRecords holding the text form of the elements
+}
+
+// snapshotRecord is an element of a snapshot
+//
+// Description: With SnapshotAbsolute, Expires is a wall-clock time and LastAccess is a wall-clock time.
+// With SnapshotRelative, Expires is the remaining time to live and LastAccess is the time elapsed since the access.
+// In both modes, an Expires of 0 means no expiration.
+type snapshotRecord[T comparable] struct {
+	Elem       T     // Elem is the element
+	Expires    int64 // Expires is the expiration time in nanoseconds
+	MaxIdle    int64 // MaxIdle is the maximum idle duration in nanoseconds, 0 meaning no limit
+	LastAccess int64 // LastAccess is the time of the last access in nanoseconds, unused without a MaxIdle
+	Deleted    bool  // Deleted is true if the element was removed, in a delta
+}
+
+// Snapshot writes the unexpired elements of the cache and their expiration times to w
+//
+// Description: The snapshot is encoded with encoding/gob, so T must be encodable by gob, unless it
+// implements encoding.TextMarshaler and its pointer encoding.TextUnmarshaler: the elements are then
+// written in their text form, which lets struct elements with unexported fields round-trip. The cache is
+// not locked while the elements are encoded: the changes made in the meantime are not part of the snapshot.
+func (c *Cache[T]) Snapshot(w io.Writer, mode SnapshotMode) error {
+	start := time.Now()
+	n, err := c.snapshot(w, mode, false)
+	if err != nil {
+		c.options.logger.Warn("cacheset: snapshot failed", slog.Any("error", err))
+		return err
+	}
+
+	c.options.logger.Debug("cacheset: wrote snapshot",
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("len", n),
+	)
+	return nil
+}
+
+// snapshot writes the snapshot, or the delta since the previous one, and returns the number of written records
+//
+// Description: The storage is frozen and wrapped in an overlay while the elements are encoded,
+// so that the cache is only locked to swap the storages. Snapshots are written one at a time.
+func (c *Cache[T]) snapshot(w io.Writer, mode SnapshotMode, delta bool) (n int, err error) {
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+
+	c.Lock()
+	base := c.set
+	frozen := newOverlay(base)
+	c.set = frozen
+	wall, now := time.Now().UnixNano(), nanotime()
+	dirty, cleared, since := c.dirty, c.dirtyCleared, c.lastSnapshot
+	if dirty != nil {
+		c.dirty, c.dirtyCleared = make(map[T]struct{}), false
+	}
+	c.lastSnapshot = wall
+	c.Unlock()
+
+	defer func() {
+		c.Lock()
+		defer c.Unlock()
+
+		if c.set == store[T](frozen) {
+			c.set = frozen.merge()
+		}
+		if err != nil && dirty != nil {
+			// the next delta must include the changes of the failed snapshot
+			for elem := range dirty {
+				c.dirty[elem] = struct{}{}
+			}
+			c.dirtyCleared = c.dirtyCleared || cleared
+			c.lastSnapshot = since
+		}
+	}()
+
+	header := snapshotHeader{
+		Magic:   snapshotMagic,
+		Version: snapshotVersion,
+		Mode:    mode,
+		Taken:   wall,
+		Text:    textElements[T](),
+	}
+	var records iter.Seq[snapshotRecord[T]]
+	if delta {
+		header.Delta, header.Since, header.Cleared = true, since, cleared
+		header.Len = len(dirty)
+		records = deltaRecords(base, dirty, mode, wall, now)
+	} else {
+		for _, e := range base.All() {
+			if !e.expired(now) {
+				header.Len++
+			}
+		}
+		records = fullRecords(base, mode, wall, now)
+	}
+
+	var sealed *sealer
+	if c.options.snapshotKeys != nil {
+		if sealed, err = newSealer(w, c.options.snapshotKeys); err != nil {
+			return 0, fmt.Errorf("cacheset: encrypting snapshot: %w", err)
+		}
+		w = sealed
+	}
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(header); err != nil {
+		return 0, fmt.Errorf("cacheset: writing snapshot header: %w", err)
+	}
+	for record := range records {
+		if err := encodeRecord(enc, record, header.Text); err != nil {
+			return 0, fmt.Errorf("cacheset: writing snapshot: %w", err)
+		}
+	}
+	if sealed != nil {
+		if err := sealed.Close(); err != nil {
+			return 0, fmt.Errorf("cacheset: writing snapshot: %w", err)
+		}
+	}
+
+	return header.Len, nil
+}
+
+// fullRecords returns the records of the unexpired elements of the given frozen storage
+func fullRecords[T comparable](base store[T], mode SnapshotMode, wall, now int64) iter.Seq[snapshotRecord[T]] {
+	return func(yield func(snapshotRecord[T]) bool) {
+		for elem, e := range base.All() {
+			if !e.expired(now) && !yield(newSnapshotRecord(elem, e, mode, wall, now)) {
+				return
+			}
+		}
+	}
+}
+
+// deltaRecords returns the records of the given changed elements of the frozen storage, the missing
+// and expired ones being recorded as deleted
+func deltaRecords[T comparable](base store[T], dirty map[T]struct{}, mode SnapshotMode, wall, now int64) iter.Seq[snapshotRecord[T]] {
+	return func(yield func(snapshotRecord[T]) bool) {
+		for elem := range dirty {
+			record := snapshotRecord[T]{Elem: elem, Deleted: true}
+			if e, ok := base.Get(elem); ok && !e.expired(now) {
+				record = newSnapshotRecord(elem, e, mode, wall, now)
+			}
+			if !yield(record) {
+				return
+			}
+		}
+	}
+}
+
+// Restore adds the unexpired elements of a snapshot written by Snapshot to the cache
+//
+// Description: The mode of the snapshot is read from its header. Restored elements replace the elements
+// already in the cache, and elements are evicted if the cache has a capacity and is full.
+// A delta written by SnapshotDelta is applied like a snapshot, its removals included: use RestoreLayers
+// to check that the deltas are applied to the snapshot they were written after.
+func (c *Cache[T]) Restore(r io.Reader) error {
+	start := time.Now()
+	header, n, err := c.restore(r, nil)
+	if err != nil {
+		c.options.logger.Warn("cacheset: restore failed", slog.Any("error", err))
+		return err
+	}
+
+	c.options.logger.Debug("cacheset: restored snapshot",
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("len", n),
+		slog.Bool("delta", header.Delta),
+	)
+	return nil
+}
+
+// restore reads the snapshot and returns its header and the number of restored records
+//
+// Description: If accept is not nil, the snapshot is only applied if accept returns nil for its header,
+// otherwise the error of accept is returned.
+func (c *Cache[T]) restore(r io.Reader, accept func(snapshotHeader) error) (snapshotHeader, int, error) {
+	var header snapshotHeader
+	r, err := decrypt(r, c.options.snapshotKeys)
+	if err != nil {
+		return header, 0, err
+	}
+	dec := gob.NewDecoder(r)
+
+	if err := dec.Decode(&header); err != nil {
+		return header, 0, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	if header.Magic != snapshotMagic || header.Version < 1 || header.Version > snapshotVersion {
+		return header, 0, ErrInvalidSnapshot
+	}
+	if accept != nil {
+		if err := accept(header); err != nil {
+			return header, 0, err
+		}
+	}
+
+	restored := newSet[T]()
+	var deleted []T
+	wall, now := time.Now().UnixNano(), nanotime()
+	for i := 0; i < header.Len; i++ {
+		record, err := decodeRecord[T](dec, header.Text)
+		if err != nil {
+			return header, 0, fmt.Errorf("cacheset: reading snapshot: %w", err)
+		}
+		if e := record.entry(header.Mode, wall, now); !record.Deleted && !e.expired(now) {
+			restored[record.Elem] = e
+		} else if header.Delta {
+			deleted = append(deleted, record.Elem)
+		}
+	}
+
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	if header.Cleared {
+		c.clear()
+	}
+	for _, elem := range deleted {
+		if c.set.Contains(elem) {
+			c.remove(elem, RemovalDeleted)
+		}
+	}
+	for elem, e := range restored {
+		if !c.set.Contains(elem) {
+			c.track(elem)
+		}
+		c.set.Set(elem, e)
+		c.markDirty(elem)
+		c.due(elem)
+		c.publishAdded(elem)
+	}
+	c.shrink()
+
+	return header, len(restored) + len(deleted), nil
+}
+
+// newSnapshotRecord returns the record of the given entry
+func newSnapshotRecord[T comparable](elem T, e *entry, mode SnapshotMode, wall, now int64) snapshotRecord[T] {
+	record := snapshotRecord[T]{Elem: elem, MaxIdle: e.maxIdle()}
+	if mode == SnapshotRelative {
+		if e.expires != 0 {
+			record.Expires = e.expires - now
+		}
+		if e.idle != nil {
+			record.LastAccess = now - e.lastAccess()
+		}
+		return record
+	}
+
+	if e.expires != 0 {
+		record.Expires = wall + e.expires - now
+	}
+	if e.idle != nil {
+		record.LastAccess = wall - (now - e.lastAccess())
+	}
+	return record
+}
+
+// entry returns the entry of the record
+func (r snapshotRecord[T]) entry(mode SnapshotMode, wall, now int64) *entry {
+	e := &entry{}
+	if mode == SnapshotRelative {
+		if r.Expires != 0 {
+			e.expires = max(1, now+r.Expires)
+		}
+		if r.MaxIdle > 0 {
+			e.idle = newIdleState(r.MaxIdle, now-r.LastAccess)
+		}
+		return e
+	}
+
+	if r.Expires != 0 {
+		e.expires = max(1, now+r.Expires-wall)
+	}
+	if r.MaxIdle > 0 {
+		e.idle = newIdleState(r.MaxIdle, now-(wall-r.LastAccess))
+	}
+	return e
+}
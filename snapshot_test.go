@@ -0,0 +1,51 @@
+package cacheset
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_Snapshot(t *testing.T) {
+	c := New[string](time.Minute)
+	defer c.Close()
+	c.Add("permanent", 0)
+	c.Add("ttl", time.Hour)
+	c.AddWithIdle("idle", 0, time.Hour)
+
+	for _, mode := range []SnapshotMode{SnapshotAbsolute, SnapshotRelative} {
+		var buf bytes.Buffer
+		if err := c.Snapshot(&buf, mode); err != nil {
+			t.Fatalf("Snapshot() error = %v", err)
+		}
+
+		restored := New[string](time.Minute)
+		if err := restored.Restore(&buf); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+
+		t.Run("RoundTrip", func(t *testing.T) {
+			if got := restored.Len(); got != 3 {
+				t.Errorf("Restore() Len = %v, want %v", got, 3)
+			}
+			for _, elem := range []string{"permanent", "ttl", "idle"} {
+				if got, want := expiration(restored, elem), expiration(c, elem); (got-want) > int64(time.Second) || (want-got) > int64(time.Second) {
+					t.Errorf("Restore() expiration of %v = %v, want %v", elem, got, want)
+				}
+			}
+		})
+		restored.Close()
+	}
+}
+
+func TestCache_Restore(t *testing.T) {
+	c := New[string](time.Minute)
+	defer c.Close()
+
+	t.Run("Invalid", func(t *testing.T) {
+		if err := c.Restore(bytes.NewBufferString("not a snapshot")); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Errorf("Restore() error = %v, want %v", err, ErrInvalidSnapshot)
+		}
+	})
+}
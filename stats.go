@@ -0,0 +1,149 @@
+// Package cacheset
+//
+// Path: stats.go
+//
+// Description: stats.go contains the counters of the cache.
+package cacheset
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the cache's counters
+type Stats struct {
+	Len                 int             // Len is the number of elements in the cache
+	Hits                uint64          // Hits is the number of Contains calls that found the element
+	Misses              uint64          // Misses is the number of Contains calls that did not find the element
+	Adds                uint64          // Adds is the number of elements added to the cache
+	Deletes             uint64          // Deletes is the number of elements removed with Delete or Clear
+	Expirations         uint64          // Expirations is the number of elements removed because they expired
+	Evictions           uint64          // Evictions is the number of elements evicted because the cache was full
+	Pinned              int             // Pinned is the number of pinned elements
+	ShardLens           []int           // ShardLens is the number of elements in each shard of a Sharded cache, nil otherwise
+	ShardSweepDurations []time.Duration // ShardSweepDurations is the duration of the last cleaning of each shard of a Sharded cache, nil otherwise
+	Breaker             BreakerState    // Breaker is the state of the loader's circuit breaker, BreakerClosed without WithLoadBreaker
+	BreakerTrips        uint64          // BreakerTrips is the number of times the loader's circuit breaker opened
+	RemainingTTLs       Histogram       // RemainingTTLs is the distribution of the remaining times to live of the expiring elements, empty without WithTTLHistograms
+	EvictedLifetimes    Histogram       // EvictedLifetimes is the distribution of the times between the addition and the eviction of the elements
+	ExpiredLifetimes    Histogram       // ExpiredLifetimes is the distribution of the times between the addition and the expiration of the elements
+	AddLatency          Histogram       // AddLatency is the distribution of the latencies of the sampled calls of Add, empty without WithLatencyHistograms
+	ContainsLatency     Histogram       // ContainsLatency is the distribution of the latencies of the sampled calls of Contains
+	SweepLatency        Histogram       // SweepLatency is the distribution of the durations of the cleanings
+	ExpiredUnused       uint64          // ExpiredUnused is the number of elements which expired without being looked up
+	History             []StatsBucket   // History are the counters of the last periods, oldest first, nil without WithStatsHistory
+}
+
+// HitRatio returns the ratio of hits to the number of Contains calls, or 0 if there was none
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// counters of the cache, the indexes of the counts of a statCell
+const (
+	statHits = iota
+	statMisses
+	statAdds
+	statDeletes
+	statExpirations
+	statEvictions
+	statCount
+)
+
+// maxStatCells is the maximum number of cells of the counters of a cache
+const maxStatCells = 64
+
+// statCell holds a count of each counter, padded to a cache line so that the cells do not share one
+type statCell struct {
+	counts [statCount]atomic.Uint64
+	_      [64 - statCount*8]byte
+}
+
+// stats are the counters of the cache, striped over cells to avoid the contention on a single cache line
+//
+// Description: Each update goes to a random cell, one per processor up to maxStatCells, and the reads
+// sum the cells. With a single processor, the counters are plain atomic counters.
+type stats struct {
+	cells []statCell
+	mask  uint32
+}
+
+// newStats returns zeroed counters with a cell per processor
+func newStats() stats {
+	n := 1 << bits.Len(uint(min(runtime.GOMAXPROCS(0), maxStatCells)-1))
+	return stats{cells: make([]statCell, n), mask: uint32(n - 1)}
+}
+
+// add adds n to the given counter
+func (s *stats) add(counter int, n uint64) {
+	cell := &s.cells[0]
+	if s.mask != 0 {
+		cell = &s.cells[rand.Uint32()&s.mask]
+	}
+	cell.counts[counter].Add(n)
+}
+
+// load returns the sum of the given counter over the cells
+func (s *stats) load(counter int) uint64 {
+	var n uint64
+	for i := range s.cells {
+		n += s.cells[i].counts[counter].Load()
+	}
+	return n
+}
+
+// reset sets all counters to zero
+func (s *stats) reset() {
+	for i := range s.cells {
+		for j := range s.cells[i].counts {
+			s.cells[i].counts[j].Store(0)
+		}
+	}
+}
+
+// hit records a Contains call
+func (s *stats) hit(found bool) {
+	if found {
+		s.add(statHits, 1)
+	} else {
+		s.add(statMisses, 1)
+	}
+}
+
+// Stats returns a snapshot of the cache's counters
+func (c *Cache[T]) Stats() Stats {
+	st := Stats{
+		Len:         c.Len(),
+		Hits:        c.stats.load(statHits),
+		Misses:      c.stats.load(statMisses),
+		Adds:        c.stats.load(statAdds),
+		Deletes:     c.stats.load(statDeletes),
+		Expirations: c.stats.load(statExpirations),
+		Evictions:   c.stats.load(statEvictions),
+		Pinned:      int(c.pinCount.Load()),
+	}
+	if c.breaker != nil {
+		st.Breaker, st.BreakerTrips = c.breaker.status()
+	}
+	if c.history != nil {
+		st.History = c.history.snapshot(c.stats.counts())
+	}
+	if c.latency != nil {
+		st.AddLatency = c.latency.add.histogram()
+		st.ContainsLatency = c.latency.contains.histogram()
+		st.SweepLatency = c.latency.sweep.histogram()
+	}
+	if c.options.ttlHistograms {
+		c.RLock()
+		c.ttlStats(&st)
+		c.RUnlock()
+	}
+	return st
+}
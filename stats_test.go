@@ -0,0 +1,47 @@
+package cacheset
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestStats_Striped(t *testing.T) {
+	if size := unsafe.Sizeof(statCell{}); size != 64 {
+		t.Errorf("Sizeof(statCell{}) = %v, want %v", size, 64)
+	}
+
+	for _, procs := range []int{1, 3, 8, 256} {
+		prev := runtime.GOMAXPROCS(procs)
+		s := newStats()
+		runtime.GOMAXPROCS(prev)
+
+		want := min(maxStatCells, 1<<bits.Len(uint(procs-1)))
+		if len(s.cells) != want {
+			t.Errorf("newStats() with GOMAXPROCS %v has %v cells, want %v", procs, len(s.cells), want)
+		}
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					s.hit(i%4 != 0)
+					s.add(statAdds, 2)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if hits, misses, adds := s.load(statHits), s.load(statMisses), s.load(statAdds); hits != 6000 || misses != 2000 || adds != 16000 {
+			t.Errorf("load() = %v hits, %v misses, %v adds, want %v, %v, %v", hits, misses, adds, 6000, 2000, 16000)
+		}
+		s.reset()
+		if hits := s.load(statHits); hits != 0 {
+			t.Errorf("load() after reset() = %v, want %v", hits, 0)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package cacheset
+//
+// Path: store.go
+//
+// Description: store.go contains the interface of the storages of the elements of a cache.
+package cacheset
+
+import (
+	"iter"
+	"time"
+)
+
+// store stores the elements of a cache with their entries, implemented by set and table, and wrapped by indexed
+type store[T comparable] interface {
+	Expire(elem T) bool
+	Copy() store[T]
+	Expirations() map[T]int64
+	ExpireAll() []T
+	Expired(elem T) bool
+	ExpiringFirst(n int) []T
+	Merge(other store[T], resolve func(a, b int64) int64) []T
+	ToSlice() []T
+	Filter(pred func(T) bool) []T
+	Partition(pred func(T) bool) (in []T, out []T)
+	Add(elem T, duration time.Duration)
+	AddWithIdle(elem T, ttl, maxIdle time.Duration)
+	Clear()
+	Contains(elem T) bool
+	Touch(elem T) bool
+	Delete(elem T)
+	Len() int
+	Get(elem T) (*entry, bool)
+	Set(elem T, e *entry)
+	All() iter.Seq2[T, *entry]
+	// Probe returns an iterator over the elements and their entries like All, starting at the given position in
+	// the storages iterated in a fixed order, and storing in it the position following the last element yielded
+	Probe(pos *int) iter.Seq2[T, *entry]
+}
+
+// WithCompactStorage stores the elements in an open-addressing table instead of a Go map
+//
+// Description: The table is an experiment for very large caches. It keeps the elements, their
+// expiration times and one control byte per slot in flat arrays, probed by groups of 8 slots with 7
+// bits of the hash of the elements before comparing the elements themselves, and keeps between 70%
+// and 88% of its slots used. The access times of the elements added with a maximum idle duration are
+// kept out of line. Nothing is allocated per element, so the garbage collector has nothing to scan
+// when the elements hold no pointers. BenchmarkStorage_Memory reports the bytes per element of both
+// storages: with int64 elements, the table uses 30 to 60% less memory than the map, depending on
+// how full each of them is. Looking up an entry outside of Contains allocates a copy of it.
+func WithCompactStorage() Option {
+	return func(o *options) {
+		o.compactStorage = true
+	}
+}
+
+// newStore returns an empty storage of the kind chosen by the options
+func newStore[T comparable](o options) store[T] {
+	var s store[T] = newSet[T]()
+	if o.compactStorage {
+		s = newTable[T]()
+	}
+	switch {
+	case o.wheelTick > 0:
+		s = newIndexed(s, newWheel[T](o.wheelTick))
+	case o.bucketWidth > 0:
+		s = newIndexed(s, newBuckets[T](o.bucketWidth))
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+// Package cacheset
+//
+// Path: strict.go
+//
+// Description: strict.go contains the strict mode, which rejects the misuses of the cache that are
+// otherwise silently accepted, to catch them during the development.
+package cacheset
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithStrict rejects the durations and the calls that are valid but most likely mistakes
+//
+// Description: In strict mode, New panics if the clean interval of a cache is not positive, rather than
+// leaving the ticker of the cleaning goroutine to panic. Every addition, through Add and its variants,
+// GetOrAdd, Warm, WriteBehind, AddIfVersion, AddUntilDone or TryAcquire, is rejected with ErrInvalidDuration
+// for a negative TTL or maximum idle duration, which would otherwise mean that the element never expires,
+// and with ErrClosed once the cache is closed. The methods that cannot return an error report a rejection
+// by not adding the element, WriteBehind passes it to the error handler. WithNegativeExpiry gives the negative durations a meaning, they
+// are accepted then.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// checkStrict panics if the given clean interval is rejected by the strict mode
+func checkStrict(cleanInterval time.Duration, o options) {
+	if !o.strict {
+		return
+	}
+	if cleanInterval <= 0 {
+		panic(fmt.Sprintf("cacheset: the clean interval %v is not positive", cleanInterval))
+	}
+}
+
+// validate returns the error of an addition for ttl and maxIdle in strict mode
+func (c *Cache[T]) validate(ttl, maxIdle time.Duration) error {
+	if !c.options.strict || c.options.negativeExpiry {
+		return nil
+	}
+	if ttl < 0 || maxIdle < 0 {
+		return fmt.Errorf("%w: ttl %v, max idle %v", ErrInvalidDuration, ttl, maxIdle)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_Strict(t *testing.T) {
+	c := New[int](time.Minute, WithStrict())
+
+	tests := []struct {
+		name    string
+		add     func() error
+		wantErr error
+	}{
+		{name: "Valid", add: func() error { return c.Add(1, time.Minute) }},
+		{name: "Permanent", add: func() error { return c.Add(2, 0) }},
+		{name: "NegativeTTL", add: func() error { return c.Add(3, -time.Second) }, wantErr: ErrInvalidDuration},
+		{name: "NegativeIdle", add: func() error { return c.AddWithIdle(3, 0, -time.Second) }, wantErr: ErrInvalidDuration},
+		{name: "GetOrAdd", add: func() error { _, err := c.GetOrAdd(3, -time.Second); return err }, wantErr: ErrInvalidDuration},
+		{name: "Closed", add: func() error { c.Close(); return c.Add(4, time.Minute) }, wantErr: ErrClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.add(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if c.Contains(3) {
+		t.Errorf("Contains() = true for a rejected element, want false")
+	}
+}
+
+func TestCache_Strict_Paths(t *testing.T) {
+	t.Run("Warm", func(t *testing.T) {
+		c := New[int](time.Minute, WithStrict())
+		defer c.Close()
+		if err := c.Warm(context.Background(), SeedSlice([]int{1}, -time.Second)); err != nil {
+			t.Fatalf("Warm() error = %v", err)
+		}
+		if c.Contains(1) {
+			t.Errorf("Contains() = true after warming with a negative duration, want false")
+		}
+	})
+
+	t.Run("WriteBehind", func(t *testing.T) {
+		errs := make(chan error, 1)
+		w := NewWriteBehind[int](time.Minute, WithStrict(), WithErrorHandler(func(err error) { errs <- err }))
+		defer w.Close()
+		_ = w.Add(1, -time.Second)
+		w.Sync()
+		if err := <-errs; !errors.Is(err, ErrInvalidDuration) {
+			t.Errorf("error handler got %v, want %v", err, ErrInvalidDuration)
+		}
+		if w.Cache().Contains(1) {
+			t.Errorf("Contains() = true after a rejected addition, want false")
+		}
+	})
+
+	t.Run("TryAcquire", func(t *testing.T) {
+		c := New[int](time.Minute, WithStrict())
+		defer c.Close()
+		if c.TryAcquire(1, 10, -time.Second) {
+			t.Errorf("TryAcquire() = true with a negative window, want false")
+		}
+	})
+}
+
+func TestNew_Strict(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("New(%v) did not panic", interval)
+				}
+			}()
+			New[int](interval, WithStrict()).Close()
+		}()
+	}
+}
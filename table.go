@@ -0,0 +1,441 @@
+// Package cacheset
+//
+// Path: table.go
+//
+// Description: table.go contains the open-addressing table storing the elements of a compact cache.
+package cacheset
+
+import (
+	"hash/maphash"
+	"iter"
+	"maps"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// groupSize is the number of slots probed together in a table
+const groupSize = 8
+
+// control bytes of the slots of a table, a full slot storing the 7 low bits of the hash of its element
+const (
+	ctrlEmpty   uint8 = 0x80 // ctrlEmpty marks a slot that was never used, ending the probing
+	ctrlDeleted uint8 = 0xFE // ctrlDeleted marks a slot whose element was deleted
+)
+
+// table is an open-addressing hash table of the elements of a set and their expiration times
+//
+// Description: The slots are split in groups of groupSize slots, probed linearly from the group
+// chosen by the high bits of the hash, whose 7 low bits are compared before the elements themselves.
+// The table grows by a quarter when 7/8 of its slots are full or deleted, so that between 70% and 88%
+// of its slots are used.
+//
+// A slot only takes a control byte, the element and its expiration time: the access times of the
+// elements with a maximum idle duration are kept out of line, in the idle map. The entries returned
+// by Get and All are built from the slots, so they hold a copy of the expiration time but share the
+// access time of the element, which is all touch writes.
+type table[T comparable] struct {
+	ctrl    []uint8          // ctrl is the control byte of each slot
+	keys    []T              // keys is the element of each slot
+	expires []int64          // expires is the expiration time of each slot, 0 meaning no expiration
+	idle    map[T]*idleState // idle tracks the accesses of the elements with a maximum idle duration
+	seed    maphash.Seed     // seed is the seed of the hashes of the elements
+	groups  uint64           // groups is the number of groups
+	live    int              // live is the number of full slots
+	used    int              // used is the number of full or deleted slots
+}
+
+// newTable returns an empty table
+func newTable[T comparable]() *table[T] {
+	t := &table[T]{seed: maphash.MakeSeed(), idle: make(map[T]*idleState)}
+	t.init(1)
+	return t
+}
+
+// init allocates the given number of empty groups
+func (t *table[T]) init(groups int) {
+	n := groups * groupSize
+	t.ctrl = make([]uint8, n)
+	for i := range t.ctrl {
+		t.ctrl[i] = ctrlEmpty
+	}
+	t.keys = make([]T, n)
+	t.expires = make([]int64, n)
+	t.groups = uint64(groups)
+	t.live, t.used = 0, 0
+}
+
+// hash returns the first probed group and the control byte of the given element
+func (t *table[T]) hash(elem T) (uint64, uint8) {
+	h := maphash.Comparable(t.seed, elem)
+	g, _ := bits.Mul64(h, t.groups)
+	return g, uint8(h & 0x7F)
+}
+
+// next returns the group probed after the given one
+func (t *table[T]) next(g uint64) uint64 {
+	if g++; g == t.groups {
+		return 0
+	}
+	return g
+}
+
+// find returns the slot of the given element, or -1 if it is not in the table
+func (t *table[T]) find(elem T) int {
+	g, h2 := t.hash(elem)
+	for {
+		base := int(g) * groupSize
+		for i := base; i < base+groupSize; i++ {
+			switch t.ctrl[i] {
+			case h2:
+				if t.keys[i] == elem {
+					return i
+				}
+			case ctrlEmpty:
+				return -1
+			}
+		}
+		g = t.next(g)
+	}
+}
+
+// insert returns the slot of the given element, taking a free slot if it is not in the table,
+// and true if it was already there
+func (t *table[T]) insert(elem T) (int, bool) {
+	if i := t.find(elem); i >= 0 {
+		return i, true
+	}
+	if (t.used+1)*8 > len(t.ctrl)*7 {
+		t.rehash()
+	}
+
+	g, h2 := t.hash(elem)
+	for {
+		base := int(g) * groupSize
+		for i := base; i < base+groupSize; i++ {
+			if t.ctrl[i]&ctrlEmpty == 0 {
+				continue
+			}
+			if t.ctrl[i] == ctrlEmpty {
+				t.used++
+			}
+			t.ctrl[i] = h2
+			t.keys[i] = elem
+			t.live++
+			return i, false
+		}
+		g = t.next(g)
+	}
+}
+
+// rehash moves the full slots to a new table, a quarter larger unless most slots were deleted
+func (t *table[T]) rehash() {
+	ctrl, keys, expires := t.ctrl, t.keys, t.expires
+	groups := len(ctrl) / groupSize
+	if t.live*16 >= len(ctrl)*7 {
+		groups += (groups + 3) / 4
+	}
+
+	t.init(groups)
+	for i, c := range ctrl {
+		if c&ctrlEmpty == 0 {
+			j, _ := t.insert(keys[i])
+			t.expires[j] = expires[i]
+		}
+	}
+}
+
+// full returns true if the given slot stores an element
+func (t *table[T]) full(i int) bool {
+	return t.ctrl[i]&ctrlEmpty == 0
+}
+
+// entry fills e with the entry of the given full slot
+func (t *table[T]) entry(i int, e *entry) {
+	e.expires, e.idle = t.expires[i], nil
+	if len(t.idle) > 0 {
+		e.idle = t.idle[t.keys[i]]
+	}
+}
+
+// store writes the given entry in the given full slot
+func (t *table[T]) store(i int, e *entry) {
+	t.expires[i] = e.expires
+	if e.idle != nil {
+		t.idle[t.keys[i]] = newIdleState(e.idle.maxIdle, e.idle.lastAccess.Load())
+	} else if len(t.idle) > 0 {
+		delete(t.idle, t.keys[i])
+	}
+}
+
+// expired returns true if the element of the given full slot has expired at the given time
+func (t *table[T]) expired(i int, now int64) bool {
+	var e entry
+	t.entry(i, &e)
+	return e.expired(now)
+}
+
+// Get returns the entry of the given element, see table for what it shares with the table
+func (t *table[T]) Get(elem T) (*entry, bool) {
+	i := t.find(elem)
+	if i < 0 {
+		return nil, false
+	}
+	e := &entry{}
+	t.entry(i, e)
+	return e, true
+}
+
+// Set stores a copy of the given entry as the entry of the given element
+func (t *table[T]) Set(elem T, e *entry) {
+	i, _ := t.insert(elem)
+	t.store(i, e)
+}
+
+// All returns an iterator over the elements of the table and their entries
+//
+// Description: Elements may be deleted while iterating, but not added. The entry yielded with an
+// element is only valid until the next one is yielded.
+func (t *table[T]) All() iter.Seq2[T, *entry] {
+	return func(yield func(T, *entry) bool) {
+		var e entry
+		for i := range t.ctrl {
+			if !t.full(i) {
+				continue
+			}
+			t.entry(i, &e)
+			if !yield(t.keys[i], &e) {
+				return
+			}
+		}
+	}
+}
+
+// Probe returns an iterator over the elements of the table and their entries like All, starting at the slot
+// at the given position and wrapping around, and storing in it the slot following the last element yielded
+func (t *table[T]) Probe(pos *int) iter.Seq2[T, *entry] {
+	return func(yield func(T, *entry) bool) {
+		var e entry
+		n := len(t.ctrl)
+		start := max(*pos, 0) % n
+		for k := range n {
+			i := (start + k) % n
+			if !t.full(i) {
+				continue
+			}
+			*pos = i + 1
+			t.entry(i, &e)
+			if !yield(t.keys[i], &e) {
+				return
+			}
+		}
+	}
+}
+
+// Expire removes the given element from the table if it has expired and returns true if it was removed
+func (t *table[T]) Expire(elem T) bool {
+	if t.Expired(elem) {
+		t.Delete(elem)
+		return true
+	}
+	return false
+}
+
+// Copy returns a copy of the table
+func (t *table[T]) Copy() store[T] {
+	c := &table[T]{
+		ctrl:    slices.Clone(t.ctrl),
+		keys:    slices.Clone(t.keys),
+		expires: slices.Clone(t.expires),
+		idle:    maps.Clone(t.idle),
+		seed:    t.seed,
+		groups:  t.groups,
+		live:    t.live,
+		used:    t.used,
+	}
+	for elem, s := range c.idle {
+		c.idle[elem] = newIdleState(s.maxIdle, s.lastAccess.Load())
+	}
+	return c
+}
+
+// Expirations returns a map of the table's elements to their expiration times in nanoseconds since the Unix epoch,
+// 0 meaning no expiration
+func (t *table[T]) Expirations() map[T]int64 {
+	m := make(map[T]int64, t.live)
+	for k, v := range t.All() {
+		m[k] = toWall(v.deadline())
+	}
+	return m
+}
+
+// ExpireAll removes all expired elements from the table and returns them
+func (t *table[T]) ExpireAll() []T {
+	var removed []T
+	now := nanotime()
+	for i := range t.ctrl {
+		if t.full(i) && t.expired(i, now) {
+			removed = append(removed, t.keys[i])
+			t.clearSlot(i)
+		}
+	}
+	return removed
+}
+
+// Expired returns true if the given element has expired
+func (t *table[T]) Expired(elem T) bool {
+	i := t.find(elem)
+	return i >= 0 && t.expired(i, nanotime())
+}
+
+// ExpiringFirst returns up to n elements of the table, the ones expiring first, the elements without expiration last
+func (t *table[T]) ExpiringFirst(n int) []T {
+	type slot struct {
+		i        int
+		deadline int64
+	}
+	slots := make([]slot, 0, t.live)
+	var e entry
+	for i := range t.ctrl {
+		if t.full(i) {
+			t.entry(i, &e)
+			slots = append(slots, slot{i: i, deadline: e.deadline()})
+		}
+	}
+	slices.SortFunc(slots, func(a, b slot) int {
+		return compareDeadlines(a.deadline, b.deadline)
+	})
+
+	elems := make([]T, 0, min(n, len(slots)))
+	for _, s := range slots[:min(n, len(slots))] {
+		elems = append(elems, t.keys[s.i])
+	}
+	return elems
+}
+
+// Merge adds all unexpired elements of other to the table and returns the elements that were not in the table
+//
+// Description: When an element exists in both, resolve is called with both expiration times
+// and its result is stored as the new expiration time.
+func (t *table[T]) Merge(other store[T], resolve func(a, b int64) int64) []T {
+	var added []T
+	now := nanotime()
+	for k, v := range other.All() {
+		if v.expired(now) {
+			continue
+		}
+		i, found := t.insert(k)
+		if found && !t.expired(i, now) {
+			t.expires[i] = resolve(t.expires[i], v.expires)
+			continue
+		}
+		if !found {
+			added = append(added, k)
+		}
+		t.store(i, v)
+	}
+	return added
+}
+
+// ToSlice returns a slice of the table's elements
+func (t *table[T]) ToSlice() []T {
+	slice := make([]T, 0, t.live)
+	for k := range t.All() {
+		slice = append(slice, k)
+	}
+	return slice
+}
+
+// Filter returns a slice of the table's unexpired elements for which pred returns true
+func (t *table[T]) Filter(pred func(T) bool) []T {
+	in, _ := t.Partition(pred)
+	return in
+}
+
+// Partition splits the table's unexpired elements into those for which pred returns true and the others
+func (t *table[T]) Partition(pred func(T) bool) (in []T, out []T) {
+	now := nanotime()
+	for k, v := range t.All() {
+		if v.expired(now) {
+			continue
+		}
+		if pred(k) {
+			in = append(in, k)
+		} else {
+			out = append(out, k)
+		}
+	}
+	return in, out
+}
+
+// Add adds the given element to the table with the given expiration time
+func (t *table[T]) Add(elem T, duration time.Duration) {
+	t.AddWithIdle(elem, duration, 0)
+}
+
+// AddWithIdle adds the given element to the table, expiring after ttl or after maxIdle without access
+func (t *table[T]) AddWithIdle(elem T, ttl, maxIdle time.Duration) {
+	var e entry
+	e.reset(ttl, maxIdle, nanotime())
+	i, _ := t.insert(elem)
+	t.expires[i] = e.expires
+	if e.idle != nil {
+		t.idle[elem] = e.idle
+	} else if len(t.idle) > 0 {
+		delete(t.idle, elem)
+	}
+}
+
+// Clear removes all elements from the table, keeping its size
+func (t *table[T]) Clear() {
+	for i := range t.ctrl {
+		t.ctrl[i] = ctrlEmpty
+	}
+	clear(t.keys)
+	clear(t.expires)
+	clear(t.idle)
+	t.live, t.used = 0, 0
+}
+
+// Contains returns true if the given element is in the table
+func (t *table[T]) Contains(elem T) bool {
+	return t.find(elem) >= 0
+}
+
+// Touch records an access to the given element and returns true if it is in the table
+func (t *table[T]) Touch(elem T) bool {
+	i := t.find(elem)
+	if i < 0 {
+		return false
+	}
+	if len(t.idle) > 0 {
+		var e entry
+		t.entry(i, &e)
+		e.touch(nanotime())
+	}
+	return true
+}
+
+// Delete removes the given element from the table
+func (t *table[T]) Delete(elem T) {
+	if i := t.find(elem); i >= 0 {
+		t.clearSlot(i)
+	}
+}
+
+// clearSlot marks the given full slot as deleted
+func (t *table[T]) clearSlot(i int) {
+	var zero T
+	if len(t.idle) > 0 {
+		delete(t.idle, t.keys[i])
+	}
+	t.ctrl[i] = ctrlDeleted
+	t.keys[i] = zero
+	t.expires[i] = 0
+	t.live--
+}
+
+// Len returns the number of elements in the table
+func (t *table[T]) Len() int {
+	return t.live
+}
@@ -0,0 +1,188 @@
+package cacheset
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_table_AddDelete(t *testing.T) {
+	tb := newTable[int]()
+	for i := 0; i < 10_000; i++ {
+		tb.Add(i, 0)
+	}
+	for i := 0; i < 10_000; i += 2 {
+		tb.Delete(i)
+	}
+
+	t.Run("Len", func(t *testing.T) {
+		if got := tb.Len(); got != 5000 {
+			t.Errorf("Len() = %v, want %v", got, 5000)
+		}
+	})
+
+	t.Run("Contains", func(t *testing.T) {
+		for i := 0; i < 10_000; i++ {
+			if got, want := tb.Contains(i), i%2 == 1; got != want {
+				t.Fatalf("Contains(%v) = %v, want %v", i, got, want)
+			}
+		}
+	})
+
+	t.Run("Reuse", func(t *testing.T) {
+		size := len(tb.ctrl)
+		for round := 0; round < 10; round++ {
+			for i := 0; i < 10_000; i += 2 {
+				tb.Add(i, 0)
+			}
+			for i := 0; i < 10_000; i += 2 {
+				tb.Delete(i)
+			}
+		}
+		if got := len(tb.ctrl); got != size {
+			t.Errorf("len(ctrl) = %v, want %v", got, size)
+		}
+	})
+}
+
+func Test_table_ExpireAll(t *testing.T) {
+	tb := newTable[int64]()
+	tb.Add(1, 0)
+	tb.Add(2, time.Nanosecond)
+	tb.AddWithIdle(3, 0, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	got := tb.ExpireAll()
+	slices.Sort(got)
+	if !slices.Equal(got, []int64{2, 3}) || tb.Len() != 1 || !tb.Contains(1) {
+		t.Errorf("ExpireAll() = %v, want %v", got, []int64{2, 3})
+	}
+}
+
+func Test_table_Copy(t *testing.T) {
+	tb := newTable[int64]()
+	tb.Add(1, time.Minute)
+	c := tb.Copy()
+	tb.Delete(1)
+
+	e, ok := c.Get(1)
+	if !ok || e.expires == 0 {
+		t.Errorf("Copy() = %v, want an independent copy", c.ToSlice())
+	}
+}
+
+func Test_table_Merge(t *testing.T) {
+	tb := newTable[int64]()
+	tb.Add(1, time.Minute)
+	other := newSet[int64]()
+	other.Add(1, time.Hour)
+	other.Add(2, 0)
+
+	added := tb.Merge(other, func(a, b int64) int64 { return max(a, b) })
+	if !slices.Equal(added, []int64{2}) {
+		t.Errorf("Merge() = %v, want %v", added, []int64{2})
+	}
+	if e, _ := tb.Get(1); e.expires != other[1].expires {
+		t.Errorf("Merge() expiration = %v, want %v", e.expires, other[1].expires)
+	}
+}
+
+func TestWithCompactStorage(t *testing.T) {
+	c := New[int64](time.Minute, WithCompactStorage(), WithCapacity(100))
+	defer c.Close()
+	for i := int64(0); i < 200; i++ {
+		c.Add(i, time.Minute)
+	}
+
+	t.Run("Capacity", func(t *testing.T) {
+		if got := c.Len(); got != 100 {
+			t.Errorf("Len() = %v, want %v", got, 100)
+		}
+		if c.Contains(0) || !c.Contains(199) {
+			t.Errorf("WithCompactStorage() did not evict the least recently used elements")
+		}
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		clone := c.Clone()
+		defer clone.Close()
+		if got := clone.Len(); got != 100 {
+			t.Errorf("Clone() Len = %v, want %v", got, 100)
+		}
+	})
+}
+
+func Test_table_Idle(t *testing.T) {
+	tb := newTable[int64]()
+	tb.AddWithIdle(1, 0, 20*time.Millisecond)
+	tb.Add(2, 0)
+	c := tb.Copy()
+
+	for range 4 {
+		time.Sleep(10 * time.Millisecond)
+		if !tb.Touch(1) {
+			t.Fatalf("Touch() = false, want true")
+		}
+	}
+	if tb.Expired(1) {
+		t.Errorf("Expired() = true after the accesses, want false")
+	}
+	if !c.Expired(1) {
+		t.Errorf("Expired() = false in the copy, want true: the copy must not share the access times")
+	}
+	if e, _ := tb.Get(2); e.idle != nil {
+		t.Errorf("Get() idle = %v, want none without a maximum idle duration", e.idle)
+	}
+
+	tb.Add(1, time.Minute)
+	if len(tb.idle) != 0 {
+		t.Errorf("len(idle) = %v after Add, want %v", len(tb.idle), 0)
+	}
+}
+
+func TestWithCompactStorage_Memory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measures the heap with large storages")
+	}
+	for _, size := range []int{100_000, 650_000, 1_650_000} {
+		m := heapPerElem(newSet[int64](), size)
+		tb := heapPerElem(newTable[int64](), size)
+		if saving := 1 - tb/m; saving < 0.3 {
+			t.Errorf("table = %.1f bytes/elem, map = %.1f bytes/elem with %v elements, want a saving of 30%%", tb, m, size)
+		}
+	}
+}
+
+// heapPerElem returns the heap bytes per element of the given storage once size elements are added
+func heapPerElem(s store[int64], size int) float64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for j := int64(0); j < int64(size); j++ {
+		s.Add(j, time.Hour)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(s)
+	return float64(after.HeapAlloc-before.HeapAlloc) / float64(size)
+}
+
+// BenchmarkStorage_Memory reports the heap bytes per element of a million elements in both storages
+func BenchmarkStorage_Memory(b *testing.B) {
+	const size = 1_000_000
+
+	for _, bb := range []struct {
+		name  string
+		store func() store[int64]
+	}{
+		{name: "Map", store: func() store[int64] { return newSet[int64]() }},
+		{name: "Table", store: func() store[int64] { return newTable[int64]() }},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.ReportMetric(heapPerElem(bb.store(), size), "bytes/elem")
+			}
+		})
+	}
+}
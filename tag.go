@@ -0,0 +1,166 @@
+// Package cacheset
+//
+// Path: tag.go
+//
+// Description: tag.go contains the tags of the elements, indexed both ways so that the cache can be queried
+// as a small inverted index whose entries expire.
+package cacheset
+
+import (
+	"maps"
+	"slices"
+)
+
+// tagIndex maps the tags to their elements and the elements to their tags, the cache must be locked
+type tagIndex[T comparable] struct {
+	members map[string]map[T]struct{} // members are the elements of each tag
+	tags    map[T]map[string]struct{} // tags are the tags of each element
+}
+
+// newTagIndex returns an empty tag index
+func newTagIndex[T comparable]() *tagIndex[T] {
+	return &tagIndex[T]{members: make(map[string]map[T]struct{}), tags: make(map[T]map[string]struct{})}
+}
+
+// add tags the given element with the given tag
+func (x *tagIndex[T]) add(elem T, tag string) {
+	if x.members[tag] == nil {
+		x.members[tag] = make(map[T]struct{})
+	}
+	x.members[tag][elem] = struct{}{}
+	if x.tags[elem] == nil {
+		x.tags[elem] = make(map[string]struct{})
+	}
+	x.tags[elem][tag] = struct{}{}
+}
+
+// untag removes the given tag from the given element
+func (x *tagIndex[T]) untag(elem T, tag string) {
+	delete(x.members[tag], elem)
+	if len(x.members[tag]) == 0 {
+		delete(x.members, tag)
+	}
+	delete(x.tags[elem], tag)
+	if len(x.tags[elem]) == 0 {
+		delete(x.tags, elem)
+	}
+}
+
+// remove removes all the tags of the given element
+func (x *tagIndex[T]) remove(elem T) {
+	for tag := range x.tags[elem] {
+		x.untag(elem, tag)
+	}
+}
+
+// Tag tags the given element with the given tags, and returns false if it is not in the cache
+//
+// Description: The tags of an element are forgotten when it is removed, not when it is added again.
+func (c *Cache[T]) Tag(elem T, tags ...string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.set.Get(elem); !ok || e.expired(nanotime()) {
+		return false
+	}
+	if c.tags == nil {
+		c.tags = newTagIndex[T]()
+	}
+	for _, tag := range tags {
+		c.tags.add(elem, tag)
+	}
+	return true
+}
+
+// Untag removes the given tags from the given element
+func (c *Cache[T]) Untag(elem T, tags ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.tags == nil {
+		return
+	}
+	for _, tag := range tags {
+		c.tags.untag(elem, tag)
+	}
+}
+
+// MembersWithTag returns the unexpired elements tagged with the given tag, in the iteration order of the cache
+func (c *Cache[T]) MembersWithTag(tag string) []T {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.tags == nil {
+		return nil
+	}
+	now := nanotime()
+	var members []T
+	for elem := range c.tags.members[tag] {
+		if e, ok := c.set.Get(elem); ok && !e.expired(now) {
+			members = append(members, elem)
+		}
+	}
+	return c.ordered(members)
+}
+
+// TagsOf returns the sorted tags of the given element, nil if it has none or has expired
+func (c *Cache[T]) TagsOf(elem T) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.tags == nil {
+		return nil
+	}
+	if e, ok := c.set.Get(elem); !ok || e.expired(nanotime()) {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(c.tags.tags[elem]))
+}
+
+// DeleteTag removes all the elements tagged with the given tag and returns their number
+func (c *Cache[T]) DeleteTag(tag string) int {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.tags == nil {
+		return 0
+	}
+	members := slices.Collect(maps.Keys(c.tags.members[tag]))
+	for _, elem := range members {
+		c.remove(elem, RemovalDeleted)
+	}
+	return len(members)
+}
+
+// Tag tags the given element with the given tags, see Cache.Tag
+func (s *Sharded[T]) Tag(elem T, tags ...string) bool {
+	return s.shard(elem).Tag(elem, tags...)
+}
+
+// Untag removes the given tags from the given element
+func (s *Sharded[T]) Untag(elem T, tags ...string) {
+	s.shard(elem).Untag(elem, tags...)
+}
+
+// MembersWithTag returns the unexpired elements tagged with the given tag, one shard at a time
+func (s *Sharded[T]) MembersWithTag(tag string) []T {
+	var members []T
+	for _, shard := range s.shards {
+		members = append(members, shard.MembersWithTag(tag)...)
+	}
+	return members
+}
+
+// TagsOf returns the sorted tags of the given element, see Cache.TagsOf
+func (s *Sharded[T]) TagsOf(elem T) []string {
+	return s.shard(elem).TagsOf(elem)
+}
+
+// DeleteTag removes all the elements tagged with the given tag, one shard at a time, and returns their number
+func (s *Sharded[T]) DeleteTag(tag string) int {
+	var deleted int
+	for _, shard := range s.shards {
+		deleted += shard.DeleteTag(tag)
+	}
+	return deleted
+}
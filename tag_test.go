@@ -0,0 +1,66 @@
+package cacheset
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_Tag(t *testing.T) {
+	c := New[string](time.Minute, WithSortedIteration(strings.Compare))
+	defer c.Close()
+
+	for _, elem := range []string{"alice", "bob", "carol"} {
+		_ = c.Add(elem, 0)
+	}
+	_ = c.Add("dave", 10*time.Millisecond)
+	c.Tag("alice", "admin", "staff")
+	c.Tag("bob", "staff")
+	c.Tag("dave", "staff")
+	if c.Tag("eve", "staff") {
+		t.Errorf("Tag() = true for a missing element, want false")
+	}
+
+	t.Run("MembersWithTag", func(t *testing.T) {
+		if got, want := c.MembersWithTag("staff"), []string{"alice", "bob", "dave"}; !slices.Equal(got, want) {
+			t.Errorf("MembersWithTag() = %v, want %v", got, want)
+		}
+		if got := c.MembersWithTag("unknown"); got != nil {
+			t.Errorf("MembersWithTag() = %v, want nil", got)
+		}
+	})
+
+	t.Run("TagsOf", func(t *testing.T) {
+		if got, want := c.TagsOf("alice"), []string{"admin", "staff"}; !slices.Equal(got, want) {
+			t.Errorf("TagsOf() = %v, want %v", got, want)
+		}
+		c.Untag("alice", "admin")
+		if got, want := c.TagsOf("alice"), []string{"staff"}; !slices.Equal(got, want) {
+			t.Errorf("TagsOf() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		time.Sleep(20 * time.Millisecond)
+		if got, want := c.MembersWithTag("staff"), []string{"alice", "bob"}; !slices.Equal(got, want) {
+			t.Errorf("MembersWithTag() = %v, want %v", got, want)
+		}
+		c.ExpireAll()
+		if c.tags.tags["dave"] != nil || len(c.tags.members["staff"]) != 2 {
+			t.Errorf("tags = %v, want the expired element forgotten", c.tags.tags)
+		}
+	})
+
+	t.Run("DeleteTag", func(t *testing.T) {
+		if got := c.DeleteTag("staff"); got != 2 {
+			t.Errorf("DeleteTag() = %v, want %v", got, 2)
+		}
+		if got, want := c.ToSlice(), []string{"carol"}; !slices.Equal(got, want) {
+			t.Errorf("ToSlice() = %v, want %v", got, want)
+		}
+		if len(c.tags.members) != 0 || len(c.tags.tags) != 0 {
+			t.Errorf("tags = %v, want empty", c.tags.members)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+// Package cacheset
+//
+// Path: text.go
+//
+// Description: text.go contains the serialization of the elements implementing encoding.TextMarshaler,
+// in the snapshots and in JSON.
+package cacheset
+
+import (
+	"context"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// textElements returns true if the elements are written in their text form: T implements
+// encoding.TextMarshaler and *T encoding.TextUnmarshaler, and T has no binary form used by gob
+func textElements[T comparable]() bool {
+	var elem T
+	switch any(elem).(type) {
+	case gob.GobEncoder, encoding.BinaryMarshaler:
+		return false
+	case encoding.TextMarshaler:
+		_, ok := any(&elem).(encoding.TextUnmarshaler)
+		return ok
+	}
+	return false
+}
+
+// textRecord is a snapshotRecord whose element is written in its text form
+type textRecord struct {
+	Text       []byte // Text is the text form of the element
+	Expires    int64  // Expires is the expiration time in nanoseconds
+	MaxIdle    int64  // MaxIdle is the maximum idle duration in nanoseconds, 0 meaning no limit
+	LastAccess int64  // LastAccess is the time of the last access in nanoseconds
+	Deleted    bool   // Deleted is true if the element was removed, in a delta
+}
+
+// encodeRecord writes the record, with the text form of its element if text is true
+func encodeRecord[T comparable](enc *gob.Encoder, record snapshotRecord[T], text bool) error {
+	if !text {
+		return enc.Encode(&record)
+	}
+	b, err := any(record.Elem).(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return err
+	}
+	return enc.Encode(&textRecord{
+		Text:       b,
+		Expires:    record.Expires,
+		MaxIdle:    record.MaxIdle,
+		LastAccess: record.LastAccess,
+		Deleted:    record.Deleted,
+	})
+}
+
+// decodeRecord reads a record, with the text form of its element if text is true
+func decodeRecord[T comparable](dec *gob.Decoder, text bool) (snapshotRecord[T], error) {
+	var record snapshotRecord[T]
+	if !text {
+		err := dec.Decode(&record)
+		return record, err
+	}
+	u, ok := any(&record.Elem).(encoding.TextUnmarshaler)
+	if !ok {
+		return record, fmt.Errorf("%w: %T has no text form", ErrInvalidSnapshot, record.Elem)
+	}
+	var tr textRecord
+	if err := dec.Decode(&tr); err != nil {
+		return record, err
+	}
+	if err := u.UnmarshalText(tr.Text); err != nil {
+		return record, err
+	}
+	record.Expires, record.MaxIdle, record.LastAccess, record.Deleted = tr.Expires, tr.MaxIdle, tr.LastAccess, tr.Deleted
+	return record, nil
+}
+
+// MarshalJSON returns the unexpired elements of the cache as a JSON object mapping each element
+// to its expiration time, null meaning never
+//
+// Description: The elements are the keys of the object, so T must be a string, an integer, or
+// implement encoding.TextMarshaler.
+func (c *Cache[T]) MarshalJSON() ([]byte, error) {
+	c.RLock()
+	now := nanotime()
+	elems := make(map[T]*time.Time, c.set.Len())
+	for elem, e := range c.set.All() {
+		if e.expired(now) {
+			continue
+		}
+		var expires *time.Time
+		if deadline := e.deadline(); deadline != 0 {
+			t := toTime(deadline).Round(0)
+			expires = &t
+		}
+		elems[elem] = expires
+	}
+	c.RUnlock()
+
+	return json.Marshal(elems)
+}
+
+// UnmarshalJSON adds the unexpired elements of a JSON object written by MarshalJSON to the cache
+//
+// Description: The elements are added like with Add. The elements that have already expired, and the
+// elements rejected because the cache is full, are skipped.
+func (c *Cache[T]) UnmarshalJSON(data []byte) error {
+	var elems map[T]*time.Time
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return err
+	}
+
+	seed := func(yield func(elem T, ttl time.Duration) bool) error {
+		for elem, expires := range elems {
+			var ttl time.Duration
+			if expires != nil {
+				if ttl = time.Until(*expires); ttl <= 0 {
+					continue
+				}
+			}
+			if !yield(elem, ttl) {
+				break
+			}
+		}
+		return nil
+	}
+	return c.Warm(context.Background(), seed)
+}
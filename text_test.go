@@ -0,0 +1,103 @@
+package cacheset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// coord is an element with unexported fields, serialized in its text form
+type coord struct {
+	x, y int
+}
+
+func (p coord) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "%d,%d", p.x, p.y), nil
+}
+
+func (p *coord) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d,%d", &p.x, &p.y)
+	return err
+}
+
+func Test_textElements(t *testing.T) {
+	if !textElements[coord]() {
+		t.Errorf("textElements[coord]() = false, want true")
+	}
+	if textElements[string]() {
+		t.Errorf("textElements[string]() = true, want false")
+	}
+	if textElements[time.Time]() {
+		t.Errorf("textElements[time.Time]() = true, want false for a gob encoder")
+	}
+}
+
+func TestCache_Snapshot_text(t *testing.T) {
+	c := New[coord](time.Minute)
+	defer c.Close()
+	c.Add(coord{1, 2}, time.Hour)
+	c.Add(coord{-3, 4}, 0)
+
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf, SnapshotAbsolute); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	restored := New[coord](time.Minute)
+	defer restored.Close()
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	for _, p := range []coord{{1, 2}, {-3, 4}} {
+		if !restored.Contains(p) {
+			t.Errorf("Restore() lost %v", p)
+		}
+	}
+	if got, want := expiration(restored, coord{1, 2}), expiration(c, coord{1, 2}); (got-want) > int64(time.Second) || (want-got) > int64(time.Second) {
+		t.Errorf("Restore() expiration = %v, want %v", got, want)
+	}
+}
+
+func TestCache_MarshalJSON(t *testing.T) {
+	c := New[coord](time.Minute)
+	defer c.Close()
+	c.Add(coord{1, 2}, time.Hour)
+	c.Add(coord{3, 4}, 0)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	var raw map[string]*time.Time
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["1,2"] == nil || raw["3,4"] != nil || len(raw) != 2 {
+		t.Errorf("MarshalJSON() = %s, want the text forms mapped to their expirations", data)
+	}
+
+	restored := New[coord](time.Minute)
+	defer restored.Close()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	got := restored.ToSlice()
+	slices.SortFunc(got, func(a, b coord) int { return a.x - b.x })
+	if want := []coord{{1, 2}, {3, 4}}; !slices.Equal(got, want) {
+		t.Errorf("UnmarshalJSON() = %v, want %v", got, want)
+	}
+}
+
+func TestCache_UnmarshalJSON_expired(t *testing.T) {
+	c := New[int](time.Minute)
+	defer c.Close()
+
+	if err := c.UnmarshalJSON([]byte(`{"1": null, "2": "2000-01-01T00:00:00Z"}`)); err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	if got, want := c.ToSlice(), []int{1}; !slices.Equal(got, want) {
+		t.Errorf("UnmarshalJSON() = %v, want %v", got, want)
+	}
+}
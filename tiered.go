@@ -0,0 +1,304 @@
+// Package cacheset
+//
+// Path: tiered.go
+//
+// Description: tiered.go contains the tiered set, which keeps its hottest elements in a cache and spills
+// the colder ones to a slower storage such as a disk, promoting them back on access.
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ColdTier is the storage of the elements spilled by a Tiered set, such as the disk-backed set of
+// cachesetbolt
+type ColdTier[T comparable] interface {
+	CacheSet[T]
+	// Expiration returns the expiration time of the given element, the zero time.Time meaning never, and
+	// false if it is not in the storage or has expired
+	Expiration(elem T) (time.Time, bool)
+}
+
+// Tiered is a set keeping its hottest elements in a cache, the hot tier, and the others in a ColdTier
+//
+// Description: The hot tier is a Cache with a capacity: the elements it evicts are spilled to the cold tier
+// with their remaining time to live, and an element found by Contains in the cold tier is promoted back to
+// the hot tier. With the default EvictLRU, the hot tier keeps the most recently used elements, which suits
+// the large but skewed workloads, whose hot elements fit in memory. The spills are written by the caller of
+// the Add or Contains that caused them, outside the lock of the hot tier, and the moves of each element
+// between the tiers are serialized, so that a spill never overwrites a promotion. Close spills the whole hot tier,
+// so that a persistent cold tier keeps all elements across restarts.
+type Tiered[T comparable] struct {
+	hot      *Cache[T]      // hot is the hot tier
+	cold     ColdTier[T]    // cold is the cold tier
+	locks    *KeyedMutex[T] // locks serialize the moves of each element between the tiers
+	mu       sync.Mutex     // mu protects spilling
+	spilling map[T]int64    // spilling are the elements evicted by the hot tier and not written to the cold tier yet, with their deadline
+	hits     atomic.Uint64  // hits is the number of Contains calls that found the element in a tier
+	misses   atomic.Uint64  // misses is the number of Contains calls that did not find the element
+	once     sync.Once      // once ensures that the tiers are closed once
+	closeErr error          // closeErr is the error of the first Shutdown
+}
+
+// NewTiered creates a set keeping up to hot elements in a cache, whose options are given, and spilling
+// the others to cold
+//
+// Description: The hot tier is evicted with EvictLRU unless another policy is given. The Tiered set owns
+// cold and closes it with itself.
+func NewTiered[T comparable](hot int, cold ColdTier[T], cleanInterval time.Duration, opts ...Option) *Tiered[T] {
+	opts = append([]Option{WithEvictionPolicy(EvictLRU)}, opts...)
+	t := &Tiered[T]{
+		hot:      New[T](cleanInterval, append(opts, WithCapacity(max(hot, 1)))...),
+		cold:     cold,
+		locks:    NewKeyedMutex[T](time.Minute),
+		spilling: make(map[T]int64),
+	}
+	t.hot.Lock()
+	t.hot.spill = t.evicted
+	t.hot.Unlock()
+	return t
+}
+
+// Hot returns the hot tier
+func (t *Tiered[T]) Hot() *Cache[T] {
+	return t.hot
+}
+
+// Add adds the given element to the hot tier for the given duration, 0 meaning no expiration, spilling the
+// element it evicts to the cold tier
+func (t *Tiered[T]) Add(elem T, ttl time.Duration) error {
+	if err := t.add(elem, ttl); err != nil {
+		return err
+	}
+	return t.spill()
+}
+
+// add adds the given element to the hot tier and removes it from the cold tier
+func (t *Tiered[T]) add(elem T, ttl time.Duration) error {
+	t.locks.Lock(elem)
+	defer t.locks.Unlock(elem)
+
+	if err := t.hot.Add(elem, ttl); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	delete(t.spilling, elem)
+	t.mu.Unlock()
+	if _, ok := t.cold.Expiration(elem); ok {
+		t.cold.Delete(elem)
+	}
+	return nil
+}
+
+// Contains returns true if the given element is in a tier, and promotes it to the hot tier if it was in
+// the cold tier
+func (t *Tiered[T]) Contains(elem T) bool {
+	found := t.contains(elem)
+	if found {
+		t.hits.Add(1)
+	} else {
+		t.misses.Add(1)
+	}
+	return found
+}
+
+// contains returns true if the given element is in a tier, promoting it from the cold tier
+func (t *Tiered[T]) contains(elem T) bool {
+	if _, ok := t.hot.Lookup(elem); ok {
+		return true
+	}
+	found, promoted := t.promote(elem)
+	if promoted {
+		if err := t.spill(); err != nil {
+			t.hot.report(err)
+		}
+	}
+	return found
+}
+
+// promote moves the given element from the cold tier to the hot tier, and returns whether it is in a tier
+// and whether it was moved
+func (t *Tiered[T]) promote(elem T) (found, promoted bool) {
+	t.locks.Lock(elem)
+	defer t.locks.Unlock(elem)
+
+	if _, ok := t.hot.Lookup(elem); ok {
+		return true, false
+	}
+	t.mu.Lock()
+	deadline, ok := t.spilling[elem]
+	t.mu.Unlock()
+	if ok {
+		return deadline == 0 || deadline > nanotime(), false
+	}
+
+	expires, ok := t.cold.Expiration(elem)
+	if !ok {
+		return false, false
+	}
+	var ttl time.Duration
+	if !expires.IsZero() {
+		if ttl = time.Until(expires); ttl <= 0 {
+			return false, false
+		}
+	}
+	if err := t.hot.Add(elem, ttl); err != nil {
+		return true, false
+	}
+	t.cold.Delete(elem)
+	return true, true
+}
+
+// Delete removes the given element from both tiers
+func (t *Tiered[T]) Delete(elem T) {
+	t.locks.Lock(elem)
+	defer t.locks.Unlock(elem)
+
+	t.hot.Delete(elem)
+	t.mu.Lock()
+	delete(t.spilling, elem)
+	t.mu.Unlock()
+	t.cold.Delete(elem)
+}
+
+// Clear removes all elements from both tiers
+func (t *Tiered[T]) Clear() {
+	t.hot.Clear()
+	t.mu.Lock()
+	clear(t.spilling)
+	t.mu.Unlock()
+	t.cold.Clear()
+}
+
+// Len returns the number of elements of both tiers
+//
+// Description: An element being spilled may be missed or counted twice while the Add or Contains causing
+// the spill has not returned yet.
+func (t *Tiered[T]) Len() int {
+	return t.hot.Len() + t.cold.Len()
+}
+
+// ToSlice returns the elements of both tiers
+func (t *Tiered[T]) ToSlice() []T {
+	seen := make(map[T]struct{})
+	var elems []T
+	t.mu.Lock()
+	spilling := make([]T, 0, len(t.spilling))
+	for elem := range t.spilling {
+		spilling = append(spilling, elem)
+	}
+	t.mu.Unlock()
+	for _, tier := range [][]T{t.hot.ToSlice(), spilling, t.cold.ToSlice()} {
+		for _, elem := range tier {
+			if _, ok := seen[elem]; !ok {
+				seen[elem] = struct{}{}
+				elems = append(elems, elem)
+			}
+		}
+	}
+	return elems
+}
+
+// ExpireAll removes the expired elements of both tiers
+func (t *Tiered[T]) ExpireAll() {
+	t.hot.ExpireAll()
+	t.cold.ExpireAll()
+}
+
+// Stats returns the counters of the hot tier, with the number of elements of both tiers, the hits and
+// misses of Contains, and the expirations of both tiers
+//
+// Description: The evictions are the spills of the hot tier.
+func (t *Tiered[T]) Stats() Stats {
+	st := t.hot.Stats()
+	cold := t.cold.Stats()
+	st.Len += cold.Len
+	st.Hits, st.Misses = t.hits.Load(), t.misses.Load()
+	st.Expirations += cold.Expirations
+	return st
+}
+
+// Close spills the hot tier to the cold tier and closes both tiers
+func (t *Tiered[T]) Close() {
+	if err := t.Shutdown(context.Background()); err != nil {
+		t.hot.report(err)
+	}
+}
+
+// Shutdown spills the hot tier to the cold tier, then gracefully closes both tiers before ctx is done
+func (t *Tiered[T]) Shutdown(ctx context.Context) error {
+	t.once.Do(func() {
+		t.hot.Lock()
+		now := nanotime()
+		for elem, e := range t.hot.set.All() {
+			if !e.expired(now) {
+				t.evicted(elem, e.deadline())
+			}
+		}
+		t.hot.Unlock()
+
+		t.closeErr = errors.Join(t.spill(), t.hot.Shutdown(ctx), t.cold.Shutdown(ctx))
+		t.locks.Close()
+	})
+	return t.closeErr
+}
+
+// evicted records an element evicted by the hot tier, with its deadline, the hot tier must be locked
+func (t *Tiered[T]) evicted(elem T, deadline int64) {
+	t.mu.Lock()
+	t.spilling[elem] = deadline
+	t.mu.Unlock()
+}
+
+// spill writes the elements evicted by the hot tier to the cold tier, skipping the expired ones
+func (t *Tiered[T]) spill() error {
+	t.mu.Lock()
+	if len(t.spilling) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	batch := make([]T, 0, len(t.spilling))
+	for elem := range t.spilling {
+		batch = append(batch, elem)
+	}
+	t.mu.Unlock()
+
+	var errs []error
+	for _, elem := range batch {
+		if err := t.spillOne(elem); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// spillOne writes the given element to the cold tier, unless it was spilled, added or deleted meanwhile
+func (t *Tiered[T]) spillOne(elem T) error {
+	t.locks.Lock(elem)
+	defer t.locks.Unlock(elem)
+
+	t.mu.Lock()
+	deadline, ok := t.spilling[elem]
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	var err error
+	switch now := nanotime(); {
+	case deadline == 0:
+		err = t.cold.Add(elem, 0)
+	case deadline > now:
+		err = t.cold.Add(elem, time.Duration(deadline-now))
+	}
+	t.mu.Lock()
+	if d, ok := t.spilling[elem]; ok && d == deadline {
+		delete(t.spilling, elem)
+	}
+	t.mu.Unlock()
+	return err
+}
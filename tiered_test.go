@@ -0,0 +1,85 @@
+package cacheset
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// memoryTier is a ColdTier keeping the spilled elements in a cache
+type memoryTier[T comparable] struct {
+	*Cache[T]
+}
+
+// Expiration returns the expiration time of the given element
+func (m memoryTier[T]) Expiration(elem T) (time.Time, bool) {
+	entry, ok := m.Lookup(elem)
+	return entry.ExpiresAt, ok
+}
+
+func TestTiered(t *testing.T) {
+	cold := memoryTier[int]{New[int](time.Minute)}
+	tiered := NewTiered[int](2, cold, time.Minute)
+	defer tiered.Close()
+
+	_ = tiered.Add(1, time.Hour)
+	_ = tiered.Add(2, 0)
+	_ = tiered.Add(3, time.Hour)
+
+	t.Run("Spill", func(t *testing.T) {
+		if got := tiered.Hot().Len(); got != 2 {
+			t.Errorf("Hot().Len() = %v, want %v", got, 2)
+		}
+		if expires, ok := cold.Expiration(1); !ok || time.Until(expires) < 59*time.Minute {
+			t.Errorf("Expiration() = %v, %v in the cold tier, want the remaining time to live", expires, ok)
+		}
+		if got := tiered.Len(); got != 3 {
+			t.Errorf("Len() = %v, want %v", got, 3)
+		}
+	})
+
+	t.Run("Promote", func(t *testing.T) {
+		if !tiered.Contains(1) {
+			t.Fatalf("Contains() = false for a spilled element, want true")
+		}
+		if _, ok := tiered.Hot().Lookup(1); !ok || cold.Contains(1) {
+			t.Errorf("Contains() did not promote the element to the hot tier")
+		}
+		if !cold.Contains(2) {
+			t.Errorf("Contains() did not spill the least recently used element %v", 2)
+		}
+		if got := tiered.ToSlice(); len(got) != 3 || !slices.Contains(got, 2) {
+			t.Errorf("ToSlice() = %v, want %v elements", got, 3)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		tiered.Delete(2)
+		if tiered.Contains(2) || tiered.Len() != 2 {
+			t.Errorf("Contains() = true after Delete, want false")
+		}
+	})
+}
+
+func TestTiered_Close(t *testing.T) {
+	cold := memoryTier[int]{New[int](time.Minute)}
+	defer cold.Close()
+
+	tiered := NewTiered[int](4, keepOpen[int]{cold}, time.Minute)
+	_ = tiered.Add(1, time.Hour)
+	_ = tiered.Add(2, 0)
+	tiered.Close()
+
+	if !cold.Contains(1) || !cold.Contains(2) {
+		t.Errorf("ToSlice() = %v in the cold tier after Close, want the hot elements spilled", cold.ToSlice())
+	}
+}
+
+// keepOpen is a ColdTier ignoring Shutdown, so that its content can be checked after the Tiered set is closed
+type keepOpen[T comparable] struct {
+	memoryTier[T]
+}
+
+// Shutdown does nothing
+func (keepOpen[T]) Shutdown(context.Context) error { return nil }
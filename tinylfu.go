@@ -0,0 +1,181 @@
+// Package cacheset
+//
+// Path: tinylfu.go
+//
+// Description: tinylfu.go contains the TinyLFU admission filter of capacity-limited caches.
+package cacheset
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+// tinyLFU is an admission filter estimating the access frequency of elements
+//
+// Description: The first access to an element is recorded in the doorkeeper bloom filter,
+// the following ones in the count-min sketch, so one-hit wonders never pollute the sketch.
+// Both are reset periodically so that the estimations follow the recent accesses.
+type tinyLFU[T comparable] struct {
+	sketch     countMinSketch // sketch counts the accesses to the elements that passed the doorkeeper
+	doorkeeper bloomFilter    // doorkeeper records the elements accessed at least once
+	seed       maphash.Seed   // seed is the seed of the elements' hashes
+	accesses   int            // accesses is the number of accesses recorded since the last reset
+	resetAt    int            // resetAt is the number of accesses after which the filter is reset
+}
+
+// minSketchWidth is the minimum number of counters per row of the count-min sketch,
+// so that small caches do not suffer from hash collisions and too frequent resets
+const minSketchWidth = 256
+
+// newTinyLFU returns a new TinyLFU admission filter sized for the given capacity
+func newTinyLFU[T comparable](capacity int) *tinyLFU[T] {
+	size := max(capacity, minSketchWidth)
+	return &tinyLFU[T]{
+		sketch:     newCountMinSketch(size),
+		doorkeeper: newBloomFilter(size),
+		seed:       maphash.MakeSeed(),
+		resetAt:    10 * size,
+	}
+}
+
+// record records an access to the given element
+func (t *tinyLFU[T]) record(elem T) {
+	h := maphash.Comparable(t.seed, elem)
+	if t.doorkeeper.add(h) {
+		t.sketch.increment(h)
+	}
+
+	t.accesses++
+	if t.accesses >= t.resetAt {
+		t.accesses = 0
+		t.sketch.halve()
+		t.doorkeeper.clear()
+	}
+}
+
+// estimate returns the estimated access frequency of the given element
+func (t *tinyLFU[T]) estimate(elem T) int {
+	h := maphash.Comparable(t.seed, elem)
+	n := t.sketch.estimate(h)
+	if t.doorkeeper.contains(h) {
+		n++
+	}
+	return n
+}
+
+// admit returns true if the candidate is likely more valuable than the victim
+func (t *tinyLFU[T]) admit(candidate, victim T) bool {
+	return t.estimate(candidate) > t.estimate(victim)
+}
+
+// sketchDepth is the number of rows of the count-min sketch
+const sketchDepth = 4
+
+// countMinSketch is a count-min sketch of saturating 8-bit counters
+type countMinSketch struct {
+	rows [sketchDepth][]uint8
+	mask uint64
+}
+
+// newCountMinSketch returns a count-min sketch with a width of at least the given number of counters per row
+func newCountMinSketch(width int) countMinSketch {
+	size := nextPowerOfTwo(width)
+	var s countMinSketch
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, size)
+	}
+	s.mask = uint64(size - 1)
+	return s
+}
+
+// index returns the index of the counter of the given hash in the given row
+func (s *countMinSketch) index(h uint64, row int) uint64 {
+	return (h + uint64(row)*(h>>32|1)) & s.mask
+}
+
+// increment increments the counters of the given hash
+func (s *countMinSketch) increment(h uint64) {
+	for i := range s.rows {
+		if idx := s.index(h, i); s.rows[i][idx] < 255 {
+			s.rows[i][idx]++
+		}
+	}
+}
+
+// estimate returns the smallest counter of the given hash
+func (s *countMinSketch) estimate(h uint64) int {
+	n := 255
+	for i := range s.rows {
+		n = min(n, int(s.rows[i][s.index(h, i)]))
+	}
+	return n
+}
+
+// halve divides all counters by two
+func (s *countMinSketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+}
+
+// bloomHashes is the number of bits set per element in the bloom filter
+const bloomHashes = 3
+
+// bloomFilter is a bloom filter of hashes
+type bloomFilter struct {
+	bits []uint64
+	mask uint64
+}
+
+// newBloomFilter returns a bloom filter sized for the given number of elements
+func newBloomFilter(n int) bloomFilter {
+	size := nextPowerOfTwo(8 * n)
+	return bloomFilter{
+		bits: make([]uint64, (size+63)/64),
+		mask: uint64(size - 1),
+	}
+}
+
+// position returns the position of the i-th bit of the given hash
+func (b *bloomFilter) position(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & b.mask
+}
+
+// add sets the bits of the given hash and returns true if they were all already set
+func (b *bloomFilter) add(h uint64) bool {
+	present := true
+	for i := 0; i < bloomHashes; i++ {
+		pos := b.position(h, i)
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			present = false
+			b.bits[pos/64] |= 1 << (pos % 64)
+		}
+	}
+	return present
+}
+
+// contains returns true if all bits of the given hash are set
+func (b *bloomFilter) contains(h uint64) bool {
+	for i := 0; i < bloomHashes; i++ {
+		pos := b.position(h, i)
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// clear unsets all bits
+func (b *bloomFilter) clear() {
+	clear(b.bits)
+}
+
+// nextPowerOfTwo returns the smallest power of two greater than or equal to n, and at least 64
+func nextPowerOfTwo(n int) int {
+	if n <= 64 {
+		return 64
+	}
+	return 1 << bits.Len(uint(n-1))
+}
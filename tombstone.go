@@ -0,0 +1,76 @@
+// Package cacheset
+//
+// Path: tombstone.go
+//
+// Description: tombstone.go contains the tombstones left by the deleted elements, which reject the additions
+// arriving late from other nodes.
+package cacheset
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTombstoned is returned by Add when the element was deleted less than the tombstone window ago
+var ErrTombstoned = errors.New("cacheset: element recently deleted")
+
+// WithTombstones leaves a tombstone for window when an element is deleted, rejecting its additions meanwhile
+//
+// Description: In a replicated setup, an addition sent by another node before a deletion may arrive after
+// it and resurrect the element. While the tombstone of an element lives, WasRecentlyDeleted returns true,
+// and Add and the other additions return ErrTombstoned while Merge skips it. Only the deletions leave a
+// tombstone, see RemovalDeleted, not the expirations, the evictions nor Clear. The tombstones are removed
+// by the cleanings once their window elapsed, and ForgetTombstone removes one at once.
+func WithTombstones(window time.Duration) Option {
+	return func(o *options) {
+		o.tombstoneWindow = max(window, 0)
+	}
+}
+
+// WasRecentlyDeleted returns true if the given element was deleted less than the tombstone window ago
+func (c *Cache[T]) WasRecentlyDeleted(elem T) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.tombstoned(elem)
+}
+
+// ForgetTombstone removes the tombstone of the given element, so that it can be added again at once
+func (c *Cache[T]) ForgetTombstone(elem T) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.tombstones, elem)
+}
+
+// bury leaves a tombstone for the given deleted element, the cache must be locked
+func (c *Cache[T]) bury(elem T) {
+	if c.options.tombstoneWindow == 0 {
+		return
+	}
+	if c.tombstones == nil {
+		c.tombstones = make(map[T]int64)
+	}
+	c.tombstones[elem] = nanotime() + int64(c.options.tombstoneWindow)
+}
+
+// tombstoned returns true if the given element has a live tombstone, the cache must be locked for reading
+func (c *Cache[T]) tombstoned(elem T) bool {
+	deadline, ok := c.tombstones[elem]
+	return ok && nanotime() < deadline
+}
+
+// sweepTombstones removes the tombstones whose window elapsed, the cache must be locked
+func (c *Cache[T]) sweepTombstones() {
+	now := nanotime()
+	for elem, deadline := range c.tombstones {
+		if now >= deadline {
+			delete(c.tombstones, elem)
+		}
+	}
+}
+
+// WasRecentlyDeleted returns true if the given element was deleted less than the tombstone window ago
+func (s *Sharded[T]) WasRecentlyDeleted(elem T) bool {
+	return s.shard(elem).WasRecentlyDeleted(elem)
+}
@@ -0,0 +1,65 @@
+package cacheset
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_Tombstones(t *testing.T) {
+	c := New[int](time.Minute, WithTombstones(20*time.Millisecond))
+	defer c.Close()
+
+	_ = c.Add(1, 0)
+	_ = c.Add(2, 0)
+	c.Delete(1)
+
+	t.Run("Rejected", func(t *testing.T) {
+		if !c.WasRecentlyDeleted(1) || c.WasRecentlyDeleted(2) {
+			t.Errorf("WasRecentlyDeleted() = %v, want %v", c.WasRecentlyDeleted(1), true)
+		}
+		if err := c.Add(1, 0); !errors.Is(err, ErrTombstoned) {
+			t.Errorf("Add() error = %v, want %v", err, ErrTombstoned)
+		}
+
+		other := New[int](time.Minute)
+		defer other.Close()
+		_ = other.Add(1, 0)
+		_ = other.Add(3, 0)
+		c.Merge(other, nil)
+		if c.Contains(1) || !c.Contains(3) {
+			t.Errorf("Merge() = %v, want the tombstoned element skipped", c.ToSlice())
+		}
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		time.Sleep(30 * time.Millisecond)
+		if c.WasRecentlyDeleted(1) {
+			t.Errorf("WasRecentlyDeleted() = true after the window, want false")
+		}
+		c.ExpireAll()
+		if len(c.tombstones) != 0 {
+			t.Errorf("tombstones = %v, want none after a cleaning", c.tombstones)
+		}
+		if err := c.Add(1, 0); err != nil {
+			t.Errorf("Add() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		c.Delete(2)
+		c.ForgetTombstone(2)
+		if err := c.Add(2, 0); err != nil {
+			t.Errorf("Add() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		_ = c.Add(4, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		c.ExpireAll()
+		if c.WasRecentlyDeleted(4) {
+			t.Errorf("WasRecentlyDeleted() = true for an expired element, want false")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+// Package cacheset
+//
+// Path: ttlpolicy.go
+//
+// Description: ttlpolicy.go contains the TTL policies, which choose the default TTL of each element from
+// rules registered with the cache rather than at the call sites.
+package cacheset
+
+import "time"
+
+// TTLRule is how the durations returned by several TTL policies are combined
+type TTLRule int
+
+const (
+	// TTLFirstMatch uses the duration of the first policy returning one, in registration order
+	TTLFirstMatch TTLRule = iota
+	// TTLMin uses the shortest of the durations returned by the policies
+	TTLMin
+	// TTLMax uses the longest of the durations returned by the policies
+	TTLMax
+)
+
+// WithTTLPolicy registers a policy returning the default TTL of an element, 0 if it does not apply to it
+//
+// Description: The policies are evaluated by AddDefault, and by GetOrLoad for the loaded elements, in
+// registration order. Their durations are combined by the rule set with WithTTLRule, TTLFirstMatch by
+// default. When no policy applies, the element falls back to the TTL of its namespace, see WithNamespaceTTL,
+// or to WithDefaultTTL. A policy is called with the cache locked and must not call the cache's methods.
+// Its element type must match the cache's element type.
+func WithTTLPolicy[T comparable](policy func(elem T) time.Duration) Option {
+	return func(o *options) {
+		o.ttlPolicies = append(o.ttlPolicies[:len(o.ttlPolicies):len(o.ttlPolicies)], policy)
+	}
+}
+
+// WithTTLRule sets how the durations of the policies registered with WithTTLPolicy are combined
+func WithTTLRule(rule TTLRule) Option {
+	return func(o *options) {
+		o.ttlRule = rule
+	}
+}
+
+// newTTLPolicy returns the composition of the given policies by the given rule, which returns false if
+// no policy applies to the element
+func newTTLPolicy[T comparable](policies []func(T) time.Duration, rule TTLRule) func(T) (time.Duration, bool) {
+	return func(elem T) (time.Duration, bool) {
+		var ttl time.Duration
+		for _, policy := range policies {
+			d := policy(elem)
+			if d <= 0 {
+				continue
+			}
+			switch {
+			case rule == TTLFirstMatch:
+				return d, true
+			case ttl == 0, rule == TTLMin && d < ttl, rule == TTLMax && d > ttl:
+				ttl = d
+			}
+		}
+		return ttl, ttl > 0
+	}
+}
@@ -0,0 +1,49 @@
+package cacheset
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_TTLPolicy(t *testing.T) {
+	premium := func(elem string) time.Duration {
+		if strings.HasPrefix(elem, "premium:") {
+			return time.Hour
+		}
+		return 0
+	}
+	guest := func(elem string) time.Duration {
+		if strings.HasSuffix(elem, ":guest") {
+			return time.Minute
+		}
+		return 0
+	}
+
+	tests := []struct {
+		name string
+		rule TTLRule
+		elem string
+		want time.Duration
+	}{
+		{"FirstMatch", TTLFirstMatch, "premium:1:guest", time.Hour},
+		{"Min", TTLMin, "premium:1:guest", time.Minute},
+		{"Max", TTLMax, "free:1:guest", time.Minute},
+		{"Fallback", TTLMax, "free:1", 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New[string](time.Minute, WithTTLPolicy(premium), WithTTLPolicy(guest), WithTTLRule(tt.rule),
+				WithDefaultTTL(10*time.Second))
+			defer c.Close()
+
+			if err := c.AddDefault(tt.elem); err != nil {
+				t.Fatalf("AddDefault() error = %v", err)
+			}
+			e, _ := c.Lookup(tt.elem)
+			if got := time.Until(e.ExpiresAt); got > tt.want || got < tt.want-time.Second {
+				t.Errorf("AddDefault() TTL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package cacheset
+//
+// Path: untildone.go
+//
+// Description: untildone.go contains the elements bound to a context, removed once the context is done.
+package cacheset
+
+import "context"
+
+// doneBatch is the number of elements of done contexts removed under a single lock
+const doneBatch = 256
+
+// doneBinding binds an element to a context
+type doneBinding struct {
+	stop func() bool // stop unregisters the callback of the context
+}
+
+// doneEvent reports that the context bound to an element is done
+type doneEvent[T comparable] struct {
+	elem    T            // elem is the element bound to the context
+	binding *doneBinding // binding is the binding of the element when its context was done
+}
+
+// AddUntilDone adds the given element to the cache until ctx is done, or at most for its default TTL
+//
+// Description: The element is removed with RemovalDeleted once ctx is done, which mirrors the lifetime of
+// an in-flight request in the cache. Its default TTL, see AddDefault, bounds the lifetime of the element
+// when ctx is never done, 0 meaning no bound. Adding the element again with AddUntilDone binds it to the new
+// context instead, while Add keeps the binding. The contexts are watched with context.AfterFunc, and a single
+// goroutine per cache removes the elements of the done contexts in batches. If ctx is already done, the
+// element is not added.
+func (c *Cache[T]) AddUntilDone(ctx context.Context, elem T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	ttl := c.defaultTTL(elem)
+	if ok, err := c.accept(elem, ttl, 0); !ok {
+		return err
+	}
+	c.put(elem, ttl, 0)
+	c.stats.add(statAdds, 1)
+	c.recordHot(elem)
+	c.recordUnique(elem)
+
+	c.unbind(elem)
+	if c.bindings == nil {
+		c.bindings = make(map[T]*doneBinding)
+		c.doneEvents = make(chan doneEvent[T], doneBatch)
+		go c.watchDone(c.doneEvents, c.close)
+	}
+	binding := &doneBinding{}
+	events, closed := c.doneEvents, c.close
+	binding.stop = context.AfterFunc(ctx, func() {
+		select {
+		case events <- doneEvent[T]{elem: elem, binding: binding}:
+		case <-closed:
+		}
+	})
+	c.bindings[elem] = binding
+
+	return nil
+}
+
+// watchDone removes the elements whose context is done until the cache is closed
+func (c *Cache[T]) watchDone(events <-chan doneEvent[T], closed <-chan struct{}) {
+	batch := make([]doneEvent[T], 0, doneBatch)
+	for {
+		select {
+		case <-closed:
+			return
+		case ev := <-events:
+			batch = append(batch[:0], ev)
+		}
+	drain:
+		for len(batch) < doneBatch {
+			select {
+			case ev := <-events:
+				batch = append(batch, ev)
+			default:
+				break drain
+			}
+		}
+
+		c.Lock()
+		for _, ev := range batch {
+			if c.bindings[ev.elem] != ev.binding {
+				continue
+			}
+			delete(c.bindings, ev.elem)
+			if c.set.Contains(ev.elem) {
+				c.remove(ev.elem, RemovalDeleted)
+			}
+		}
+		c.Unlock()
+	}
+}
+
+// unbind stops watching the context bound to the given element, the cache must be locked
+func (c *Cache[T]) unbind(elem T) {
+	if binding, ok := c.bindings[elem]; ok {
+		binding.stop()
+		delete(c.bindings, elem)
+	}
+}
+
+// unbindAll stops watching all contexts, the cache must be locked
+func (c *Cache[T]) unbindAll() {
+	for elem := range c.bindings {
+		c.unbind(elem)
+	}
+}
+
+// AddUntilDone adds the given element to its shard until ctx is done, see Cache.AddUntilDone
+func (s *Sharded[T]) AddUntilDone(ctx context.Context, elem T) error {
+	return s.shard(elem).AddUntilDone(ctx, elem)
+}
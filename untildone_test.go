@@ -0,0 +1,109 @@
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_AddUntilDone(t *testing.T) {
+	c := New[int](time.Minute, WithDefaultTTL(time.Hour))
+	defer c.Close()
+
+	waitGone := func(elem int) bool {
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if !c.Contains(elem) {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("Done", func(t *testing.T) {
+		ctxs := make([]context.CancelFunc, 100)
+		for i := range ctxs {
+			ctx, cancel := context.WithCancel(context.Background())
+			ctxs[i] = cancel
+			if err := c.AddUntilDone(ctx, i); err != nil {
+				t.Fatalf("AddUntilDone() error = %v", err)
+			}
+		}
+		if c.Len() != 100 {
+			t.Fatalf("Len() = %v, want %v", c.Len(), 100)
+		}
+		for _, cancel := range ctxs {
+			cancel()
+		}
+		for i := range ctxs {
+			if !waitGone(i) {
+				t.Fatalf("Contains(%v) = true after its context was done, want false", i)
+			}
+		}
+	})
+
+	t.Run("Rebound", func(t *testing.T) {
+		first, cancelFirst := context.WithCancel(context.Background())
+		second, cancelSecond := context.WithCancel(context.Background())
+		_ = c.AddUntilDone(first, 1)
+		_ = c.AddUntilDone(second, 1)
+		cancelFirst()
+		time.Sleep(10 * time.Millisecond)
+		if !c.Contains(1) {
+			t.Errorf("Contains() = false after the previous context was done, want true")
+		}
+		cancelSecond()
+		if !waitGone(1) {
+			t.Errorf("Contains() = true after the current context was done, want false")
+		}
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_ = c.AddUntilDone(ctx, 2)
+		c.Delete(2)
+		if len(c.bindings) != 0 {
+			t.Errorf("bindings = %v, want none after Delete", c.bindings)
+		}
+		cancel()
+	})
+
+	t.Run("AlreadyDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := c.AddUntilDone(ctx, 3); !errors.Is(err, context.Canceled) || c.Contains(3) {
+			t.Errorf("AddUntilDone() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		d := New[int](time.Minute)
+		defer d.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		_ = d.AddUntilDone(ctx, 1)
+		cancel()
+		d.Reset()
+
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		if err := d.AddUntilDone(ctx, 2); err != nil {
+			t.Fatalf("AddUntilDone() error = %v after Reset", err)
+		}
+		cancel()
+		for deadline := time.Now().Add(time.Second); d.Contains(2); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Contains() = true after its context was done following Reset, want false")
+			}
+		}
+	})
+
+	t.Run("MaxTTL", func(t *testing.T) {
+		d := New[int](time.Minute, WithDefaultTTL(time.Millisecond))
+		defer d.Close()
+		_ = d.AddUntilDone(context.Background(), 1)
+		time.Sleep(5 * time.Millisecond)
+		if _, ok := d.Lookup(1); ok {
+			t.Errorf("Lookup() ok = true after the default TTL, want false")
+		}
+	})
+}
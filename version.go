@@ -0,0 +1,91 @@
+// Package cacheset
+//
+// Path: version.go
+//
+// Description: version.go contains the versions of the elements, which let several subsystems update an
+// element with optimistic concurrency.
+package cacheset
+
+// WithVersions tracks a version of each element, changed by each of its additions
+//
+// Description: The versions are opaque: they increase with each addition of an element, are not reused by
+// the cache, and are never 0, which stands for an element that is not in the cache. A read-modify-write
+// reads the version with Version, decides, and adds the element with AddIfVersion, which fails if another
+// caller added or removed the element meanwhile.
+func WithVersions() Option {
+	return func(o *options) {
+		o.versions = true
+	}
+}
+
+// Version returns the version of the given element, false if it is not in the cache or has expired or
+// if the cache was created without WithVersions
+func (c *Cache[T]) Version(elem T) (uint64, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	v := c.version(elem)
+	return v, v != 0
+}
+
+// AddIfVersion adds the given element for its default TTL, see AddDefault, if its version is still the
+// expected one, and returns true if it was added
+//
+// Description: An expected version of 0 adds the element only if it is not in the cache. The comparison
+// and the addition happen under a single lock. AddIfVersion returns false if the cache was created without
+// WithVersions, or if the addition failed, as when the cache is full.
+func (c *Cache[T]) AddIfVersion(elem T, expectedVersion uint64) bool {
+	if !c.options.versions {
+		return false
+	}
+
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	if c.version(elem) != expectedVersion {
+		return false
+	}
+	ttl := c.defaultTTL(elem)
+	if ok, _ := c.accept(elem, ttl, 0); !ok {
+		return false
+	}
+
+	c.put(elem, ttl, 0)
+	c.stats.add(statAdds, 1)
+	c.recordHot(elem)
+	c.recordUnique(elem)
+
+	return true
+}
+
+// version returns the version of the given unexpired element, 0 if there is none, the cache must be locked
+// for reading
+func (c *Cache[T]) version(elem T) uint64 {
+	if e, ok := c.set.Get(elem); !ok || e.expired(nanotime()) {
+		return 0
+	}
+	return c.versions[elem]
+}
+
+// bump gives the given element a new version with WithVersions, the cache must be locked
+func (c *Cache[T]) bump(elem T) {
+	if !c.options.versions {
+		return
+	}
+	if c.versions == nil {
+		c.versions = make(map[T]uint64)
+	}
+	c.lastVersion++
+	c.versions[elem] = c.lastVersion
+}
+
+// Version returns the version of the given element, see Cache.Version
+func (s *Sharded[T]) Version(elem T) (uint64, bool) {
+	return s.shard(elem).Version(elem)
+}
+
+// AddIfVersion adds the given element if its version is still the expected one, see Cache.AddIfVersion
+func (s *Sharded[T]) AddIfVersion(elem T, expectedVersion uint64) bool {
+	return s.shard(elem).AddIfVersion(elem, expectedVersion)
+}
@@ -0,0 +1,80 @@
+package cacheset
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_AddIfVersion(t *testing.T) {
+	c := New[string](time.Minute, WithVersions())
+	defer c.Close()
+
+	t.Run("Missing", func(t *testing.T) {
+		if _, ok := c.Version("a"); ok {
+			t.Errorf("Version() ok = true for a missing element, want false")
+		}
+		if !c.AddIfVersion("a", 0) {
+			t.Errorf("AddIfVersion() = false for a missing element and version 0, want true")
+		}
+		if c.AddIfVersion("a", 0) {
+			t.Errorf("AddIfVersion() = true for a present element and version 0, want false")
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		v, ok := c.Version("a")
+		if !ok || v == 0 {
+			t.Fatalf("Version() = %v, %v, want a version", v, ok)
+		}
+		_ = c.Add("a", 0)
+		if c.AddIfVersion("a", v) {
+			t.Errorf("AddIfVersion() = true after a concurrent addition, want false")
+		}
+		v, _ = c.Version("a")
+		if !c.AddIfVersion("a", v) {
+			t.Errorf("AddIfVersion() = false with the current version, want true")
+		}
+		if w, _ := c.Version("a"); w <= v {
+			t.Errorf("Version() = %v after AddIfVersion, want more than %v", w, v)
+		}
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		v, _ := c.Version("a")
+		c.Delete("a")
+		_ = c.Add("a", 0)
+		if w, _ := c.Version("a"); w == v {
+			t.Errorf("Version() = %v after a removal, want a new version", w)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var won atomic.Int64
+		v, _ := c.Version("a")
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if c.AddIfVersion("a", v) {
+					won.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if won.Load() != 1 {
+			t.Errorf("AddIfVersion() succeeded %v times, want %v", won.Load(), 1)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		d := New[string](time.Minute)
+		defer d.Close()
+		_ = d.Add("a", 0)
+		if _, ok := d.Version("a"); ok || d.AddIfVersion("a", 0) {
+			t.Errorf("Version() ok = %v, want false without WithVersions", ok)
+		}
+	})
+}
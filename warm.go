@@ -0,0 +1,158 @@
+// Package cacheset
+//
+// Path: warm.go
+//
+// Description: warm.go contains the bulk loading of the cache from a seed source.
+package cacheset
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Seed produces the elements loaded by Warm, calling yield for each element until it returns false
+type Seed[T comparable] func(yield func(elem T, ttl time.Duration) bool) error
+
+// SeedSlice returns a Seed producing the given elements with the same time to live
+func SeedSlice[T comparable](elems []T, ttl time.Duration) Seed[T] {
+	return func(yield func(elem T, ttl time.Duration) bool) error {
+		for _, elem := range elems {
+			if !yield(elem, ttl) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// WarmOption configures Warm
+type WarmOption func(*warmConfig)
+
+// warmConfig is the configuration of Warm
+type warmConfig struct {
+	progress    func(loaded int) // progress is called with the number of loaded elements after each batch
+	batchSize   int              // batchSize is the number of elements added under a single lock
+	concurrency int              // concurrency is the number of goroutines adding the batches, 0 meaning the caller's
+}
+
+// WithWarmBatchSize sets the number of elements added under a single lock, 1000 by default
+func WithWarmBatchSize(size int) WarmOption {
+	return func(c *warmConfig) {
+		c.batchSize = max(size, 1)
+	}
+}
+
+// WithWarmConcurrency adds the batches from the given number of goroutines while the seed produces the next ones
+//
+// Description: By default, the batches are added by the calling goroutine between two calls of yield.
+func WithWarmConcurrency(n int) WarmOption {
+	return func(c *warmConfig) {
+		c.concurrency = max(n, 0)
+	}
+}
+
+// WithWarmProgress sets a function called with the total number of loaded elements after each batch
+//
+// Description: The calls are serialized, even with WithWarmConcurrency.
+func WithWarmProgress(progress func(loaded int)) WarmOption {
+	return func(c *warmConfig) {
+		c.progress = progress
+	}
+}
+
+// warmItem is an element produced by a Seed
+type warmItem[T comparable] struct {
+	elem T
+	ttl  time.Duration
+}
+
+// Warm bulk-loads the elements produced by seed into the cache
+//
+// Description: Elements are added in batches under a single lock, as if they were added with Add.
+// Elements rejected because the cache is full are skipped and not counted as loaded.
+// Warm stops when the seed returns, when it returns an error, or when ctx is done.
+func (c *Cache[T]) Warm(ctx context.Context, seed Seed[T], opts ...WarmOption) error {
+	cfg := warmConfig{batchSize: 1000}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var (
+		loaded     int
+		progressMu sync.Mutex
+		wg         sync.WaitGroup
+	)
+	apply := func(batch []warmItem[T]) {
+		n := c.addBatch(batch)
+
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		loaded += n
+		if cfg.progress != nil {
+			cfg.progress(loaded)
+		}
+	}
+
+	batches := make(chan []warmItem[T])
+	for i := 0; i < cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				apply(batch)
+			}
+		}()
+	}
+	flush := func(batch []warmItem[T]) {
+		if cfg.concurrency == 0 {
+			apply(batch)
+			return
+		}
+		batches <- batch
+	}
+
+	batch := make([]warmItem[T], 0, cfg.batchSize)
+	err := seed(func(elem T, ttl time.Duration) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		batch = append(batch, warmItem[T]{elem: elem, ttl: ttl})
+		if len(batch) == cfg.batchSize {
+			flush(batch)
+			batch = make([]warmItem[T], 0, cfg.batchSize)
+		}
+		return true
+	})
+	if len(batch) > 0 && ctx.Err() == nil {
+		flush(batch)
+	}
+
+	close(batches)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// addBatch adds the given elements under a single lock and returns the number of added elements
+func (c *Cache[T]) addBatch(batch []warmItem[T]) int {
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	var added int
+	for _, item := range batch {
+		if ok, _ := c.accept(item.elem, item.ttl, 0); !ok {
+			continue
+		}
+		c.put(item.elem, item.ttl, 0)
+		c.recordUnique(item.elem)
+		added++
+	}
+	c.stats.add(statAdds, uint64(added))
+
+	return added
+}
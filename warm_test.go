@@ -0,0 +1,63 @@
+package cacheset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_Warm(t *testing.T) {
+	elems := make([]int64, 2500)
+	for i := range elems {
+		elems[i] = int64(i)
+	}
+
+	for _, concurrency := range []int{0, 4} {
+		c := New[int64](time.Minute)
+
+		var calls, last int
+		err := c.Warm(context.Background(), SeedSlice(elems, time.Hour),
+			WithWarmBatchSize(1000),
+			WithWarmConcurrency(concurrency),
+			WithWarmProgress(func(loaded int) {
+				calls++
+				last = loaded
+			}),
+		)
+
+		t.Run("Warm", func(t *testing.T) {
+			if err != nil {
+				t.Fatalf("Warm() error = %v", err)
+			}
+			if got := c.Len(); got != len(elems) {
+				t.Errorf("Warm() Len = %v, want %v", got, len(elems))
+			}
+			if calls != 3 || last != len(elems) {
+				t.Errorf("Warm() progress = %v calls, last %v, want 3 calls, last %v", calls, last, len(elems))
+			}
+		})
+		c.Close()
+	}
+}
+
+func TestCache_Warm_Canceled(t *testing.T) {
+	c := New[int64](time.Minute)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := c.Warm(ctx, func(yield func(elem int64, ttl time.Duration) bool) error {
+		for i := int64(0); yield(i, 0); i++ {
+			if i == 10 {
+				cancel()
+			}
+		}
+		return nil
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Warm() error = %v, want %v", err, context.Canceled)
+		}
+	})
+}
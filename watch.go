@@ -0,0 +1,129 @@
+// Package cacheset
+//
+// Path: watch.go
+//
+// Description: watch.go contains the per-element removal notifications of the cache, and the waits for
+// the additions of elements.
+package cacheset
+
+import "context"
+
+// RemovalReason describes why an element was removed from the cache
+type RemovalReason int
+
+const (
+	// RemovalExpired means that the element's expiration time has passed
+	RemovalExpired RemovalReason = iota
+	// RemovalDeleted means that the element was removed with Delete or Clear
+	RemovalDeleted
+	// RemovalEvicted means that the element was evicted to make room for another one
+	RemovalEvicted
+)
+
+// String returns the name of the removal reason
+func (r RemovalReason) String() string {
+	switch r {
+	case RemovalExpired:
+		return "expired"
+	case RemovalDeleted:
+		return "deleted"
+	case RemovalEvicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+// RemovalEvent is sent to the watchers of an element when it is removed from the cache
+type RemovalEvent[T comparable] struct {
+	Elem          T             // Elem is the removed element
+	Reason        RemovalReason // Reason is the reason of the removal
+	EntryMetadata               // EntryMetadata describes the removed element, zero without WithEntryMetadata
+}
+
+// Watch returns a channel that receives a single RemovalEvent when the given element is removed from the cache
+//
+// Description: The channel is closed after the event is sent. Watchers of elements that are not in the cache
+// when the cleaning goroutine runs are dropped and their channel is closed without any event,
+// so watching an element that never existed does not leak. Closing the cache closes all channels.
+func (c *Cache[T]) Watch(elem T) <-chan RemovalEvent[T] {
+	c.Lock()
+	defer c.Unlock()
+
+	ch := make(chan RemovalEvent[T], 1)
+	if c.closed {
+		close(ch)
+		return ch
+	}
+	c.watchers[elem] = append(c.watchers[elem], ch)
+
+	return ch
+}
+
+// notify sends the given RemovalEvent to the watchers of its element and closes their channels
+func (c *Cache[T]) notify(ev RemovalEvent[T]) {
+	chans, ok := c.watchers[ev.Elem]
+	if !ok {
+		return
+	}
+	delete(c.watchers, ev.Elem)
+
+	for _, ch := range chans {
+		ch <- ev
+		close(ch)
+	}
+}
+
+// dropWatchers closes the channels of the watchers whose element is not in the cache
+func (c *Cache[T]) dropWatchers() {
+	for elem, chans := range c.watchers {
+		if c.set.Contains(elem) {
+			continue
+		}
+		delete(c.watchers, elem)
+		for _, ch := range chans {
+			close(ch)
+		}
+	}
+}
+
+// closeWatchers closes the channels of all watchers
+func (c *Cache[T]) closeWatchers() {
+	for elem, chans := range c.watchers {
+		delete(c.watchers, elem)
+		for _, ch := range chans {
+			close(ch)
+		}
+	}
+}
+
+// WaitFor blocks until the given element is in the cache, and returns the error of ctx if it is done first
+//
+// Description: WaitFor returns at once if the element is already in the cache and has not expired, and
+// otherwise waits for its EventAdded through a subscription of the cache, see SubscribeFunc. It returns
+// ErrClosed if the cache is closed first. An element added and removed again while the waiter is being
+// woken up still counts as having appeared.
+func (c *Cache[T]) WaitFor(ctx context.Context, elem T) error {
+	sub := c.SubscribeFunc(func(ev Event[T]) bool {
+		return ev.Kind == EventAdded && ev.Elem == elem
+	})
+	defer sub.Close()
+
+	if _, ok := c.Lookup(elem); ok {
+		return nil
+	}
+	select {
+	case _, ok := <-sub.Events():
+		if !ok {
+			return ErrClosed
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitFor blocks until the given element is in its shard, see Cache.WaitFor
+func (s *Sharded[T]) WaitFor(ctx context.Context, elem T) error {
+	return s.shard(elem).WaitFor(ctx, elem)
+}
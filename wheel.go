@@ -0,0 +1,213 @@
+// Package cacheset
+//
+// Path: wheel.go
+//
+// Description: wheel.go contains the hierarchical timing wheel, an index of the elements by expiration time.
+//
+// The wheel has 6 levels of 64 slots. A slot of level 0 spans a tick, the granularity of the wheel,
+// and a slot of level l spans 64 slots of level l-1. An element is put in the slot of the lowest level
+// spanning its expiration tick, and moved down a level each time the wheel reaches the start of its slot,
+// so that it is in level 0 during the 64 ticks before it expires. Adding and removing an element costs
+// a constant time, and a cleaning costs a time proportional to the number of expired and moved elements,
+// the empty slots being skipped with a bitmap per level.
+package cacheset
+
+import (
+	"math/bits"
+	"time"
+)
+
+// WithTimingWheel indexes the elements in a hierarchical timing wheel of the given granularity, so that
+// the cleanings only visit the expired elements, and takes precedence over WithExpirationBuckets
+//
+// Description: Unlike the expiration buckets, the wheel suits mixed times to live: the elements expiring
+// in the next 64 ticks are in slots of one tick, and the later ones in coarser slots. A granularity close
+// to the clean interval divided by 64 is a good start, the wheel spans 2^36 ticks, the elements expiring
+// later being kept aside until then. The elements with a maximum idle duration are still checked at each
+// cleaning.
+func WithTimingWheel(granularity time.Duration) Option {
+	return func(o *options) {
+		o.wheelTick = granularity
+	}
+}
+
+const (
+	wheelBits   = 6              // wheelBits is the number of bits of the slot numbers
+	wheelSlots  = 1 << wheelBits // wheelSlots is the number of slots of a level
+	wheelLevels = 6              // wheelLevels is the number of levels
+)
+
+// wheelPos is the position of an element in a wheel
+type wheelPos struct {
+	level int // level is the level of the slot, or wheelLate or wheelFar
+	slot  int // slot is the number of the slot in its level
+}
+
+const (
+	wheelLate = -1 // wheelLate is the level of the elements expiring before the next processed tick
+	wheelFar  = -2 // wheelFar is the level of the elements expiring after the span of the wheel
+)
+
+// wheel is an expiration index made of a hierarchical timing wheel
+type wheel[T comparable] struct {
+	tick     int64                                // tick is the granularity of the wheel in nanoseconds
+	next     int64                                // next is the number of the next tick to process
+	slots    [wheelLevels][wheelSlots]map[T]int64 // slots are the elements of each slot with their expiration tick
+	occupied [wheelLevels]uint64                  // occupied has a bit set for each non-empty slot of each level
+	aside    map[T]int64                          // aside are the elements at the wheelLate and wheelFar levels
+	pos      map[T]wheelPos                       // pos is the position of each indexed element
+}
+
+// newWheel returns an empty timing wheel of the given granularity
+func newWheel[T comparable](tick time.Duration) *wheel[T] {
+	w := &wheel[T]{
+		tick:  max(int64(tick), 1),
+		aside: make(map[T]int64),
+		pos:   make(map[T]wheelPos),
+	}
+	w.next = nanotime() / w.tick
+	return w
+}
+
+func (w *wheel[T]) add(elem T, expires int64) {
+	w.remove(elem)
+	w.place(elem, expires/w.tick)
+}
+
+// place puts the element expiring at the given tick in its slot
+func (w *wheel[T]) place(elem T, at int64) {
+	if at < w.next {
+		w.aside[elem] = at
+		w.pos[elem] = wheelPos{level: wheelLate}
+		return
+	}
+	delta := at - w.next
+	for l := 0; l < wheelLevels; l++ {
+		if delta < 1<<(wheelBits*(l+1)) {
+			s := int(at>>(wheelBits*l)) & (wheelSlots - 1)
+			if w.slots[l][s] == nil {
+				w.slots[l][s] = make(map[T]int64)
+			}
+			w.slots[l][s][elem] = at
+			w.occupied[l] |= 1 << s
+			w.pos[elem] = wheelPos{level: l, slot: s}
+			return
+		}
+	}
+	w.aside[elem] = at
+	w.pos[elem] = wheelPos{level: wheelFar}
+}
+
+func (w *wheel[T]) remove(elem T) {
+	p, ok := w.pos[elem]
+	if !ok {
+		return
+	}
+	delete(w.pos, elem)
+	if p.level < 0 {
+		delete(w.aside, elem)
+		return
+	}
+	slot := w.slots[p.level][p.slot]
+	delete(slot, elem)
+	if len(slot) == 0 {
+		w.occupied[p.level] &^= 1 << p.slot
+	}
+}
+
+// due processes the ticks that have entirely passed and returns their elements, with the late ones
+func (w *wheel[T]) due(now int64) []T {
+	current := now / w.tick
+
+	var due []T
+	for elem, at := range w.aside {
+		if w.pos[elem].level == wheelLate || at-w.next < 1<<(wheelBits*wheelLevels) {
+			delete(w.aside, elem)
+			delete(w.pos, elem)
+			if at < current {
+				due = append(due, elem)
+			} else {
+				w.place(elem, at)
+			}
+		}
+	}
+
+	for w.next < current {
+		// nothing happens before the start of the next slot of the lowest non-empty level
+		if l := w.lowest(); l != 0 {
+			span := int64(1) << (wheelBits * wheelLevels)
+			if l > 0 {
+				span = int64(1) << (wheelBits * l)
+			}
+			start := (w.next + span - 1) / span * span
+			if start >= current {
+				w.next = current
+				break
+			}
+			w.next = start
+		}
+
+		t := w.next
+		if t&(wheelSlots-1) == 0 {
+			for l := 1; l < wheelLevels; l++ {
+				s := int(t>>(wheelBits*l)) & (wheelSlots - 1)
+				w.cascade(l, s)
+				if s != 0 {
+					break
+				}
+			}
+		}
+		s := int(t) & (wheelSlots - 1)
+		for elem := range w.slots[0][s] {
+			due = append(due, elem)
+			delete(w.pos, elem)
+		}
+		w.slots[0][s] = nil
+		w.occupied[0] &^= 1 << s
+		w.next++
+	}
+	return due
+}
+
+// lowest returns the lowest level with a non-empty slot, or -1 if the wheel is empty
+func (w *wheel[T]) lowest() int {
+	for l := 0; l < wheelLevels; l++ {
+		if w.occupied[l] != 0 {
+			return l
+		}
+	}
+	return -1
+}
+
+// cascade moves the elements of the given slot to the lower levels
+func (w *wheel[T]) cascade(l, s int) {
+	slot := w.slots[l][s]
+	if len(slot) == 0 {
+		return
+	}
+	w.slots[l][s] = nil
+	w.occupied[l] &^= 1 << s
+	for elem, at := range slot {
+		w.place(elem, at)
+	}
+}
+
+func (w *wheel[T]) clear() {
+	w.slots = [wheelLevels][wheelSlots]map[T]int64{}
+	w.occupied = [wheelLevels]uint64{}
+	clear(w.aside)
+	clear(w.pos)
+}
+
+func (w *wheel[T]) empty() expiryIndex[T] {
+	return newWheel[T](time.Duration(w.tick))
+}
+
+// slotsInUse returns the number of non-empty slots, for the tests
+func (w *wheel[T]) slotsInUse() int {
+	var n int
+	for _, o := range w.occupied {
+		n += bits.OnesCount64(o)
+	}
+	return n
+}
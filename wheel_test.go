@@ -0,0 +1,56 @@
+package cacheset
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// Test_wheel_due compares the due elements of a wheel advanced by random steps with the expired elements
+func Test_wheel_due(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	w := newWheel[int](1)
+	w.next = 0
+	expires := make(map[int]int64)
+	now := int64(0)
+
+	for step := 0; step < 2000; step++ {
+		for i := 0; i < 20; i++ {
+			elem := r.Intn(5000)
+			// mixed times to live, from a tick to beyond the span of the wheel
+			at := now + 1 + r.Int63n(int64(1)<<(6*(1+r.Intn(wheelLevels+1))))
+			w.add(elem, at)
+			expires[elem] = at
+		}
+		if r.Intn(3) == 0 {
+			for elem := range expires {
+				w.remove(elem)
+				delete(expires, elem)
+				break
+			}
+		}
+
+		now += r.Int63n(1 << (6 * (1 + r.Intn(3))))
+		got := w.due(now)
+		var want []int
+		for elem, at := range expires {
+			if at < now-now%w.tick-w.tick+1 {
+				want = append(want, elem)
+			}
+		}
+		for _, elem := range got {
+			delete(expires, elem)
+		}
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("step %d: due(%d) = %v, want %v", step, now, got, want)
+		}
+	}
+	if got, want := len(w.pos), len(expires); got != want {
+		t.Errorf("len(pos) = %v, want %v", got, want)
+	}
+	if w.slotsInUse() == 0 {
+		t.Errorf("slotsInUse() = 0, want the far elements in the wheel")
+	}
+}
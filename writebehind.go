@@ -0,0 +1,376 @@
+// Package cacheset
+//
+// Path: writebehind.go
+//
+// Description: writebehind.go contains the WriteBehind type, a cache whose mutations are queued by the
+// callers and applied by a single goroutine.
+package cacheset
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// writeBehindBatch is the maximum number of mutations applied under a single lock
+const writeBehindBatch = 256
+
+// WriteBehind is a cache whose Add, Delete and Clear enqueue their mutation instead of locking the cache
+//
+// Description: The mutations are pushed to a lock-free multi-producer single-consumer queue and applied in
+// order by a single applier goroutine, in batches locking the cache once. Writers never wait for the lock,
+// at the cost of a small delay before the other methods of the underlying cache see the mutation.
+// Contains looks for the last pending mutation of the element before looking in the cache, so a caller
+// always sees its own writes, while the reads through Cache only see them after Sync. The time to live of
+// an element starts when it is enqueued. Errors of the additions, such as ErrCapacityExceeded, are reported
+// to the error handler.
+type WriteBehind[T comparable] struct {
+	cache   *Cache[T]             // cache is the underlying cache, only mutated by the applier
+	queue   mpscQueue[writeOp[T]] // queue holds the pending mutations
+	pending atomic.Int64          // pending is the number of mutations enqueued but not applied yet
+	wake    chan struct{}         // wake tells the applier that mutations were enqueued
+	close   chan struct{}         // close stops the applier
+	done    chan struct{}         // done is closed when the applier returns
+	once    sync.Once             // once ensures that the write-behind cache is closed once
+}
+
+// writeKind is the kind of a queued mutation
+type writeKind int
+
+const (
+	writeAdd writeKind = iota
+	writeDelete
+	writeClear
+	writeFlush
+)
+
+// writeOp is a queued mutation
+type writeOp[T comparable] struct {
+	kind     writeKind     // kind is the kind of the mutation
+	elem     T             // elem is the added or deleted element
+	expires  int64         // expires is the expiration time of an added element, 0 meaning no expiration
+	negative bool          // negative is true for an addition with a negative duration, see WithNegativeExpiry
+	flushed  chan struct{} // flushed is closed once the mutations enqueued before a flush are applied
+}
+
+// NewWriteBehind creates a new write-behind cache, whose underlying cache asynchronously cleans
+func NewWriteBehind[T comparable](cleanInterval time.Duration, opts ...Option) *WriteBehind[T] {
+	w := &WriteBehind[T]{
+		cache: New[T](cleanInterval, opts...),
+		wake:  make(chan struct{}, 1),
+		close: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	w.queue.init()
+	go w.apply()
+	return w
+}
+
+// Cache returns the underlying cache, which reflects the mutations once they are applied
+func (w *WriteBehind[T]) Cache() *Cache[T] {
+	return w.cache
+}
+
+// Add enqueues the addition of the given element for the given duration, 0 meaning no expiration
+//
+// Description: Add never fails, the error is always nil: a rejected addition is reported to the error handler.
+func (w *WriteBehind[T]) Add(elem T, duration time.Duration) error {
+	op := writeOp[T]{kind: writeAdd, elem: elem}
+	switch {
+	case duration > 0:
+		op.expires = nanotime() + int64(duration)
+	case duration < 0:
+		op.negative = true
+	}
+	w.push(op)
+	return nil
+}
+
+// Delete enqueues the removal of the given element
+func (w *WriteBehind[T]) Delete(elem T) {
+	w.push(writeOp[T]{kind: writeDelete, elem: elem})
+}
+
+// Clear enqueues the removal of all elements
+func (w *WriteBehind[T]) Clear() {
+	w.push(writeOp[T]{kind: writeClear})
+}
+
+// Contains returns true if the given element is in the cache once the pending mutations are applied
+//
+// Description: The pending mutations are visited from the oldest, so Contains is slower while the applier
+// lags behind. An element found in the queue is not counted as a hit of the underlying cache.
+func (w *WriteBehind[T]) Contains(elem T) bool {
+	var found, known bool
+	for op := range w.queue.all() {
+		switch {
+		case op.kind == writeClear:
+			found, known = false, true
+		case op.kind != writeFlush && op.elem == elem:
+			found = op.kind == writeAdd && !expired(op.expires, nanotime()) &&
+				!(op.negative && w.cache.options.negativeExpiry)
+			known = true
+		}
+	}
+	if known {
+		return found
+	}
+	return w.cache.Contains(elem)
+}
+
+// Pending returns the number of mutations enqueued but not applied yet
+func (w *WriteBehind[T]) Pending() int {
+	return int(w.pending.Load())
+}
+
+// Flush waits until the mutations enqueued before the call are applied, see Sync
+func (w *WriteBehind[T]) Flush() {
+	w.Sync()
+}
+
+// Sync blocks until the mutations enqueued before the call, by any goroutine, are applied to the underlying cache
+//
+// Description: Sync is the read-your-writes barrier of the write-behind cache. The applier applies a batch,
+// then decrements the number of pending mutations and closes the channels of the barriers of the batch: Sync
+// either reads that number as 0 or receives from its channel, so the mutations it waited for happen before
+// its return in the sense of the Go memory model. Once Sync returns, the reads of the calling goroutine through
+// Cache see its previous writes, and so do the reads of the goroutines it then communicates with. Sync
+// returns at once if the write-behind cache is closed.
+func (w *WriteBehind[T]) Sync() {
+	if w.pending.Load() == 0 {
+		return
+	}
+	flushed := make(chan struct{})
+	w.push(writeOp[T]{kind: writeFlush, flushed: flushed})
+	select {
+	case <-flushed:
+	case <-w.done:
+	}
+}
+
+// Len returns the number of elements in the cache, after flushing the pending mutations
+func (w *WriteBehind[T]) Len() int {
+	w.Flush()
+	return w.cache.Len()
+}
+
+// ToSlice returns a slice of all elements in the cache, after flushing the pending mutations
+func (w *WriteBehind[T]) ToSlice() []T {
+	w.Flush()
+	return w.cache.ToSlice()
+}
+
+// ExpireAll expires all elements in the cache, after flushing the pending mutations
+func (w *WriteBehind[T]) ExpireAll() {
+	w.Flush()
+	w.cache.ExpireAll()
+}
+
+// Stats returns the counters of the underlying cache, after flushing the pending mutations
+func (w *WriteBehind[T]) Stats() Stats {
+	w.Flush()
+	return w.cache.Stats()
+}
+
+// Close applies the pending mutations, then stops the applier and closes the underlying cache
+//
+// Description: The mutations enqueued after Close are never applied.
+func (w *WriteBehind[T]) Close() {
+	if err := w.Shutdown(context.Background()); err != nil {
+		w.cache.report(err)
+	}
+}
+
+// Shutdown applies the pending mutations, then stops the applier and gracefully closes the underlying
+// cache before ctx is done, see Cache.Shutdown
+func (w *WriteBehind[T]) Shutdown(ctx context.Context) error {
+	var err error
+	w.once.Do(func() {
+		close(w.close)
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			err = fmt.Errorf("cacheset: close abandoned %d pending mutations: %w", w.Pending(), ctx.Err())
+			return
+		}
+		err = w.cache.Shutdown(ctx)
+	})
+	return err
+}
+
+// push enqueues the given mutation and wakes the applier
+func (w *WriteBehind[T]) push(op writeOp[T]) {
+	w.pending.Add(1)
+	w.queue.push(op)
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// apply applies the queued mutations until the write-behind cache is closed, then applies the remaining ones
+func (w *WriteBehind[T]) apply() {
+	defer close(w.done)
+
+	for {
+		select {
+		case <-w.wake:
+			w.drain()
+		case <-w.close:
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain applies the queued mutations in batches until the queue is empty
+func (w *WriteBehind[T]) drain() {
+	batch := make([]writeOp[T], 0, writeBehindBatch)
+	for {
+		batch = batch[:0]
+		for len(batch) < writeBehindBatch {
+			op, ok := w.queue.peek()
+			if !ok {
+				break
+			}
+			batch = append(batch, op)
+			w.queue.advance()
+		}
+		if len(batch) == 0 {
+			return
+		}
+		w.applyBatch(batch)
+		w.queue.commit()
+		w.pending.Add(-int64(len(batch)))
+		for _, op := range batch {
+			if op.kind == writeFlush {
+				close(op.flushed)
+			}
+		}
+	}
+}
+
+// applyBatch applies the given mutations to the underlying cache under a single lock
+func (w *WriteBehind[T]) applyBatch(batch []writeOp[T]) {
+	c := w.cache
+	var rejected []error
+	defer func() {
+		for _, err := range rejected {
+			c.report(err)
+		}
+	}()
+	defer c.spend()
+	c.Lock()
+	defer c.Unlock()
+
+	now := nanotime()
+	var added uint64
+	for _, op := range batch {
+		switch op.kind {
+		case writeAdd:
+			var ttl time.Duration
+			switch {
+			case op.expires != 0:
+				ttl = time.Duration(max(op.expires-now, 1))
+			case op.negative:
+				ttl = -1
+			}
+			if ok, err := c.accept(op.elem, ttl, 0); !ok {
+				if err != nil {
+					rejected = append(rejected, fmt.Errorf("cacheset: write-behind addition rejected: %w", err))
+				}
+				continue
+			}
+			c.put(op.elem, ttl, 0)
+			c.recordHot(op.elem)
+			c.recordUnique(op.elem)
+			added++
+		case writeDelete:
+			if c.set.Contains(op.elem) {
+				c.remove(op.elem, RemovalDeleted)
+			}
+		case writeClear:
+			c.clear()
+		}
+	}
+	c.stats.add(statAdds, added)
+}
+
+// mpscQueue is an unbounded lock-free multi-producer single-consumer queue
+//
+// Description: The producers swap the head and link the previous head to their node. The consumer reads
+// the nodes following its cursor, and publishes the cursor once their values are applied, so that the
+// readers walking the queue from the published cursor see every value not applied yet.
+type mpscQueue[V any] struct {
+	head      atomic.Pointer[mpscNode[V]] // head is the last pushed node
+	committed atomic.Pointer[mpscNode[V]] // committed is the last applied node, whose successors are pending
+	cursor    *mpscNode[V]                // cursor is the last node read by the consumer
+}
+
+// mpscNode is a node of an mpscQueue
+type mpscNode[V any] struct {
+	next  atomic.Pointer[mpscNode[V]] // next is the following node, nil until it is linked
+	value V                           // value is the value of the node, unused for the initial node
+}
+
+// init creates the initial node of the queue
+func (q *mpscQueue[V]) init() {
+	stub := &mpscNode[V]{}
+	q.head.Store(stub)
+	q.committed.Store(stub)
+	q.cursor = stub
+}
+
+// push appends the given value, it can be called concurrently
+func (q *mpscQueue[V]) push(v V) {
+	n := &mpscNode[V]{value: v}
+	prev := q.head.Swap(n)
+	prev.next.Store(n)
+}
+
+// peek returns the value following the consumer's cursor, false if there is none yet
+func (q *mpscQueue[V]) peek() (V, bool) {
+	next := q.cursor.next.Load()
+	if next == nil {
+		var zero V
+		return zero, false
+	}
+	return next.value, true
+}
+
+// advance moves the consumer's cursor to the next node, which must exist
+func (q *mpscQueue[V]) advance() {
+	q.cursor = q.cursor.next.Load()
+}
+
+// commit publishes the consumer's cursor, the values read so far are not visited by all anymore
+func (q *mpscQueue[V]) commit() {
+	q.committed.Store(q.cursor)
+}
+
+// all returns the values pushed but not committed, oldest first, it can be called concurrently
+//
+// Description: The walk stops at the head loaded when it starts, so that it visits every value pushed before
+// the call. A producer links its node after swapping the head: the walk yields until the missing links are
+// stored, instead of stopping at them and missing the values pushed after them.
+func (q *mpscQueue[V]) all() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		n := q.committed.Load()
+		last := q.head.Load()
+		for n != last {
+			next := n.next.Load()
+			if next == nil {
+				runtime.Gosched()
+				continue
+			}
+			n = next
+			if !yield(n.value) {
+				return
+			}
+		}
+	}
+}
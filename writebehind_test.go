@@ -0,0 +1,155 @@
+package cacheset
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteBehind(t *testing.T) {
+	w := NewWriteBehind[int64](time.Hour)
+	defer w.Close()
+
+	w.Add(1, 0)
+	w.Add(2, 0)
+	w.Add(3, time.Millisecond)
+	w.Delete(2)
+	if !w.Contains(1) || w.Contains(2) {
+		t.Errorf("Contains() = %v, %v, want %v, %v", w.Contains(1), w.Contains(2), true, false)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	w.Flush()
+	w.Cache().ExpireAll()
+	if w.Contains(3) {
+		t.Errorf("Contains(3) = %v, want %v", true, false)
+	}
+	if got := w.ToSlice(); !slices.Equal(got, []int64{1}) {
+		t.Errorf("ToSlice() = %v, want %v", got, []int64{1})
+	}
+	if w.Pending() != 0 || w.Len() != 1 || !w.Cache().Contains(1) {
+		t.Errorf("Pending() = %v, Len() = %v, want %v, %v", w.Pending(), w.Len(), 0, 1)
+	}
+
+	w.Clear()
+	w.Add(4, 0)
+	if w.Contains(1) || !w.Contains(4) {
+		t.Errorf("Contains() = %v, %v, want %v, %v", w.Contains(1), w.Contains(4), false, true)
+	}
+	if n, st := w.Len(), w.Stats(); n != 1 || st.Adds != 4 || st.Deletes != 2 {
+		t.Errorf("Len() = %v, Stats() = %+v, want %v element, %v adds and %v deletes", n, st, 1, 4, 2)
+	}
+}
+
+func TestWriteBehind_Rejected(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	w := NewWriteBehind[int64](time.Hour, WithCapacity(1), WithOverflowPolicy(OverflowReject), WithErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	defer w.Close()
+
+	if err := w.Add(1, 0); err != nil {
+		t.Errorf("Add() error = %v, want nil", err)
+	}
+	w.Add(2, 0)
+	w.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrCapacityExceeded) {
+		t.Errorf("reported errors = %v, want %v", errs, ErrCapacityExceeded)
+	}
+}
+
+func TestWriteBehind_Concurrent(t *testing.T) {
+	w := NewWriteBehind[int64](time.Hour)
+
+	var wg sync.WaitGroup
+	for g := int64(0); g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := g * 1000; i < (g+1)*1000; i++ {
+				w.Add(i, 0)
+				if !w.Contains(i) {
+					t.Errorf("Contains(%v) = %v, want %v", i, false, true)
+				}
+				if i%2 == 1 {
+					w.Delete(i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	defer w.Close()
+
+	if got := w.Len(); got != 4000 {
+		t.Errorf("Len() = %v, want %v", got, 4000)
+	}
+}
+
+func TestWriteBehind_Sync(t *testing.T) {
+	w := NewWriteBehind[int64](time.Hour)
+	defer w.Close()
+
+	t.Run("ReadYourWrites", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for g := int64(0); g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := g * 100; i < (g+1)*100; i++ {
+					w.Add(i, 0)
+					w.Sync()
+					if !w.Cache().Contains(i) {
+						t.Errorf("Cache().Contains(%v) = false after Sync, want true", i)
+					}
+					w.Delete(i)
+					w.Sync()
+					if w.Cache().Contains(i) {
+						t.Errorf("Cache().Contains(%v) = true after Delete and Sync, want false", i)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("HandOff", func(t *testing.T) {
+		handoff := make(chan int64)
+		go func() {
+			defer close(handoff)
+			for i := int64(1000); i < 1100; i++ {
+				w.Add(i, 0)
+				w.Sync()
+				handoff <- i
+			}
+		}()
+		for i := range handoff {
+			if !w.Cache().Contains(i) {
+				t.Errorf("Cache().Contains(%v) = false after the writer's Sync, want true", i)
+			}
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		c := NewWriteBehind[int64](time.Hour)
+		c.Close()
+		c.Add(1, 0)
+		done := make(chan struct{})
+		go func() {
+			c.Sync()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Sync() blocked on a closed write-behind cache")
+		}
+	})
+}